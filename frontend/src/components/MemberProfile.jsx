@@ -15,6 +15,8 @@ const STATUS_MAP = {
   CANCELLED: { label: 'Cancelada',  cls: 'bg-red-100 text-red-800' },
   EXPIRED:   { label: 'Expirada',   cls: 'bg-gray-100 text-gray-600' },
   FROZEN:    { label: 'Congelada',  cls: 'bg-blue-100 text-blue-800' },
+  PLAN_CHANGED: { label: 'Cambio de plan', cls: 'bg-gray-100 text-gray-600' },
+  TRANSFERRED:  { label: 'Traspasada', cls: 'bg-gray-100 text-gray-600' },
 };
 
 function fmtDate(d) {
@@ -37,6 +37,8 @@ const STATUS_MAP = {
   CANCELLED: { label: 'Cancelada',  cls: 'bg-[#FEE2E2] text-[#DC2626]' },
   EXPIRED:   { label: 'Expirada',   cls: 'bg-[#F1F5F9] text-[#64748B]' },
   FROZEN:    { label: 'Congelada',  cls: 'bg-[#EBF3FF] text-[#1272D6]' },
+  PLAN_CHANGED: { label: 'Cambio de plan', cls: 'bg-[#F1F5F9] text-[#64748B]' },
+  TRANSFERRED:  { label: 'Traspasada', cls: 'bg-[#F1F5F9] text-[#64748B]' },
 };
 
 const StatusBadge = ({ status }) => {
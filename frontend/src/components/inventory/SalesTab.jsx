@@ -96,7 +96,7 @@ export default function SalesTab({ user }) {
       setCart([...cart, {
         product_id: product.id,
         product_name: product.name,
-        unit_price: product.unit_price,
+        unit_price: product.price_with_tax ?? product.unit_price,
         quantity: 1,
         discount: 0,
         max_stock: product.stock
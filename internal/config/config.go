@@ -16,6 +16,18 @@ type Config struct {
 	JWT      JWTConfig
 	App      AppConfig
 	SMTP     SMTPConfig
+	Gateway  GatewayConfig
+}
+
+// GatewayConfig holds the online payment gateway configuration. Provider is
+// "mercadopago", "fake" (for development: it charges nothing) or empty, which
+// leaves online payments off. Either gateway needs WebhookSecret, which has no
+// default.
+type GatewayConfig struct {
+	Provider               string
+	PublicURL              string // where the server is reachable from outside, for the webhook
+	WebhookSecret          string
+	MercadoPagoAccessToken string
 }
 
 // SMTPConfig holds email configuration
@@ -50,6 +62,12 @@ type AppConfig struct {
 	Name            string
 	Version         string
 	DefaultTimezone string // fallback when gym has no timezone configured
+
+	// AutoRenewGrace is how far ahead of its end date an auto-renew subscription
+	// gets its next period. It has to be wider than the hourly job's interval: a
+	// member renewed after their end date would already have been expired, and
+	// refused at the turnstile, by the pass that runs right after the renewal.
+	AutoRenewGrace time.Duration
 }
 
 // LoadConfig loads configuration from environment variables
@@ -85,6 +103,7 @@ func LoadConfig() *Config {
 			Name:            getEnv("APP_NAME", "Gym-Go"),
 			Version:         getEnv("APP_VERSION", "1.0.0"),
 			DefaultTimezone: getEnv("DEFAULT_TIMEZONE", "America/Bogota"),
+			AutoRenewGrace:  getDurationEnv("AUTO_RENEW_GRACE", 48*time.Hour),
 		},
 		SMTP: SMTPConfig{
 			Host:     getEnv("SMTP_HOST", ""),
@@ -93,6 +112,12 @@ func LoadConfig() *Config {
 			Password: getEnv("SMTP_PASSWORD", ""),
 			From:     getEnv("SMTP_FROM", ""),
 		},
+		Gateway: GatewayConfig{
+			Provider:               getEnv("PAYMENT_GATEWAY", ""),
+			PublicURL:              getEnv("PUBLIC_URL", "http://localhost:8080"),
+			WebhookSecret:          getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+			MercadoPagoAccessToken: getEnv("MERCADOPAGO_ACCESS_TOKEN", ""),
+		},
 	}
 }
 
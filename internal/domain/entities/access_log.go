@@ -46,6 +46,9 @@ type AccessLog struct {
 	AccessMethod   AccessLogMethod `json:"access_method"`
 	Status         AccessLogStatus `json:"status"`
 	DenialReason   string          `json:"denial_reason,omitempty"`
+	// Warning is for reception on an entry that was granted anyway, such as an
+	// overdue balance under Gym.OverdueBalancePolicy WARN.
+	Warning        string          `json:"warning,omitempty"`
 	SubscriptionID *uuid.UUID      `json:"subscription_id,omitempty"`
 	Temperature    *float64        `json:"temperature,omitempty"`
 	PhotoURL       string          `json:"photo_url,omitempty"`
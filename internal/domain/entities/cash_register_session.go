@@ -0,0 +1,98 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CashRegisterStatus represents the status of a register session
+type CashRegisterStatus string
+
+const (
+	CashRegisterStatusOpen   CashRegisterStatus = "OPEN"
+	CashRegisterStatusClosed CashRegisterStatus = "CLOSED"
+)
+
+// CashRegisterSession is a receptionist's shift at the till: it opens with the
+// float left in the drawer and closes with the cash counted at the end. The
+// payments the receptionist takes while it is open point to it through
+// Payment.CashRegisterID, so at the close the cash that should be in the drawer
+// is known and whoever is short is known too.
+//
+// A user has at most one open session. Date/Hour are when it was opened, local
+// to the gym like Sale.Date.
+type CashRegisterSession struct {
+	ID           uuid.UUID          `json:"id"`
+	GymID        uuid.UUID          `json:"gym_id" gorm:"index"`
+	UserID       uuid.UUID          `json:"user_id" gorm:"index"`
+	UserName     string             `json:"user_name"`
+	Status       CashRegisterStatus `json:"status" gorm:"index"`
+	OpeningFloat float64            `json:"opening_float"`
+	OpenedAt     time.Time          `json:"opened_at"`
+	Date         string             `json:"date"`
+	Hour         string             `json:"hour"`
+	// Filled in at the close. ExpectedCash is OpeningFloat plus the cash taken
+	// minus the cash given back; Variance is CountedCash - ExpectedCash, so a
+	// negative variance is money missing from the drawer.
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	CashIn       float64    `json:"cash_in"`
+	CashOut      float64    `json:"cash_out"`
+	ExpectedCash float64    `json:"expected_cash"`
+	CountedCash  float64    `json:"counted_cash"`
+	Variance     float64    `json:"variance"`
+	Notes        string     `json:"notes,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// NewCashRegisterSession opens a session for userID with openingFloat in the
+// drawer.
+func NewCashRegisterSession(gymID, userID uuid.UUID, userName string, openingFloat float64, loc *time.Location) (*CashRegisterSession, error) {
+	if openingFloat < 0 {
+		return nil, fmt.Errorf("la base de caja no puede ser negativa")
+	}
+	now := time.Now().UTC().Round(0)
+	local := now.In(loc)
+	return &CashRegisterSession{
+		ID:           uuid.New(),
+		GymID:        gymID,
+		UserID:       userID,
+		UserName:     userName,
+		Status:       CashRegisterStatusOpen,
+		OpeningFloat: openingFloat,
+		OpenedAt:     now,
+		Date:         local.Format("2006-01-02"),
+		Hour:         local.Format("15:04"),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}, nil
+}
+
+// IsOpen checks if the session is still taking payments
+func (s *CashRegisterSession) IsOpen() bool {
+	return s.Status == CashRegisterStatusOpen
+}
+
+// Close closes the session with the cash counted in the drawer. cashIn and
+// cashOut are the cash taken and given back while it was open.
+func (s *CashRegisterSession) Close(cashIn, cashOut, counted float64, notes string) error {
+	if !s.IsOpen() {
+		return fmt.Errorf("la caja ya está cerrada")
+	}
+	if counted < 0 {
+		return fmt.Errorf("el efectivo contado no puede ser negativo")
+	}
+	now := time.Now().UTC().Round(0)
+	s.Status = CashRegisterStatusClosed
+	s.ClosedAt = &now
+	s.CashIn = cashIn
+	s.CashOut = cashOut
+	s.ExpectedCash = s.OpeningFloat + cashIn - cashOut
+	s.CountedCash = counted
+	s.Variance = counted - s.ExpectedCash
+	s.Notes = notes
+	s.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,235 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// CouponKind is how a coupon's Value is read.
+type CouponKind string
+
+const (
+	CouponKindPercent CouponKind = "PERCENT" // Value is a percentage, 0-100
+	CouponKindFixed   CouponKind = "FIXED"   // Value is an amount of money
+)
+
+// CouponScope is what a coupon can be redeemed on.
+type CouponScope string
+
+const (
+	CouponScopeAll           CouponScope = "ALL"
+	CouponScopeSubscriptions CouponScope = "SUBSCRIPTIONS"
+	CouponScopeSales         CouponScope = "SALES"
+)
+
+// Coupon is a promo code the gym hands out. It replaces the discount typed in
+// by hand at the counter: what it takes off, when, how often and on what is
+// decided once, here, by whoever creates it.
+//
+// ValidFrom/ValidUntil are both inclusive instants; the handler turns the dates
+// it receives into the start and the end of those days in the gym's timezone.
+// PlanIDs/ProductIDs narrow the scope to some plans or products; empty means
+// all of them. MaxUses and MaxUsesPerMember are 0 for no limit. TimesUsed only
+// goes up, through CouponRepository.IncrementUses, so the overall limit holds
+// with two tills redeeming the last use at once.
+type Coupon struct {
+	ID               uuid.UUID   `json:"id"`
+	GymID            uuid.UUID   `json:"gym_id" gorm:"uniqueIndex:idx_coupons_gym_code,priority:1"`
+	Code             string      `json:"code" gorm:"uniqueIndex:idx_coupons_gym_code,priority:2"`
+	Description      string      `json:"description,omitempty"`
+	Kind             CouponKind  `json:"kind"`
+	Value            float64     `json:"value"`
+	Scope            CouponScope `json:"scope"`
+	PlanIDs          []uuid.UUID `json:"plan_ids,omitempty" gorm:"serializer:json"`
+	ProductIDs       []uuid.UUID `json:"product_ids,omitempty" gorm:"serializer:json"`
+	ValidFrom        *time.Time  `json:"valid_from,omitempty"`
+	ValidUntil       *time.Time  `json:"valid_until,omitempty"`
+	MaxUses          int         `json:"max_uses"`
+	MaxUsesPerMember int         `json:"max_uses_per_member"`
+	TimesUsed        int         `json:"times_used"`
+	Status           string      `json:"status"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// NewCoupon creates an active coupon usable on anything, with no limits.
+func NewCoupon(gymID uuid.UUID, code string, kind CouponKind, value float64) *Coupon {
+	now := time.Now().UTC().Round(0)
+	return &Coupon{
+		ID:        uuid.New(),
+		GymID:     gymID,
+		Code:      NormalizeCouponCode(code),
+		Kind:      kind,
+		Value:     value,
+		Scope:     CouponScopeAll,
+		Status:    "ACTIVE",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// NormalizeCouponCode is how codes are stored and looked up: reception types
+// "verano25" as often as "VERANO25".
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks the coupon's rules before it is stored.
+func (c *Coupon) Validate() error {
+	if c.Code == "" {
+		return fmt.Errorf("el cupón necesita un código")
+	}
+	switch c.Kind {
+	case CouponKindPercent:
+		if c.Value <= 0 || c.Value > 100 {
+			return fmt.Errorf("el porcentaje del cupón debe estar entre 0 y 100")
+		}
+	case CouponKindFixed:
+		if c.Value <= 0 {
+			return fmt.Errorf("el valor del cupón debe ser mayor que cero")
+		}
+	default:
+		return fmt.Errorf("tipo de cupón inválido %q, use PERCENT o FIXED", c.Kind)
+	}
+	switch c.Scope {
+	case CouponScopeAll, CouponScopeSubscriptions, CouponScopeSales:
+	default:
+		return fmt.Errorf("alcance de cupón inválido %q", c.Scope)
+	}
+	if c.MaxUses < 0 || c.MaxUsesPerMember < 0 {
+		return fmt.Errorf("los límites de uso no pueden ser negativos")
+	}
+	if c.ValidFrom != nil && c.ValidUntil != nil && !c.ValidFrom.Before(*c.ValidUntil) {
+		return fmt.Errorf("la vigencia del cupón termina antes de empezar")
+	}
+	return nil
+}
+
+// CheckUsable tells whether the coupon can be redeemed at now. The per-member
+// limit needs the redemptions and is checked by the caller.
+func (c *Coupon) CheckUsable(now time.Time) error {
+	switch {
+	case c.Status != "ACTIVE":
+		return fmt.Errorf("%w: el cupón %s está desactivado", errors.ErrInvalidCoupon, c.Code)
+	case c.ValidFrom != nil && now.Before(*c.ValidFrom):
+		return fmt.Errorf("%w: el cupón %s todavía no está vigente", errors.ErrInvalidCoupon, c.Code)
+	case c.ValidUntil != nil && now.After(*c.ValidUntil):
+		return fmt.Errorf("%w: el cupón %s ya venció", errors.ErrInvalidCoupon, c.Code)
+	case c.MaxUses > 0 && c.TimesUsed >= c.MaxUses:
+		return fmt.Errorf("%w: el cupón %s ya se usó las %d veces permitidas", errors.ErrInvalidCoupon, c.Code, c.MaxUses)
+	}
+	return nil
+}
+
+// AppliesToPlan reports whether the coupon can be redeemed on a subscription
+// to planID.
+func (c *Coupon) AppliesToPlan(planID uuid.UUID) bool {
+	if c.Scope == CouponScopeSales {
+		return false
+	}
+	return len(c.PlanIDs) == 0 || containsID(c.PlanIDs, planID)
+}
+
+// AppliesToProduct reports whether the coupon discounts productID in a sale.
+func (c *Coupon) AppliesToProduct(productID uuid.UUID) bool {
+	if c.Scope == CouponScopeSubscriptions {
+		return false
+	}
+	return len(c.ProductIDs) == 0 || containsID(c.ProductIDs, productID)
+}
+
+// DiscountOn is what the coupon takes off base, never more than base.
+func (c *Coupon) DiscountOn(base float64) float64 {
+	if base <= 0 {
+		return 0
+	}
+	discount := c.Value
+	if c.Kind == CouponKindPercent {
+		discount = math.Round(base * c.Value / 100)
+	}
+	return math.Min(discount, base)
+}
+
+// ApplyToSale adds the coupon's discount to the lines it applies to and returns
+// the total taken off. A fixed amount is spread over those lines in proportion
+// to their subtotal, the rounding remainder going to the last one; no line goes
+// below zero, so every line still passes SaleDetail.Validate. Subtotals must
+// already be calculated.
+func (c *Coupon) ApplyToSale(details []SaleDetail) float64 {
+	var eligible []int
+	base := 0.0
+	for i := range details {
+		if c.AppliesToProduct(details[i].ProductID) {
+			eligible = append(eligible, i)
+			base += details[i].Subtotal
+		}
+	}
+	total := c.DiscountOn(base)
+	if total == 0 {
+		return 0
+	}
+
+	applied := 0.0
+	for n, i := range eligible {
+		d := &details[i]
+		share := total - applied
+		if n < len(eligible)-1 {
+			share = math.Round(total * d.Subtotal / base)
+		}
+		share = math.Min(share, d.Subtotal)
+		d.Discount += share
+		d.CalculateSubtotal()
+		applied += share
+	}
+	return applied
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CouponRedemption records one use of a coupon: on which subscription or sale,
+// for which member and how much it took off. Date/Hour are local to the gym, so
+// the daily close can list the redemptions of the day by string range.
+type CouponRedemption struct {
+	ID             uuid.UUID  `json:"id"`
+	CouponID       uuid.UUID  `json:"coupon_id" gorm:"index:idx_coupon_redemptions_coupon_user,priority:1"`
+	GymID          uuid.UUID  `json:"gym_id" gorm:"index:idx_coupon_redemptions_gym_date,priority:1"`
+	Code           string     `json:"code"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" gorm:"index:idx_coupon_redemptions_coupon_user,priority:2"`
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"`
+	SaleID         *uuid.UUID `json:"sale_id,omitempty"`
+	Amount         float64    `json:"amount"`
+	RedeemedBy     uuid.UUID  `json:"redeemed_by"`
+	Date           string     `json:"date" gorm:"index:idx_coupon_redemptions_gym_date,priority:2"`
+	Hour           string     `json:"hour"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// NewCouponRedemption builds the redemption of coupon for amount, dated at now
+// in loc. The caller sets the subscription or sale it belongs to.
+func NewCouponRedemption(coupon *Coupon, userID *uuid.UUID, amount float64, redeemedBy uuid.UUID, now time.Time, loc *time.Location) *CouponRedemption {
+	localNow := now.In(loc)
+	return &CouponRedemption{
+		ID:         uuid.New(),
+		CouponID:   coupon.ID,
+		GymID:      coupon.GymID,
+		Code:       coupon.Code,
+		UserID:     userID,
+		Amount:     amount,
+		RedeemedBy: redeemedBy,
+		Date:       localNow.Format("2006-01-02"),
+		Hour:       localNow.Format("15:04"),
+		CreatedAt:  now.UTC().Round(0),
+	}
+}
@@ -17,6 +17,9 @@ const (
 	DeviceTypeTablet      DeviceType = "TABLET"
 	DeviceTypeKiosk       DeviceType = "KIOSK"
 	DeviceTypeRelay       DeviceType = "RELAY"
+	// DeviceTypeReceiptPrinter is an ESC/POS thermal printer on a COM port,
+	// where the receipts of the sales and subscriptions are printed.
+	DeviceTypeReceiptPrinter DeviceType = "RECEIPT_PRINTER"
 )
 
 // DeviceStatus represents device status
@@ -47,6 +50,7 @@ type Device struct {
 	Notes           string       `json:"notes,omitempty"`
 	COMPort         string       `json:"com_port,omitempty"`
 	BaudRate        int          `json:"baud_rate,omitempty"`
+	PaperWidth      int          `json:"paper_width,omitempty"` // mm, receipt printers only: 58 or 80 (default)
 	CreatedAt       time.Time    `json:"created_at"`
 	UpdatedAt       time.Time    `json:"updated_at"`
 }
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,6 +31,14 @@ type Gym struct {
 	SMTPUsername string `json:"smtp_username,omitempty"`
 	SMTPPassword string `json:"smtp_password,omitempty"`
 	SMTPFrom     string `json:"smtp_from,omitempty"`
+	// Cancellation refunds (see CancellationRefund)
+	RefundPolicy     RefundPolicy `json:"refund_policy" gorm:"default:NONE"`
+	RefundWindowDays int          `json:"refund_window_days"`
+	// Overdue installment balances at check-in (see Subscription.BalanceOverdue)
+	OverdueBalancePolicy    OverdueBalancePolicy `json:"overdue_balance_policy" gorm:"default:WARN"`
+	OverdueBalanceGraceDays int                  `json:"overdue_balance_grace_days"`
+	// Days the gym is closed (see GymClosure)
+	ClosureCompensation ClosureCompensation `json:"closure_compensation" gorm:"default:NONE"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -51,5 +60,81 @@ func NewGym(name, email, phone string) *Gym {
 	}
 }
 
+// RefundPolicy decides how much of a subscription goes back to the member when
+// it is cancelled.
+type RefundPolicy string
 
+const (
+	RefundPolicyNone     RefundPolicy = "NONE"
+	RefundPolicyFull     RefundPolicy = "FULL"
+	RefundPolicyProrated RefundPolicy = "PRORATED"
+)
+
+// IsValid reports whether p is one of the known policies.
+func (p RefundPolicy) IsValid() bool {
+	switch p {
+	case RefundPolicyNone, RefundPolicyFull, RefundPolicyProrated:
+		return true
+	}
+	return false
+}
+
+// CancellationRefund returns what the gym owes a member who cancels sub at now.
+//
+// Only what paid for time is refundable: the enrollment fee pays for joining
+// and stays with the gym under every policy. Past RefundWindowDays from the
+// start of the period nothing is refunded (0 means no window). PRORATED gives
+// back the unused days, the same credit ChangePlan applies to a new plan, and
+// never more than was actually charged: of a subscription sold in installments,
+// only what has been paid so far.
+func (g *Gym) CancellationRefund(sub *Subscription, now time.Time) float64 {
+	refundable := sub.AmountPaid - sub.EnrollmentFeePaid
+	if refundable <= 0 {
+		return 0
+	}
+	if g.RefundWindowDays > 0 && now.After(sub.StartDate.AddDate(0, 0, g.RefundWindowDays)) {
+		return 0
+	}
+
+	switch g.RefundPolicy {
+	case RefundPolicyFull:
+		return refundable
+	case RefundPolicyProrated:
+		return math.Min(sub.UnusedCredit(now), refundable)
+	default:
+		return 0
+	}
+}
 
+// OverdueBalancePolicy decides what happens at the door when a member still owes
+// part of a subscription past its due date (plus the gym's grace days).
+type OverdueBalancePolicy string
+
+const (
+	OverdueBalanceIgnore OverdueBalancePolicy = "NONE"
+	OverdueBalanceWarn   OverdueBalancePolicy = "WARN" // let in, but tell reception
+	OverdueBalanceDeny   OverdueBalancePolicy = "DENY"
+)
+
+// IsValid reports whether p is one of the known policies.
+func (p OverdueBalancePolicy) IsValid() bool {
+	switch p {
+	case OverdueBalanceIgnore, OverdueBalanceWarn, OverdueBalanceDeny:
+		return true
+	}
+	return false
+}
+
+// ClosureCompensation decides whether members get back the days the gym is
+// closed.
+type ClosureCompensation string
+
+const (
+	ClosureCompensationNone   ClosureCompensation = "NONE"
+	ClosureCompensationExtend ClosureCompensation = "EXTEND" // one more day per closed day
+)
+
+// IsValid reports whether c is one of the known settings.
+func (c ClosureCompensation) IsValid() bool {
+	return c == ClosureCompensationNone || c == ClosureCompensationExtend
+}
@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/pkg/timeutil"
+)
+
+// GymClosure is a day the gym does not open: a public holiday, maintenance.
+// Date is the local day (YYYY-MM-DD in the gym's timezone), like the Date of a
+// sale, so "is it closed today" is a string match and not a UTC range.
+//
+// CompensatedAt is set once the members were given the day back (see
+// Gym.ClosureCompensation). A compensated closure stays: deleting it would not
+// take the day back from anyone.
+type GymClosure struct {
+	ID            uuid.UUID  `json:"id"`
+	GymID         uuid.UUID  `json:"gym_id" gorm:"uniqueIndex:idx_gym_closures_gym_date,priority:1"`
+	Date          string     `json:"date" gorm:"uniqueIndex:idx_gym_closures_gym_date,priority:2"`
+	Reason        string     `json:"reason"`
+	CreatedBy     uuid.UUID  `json:"created_by"`
+	CompensatedAt *time.Time `json:"compensated_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NewGymClosure creates a closure of the gym on date, a YYYY-MM-DD local day.
+func NewGymClosure(gymID uuid.UUID, date, reason string, createdBy uuid.UUID) (*GymClosure, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("fecha de cierre inválida %q, use YYYY-MM-DD", date)
+	}
+	return &GymClosure{
+		ID:        uuid.New(),
+		GymID:     gymID,
+		Date:      date,
+		Reason:    reason,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC().Round(0),
+	}, nil
+}
+
+// Bounds returns the first and last instant of the closed day in loc, as UTC.
+func (c *GymClosure) Bounds(loc *time.Location) (start, end time.Time) {
+	start, _ = timeutil.ParseLocalDate(c.Date, loc)
+	return start, timeutil.EndOfDay(start, loc)
+}
+
+// DenialReason is what reception sees when a member tries to check in.
+func (c *GymClosure) DenialReason() string {
+	if c.Reason == "" {
+		return "Gimnasio cerrado hoy"
+	}
+	return "Gimnasio cerrado hoy: " + c.Reason
+}
@@ -0,0 +1,188 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceStatus represents where an invoice is in its trip to the DIAN
+type InvoiceStatus string
+
+const (
+	InvoiceStatusIssued   InvoiceStatus = "ISSUED"   // Numbered and generated, not sent yet
+	InvoiceStatusAccepted InvoiceStatus = "ACCEPTED" // Validated by the DIAN
+	InvoiceStatusRejected InvoiceStatus = "REJECTED"
+)
+
+// InvoiceSource is what an invoice bills: a sale of the shop or a payment of a
+// membership.
+type InvoiceSource string
+
+const (
+	InvoiceSourceSale    InvoiceSource = "SALE"
+	InvoiceSourcePayment InvoiceSource = "PAYMENT"
+)
+
+// Consumidor final: the buyer the DIAN accepts when the invoice is not issued
+// to anyone in particular.
+const (
+	FinalConsumerDocument = "222222222222"
+	FinalConsumerName     = "Consumidor final"
+)
+
+// Invoice is a DIAN electronic invoice (factura electrónica de venta) for a sale
+// or a membership payment. It is immutable once issued: the number, the totals
+// and the CUFE are fixed, and XML keeps the UBL exactly as it was generated, the
+// document that is sent and the one the CUFE vouches for.
+//
+// SaleID and PaymentID are unique, so a source is invoiced once however many
+// times it is asked for.
+type Invoice struct {
+	ID               uuid.UUID     `json:"id"`
+	GymID            uuid.UUID     `json:"gym_id" gorm:"index:idx_invoices_gym_date,priority:1"`
+	ResolutionID     uuid.UUID     `json:"resolution_id"`
+	Prefix           string        `json:"prefix"`
+	Number           int64         `json:"number"`
+	FullNumber       string        `json:"full_number"` // Prefix + Number, the ID of the UBL
+	Source           InvoiceSource `json:"source"`
+	SaleID           *uuid.UUID    `json:"sale_id,omitempty" gorm:"uniqueIndex"`
+	PaymentID        *uuid.UUID    `json:"payment_id,omitempty" gorm:"uniqueIndex"`
+	CustomerID       *uuid.UUID    `json:"customer_id,omitempty"`
+	CustomerName     string        `json:"customer_name"`
+	CustomerDocType  string        `json:"customer_doc_type"`
+	CustomerDocument string        `json:"customer_document"`
+	CustomerEmail    string        `json:"customer_email,omitempty"`
+	Currency         string        `json:"currency"`
+	Subtotal         float64       `json:"subtotal"` // Before discounts
+	Discount         float64       `json:"discount"`
+	TaxTotal         float64       `json:"tax_total"`
+	Total            float64       `json:"total"`
+	IssueDate        string        `json:"issue_date" gorm:"index:idx_invoices_gym_date,priority:2"` // YYYY-MM-DD, local to the gym
+	IssueTime        string        `json:"issue_time"`                                               // HH:MM:SS-05:00
+	CUFE             string        `json:"cufe"`
+	XML              string        `json:"-"`
+	Status           InvoiceStatus `json:"status"`
+	TrackID          string        `json:"track_id,omitempty"` // The DIAN's receipt of the submission
+	StatusMessage    string        `json:"status_message,omitempty"`
+	SubmittedAt      *time.Time    `json:"submitted_at,omitempty"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+
+	// Relations - not stored in DB directly
+	Lines []InvoiceLine `json:"lines,omitempty" gorm:"-"`
+}
+
+// InvoiceLine is a line of an invoice, copied from the sale detail or the
+// payment it bills so that the invoice does not change if they do.
+type InvoiceLine struct {
+	ID          uuid.UUID `json:"id"`
+	InvoiceID   uuid.UUID `json:"invoice_id" gorm:"index"`
+	Position    int       `json:"position"`
+	Description string    `json:"description"`
+	Quantity    int       `json:"quantity"`
+	UnitPrice   float64   `json:"unit_price"`
+	Discount    float64   `json:"discount"`
+	TaxRate     float64   `json:"tax_rate"` // Percentage, 19 for 19%
+	TaxAmount   float64   `json:"tax_amount"`
+	Total       float64   `json:"total"` // Quantity * UnitPrice - Discount, before tax
+}
+
+// TaxedInvoiceLine builds the line of something charged with its IVA included,
+// as sale details and payments are stored: unitPrice and discount include
+// taxRate. The invoice carries them before IVA, and the rounding of the unit
+// price goes to the discount, so that the line adds up to exactly what was
+// charged.
+func TaxedInvoiceLine(description string, quantity int, unitPrice, discount, taxRate float64) InvoiceLine {
+	charged := float64(quantity)*unitPrice - discount
+	base, tax := SplitTax(charged, taxRate)
+	line := InvoiceLine{
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   roundCents(unitPrice / (1 + taxRate/100)),
+		TaxRate:     taxRate,
+		TaxAmount:   tax,
+	}
+	line.Discount = roundCents(float64(quantity)*line.UnitPrice - base)
+	if line.Discount < 0 {
+		// Rounded down, the unit price leaves nothing to discount: the IVA
+		// takes the cents.
+		line.Discount = 0
+		line.TaxAmount = roundCents(charged - float64(quantity)*line.UnitPrice)
+	}
+	return line
+}
+
+// NewInvoice creates an invoice with its lines, not numbered yet: see Numbered.
+func NewInvoice(gymID uuid.UUID, source InvoiceSource, currency string, lines []InvoiceLine) *Invoice {
+	now := time.Now().UTC().Round(0)
+	inv := &Invoice{
+		ID:               uuid.New(),
+		GymID:            gymID,
+		Source:           source,
+		CustomerName:     FinalConsumerName,
+		CustomerDocType:  "13",
+		CustomerDocument: FinalConsumerDocument,
+		Currency:         currency,
+		Status:           InvoiceStatusIssued,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	for i := range lines {
+		l := lines[i]
+		l.ID = uuid.New()
+		l.InvoiceID = inv.ID
+		l.Position = i + 1
+		l.Total = float64(l.Quantity)*l.UnitPrice - l.Discount
+		inv.Subtotal += float64(l.Quantity) * l.UnitPrice
+		inv.Discount += l.Discount
+		inv.TaxTotal += l.TaxAmount
+		inv.Lines = append(inv.Lines, l)
+	}
+	inv.Total = inv.Subtotal - inv.Discount + inv.TaxTotal
+	return inv
+}
+
+// Taxes is the taxable base and the IVA of the invoice by rate.
+func (i *Invoice) Taxes() []TaxBreakdown {
+	var taxes []TaxBreakdown
+	for _, l := range i.Lines {
+		taxes = AddToBreakdown(taxes, l.TaxRate, l.Total, l.TaxAmount)
+	}
+	return taxes
+}
+
+// LineExtension is the total of the lines before taxes.
+func (i *Invoice) LineExtension() float64 {
+	return i.Subtotal - i.Discount
+}
+
+// Numbered gives the invoice its number in res, issued at the local time
+// issuedAt.
+func (i *Invoice) Numbered(res *InvoiceResolution, number int64, issuedAt time.Time) {
+	i.ResolutionID = res.ID
+	i.Prefix = res.Prefix
+	i.Number = number
+	i.FullNumber = fmt.Sprintf("%s%d", res.Prefix, number)
+	i.IssueDate = issuedAt.Format("2006-01-02")
+	i.IssueTime = issuedAt.Format("15:04:05-07:00")
+}
+
+// Submitted records the answer of the DIAN to the submission of the invoice.
+func (i *Invoice) Submitted(accepted bool, trackID, message string) {
+	now := time.Now().UTC().Round(0)
+	i.Status = InvoiceStatusRejected
+	if accepted {
+		i.Status = InvoiceStatusAccepted
+	}
+	i.TrackID = trackID
+	i.StatusMessage = message
+	i.SubmittedAt = &now
+	i.UpdatedAt = now
+}
+
+// IsAccepted checks if the DIAN has validated the invoice
+func (i *Invoice) IsAccepted() bool {
+	return i.Status == InvoiceStatusAccepted
+}
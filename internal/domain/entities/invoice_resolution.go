@@ -0,0 +1,99 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceEnvironment is the DIAN environment the invoices are issued in, the
+// ProfileExecutionID of the UBL and the last field of the CUFE.
+type InvoiceEnvironment string
+
+const (
+	InvoiceEnvironmentProduction InvoiceEnvironment = "1"
+	InvoiceEnvironmentTest       InvoiceEnvironment = "2"
+)
+
+// InvoiceResolution is the numbering the DIAN authorizes a gym to invoice with:
+// the numbers From..To under Prefix, between ValidFrom and ValidTo. NextNumber
+// is the next one to give out; the numbers go out in order and without gaps,
+// which is why Take is only called inside the transaction that saves the
+// invoice.
+//
+// A gym has at most one active resolution; a new one deactivates the previous.
+type InvoiceResolution struct {
+	ID               uuid.UUID          `json:"id"`
+	GymID            uuid.UUID          `json:"gym_id" gorm:"index"`
+	ResolutionNumber string             `json:"resolution_number"`
+	Prefix           string             `json:"prefix"`
+	From             int64              `json:"from" gorm:"column:range_from"`
+	To               int64              `json:"to" gorm:"column:range_to"`
+	NextNumber       int64              `json:"next_number"`
+	ValidFrom        string             `json:"valid_from"` // YYYY-MM-DD
+	ValidTo          string             `json:"valid_to"`
+	TechnicalKey     string             `json:"-"` // Clave técnica, part of the CUFE
+	Environment      InvoiceEnvironment `json:"environment"`
+	Active           bool               `json:"active"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// NewInvoiceResolution creates an active resolution for the range from..to.
+func NewInvoiceResolution(gymID uuid.UUID, number, prefix string, from, to int64, validFrom, validTo, technicalKey string, env InvoiceEnvironment) (*InvoiceResolution, error) {
+	if number == "" || technicalKey == "" {
+		return nil, fmt.Errorf("el número de resolución y la clave técnica son obligatorios")
+	}
+	if from <= 0 || to < from {
+		return nil, fmt.Errorf("rango de numeración inválido: %d a %d", from, to)
+	}
+	if validFrom == "" || validTo == "" || validTo < validFrom {
+		return nil, fmt.Errorf("vigencia inválida: %s a %s", validFrom, validTo)
+	}
+	if env == "" {
+		env = InvoiceEnvironmentTest
+	}
+	if env != InvoiceEnvironmentProduction && env != InvoiceEnvironmentTest {
+		return nil, fmt.Errorf("ambiente inválido: %s", env)
+	}
+	now := time.Now().UTC().Round(0)
+	return &InvoiceResolution{
+		ID:               uuid.New(),
+		GymID:            gymID,
+		ResolutionNumber: number,
+		Prefix:           prefix,
+		From:             from,
+		To:               to,
+		NextNumber:       from,
+		ValidFrom:        validFrom,
+		ValidTo:          validTo,
+		TechnicalKey:     technicalKey,
+		Environment:      env,
+		Active:           true,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}, nil
+}
+
+// Take gives out the next number for an invoice issued on date (YYYY-MM-DD).
+func (r *InvoiceResolution) Take(date string) (int64, error) {
+	if !r.Active {
+		return 0, fmt.Errorf("la resolución %s no está activa", r.ResolutionNumber)
+	}
+	if date < r.ValidFrom || date > r.ValidTo {
+		return 0, fmt.Errorf("la resolución %s no está vigente el %s", r.ResolutionNumber, date)
+	}
+	if r.NextNumber > r.To {
+		return 0, fmt.Errorf("se agotó la numeración de la resolución %s (%s%d)", r.ResolutionNumber, r.Prefix, r.To)
+	}
+	number := r.NextNumber
+	r.NextNumber++
+	r.UpdatedAt = time.Now().UTC().Round(0)
+	return number, nil
+}
+
+// Remaining returns how many numbers are left in the range.
+func (r *InvoiceResolution) Remaining() int64 {
+	return r.To - r.NextNumber + 1
+}
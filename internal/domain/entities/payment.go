@@ -1,6 +1,8 @@
 package entities
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -35,6 +37,7 @@ type PaymentType string
 
 const (
 	PaymentTypeSubscription PaymentType = "SUBSCRIPTION"
+	PaymentTypeInstallment  PaymentType = "INSTALLMENT" // a later payment towards a subscription's balance
 	PaymentTypeEnrollment   PaymentType = "ENROLLMENT"
 	PaymentTypeClass        PaymentType = "CLASS"
 	PaymentTypeProduct      PaymentType = "PRODUCT"
@@ -43,11 +46,28 @@ const (
 )
 
 // Payment represents a payment transaction
+//
+// Payments are the gym's ledger: every subscription sold or renewed, every
+// balance payment, fee and product sale has its row, so "how much came in, by
+// method" is answered here and nowhere else. SubscriptionID or SaleID say what
+// was paid for.
+//
+// Goods returned from a sale are not the refund of its charge: their money
+// goes back as a row of its own, with a negative Amount, on the SaleID of the
+// return document (see IsReturn).
+//
+// Date/Hour and RefundDate are local to the gym, like Subscription.Date, so the
+// daily close can pick up the charges and the refunds of a day by string range.
+//
+// CashRegisterID is the register session open by ProcessedBy when the payment
+// was taken, RefundCashRegisterID the one the refund was given out of; a refund
+// may well come out of another shift's drawer.
 type Payment struct {
 	ID                uuid.UUID     `json:"id"`
-	GymID             uuid.UUID     `json:"gym_id"`
+	GymID             uuid.UUID     `json:"gym_id" gorm:"index:idx_payments_gym_date,priority:1;index:idx_payments_gym_refund_date,priority:1"`
 	UserID            uuid.UUID     `json:"user_id"`
-	SubscriptionID    *uuid.UUID    `json:"subscription_id,omitempty"`
+	SubscriptionID    *uuid.UUID    `json:"subscription_id,omitempty" gorm:"index"`
+	SaleID            *uuid.UUID    `json:"sale_id,omitempty" gorm:"index"`
 	Amount            float64       `json:"amount"`
 	Currency          string        `json:"currency"`
 	PaymentMethod     PaymentMethod `json:"payment_method"`
@@ -56,13 +76,20 @@ type Payment struct {
 	TransactionID     string        `json:"transaction_id,omitempty"`
 	ExternalReference string        `json:"external_reference,omitempty"`
 	Description       string        `json:"description"`
+	// TaxRate is the IVA % included in Amount, for the charges of a
+	// subscription. A sale can mix rates: its IVA is in its details.
+	TaxRate           float64       `json:"tax_rate"`
 	Notes             string        `json:"notes,omitempty"`
 	RefundedAmount    float64       `json:"refunded_amount"`
 	RefundedAt        *time.Time    `json:"refunded_at,omitempty"`
 	RefundReason      string        `json:"refund_reason,omitempty"`
+	RefundDate        string        `json:"refund_date,omitempty" gorm:"index:idx_payments_gym_refund_date,priority:2"`
 	ProcessedBy       uuid.UUID     `json:"processed_by"`
-	CashRegisterID    *uuid.UUID    `json:"cash_register_id,omitempty"`
+	CashRegisterID    *uuid.UUID    `json:"cash_register_id,omitempty" gorm:"index"`
+	RefundCashRegisterID *uuid.UUID `json:"refund_cash_register_id,omitempty" gorm:"index"`
 	PaymentDate       time.Time     `json:"payment_date"`
+	Date              string        `json:"date" gorm:"index:idx_payments_gym_date,priority:2"`
+	Hour              string        `json:"hour"`
 	CreatedAt         time.Time     `json:"created_at"`
 	UpdatedAt         time.Time     `json:"updated_at"`
 }
@@ -100,10 +127,14 @@ func (p *Payment) Fail() {
 	p.UpdatedAt = time.Now().UTC().Round(0)
 }
 
-// Refund refunds the payment
+// Refund refunds the payment. The amount may be less than what was charged (a
+// prorated cancellation); RefundedAmount keeps what actually went back.
 func (p *Payment) Refund(amount float64, reason string) error {
 	if p.Status != PaymentStatusCompleted {
-		return nil
+		return fmt.Errorf("solo se puede reembolsar un pago completado (estado %s)", p.Status)
+	}
+	if amount <= 0 || amount > p.Amount {
+		return fmt.Errorf("el reembolso debe estar entre 0 y %.0f, se pidió %.0f", p.Amount, amount)
 	}
 	now := time.Now().UTC().Round(0)
 	p.Status = PaymentStatusRefunded
@@ -125,10 +156,24 @@ func (p *Payment) IsCompleted() bool {
 	return p.Status == PaymentStatusCompleted
 }
 
+// IsReturn tells the row of a return of goods: money given back on a return
+// document, which the ledger carries as a negative charge.
+func (p *Payment) IsReturn() bool {
+	return p.Amount < 0
+}
+
 // IsRefunded checks if payment is refunded
 func (p *Payment) IsRefunded() bool {
 	return p.Status == PaymentStatusRefunded
 }
 
+// IsCash tells whether the payment went in or out of the drawer. The method is
+// free text: CASH from the API, EFECTIVO from the subscriptions screen and the
+// name of the sale's payment method ("Efectivo") from the POS.
+func (p *Payment) IsCash() bool {
+	method := strings.ToUpper(strings.TrimSpace(string(p.PaymentMethod)))
+	return method == string(PaymentMethodCash) || method == "EFECTIVO"
+}
+
 
 
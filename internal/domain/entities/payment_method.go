@@ -26,6 +26,7 @@ const (
 // SalePaymentMethod represents a payment method available for sales
 type SalePaymentMethod struct {
 	ID        uuid.UUID           `json:"id" db:"id"`
+	GymID     uuid.UUID           `json:"gym_id" db:"gym_id" gorm:"index"`
 	Name      string              `json:"name" db:"name"`
 	Type      PaymentMethodType   `json:"type" db:"type"`
 	Status    PaymentMethodStatus `json:"status" db:"status"`
@@ -37,3 +38,20 @@ type SalePaymentMethod struct {
 func (pm *SalePaymentMethod) IsActive() bool {
 	return pm.Status == PaymentMethodStatusActive
 }
+
+// DefaultPaymentMethods are the methods a gym starts selling with.
+func DefaultPaymentMethods(gymID uuid.UUID) []SalePaymentMethod {
+	now := time.Now().UTC().Round(0)
+	methods := []SalePaymentMethod{
+		{Name: "Efectivo", Type: PaymentTypeCash},
+		{Name: "Tarjeta", Type: PaymentTypeCard},
+		{Name: "Transferencia", Type: PaymentTypeTransfer},
+	}
+	for i := range methods {
+		methods[i].ID = uuid.New()
+		methods[i].GymID = gymID
+		methods[i].Status = PaymentMethodStatusActive
+		methods[i].CreatedAt, methods[i].UpdatedAt = now, now
+	}
+	return methods
+}
@@ -1,9 +1,12 @@
 package entities
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/pkg/errors"
 )
 
 // BillingMode defines how subscription end date is calculated for monthly plans.
@@ -25,12 +28,26 @@ type Plan struct {
 	DurationDays  int         `json:"duration_days"`
 	Price         float64     `json:"price"`
 	EnrollmentFee float64     `json:"enrollment_fee"`
+	// TaxRate is the IVA % of the plan, on its price, enrollment and freeze
+	// fees; TaxExcluded says those are before IVA. See tax.go.
+	TaxRate       float64     `json:"tax_rate"`
+	TaxExcluded   bool        `json:"tax_excluded"`
 	Color         string      `json:"color"`
 	Icon          string      `json:"icon,omitempty"`
 	DisplayOrder  int         `json:"display_order"`
 	IsFeatured    bool        `json:"is_featured"`
 	MaxMembers    int         `json:"max_members"`
 	BillingMode   BillingMode `json:"billing_mode"`
+	// VisitAllowance turns the plan into a punch card ("10 entradas", day pass):
+	// each granted entry uses one visit and DurationDays is only how long the
+	// visits stay valid. 0 means a regular time-based plan.
+	VisitAllowance int        `json:"visit_allowance"`
+	// AccessWindows restricts when members of the plan may enter ("mañanas",
+	// weekends only). Entry is allowed if any window matches; none means any
+	// time the gym is open. Stored as JSON: they are always read with the plan.
+	AccessWindows []AccessWindow `json:"access_windows,omitempty" gorm:"serializer:json"`
+	// Freeze is how much a member of the plan may pause their subscription.
+	Freeze        FreezePolicy `json:"freeze" gorm:"embedded;embeddedPrefix:freeze_"`
 	Status        string      `json:"status" gorm:"index:idx_plans_gym_status,priority:2"`
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
@@ -55,6 +72,196 @@ func NewPlan(gymID uuid.UUID, name string, durationDays int, price float64) *Pla
 	}
 }
 
+// Gross is an amount of the plan (its price, enrollment or a fee) as it is
+// charged, with its IVA: the amount itself when the plan's prices include
+// it, with TaxRate on top when not.
+func (p *Plan) Gross(amount float64) float64 {
+	if p.TaxExcluded {
+		return AddTax(amount, p.TaxRate)
+	}
+	return amount
+}
+
+// FreezePolicy are the freeze rules of a plan. Every limit applies to one
+// subscription period, i.e. it starts over on renewal, and 0 means no limit.
+type FreezePolicy struct {
+	MaxDays  int     `json:"max_days"`  // total days frozen per period
+	MinDays  int     `json:"min_days"`  // shortest freeze that can be requested
+	MaxCount int     `json:"max_count"` // freezes allowed per period
+	Fee      float64 `json:"fee"`       // charged on each freeze
+}
+
+// Validate checks the policy before it is stored.
+func (f FreezePolicy) Validate() error {
+	if f.MaxDays < 0 || f.MinDays < 0 || f.MaxCount < 0 || f.Fee < 0 {
+		return fmt.Errorf("los límites de congelamiento no pueden ser negativos")
+	}
+	if f.MaxDays > 0 && f.MinDays > f.MaxDays {
+		return fmt.Errorf("el congelamiento mínimo (%d días) supera el máximo (%d días)", f.MinDays, f.MaxDays)
+	}
+	return nil
+}
+
+// Check tells whether sub may be frozen for days more days. TotalFreezeDays
+// already excludes the days given back by an early unfreeze, so a member who
+// came back early can use them later.
+func (f FreezePolicy) Check(sub *Subscription, days int) error {
+	if days <= 0 {
+		return fmt.Errorf("%w: el congelamiento debe ser de al menos un día", errors.ErrFreezeNotAllowed)
+	}
+	if f.MinDays > 0 && days < f.MinDays {
+		return fmt.Errorf("%w: el plan exige congelar al menos %d días", errors.ErrFreezeNotAllowed, f.MinDays)
+	}
+	if f.MaxCount > 0 && sub.FreezeCount >= f.MaxCount {
+		return fmt.Errorf("%w: ya se usaron los %d congelamiento(s) que permite el plan", errors.ErrFreezeNotAllowed, f.MaxCount)
+	}
+	if f.MaxDays > 0 && sub.TotalFreezeDays+days > f.MaxDays {
+		left := f.MaxDays - sub.TotalFreezeDays
+		if left < 0 {
+			left = 0
+		}
+		return fmt.Errorf("%w: el plan permite %d días de congelamiento por periodo y quedan %d", errors.ErrFreezeNotAllowed, f.MaxDays, left)
+	}
+	return nil
+}
+
+// AccessWindow is a slot in which a plan allows entry. Days uses time.Weekday
+// numbering (0 = Sunday) and empty means every day. From/To are "HH:MM" in the
+// gym's local time, To exclusive; both empty means the whole day. A window whose
+// To comes before its From crosses midnight: "22:00"-"02:00" on Friday lets
+// members in from Friday 22:00 to Saturday 02:00.
+type AccessWindow struct {
+	Days []time.Weekday `json:"days,omitempty"`
+	From string         `json:"from,omitempty"`
+	To   string         `json:"to,omitempty"`
+}
+
+var weekdayNames = [...]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"}
+
+// WeekdayName returns the Spanish name of d, as members read it in a denial.
+func WeekdayName(d time.Weekday) string {
+	return weekdayNames[d]
+}
+
+// Validate checks the window is well formed before it is stored.
+func (w AccessWindow) Validate() error {
+	for _, d := range w.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("día de la semana inválido: %d", d)
+		}
+	}
+	if (w.From == "") != (w.To == "") {
+		return fmt.Errorf("la franja necesita hora de inicio y de fin")
+	}
+	if w.From == "" {
+		return nil
+	}
+	from, err := time.Parse("15:04", w.From)
+	if err != nil {
+		return fmt.Errorf("hora de inicio inválida %q, use HH:MM", w.From)
+	}
+	to, err := time.Parse("15:04", w.To)
+	if err != nil {
+		return fmt.Errorf("hora de fin inválida %q, use HH:MM", w.To)
+	}
+	if from.Equal(to) {
+		return fmt.Errorf("la franja %s-%s no dura nada", w.From, w.To)
+	}
+	return nil
+}
+
+// Contains reports whether the local instant t falls inside the window. t must
+// already be in the gym's timezone: the weekday and the clock are read off it.
+func (w AccessWindow) Contains(t time.Time) bool {
+	if w.From == "" {
+		return w.onDay(t.Weekday())
+	}
+	// "HH:MM" compares correctly as text, and avoids rebuilding dates in loc.
+	clock := t.Format("15:04")
+	if w.From < w.To {
+		return w.onDay(t.Weekday()) && clock >= w.From && clock < w.To
+	}
+	// Across midnight the hours after it belong to the day the window opened.
+	if clock >= w.From {
+		return w.onDay(t.Weekday())
+	}
+	return clock < w.To && w.onDay((t.Weekday()+6)%7)
+}
+
+// onDay reports whether the window opens on weekday d.
+func (w AccessWindow) onDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the window for a denial reason, e.g. "lun-vie 05:00-12:00".
+func (w AccessWindow) String() string {
+	days := "todos los días"
+	if len(w.Days) > 0 {
+		names := make([]string, 0, len(w.Days))
+		for _, d := range w.Days {
+			names = append(names, string([]rune(weekdayNames[d])[:3]))
+		}
+		days = strings.Join(names, ",")
+	}
+	if w.From == "" {
+		return days
+	}
+	return days + " " + w.From + "-" + w.To
+}
+
+// Validate checks the plan's rules before it is stored.
+func (p *Plan) Validate() error {
+	if err := ValidateTaxRate(p.TaxRate); err != nil {
+		return err
+	}
+	if err := p.Freeze.Validate(); err != nil {
+		return err
+	}
+	for _, w := range p.AccessWindows {
+		if err := w.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllowsEntryAt reports whether the plan lets a member in at the local instant
+// t (already in the gym's timezone).
+func (p *Plan) AllowsEntryAt(t time.Time) bool {
+	if len(p.AccessWindows) == 0 {
+		return true
+	}
+	for _, w := range p.AccessWindows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessSchedule describes the plan's windows for a denial reason.
+func (p *Plan) AccessSchedule() string {
+	parts := make([]string, 0, len(p.AccessWindows))
+	for _, w := range p.AccessWindows {
+		parts = append(parts, w.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// IsVisitBased reports whether the plan sells a number of visits rather than
+// unlimited access for a period.
+func (p *Plan) IsVisitBased() bool {
+	return p.VisitAllowance > 0
+}
+
 // IsActive checks if the plan is active
 func (p *Plan) IsActive() bool {
 	return p.Status == "ACTIVE"
@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessWindowsAreReadInTheGymTimezone(t *testing.T) {
+	bogota, err := time.LoadLocation("America/Bogota")
+	if err != nil {
+		t.Skipf("sin base de zonas horarias: %v", err)
+	}
+
+	mornings := &Plan{AccessWindows: []AccessWindow{{
+		Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		From: "05:00",
+		To:   "12:00",
+	}}}
+
+	// Lunes 11:30 en Bogotá son las 16:30 UTC: dentro de la franja aunque en UTC
+	// ya sea la tarde.
+	inside := time.Date(2026, 4, 13, 16, 30, 0, 0, time.UTC).In(bogota)
+	if !mornings.AllowsEntryAt(inside) {
+		t.Errorf("lunes %s debería estar dentro de %s", inside.Format("15:04"), mornings.AccessSchedule())
+	}
+
+	// Lunes 12:00 es el límite exclusivo.
+	if at := time.Date(2026, 4, 13, 12, 0, 0, 0, bogota); mornings.AllowsEntryAt(at) {
+		t.Error("las 12:00 no deberían estar dentro de una franja que termina a las 12:00")
+	}
+
+	// Sábado por la mañana: hora válida, día no.
+	if at := time.Date(2026, 4, 18, 8, 0, 0, 0, bogota); mornings.AllowsEntryAt(at) {
+		t.Error("un plan de lunes a viernes no debería dejar entrar el sábado")
+	}
+
+	if !(&Plan{}).AllowsEntryAt(time.Date(2026, 4, 18, 23, 0, 0, 0, bogota)) {
+		t.Error("un plan sin franjas deja entrar a cualquier hora")
+	}
+}
+
+func TestAccessWindowCanCrossMidnight(t *testing.T) {
+	// Viernes 22:00 a sábado 02:00.
+	nights := &Plan{AccessWindows: []AccessWindow{{Days: []time.Weekday{time.Friday}, From: "22:00", To: "02:00"}}}
+	if err := nights.Validate(); err != nil {
+		t.Fatalf("franja nocturna: %v", err)
+	}
+
+	cases := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 4, 17, 23, 0, 0, 0, time.UTC), true},  // viernes 23:00
+		{time.Date(2026, 4, 18, 1, 30, 0, 0, time.UTC), true},  // sábado 01:30, la misma noche
+		{time.Date(2026, 4, 18, 2, 0, 0, 0, time.UTC), false},  // sábado 02:00, límite exclusivo
+		{time.Date(2026, 4, 17, 1, 0, 0, 0, time.UTC), false},  // viernes 01:00, noche del jueves
+		{time.Date(2026, 4, 18, 23, 0, 0, 0, time.UTC), false}, // sábado 23:00
+	}
+	for _, tc := range cases {
+		if got := nights.AllowsEntryAt(tc.at); got != tc.want {
+			t.Errorf("%s %s: AllowsEntryAt = %v, want %v", tc.at.Weekday(), tc.at.Format("15:04"), got, tc.want)
+		}
+	}
+	if got := nights.AccessSchedule(); got != "vie 22:00-02:00" {
+		t.Errorf("AccessSchedule = %q, want %q", got, "vie 22:00-02:00")
+	}
+}
+
+func TestAccessWindowValidate(t *testing.T) {
+	bad := []AccessWindow{
+		{From: "06:00"},
+		{From: "06:00", To: "06:00"},
+		{From: "6am", To: "9am"},
+		{Days: []time.Weekday{7}},
+	}
+	for _, w := range bad {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%+v debería ser inválida", w)
+		}
+	}
+	if err := (AccessWindow{Days: []time.Weekday{time.Saturday, time.Sunday}}).Validate(); err != nil {
+		t.Errorf("fin de semana completo: %v", err)
+	}
+}
@@ -18,9 +18,12 @@ const (
 // Product represents a product in the inventory
 type Product struct {
 	ID          uuid.UUID     `json:"id" db:"id"`
+	GymID       uuid.UUID     `json:"gym_id" db:"gym_id" gorm:"index"`
 	Name        string        `json:"name" db:"name"`
 	Description string        `json:"description" db:"description"`
 	UnitPrice   float64       `json:"unit_price" db:"unit_price"`
+	TaxRate     float64       `json:"tax_rate" db:"tax_rate"`         // IVA %, see tax.go
+	TaxExcluded bool          `json:"tax_excluded" db:"tax_excluded"` // UnitPrice is before IVA
 	Stock       int           `json:"stock" db:"stock"`
 	Status      ProductStatus `json:"status" db:"status" gorm:"index:idx_products_status"`
 	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
@@ -46,6 +49,15 @@ func (p *Product) IncreaseStock(quantity int) {
 	p.Stock += quantity
 }
 
+// Gross is a price of the product as it is charged, with its IVA: price
+// itself when the product's prices include it, with TaxRate on top when not.
+func (p *Product) Gross(price float64) float64 {
+	if p.TaxExcluded {
+		return AddTax(price, p.TaxRate)
+	}
+	return price
+}
+
 // IsActive checks if the product is active
 func (p *Product) IsActive() bool {
 	return p.Status == ProductStatusActive
@@ -12,6 +12,9 @@ type SaleType string
 const (
 	SaleTypeNormal SaleType = "normal"
 	SaleTypeVoid   SaleType = "void"
+	// SaleTypeReturn is the document of a partial return: some units of some
+	// lines of a sale given back, with negative amounts like a void's.
+	SaleTypeReturn SaleType = "return"
 )
 
 // SaleStatus represents the status of a sale
@@ -25,26 +28,36 @@ const (
 
 // Sale represents a sale transaction
 //
-// Índices: los reportes y el cierre diario filtran por la columna `date` (string
-// local), no por sale_date. `user_id` es el VENDEDOR, y es como se resuelve el gym
-// (Sale no tiene gym_id).
+// Índices: los reportes y el cierre diario filtran por gym_id y la columna `date`
+// (string local), no por sale_date. `user_id` es el VENDEDOR.
 type Sale struct {
-	ID              uuid.UUID  `json:"id" db:"id"`
-	SaleDate        time.Time  `json:"sale_date" db:"sale_date"`
-	Total           float64    `json:"total" db:"total"`
-	TotalDiscount   float64    `json:"total_discount" db:"total_discount"`
-	UserID          uuid.UUID  `json:"user_id" db:"user_id" gorm:"index:idx_sales_user_date,priority:1"`
-	Type            SaleType   `json:"type" db:"type"`
-	Status          SaleStatus `json:"status" db:"status"`
+	ID            uuid.UUID  `json:"id" db:"id"`
+	GymID         uuid.UUID  `json:"gym_id" db:"gym_id" gorm:"index:idx_sales_gym_date,priority:1"`
+	SaleDate      time.Time  `json:"sale_date" db:"sale_date"`
+	Total         float64    `json:"total" db:"total"`
+	TotalDiscount float64    `json:"total_discount" db:"total_discount"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id" gorm:"index:idx_sales_user_date,priority:1"`
+	Type          SaleType   `json:"type" db:"type"`
+	Status        SaleStatus `json:"status" db:"status"`
+	// PaymentMethodID is the tender that covers the most of the sale, the one
+	// shown where a sale has a single method. The full split is in Tenders.
 	PaymentMethodID uuid.UUID  `json:"payment_method_id" db:"payment_method_id"`
 	VoidedSaleID    *uuid.UUID `json:"voided_sale_id,omitempty" db:"voided_sale_id"` // If this is a void, references the original sale
-	Date            string     `json:"date" db:"date" gorm:"index:idx_sales_date;index:idx_sales_user_date,priority:2"`
-	Hour            string     `json:"hour" db:"hour"`
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	// ReturnedSaleID is, on a return, the sale the goods came from. A sale may
+	// have several returns; it stays completed with what was kept.
+	ReturnedSaleID *uuid.UUID `json:"returned_sale_id,omitempty" db:"returned_sale_id" gorm:"index"`
+	// MemberID is the buyer, when reception says who it is. Only needed for
+	// coupons limited per member.
+	MemberID   *uuid.UUID `json:"member_id,omitempty" db:"member_id"`
+	CouponCode string     `json:"coupon_code,omitempty" db:"coupon_code"` // Its discount is already in the details
+	Date       string     `json:"date" db:"date" gorm:"index:idx_sales_date;index:idx_sales_user_date,priority:2;index:idx_sales_gym_date,priority:2"`
+	Hour       string     `json:"hour" db:"hour"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
 
 	// Relations - not stored in DB directly
 	Details       []SaleDetail       `json:"details,omitempty" gorm:"-" db:"-"`
+	Tenders       []SaleTender       `json:"tenders,omitempty" gorm:"-" db:"-"`
 	User          *User              `json:"user,omitempty" gorm:"-" db:"-"`
 	PaymentMethod *SalePaymentMethod `json:"payment_method,omitempty" gorm:"-" db:"-"`
 }
@@ -61,6 +74,15 @@ func (s *Sale) CalculateTotal() {
 	s.TotalDiscount = totalDiscount
 }
 
+// TendersTotal adds up the amounts of the tenders of the sale
+func (s *Sale) TendersTotal() float64 {
+	total := 0.0
+	for _, t := range s.Tenders {
+		total += t.Amount
+	}
+	return total
+}
+
 // IsNormal checks if this is a normal sale
 func (s *Sale) IsNormal() bool {
 	return s.Type == SaleTypeNormal
@@ -71,6 +93,11 @@ func (s *Sale) IsVoid() bool {
 	return s.Type == SaleTypeVoid
 }
 
+// IsReturn checks if this is the document of a partial return
+func (s *Sale) IsReturn() bool {
+	return s.Type == SaleTypeReturn
+}
+
 // CanBeVoided checks if the sale can be voided
 func (s *Sale) CanBeVoided() bool {
 	return s.Status == SaleStatusCompleted && s.Type == SaleTypeNormal
@@ -20,17 +20,51 @@ type SaleDetail struct {
 	Quantity   int       `json:"quantity" db:"quantity"`
 	TotalPrice float64   `json:"total_price" db:"total_price"`
 	Discount   float64   `json:"discount" db:"discount"`
-	Subtotal   float64   `json:"subtotal" db:"subtotal"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	Subtotal   float64   `json:"subtotal" db:"subtotal"` // What the line charges, IVA included
+	// TaxRate is the IVA % of the product when sold; TaxBase and TaxAmount split
+	// Subtotal into the taxable base and the IVA.
+	TaxRate   float64 `json:"tax_rate" db:"tax_rate"`
+	TaxBase   float64 `json:"tax_base" db:"tax_base"`
+	TaxAmount float64 `json:"tax_amount" db:"tax_amount"`
+	// ReturnedDetailID is, on a return, the line of the sale the units come
+	// from, which is how what is left to return of it is known.
+	ReturnedDetailID *uuid.UUID `json:"returned_detail_id,omitempty" db:"returned_detail_id" gorm:"index"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 
 	// Relations - not stored in DB directly
 	Product *Product `json:"product,omitempty" gorm:"-" db:"-"`
 }
 
-// CalculateSubtotal calculates the subtotal for this detail
+// CalculateSubtotal calculates the subtotal for this detail, and its IVA at
+// TaxRate. UnitPrice includes the IVA.
 func (sd *SaleDetail) CalculateSubtotal() {
 	sd.TotalPrice = sd.UnitPrice * float64(sd.Quantity)
 	sd.Subtotal = sd.TotalPrice - sd.Discount
+	sd.TaxBase, sd.TaxAmount = SplitTax(sd.Subtotal, sd.TaxRate)
+}
+
+// Return builds the line of a return document giving back qty units of sd, of
+// which returned went back already. Its amounts are negative, like a void's,
+// and prorated by units so that returning the whole line, at once or bit by
+// bit, gives back exactly what it charged, discount and IVA included.
+func (sd *SaleDetail) Return(qty, returned int) SaleDetail {
+	share := func(v float64) float64 {
+		upTo := func(units int) float64 { return roundCents(v * float64(units) / float64(sd.Quantity)) }
+		return -(upTo(returned+qty) - upTo(returned))
+	}
+	detailID := sd.ID
+	return SaleDetail{
+		ProductID:        sd.ProductID,
+		UnitPrice:        sd.UnitPrice,
+		Quantity:         qty, // Positive, as on a void
+		TotalPrice:       share(sd.TotalPrice),
+		Discount:         share(sd.Discount),
+		Subtotal:         share(sd.Subtotal),
+		TaxRate:          sd.TaxRate,
+		TaxBase:          share(sd.TaxBase),
+		TaxAmount:        share(sd.TaxAmount),
+		ReturnedDetailID: &detailID,
+	}
 }
 
 // Validate validates the sale detail
@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SaleTender is one of the means a sale was paid with: part in cash and the
+// rest by transfer is two tenders. The tenders of a sale add up to its Total,
+// and each goes into the payments ledger as a charge of its own under the name
+// of its method, which is how the daily close and the till split the money.
+//
+// Sales from before tenders existed have no rows here: their PaymentMethodID
+// covers the whole Total.
+type SaleTender struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	SaleID          uuid.UUID `json:"sale_id" db:"sale_id" gorm:"index:idx_sale_tenders_sale"`
+	PaymentMethodID uuid.UUID `json:"payment_method_id" db:"payment_method_id"`
+	Amount          float64   `json:"amount" db:"amount"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+
+	// Relations - not stored in DB directly
+	PaymentMethod *SalePaymentMethod `json:"payment_method,omitempty" gorm:"-" db:"-"`
+}
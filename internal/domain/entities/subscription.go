@@ -1,9 +1,12 @@
 package entities
 
 import (
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/pkg/errors"
 )
 
 // SubscriptionStatus represents subscription status
@@ -16,14 +19,76 @@ const (
 	SubscriptionStatusCancelled SubscriptionStatus = "CANCELLED"
 	SubscriptionStatusSuspended SubscriptionStatus = "SUSPENDED"
 	SubscriptionStatusFrozen    SubscriptionStatus = "FROZEN"
+	// SubscriptionStatusPlanChanged closes a period whose remaining days moved
+	// to a subscription on another plan. It is not a cancellation: the member
+	// stayed, so it must not count as churn.
+	SubscriptionStatusPlanChanged SubscriptionStatus = "PLAN_CHANGED"
+	// SubscriptionStatusTransferred closes a period whose remaining days were
+	// handed to another member. Like a plan change, it is not churn: the days
+	// are still being used.
+	SubscriptionStatusTransferred SubscriptionStatus = "TRANSFERRED"
 )
 
+// subscriptionTransitions is the whole life cycle of a subscription: the
+// statuses each one may move to. Every status change goes through
+// TransitionTo, so an operation on a subscription in the wrong state (freezing
+// a cancelled one, activating it again) fails with an InvalidTransitionError
+// instead of silently rewriting its status. CANCELLED, PLAN_CHANGED and
+// TRANSFERRED are final. EXPIRED only comes back to ACTIVE when its dates are corrected into the
+// future.
+var subscriptionTransitions = map[SubscriptionStatus][]SubscriptionStatus{
+	SubscriptionStatusPending:     {SubscriptionStatusActive, SubscriptionStatusCancelled},
+	SubscriptionStatusActive:      {SubscriptionStatusFrozen, SubscriptionStatusSuspended, SubscriptionStatusExpired, SubscriptionStatusCancelled, SubscriptionStatusPlanChanged, SubscriptionStatusTransferred},
+	SubscriptionStatusFrozen:      {SubscriptionStatusActive, SubscriptionStatusCancelled},
+	SubscriptionStatusSuspended:   {SubscriptionStatusActive, SubscriptionStatusExpired, SubscriptionStatusCancelled},
+	SubscriptionStatusExpired:     {SubscriptionStatusActive},
+	SubscriptionStatusCancelled:   {},
+	SubscriptionStatusPlanChanged: {},
+	SubscriptionStatusTransferred: {},
+}
+
+// InvalidTransitionError is returned when a subscription is asked to move to a
+// status its current one does not lead to. It wraps ErrInvalidTransition.
+type InvalidTransitionError struct {
+	From SubscriptionStatus
+	To   SubscriptionStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("%v: una suscripción %s no puede pasar a %s", errors.ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Unwrap() error {
+	return errors.ErrInvalidTransition
+}
+
+// CanTransitionTo reports, as an InvalidTransitionError, whether s may move to
+// the status to. It changes nothing.
+func (s *Subscription) CanTransitionTo(to SubscriptionStatus) error {
+	for _, next := range subscriptionTransitions[s.Status] {
+		if next == to {
+			return nil
+		}
+	}
+	return &InvalidTransitionError{From: s.Status, To: to}
+}
+
+// TransitionTo moves s to the status to if the life cycle allows it.
+func (s *Subscription) TransitionTo(to SubscriptionStatus) error {
+	if err := s.CanTransitionTo(to); err != nil {
+		return err
+	}
+	s.Status = to
+	s.UpdatedAt = time.Now().UTC().Round(0)
+	return nil
+}
+
 // Subscription represents a user's subscription to a plan
 //
 // Índices: cubren los filtros reales de sqlite_subscription_repository.go.
 //   - idx_subs_user_status_end  → FindActiveByUserID, en cada check-in y en cada alta
 //   - idx_subs_gym_status_end   → FindByGymIDWithFilters (el listado y sus filtros)
-//   - idx_subs_status_end       → MarkExpiredSubscriptions, que corre cada hora y
+//   - idx_subs_status_end       → FindToExpire, que corre cada hora; antes era un UPDATE que
 //     hacía un recorrido completo de la tabla bajo lock de ESCRITURA
 //   - idx_subs_gym_date         → FindByGymIDAndDateRange (reportes y cierre diario)
 type Subscription struct {
@@ -40,12 +105,42 @@ type Subscription struct {
 	EnrollmentFeePaid   float64            `json:"enrollment_fee_paid"`
 	DiscountApplied     float64            `json:"discount_applied"`
 	TotalPaid           float64            `json:"total_paid"`
+	ProrationCredit     float64            `json:"proration_credit"`
 	PaymentMethod       string             `json:"payment_method,omitempty"`
+	// TaxRate is the IVA % of the plan when sold. Every amount of the
+	// subscription includes it.
+	TaxRate             float64            `json:"tax_rate"`
+	// AmountPaid is how much of TotalPaid the member has actually paid. It only
+	// falls short on a subscription sold in installments, whose rest is due by
+	// BalanceDueDate (the end of that day in the gym); the payments themselves
+	// are Payment rows hanging from the subscription.
+	AmountPaid          float64            `json:"amount_paid"`
+	BalanceDueDate      *time.Time         `json:"balance_due_date,omitempty"`
+	// AwaitingPayment marks a subscription sold through an online gateway whose
+	// payment has not come in yet: it stays PENDING, with nothing paid, until
+	// the gateway's webhook confirms it (see ConfirmPayment).
+	AwaitingPayment     bool               `json:"awaiting_payment"`
+	// CouponCode is the coupon redeemed on the sale, already part of
+	// DiscountApplied; see CouponRedemption for how much it took off.
+	CouponCode          string             `json:"coupon_code,omitempty"`
 	Status              SubscriptionStatus `json:"status" gorm:"index:idx_subs_user_status_end,priority:2;index:idx_subs_gym_status_end,priority:2;index:idx_subs_status_end,priority:1"`
 	FrozenUntil         *time.Time         `json:"frozen_until,omitempty"`
 	FreezeReason        string             `json:"freeze_reason,omitempty"`
 	TotalFreezeDays     int                `json:"total_freeze_days"`
+	FreezeCount         int                `json:"freeze_count"`
+	// VisitAllowance is copied from the plan when the subscription is sold, so
+	// editing the plan later does not change what the member already paid for.
+	// VisitsUsed only ever goes up, through SubscriptionRepository.ConsumeVisit.
+	VisitAllowance      int                `json:"visit_allowance"`
+	VisitsUsed          int                `json:"visits_used"`
 	AutoRenew           bool               `json:"auto_renew"`
+	// RenewedFromID links a period created by the auto-renew job to the one it
+	// continues, so reception can tell a charge the member asked for from one
+	// the system made on their behalf.
+	RenewedFromID       *uuid.UUID         `json:"renewed_from_id,omitempty"`
+	// TransferredFromID points at the subscription whose remaining period this
+	// one took over (see SubscriptionUseCase.TransferSubscription).
+	TransferredFromID   *uuid.UUID         `json:"transferred_from_id,omitempty"`
 	RenewalReminderSent bool               `json:"renewal_reminder_sent"`
 	Notes               string             `json:"notes,omitempty"`
 	CancellationReason  string             `json:"cancellation_reason,omitempty"`
@@ -89,6 +184,7 @@ func NewSubscription(userID, planID, gymID uuid.UUID, startDate time.Time, durat
 		EnrollmentFeePaid: enrollmentFee,
 		DiscountApplied:   discount,
 		TotalPaid:         total,
+		AmountPaid:        total,
 		Status:            SubscriptionStatusPending,
 		AutoRenew:         false,
 		CreatedAt:         now,
@@ -96,11 +192,87 @@ func NewSubscription(userID, planID, gymID uuid.UUID, startDate time.Time, durat
 	}
 }
 
+// SellInInstallments leaves part of the subscription owed: the member pays
+// downPayment now and the rest by dueDate. Call it once the price is final.
+func (s *Subscription) SellInInstallments(downPayment float64, dueDate time.Time) error {
+	if downPayment < 0 || downPayment >= s.TotalPaid {
+		return fmt.Errorf("el pago inicial debe estar entre 0 y %.0f, se pidió %.0f", s.TotalPaid, downPayment)
+	}
+	if !dueDate.After(time.Now()) {
+		return fmt.Errorf("la fecha límite del saldo ya pasó")
+	}
+	due := dueDate.UTC().Round(0)
+	s.AmountPaid = downPayment
+	s.BalanceDueDate = &due
+	return nil
+}
+
+// Balance is what the member still owes on the subscription.
+func (s *Subscription) Balance() float64 {
+	return math.Max(0, s.TotalPaid-s.AmountPaid)
+}
+
+// AddPayment credits a partial payment against the balance. Paying more than is
+// owed is refused rather than kept as credit: there is nowhere to spend it.
+func (s *Subscription) AddPayment(amount float64) error {
+	if amount <= 0 || amount > s.Balance() {
+		return fmt.Errorf("el abono debe estar entre 0 y el saldo de %.0f, se pidió %.0f", s.Balance(), amount)
+	}
+	s.AmountPaid += amount
+	s.UpdatedAt = time.Now().UTC().Round(0)
+	return nil
+}
+
+// BalanceOverdue reports whether something is still owed graceDays after the
+// balance was due.
+func (s *Subscription) BalanceOverdue(now time.Time, graceDays int) bool {
+	return s.Balance() > 0 && s.BalanceDueDate != nil && now.After(s.BalanceDueDate.AddDate(0, 0, graceDays))
+}
+
 // IsActive checks if subscription is active
 func (s *Subscription) IsActive() bool {
 	return s.Status == SubscriptionStatusActive && time.Now().Before(s.EndDate)
 }
 
+// IsLive reports whether the subscription is still in force: active, frozen or
+// about to start, with its end date ahead.
+func (s *Subscription) IsLive() bool {
+	switch s.Status {
+	case SubscriptionStatusActive, SubscriptionStatusFrozen, SubscriptionStatusPending:
+		return time.Now().Before(s.EndDate)
+	}
+	return false
+}
+
+// HasEnded reports whether the subscription is over for good or until its dates
+// are corrected: cancelled, expired, or closed by a plan change or a transfer.
+func (s *Subscription) HasEnded() bool {
+	switch s.Status {
+	case SubscriptionStatusCancelled, SubscriptionStatusExpired, SubscriptionStatusPlanChanged, SubscriptionStatusTransferred:
+		return true
+	}
+	return false
+}
+
+// IsVisitBased reports whether the subscription is a punch card.
+func (s *Subscription) IsVisitBased() bool {
+	return s.VisitAllowance > 0
+}
+
+// VisitsLeft returns the entries still available on a punch card. It is
+// meaningless for time-based subscriptions, which have no allowance.
+func (s *Subscription) VisitsLeft() int {
+	if left := s.VisitAllowance - s.VisitsUsed; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// VisitsExhausted reports whether a punch card has used every entry it had.
+func (s *Subscription) VisitsExhausted() bool {
+	return s.IsVisitBased() && s.VisitsUsed >= s.VisitAllowance
+}
+
 // IsExpired checks if subscription is expired
 func (s *Subscription) IsExpired() bool {
 	return time.Now().After(s.EndDate)
@@ -115,22 +287,103 @@ func (s *Subscription) DaysRemaining() int {
 	return int(duration.Hours() / 24)
 }
 
-// Activate activates the subscription
-func (s *Subscription) Activate() {
-	now := time.Now().UTC().Round(0)
-	s.Status = SubscriptionStatusActive
+// Activate starts the period of a pending subscription.
+func (s *Subscription) Activate() error {
+	if s.Status != SubscriptionStatusPending {
+		return &InvalidTransitionError{From: s.Status, To: SubscriptionStatusActive}
+	}
+	if err := s.TransitionTo(SubscriptionStatusActive); err != nil {
+		return err
+	}
+	now := s.UpdatedAt
 	s.ActivatedAt = &now
-	s.UpdatedAt = now
+	return nil
+}
+
+// StartsLater reports whether s is paid for but its period has not begun yet.
+func (s *Subscription) StartsLater(now time.Time) bool {
+	return s.Status == SubscriptionStatusPending && now.Before(s.StartDate)
+}
+
+// ActivateIfDue activates a pending subscription once its start date arrives.
+// It reports whether it did.
+func (s *Subscription) ActivateIfDue(now time.Time) bool {
+	if s.Status != SubscriptionStatusPending || s.AwaitingPayment || now.Before(s.StartDate) {
+		return false
+	}
+	return s.Activate() == nil
+}
+
+// AwaitPayment leaves a new subscription waiting for its online payment.
+func (s *Subscription) AwaitPayment() error {
+	if s.Status != SubscriptionStatusPending {
+		return &InvalidTransitionError{From: s.Status, To: SubscriptionStatusPending}
+	}
+	if s.BalanceDueDate != nil {
+		return fmt.Errorf("una suscripción a cuotas no se puede pagar en línea")
+	}
+	s.AwaitingPayment = true
+	s.AmountPaid = 0
+	return nil
+}
+
+// ConfirmPayment records that the online payment came in. A period that was to
+// start before the payment arrived starts now instead, with all its days: the
+// member could not get in while it was unpaid. One that starts later is left to
+// ActivateIfDue.
+func (s *Subscription) ConfirmPayment(now time.Time) error {
+	if !s.AwaitingPayment {
+		return fmt.Errorf("la suscripción no espera ningún pago")
+	}
+	if s.Status != SubscriptionStatusPending {
+		return &InvalidTransitionError{From: s.Status, To: SubscriptionStatusActive}
+	}
+	s.AwaitingPayment = false
+	s.AmountPaid = s.TotalPaid
+	if now.Before(s.StartDate) {
+		s.UpdatedAt = time.Now().UTC().Round(0)
+		return nil
+	}
+	late := now.Sub(s.StartDate)
+	s.StartDate = now
+	s.EndDate = s.EndDate.Add(late)
+	return s.Activate()
 }
 
 // Cancel cancels the subscription
-func (s *Subscription) Cancel(reason string, cancelledBy uuid.UUID) {
-	now := time.Now().UTC().Round(0)
-	s.Status = SubscriptionStatusCancelled
+func (s *Subscription) Cancel(reason string, cancelledBy uuid.UUID) error {
+	if err := s.TransitionTo(SubscriptionStatusCancelled); err != nil {
+		return err
+	}
 	s.CancellationReason = reason
 	s.CancelledBy = &cancelledBy
+	now := s.UpdatedAt
 	s.CancelledAt = &now
-	s.UpdatedAt = now
+	return nil
+}
+
+// CloseForPlanChange ends the period at `at` because its unused days were
+// credited to a subscription on another plan. The end date moves to `at` so a
+// later renewal does not chain from days that now live elsewhere, and
+// auto-renew goes off: the new subscription carries it.
+func (s *Subscription) CloseForPlanChange(at time.Time) error {
+	if err := s.TransitionTo(SubscriptionStatusPlanChanged); err != nil {
+		return err
+	}
+	s.EndDate = at
+	s.AutoRenew = false
+	return nil
+}
+
+// CloseForTransfer ends the period at `at` because its remaining days were
+// handed to another member, who carries on from here.
+func (s *Subscription) CloseForTransfer(at time.Time) error {
+	if err := s.TransitionTo(SubscriptionStatusTransferred); err != nil {
+		return err
+	}
+	s.EndDate = at
+	s.AutoRenew = false
+	return nil
 }
 
 // Freeze pauses the subscription for `days` days and extends the end date by the
@@ -142,20 +395,23 @@ func (s *Subscription) Cancel(reason string, cancelledBy uuid.UUID) {
 // time, by the days elapsed since the subscription STARTED rather than since the
 // freeze began). Freezing and unfreezing a two-month-old subscription for a week
 // handed out about two extra months.
-func (s *Subscription) Freeze(days int, reason string) {
+func (s *Subscription) Freeze(days int, reason string) error {
 	if days <= 0 {
-		return
+		return nil
+	}
+	if err := s.TransitionTo(SubscriptionStatusFrozen); err != nil {
+		return err
 	}
 
-	now := time.Now().UTC().Round(0)
+	now := s.UpdatedAt
 	until := now.AddDate(0, 0, days)
 
-	s.Status = SubscriptionStatusFrozen
 	s.FrozenUntil = &until
 	s.FreezeReason = reason
 	s.EndDate = s.EndDate.AddDate(0, 0, days)
 	s.TotalFreezeDays += days
-	s.UpdatedAt = now
+	s.FreezeCount++
+	return nil
 }
 
 // Unfreeze reactivates the subscription, returning the days of the freeze that
@@ -165,19 +421,26 @@ func (s *Subscription) Freeze(days int, reason string) {
 //
 // Partial days are rounded in the member's favour: half a day left is not
 // reclaimed.
-func (s *Subscription) Unfreeze() {
-	now := time.Now().UTC().Round(0)
+func (s *Subscription) Unfreeze() error {
+	if s.Status != SubscriptionStatusFrozen {
+		return &InvalidTransitionError{From: s.Status, To: SubscriptionStatusActive}
+	}
+	if err := s.TransitionTo(SubscriptionStatusActive); err != nil {
+		return err
+	}
+	now := s.UpdatedAt
 
-	if s.Status == SubscriptionStatusFrozen && s.FrozenUntil != nil {
-		if unused := int(s.FrozenUntil.Sub(now).Hours() / 24); unused > 0 {
+	if s.FrozenUntil != nil {
+		// Rounded to the minute first: the few microseconds between freezing and
+		// this call would otherwise turn "5 days left" into 4.99 and reclaim 4.
+		if unused := int(s.FrozenUntil.Sub(now).Round(time.Minute).Hours() / 24); unused > 0 {
 			s.EndDate = s.EndDate.AddDate(0, 0, -unused)
 			s.TotalFreezeDays -= unused
 		}
 	}
 
-	s.Status = SubscriptionStatusActive
 	s.FrozenUntil = nil
-	s.UpdatedAt = now
+	return nil
 }
 
 // FreezeExpired reports whether a frozen subscription has reached the end of its
@@ -188,19 +451,101 @@ func (s *Subscription) FreezeExpired() bool {
 		!time.Now().Before(*s.FrozenUntil)
 }
 
-// Expire marks the subscription as expired
-func (s *Subscription) Expire() {
-	s.Status = SubscriptionStatusExpired
+// Extend pushes the end date out by days the member gets for free, such as the
+// days the gym was closed. Only a subscription still running can be extended.
+func (s *Subscription) Extend(days int) error {
+	if s.Status != SubscriptionStatusActive && s.Status != SubscriptionStatusFrozen {
+		return fmt.Errorf("%w: una suscripción %s no se puede extender", errors.ErrInvalidTransition, s.Status)
+	}
+	s.EndDate = s.EndDate.AddDate(0, 0, days)
 	s.UpdatedAt = time.Now().UTC().Round(0)
+	return nil
 }
 
-// Renew renews the subscription
-func (s *Subscription) Renew(durationDays int, billingMode string) {
-	s.StartDate = s.EndDate
-	s.EndDate = addPlanDuration(s.EndDate, durationDays, billingMode)
-	s.Status = SubscriptionStatusActive
-	s.UpdatedAt = time.Now().UTC().Round(0)
+// Expire marks the subscription as expired
+func (s *Subscription) Expire() error {
+	return s.TransitionTo(SubscriptionStatusExpired)
+}
+
+// CanRenew reports, as an InvalidTransitionError, whether another period may be
+// chained onto s. Whatever can still come back to ACTIVE can be renewed; a
+// subscription in a final status is over and its member needs a new one.
+func (s *Subscription) CanRenew() error {
+	if s.Status == SubscriptionStatusActive {
+		return nil
+	}
+	return s.CanTransitionTo(SubscriptionStatusActive)
+}
+
+// UnusedCredit returns the share of the plan price (net of discount) that pays
+// for the days still ahead at `now`. The enrollment fee is not part of it: it
+// pays for joining, not for time.
+//
+// Days are counted whole and the days left are rounded up, in the member's
+// favour like everywhere else. Days added by freezes extend the period without
+// having been paid for, so they are left out of the base.
+//
+// A punch card is paid per visit, not per day: its credit is the share of
+// visits not yet used.
+//
+// The credit assumes the price was paid in full, whatever Balance says: callers
+// refuse subscriptions that still owe, or cap it by what was paid.
+func (s *Subscription) UnusedCredit(now time.Time) float64 {
+	paid := s.PricePaid - s.DiscountApplied
+	if paid <= 0 || !now.Before(s.EndDate) {
+		return 0
+	}
+	if s.IsVisitBased() {
+		return math.Round(paid * float64(s.VisitsLeft()) / float64(s.VisitAllowance))
+	}
+
+	from := s.StartDate
+	if now.After(from) {
+		from = now
+	}
+	left := math.Ceil(s.EndDate.Sub(from).Hours() / 24)
+	total := math.Round(s.EndDate.Sub(s.StartDate).Hours()/24) - float64(s.TotalFreezeDays)
+	if total <= 0 {
+		return 0
+	}
+	if left > total {
+		left = total
+	}
+
+	return math.Round(paid * left / total)
 }
 
+// ApplyCredit discounts a proration credit from what is due for this period.
+// When the credit is larger than the price — a downgrade with plenty of time
+// left — the rest buys extra days at this plan's daily rate instead of being
+// lost, so the member never pays for time twice. On a punch card it buys extra
+// visits instead. Set VisitAllowance before calling it.
+func (s *Subscription) ApplyCredit(credit float64) {
+	if credit <= 0 {
+		return
+	}
 
+	s.ProrationCredit = credit
+	// Credit is applied while pricing, before any installment: what is due is
+	// still what is paid.
+	if credit <= s.TotalPaid {
+		s.TotalPaid -= credit
+		s.AmountPaid = s.TotalPaid
+		return
+	}
 
+	leftover := credit - s.TotalPaid
+	s.TotalPaid = 0
+	s.AmountPaid = 0
+	if s.IsVisitBased() {
+		if s.PricePaid > 0 {
+			s.VisitAllowance += int(leftover / (s.PricePaid / float64(s.VisitAllowance)))
+		}
+		return
+	}
+	if days := math.Round(s.EndDate.Sub(s.StartDate).Hours() / 24); days > 0 && s.PricePaid > 0 {
+		if extra := int(leftover / (s.PricePaid / days)); extra > 0 {
+			s.EndDate = s.EndDate.AddDate(0, 0, extra)
+		}
+	}
+}
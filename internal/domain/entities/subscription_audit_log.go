@@ -6,16 +6,127 @@ import (
 	"github.com/google/uuid"
 )
 
-// SubscriptionAuditLog records manual changes to a subscription
+// AuditEvent is what happened to a subscription in one audit entry.
+type AuditEvent string
+
+const (
+	AuditEventCreated          AuditEvent = "CREATED"
+	AuditEventActivated        AuditEvent = "ACTIVATED"
+	AuditEventRenewed          AuditEvent = "RENEWED"
+	AuditEventAutoRenewed      AuditEvent = "AUTO_RENEWED"
+	AuditEventAutoRenewChanged AuditEvent = "AUTO_RENEW_CHANGED"
+	AuditEventPlanChanged      AuditEvent = "PLAN_CHANGED"
+	AuditEventTransferred      AuditEvent = "TRANSFERRED"
+	AuditEventMembersChanged   AuditEvent = "MEMBERS_CHANGED"
+	AuditEventDatesEdited      AuditEvent = "DATES_EDITED"
+	AuditEventExtended         AuditEvent = "EXTENDED"
+	AuditEventPaymentRecorded  AuditEvent = "PAYMENT_RECORDED"
+	AuditEventFrozen           AuditEvent = "FROZEN"
+	AuditEventUnfrozen         AuditEvent = "UNFROZEN"
+	AuditEventCancelled        AuditEvent = "CANCELLED"
+	AuditEventExpired          AuditEvent = "EXPIRED"
+)
+
+// SystemActor is the ChangedByName of the entries written by the scheduler
+// jobs (auto-renew, auto-expire, auto-unfreeze). Their ChangedByID is uuid.Nil.
+const SystemActor = "system"
+
+// SubscriptionSnapshot is the state of a subscription as the audit log sees it:
+// the fields an operation can change, and nothing else.
+type SubscriptionSnapshot struct {
+	Status          SubscriptionStatus `json:"status"`
+	PlanID          uuid.UUID          `json:"plan_id"`
+	StartDate       time.Time          `json:"start_date"`
+	EndDate         time.Time          `json:"end_date"`
+	FrozenUntil     *time.Time         `json:"frozen_until,omitempty"`
+	TotalFreezeDays int                `json:"total_freeze_days"`
+	FreezeCount     int                `json:"freeze_count,omitempty"`
+	TotalPaid       float64            `json:"total_paid"`
+	AmountPaid      float64            `json:"amount_paid"`
+	VisitAllowance  int                `json:"visit_allowance,omitempty"`
+	VisitsUsed      int                `json:"visits_used,omitempty"`
+	AutoRenew       bool               `json:"auto_renew"`
+}
+
+// Snapshot captures the audited state of s. Take it before mutating s: the
+// subscription is changed in place.
+func (s *Subscription) Snapshot() *SubscriptionSnapshot {
+	snap := &SubscriptionSnapshot{
+		Status:          s.Status,
+		PlanID:          s.PlanID,
+		StartDate:       s.StartDate,
+		EndDate:         s.EndDate,
+		TotalFreezeDays: s.TotalFreezeDays,
+		FreezeCount:     s.FreezeCount,
+		TotalPaid:       s.TotalPaid,
+		AmountPaid:      s.AmountPaid,
+		VisitAllowance:  s.VisitAllowance,
+		VisitsUsed:      s.VisitsUsed,
+		AutoRenew:       s.AutoRenew,
+	}
+	if s.FrozenUntil != nil {
+		until := *s.FrozenUntil
+		snap.FrozenUntil = &until
+	}
+	return snap
+}
+
+// SubscriptionAuditLog records one change to a subscription: what happened
+// (Event), who did it (ChangedByID/Name, SystemActor for the scheduler jobs) and
+// the state before and after. Before is nil for a subscription that was just
+// created.
+//
+// The Old/New date and amount columns predate the snapshots and are still filled
+// from them, so entries read the same wherever those columns are already used.
 type SubscriptionAuditLog struct {
-	ID             uuid.UUID `json:"id" gorm:"primaryKey"`
-	SubscriptionID uuid.UUID `json:"subscription_id" gorm:"index:idx_sub_audit_sub,priority:1"`
-	ChangedByID    uuid.UUID `json:"changed_by_id"`
-	ChangedByName  string    `json:"changed_by_name"`
-	Description    string    `json:"description"`
-	OldStartDate   time.Time `json:"old_start_date"`
-	NewStartDate   time.Time `json:"new_start_date"`
-	OldEndDate     time.Time `json:"old_end_date"`
-	NewEndDate     time.Time `json:"new_end_date"`
-	CreatedAt      time.Time `json:"created_at" gorm:"index:idx_sub_audit_sub,priority:2"`
+	ID             uuid.UUID             `json:"id" gorm:"primaryKey"`
+	SubscriptionID uuid.UUID             `json:"subscription_id" gorm:"index:idx_sub_audit_sub,priority:1"`
+	Event          AuditEvent            `json:"event"`
+	ChangedByID    uuid.UUID             `json:"changed_by_id"`
+	ChangedByName  string                `json:"changed_by_name"`
+	Description    string                `json:"description"`
+	Before         *SubscriptionSnapshot `json:"before,omitempty" gorm:"serializer:json"`
+	After          *SubscriptionSnapshot `json:"after,omitempty" gorm:"serializer:json"`
+	OldStartDate   time.Time             `json:"old_start_date"`
+	NewStartDate   time.Time             `json:"new_start_date"`
+	OldEndDate     time.Time             `json:"old_end_date"`
+	NewEndDate     time.Time             `json:"new_end_date"`
+	// OldAmount/NewAmount record money moved by the change (a plan change's
+	// price before and after proration). Zero for changes that move no money.
+	OldAmount float64   `json:"old_amount"`
+	NewAmount float64   `json:"new_amount"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_sub_audit_sub,priority:2"`
+}
+
+// NewSubscriptionAuditLog builds the entry for an event on subscriptionID, going
+// from before to after (either may be nil, not both).
+func NewSubscriptionAuditLog(subscriptionID uuid.UUID, event AuditEvent, before, after *SubscriptionSnapshot, changedByID uuid.UUID, changedByName, description string) *SubscriptionAuditLog {
+	log := &SubscriptionAuditLog{
+		ID:             uuid.New(),
+		SubscriptionID: subscriptionID,
+		Event:          event,
+		ChangedByID:    changedByID,
+		ChangedByName:  changedByName,
+		Description:    description,
+		Before:         before,
+		After:          after,
+		CreatedAt:      time.Now().UTC().Round(0),
+	}
+
+	from, to := before, after
+	if from == nil {
+		from = after
+	}
+	if to == nil {
+		to = before
+	}
+	log.OldStartDate, log.OldEndDate = from.StartDate, from.EndDate
+	log.NewStartDate, log.NewEndDate = to.StartDate, to.EndDate
+	switch {
+	case before == nil:
+		log.NewAmount = after.TotalPaid
+	case after != nil && before.TotalPaid != after.TotalPaid:
+		log.OldAmount, log.NewAmount = before.TotalPaid, after.TotalPaid
+	}
+	return log
 }
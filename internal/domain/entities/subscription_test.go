@@ -1,8 +1,12 @@
 package entities
 
 import (
+	stderrors "errors"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
 )
 
 // activeSubscription returns a subscription that started 60 days ago and ends in
@@ -147,3 +151,77 @@ func TestFreezeExpired(t *testing.T) {
 		t.Error("un congelamiento cuya fecha ya pasó debería estar vencido")
 	}
 }
+
+func TestUnusedCreditIsProportionalToTheDaysLeft(t *testing.T) {
+	sub := activeSubscription() // 90 días, quedan 30
+	sub.PricePaid = 90000
+	sub.DiscountApplied = 0
+
+	if got := sub.UnusedCredit(time.Now().UTC()); got != 30000 {
+		t.Errorf("crédito = %v, want 30000 (30 de 90 días)", got)
+	}
+
+	sub.EnrollmentFeePaid = 50000
+	if got := sub.UnusedCredit(time.Now().UTC()); got != 30000 {
+		t.Errorf("la matrícula no se acredita: crédito = %v, want 30000", got)
+	}
+
+	if got := sub.UnusedCredit(sub.EndDate.Add(time.Hour)); got != 0 {
+		t.Errorf("una suscripción vencida no deja crédito, got %v", got)
+	}
+}
+
+func TestApplyCreditTurnsTheExcessIntoDays(t *testing.T) {
+	start := time.Now().UTC().Round(0)
+	sub := NewSubscription(uuid.New(), uuid.New(), uuid.New(), start, 30, "30_DAYS", 60000, 0, 0)
+
+	sub.ApplyCredit(20000)
+	if sub.TotalPaid != 40000 || sub.ProrationCredit != 20000 {
+		t.Errorf("upgrade: total %v crédito %v, want 40000 y 20000", sub.TotalPaid, sub.ProrationCredit)
+	}
+
+	sub = NewSubscription(uuid.New(), uuid.New(), uuid.New(), start, 30, "30_DAYS", 60000, 0, 0)
+	originalEnd := sub.EndDate
+	sub.ApplyCredit(80000) // sobran 20000 a 2000/día
+	if sub.TotalPaid != 0 {
+		t.Errorf("downgrade: total %v, want 0", sub.TotalPaid)
+	}
+	if got := daysApart(originalEnd, sub.EndDate); got != 10 {
+		t.Errorf("el excedente compró %d días, want 10", got)
+	}
+}
+
+// A cancelled subscription is final: nothing brings it back, and the error says
+// from where to where so the 409 can be read by reception.
+func TestTransitionsRefuseWhatTheLifeCycleDoesNotAllow(t *testing.T) {
+	sub := activeSubscription()
+	if err := sub.Cancel("mudanza", uuid.New()); err != nil {
+		t.Fatalf("cancelar una activa: %v", err)
+	}
+
+	err := sub.Freeze(7, "viaje")
+	var transition *InvalidTransitionError
+	if !stderrors.As(err, &transition) || !stderrors.Is(err, apperrors.ErrInvalidTransition) {
+		t.Fatalf("congelar una cancelada: err = %v, want InvalidTransitionError", err)
+	}
+	if transition.From != SubscriptionStatusCancelled || transition.To != SubscriptionStatusFrozen {
+		t.Errorf("transición %s → %s, want CANCELLED → FROZEN", transition.From, transition.To)
+	}
+	if sub.Status != SubscriptionStatusCancelled || sub.FrozenUntil != nil {
+		t.Errorf("el intento fallido cambió la suscripción: estado %s", sub.Status)
+	}
+	if err := sub.CanRenew(); !stderrors.Is(err, apperrors.ErrInvalidTransition) {
+		t.Error("renovar una cancelada debería fallar")
+	}
+
+	expired := activeSubscription()
+	if err := expired.Expire(); err != nil {
+		t.Fatalf("vencer una activa: %v", err)
+	}
+	if err := expired.Unfreeze(); err == nil {
+		t.Error("descongelar una vencida debería fallar")
+	}
+	if err := expired.TransitionTo(SubscriptionStatusActive); err != nil {
+		t.Errorf("una vencida con fechas corregidas vuelve a activa: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Prices of products and plans carry a TaxRate, the IVA percentage (0, 5 or
+// 19 in Colombia), and TaxExcluded: false, the default, is a price that
+// already includes the IVA, the one shown to the member; true is a price
+// before IVA, which is added on top when it is charged. Everything charged
+// (sale details, subscriptions, payments) is stored with the IVA included.
+
+// ValidateTaxRate checks an IVA percentage.
+func ValidateTaxRate(rate float64) error {
+	if rate < 0 || rate >= 100 {
+		return fmt.Errorf("la tarifa de IVA debe estar entre 0 y 100, se pidió %.2f", rate)
+	}
+	return nil
+}
+
+// AddTax is price with rate percent of IVA on top.
+func AddTax(price, rate float64) float64 {
+	return roundCents(price * (1 + rate/100))
+}
+
+// SplitTax separates an amount that includes rate percent of IVA into its
+// taxable base and the tax. base + tax is always amount.
+func SplitTax(amount, rate float64) (base, tax float64) {
+	if rate == 0 {
+		return amount, 0
+	}
+	base = roundCents(amount / (1 + rate/100))
+	return base, roundCents(amount - base)
+}
+
+// TaxBreakdown is the taxable base and the IVA of everything charged at one
+// rate.
+type TaxBreakdown struct {
+	Rate float64 `json:"rate"`
+	Base float64 `json:"base"`
+	Tax  float64 `json:"tax"`
+}
+
+// Total is what was charged at the rate, IVA included.
+func (t TaxBreakdown) Total() float64 {
+	return t.Base + t.Tax
+}
+
+// AddToBreakdown adds base and tax to their rate in taxes, kept ordered by
+// rate.
+func AddToBreakdown(taxes []TaxBreakdown, rate, base, tax float64) []TaxBreakdown {
+	for i := range taxes {
+		if taxes[i].Rate == rate {
+			taxes[i].Base += base
+			taxes[i].Tax += tax
+			return taxes
+		}
+	}
+	taxes = append(taxes, TaxBreakdown{Rate: rate, Base: base, Tax: tax})
+	sort.Slice(taxes, func(i, j int) bool { return taxes[i].Rate < taxes[j].Rate })
+	return taxes
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
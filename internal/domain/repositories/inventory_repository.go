@@ -7,11 +7,13 @@ import (
 	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
 )
 
-// ProductRepository defines the interface for product data operations
+// ProductRepository defines the interface for product data operations. Every
+// gym has its own inventory: the listings take the gym, and a product read by ID
+// is checked against the caller's gym by the use case.
 type ProductRepository interface {
 	Create(ctx context.Context, product *entities.Product) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.Product, error)
-	GetAll(ctx context.Context, status *entities.ProductStatus) ([]entities.Product, error)
+	GetAll(ctx context.Context, gymID uuid.UUID, status *entities.ProductStatus) ([]entities.Product, error)
 	Update(ctx context.Context, product *entities.Product) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	UpdateStock(ctx context.Context, productID uuid.UUID, quantity int) error
@@ -23,18 +25,21 @@ type ProductRepository interface {
 	DecrementStock(ctx context.Context, productID uuid.UUID, qty int) error
 	// IncrementStock returns qty to stock (voided sales).
 	IncrementStock(ctx context.Context, productID uuid.UUID, qty int) error
-	Search(ctx context.Context, searchTerm string) ([]entities.Product, error)
+	Search(ctx context.Context, gymID uuid.UUID, searchTerm string) ([]entities.Product, error)
 }
 
-// SaleRepository defines the interface for sale data operations
+// SaleRepository defines the interface for sale data operations, scoped by gym
+// like ProductRepository.
 type SaleRepository interface {
 	Create(ctx context.Context, sale *entities.Sale) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.Sale, error)
-	GetAll(ctx context.Context) ([]entities.Sale, error)
+	GetAll(ctx context.Context, gymID uuid.UUID) ([]entities.Sale, error)
 	Update(ctx context.Context, sale *entities.Sale) error
-	GetByDateRange(ctx context.Context, startDate, endDate string, userID *uuid.UUID) ([]entities.Sale, error)
-	GetSalesReport(ctx context.Context, startDate, endDate string, userID *uuid.UUID) ([]SaleReport, error)
-	GetSalesReportByProduct(ctx context.Context, startDate, endDate string) ([]SaleProductReport, error)
+	GetByDateRange(ctx context.Context, gymID uuid.UUID, startDate, endDate string, userID *uuid.UUID) ([]entities.Sale, error)
+	// GetReturns returns the return documents of a sale, oldest first.
+	GetReturns(ctx context.Context, saleID uuid.UUID) ([]entities.Sale, error)
+	GetSalesReport(ctx context.Context, gymID uuid.UUID, startDate, endDate string, userID *uuid.UUID) ([]SaleReport, error)
+	GetSalesReportByProduct(ctx context.Context, gymID uuid.UUID, startDate, endDate string) ([]SaleProductReport, error)
 }
 
 // SaleDetailRepository defines the interface for sale detail data operations
@@ -48,21 +53,33 @@ type SaleDetailRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.SaleDetail, error)
 }
 
-// PaymentMethodRepository defines the interface for payment method data operations
+// SaleTenderRepository defines the interface for the tenders a sale was paid with
+type SaleTenderRepository interface {
+	CreateBatch(ctx context.Context, tenders []entities.SaleTender) error
+	// GetBySaleID returns no tenders for a sale from before they existed.
+	GetBySaleID(ctx context.Context, saleID uuid.UUID) ([]entities.SaleTender, error)
+}
+
+// PaymentMethodRepository defines the interface for payment method data
+// operations, scoped by gym like ProductRepository.
 type PaymentMethodRepository interface {
 	Create(ctx context.Context, method *entities.SalePaymentMethod) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.SalePaymentMethod, error)
-	GetAll(ctx context.Context, status *entities.PaymentMethodStatus) ([]entities.SalePaymentMethod, error)
+	GetAll(ctx context.Context, gymID uuid.UUID, status *entities.PaymentMethodStatus) ([]entities.SalePaymentMethod, error)
 	Update(ctx context.Context, method *entities.SalePaymentMethod) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
-// SaleReport represents aggregated sale data
+// SaleReport represents aggregated sale data. The IVA is that of the sales
+// completed in the range, by rate; TaxableBase + TaxTotal is what they charged.
 type SaleReport struct {
-	TotalSales    float64 `json:"total_sales"`
-	TotalDiscount float64 `json:"total_discount"`
-	NetSales      float64 `json:"net_sales"`
-	SalesCount    int     `json:"sales_count"`
+	TotalSales    float64                 `json:"total_sales"`
+	TotalDiscount float64                 `json:"total_discount"`
+	NetSales      float64                 `json:"net_sales"`
+	SalesCount    int                     `json:"sales_count"`
+	TaxableBase   float64                 `json:"taxable_base"`
+	TaxTotal      float64                 `json:"tax_total"`
+	Taxes         []entities.TaxBreakdown `json:"taxes,omitempty" gorm:"-"`
 }
 
 // SaleProductReport represents sales grouped by product
@@ -71,12 +71,32 @@ type SubscriptionRepository interface {
 	Update(subscription *entities.Subscription) error
 	Delete(id uuid.UUID) error
 	CountActiveByGymID(gymID uuid.UUID) (int64, error)
-	MarkExpiredSubscriptions() (int64, error)
+	// FindToExpire returns the active subscriptions whose end date is before
+	// `now`: what the hourly job expires through ExpireByIDs.
+	FindToExpire(now time.Time) ([]*entities.Subscription, error)
+	// ExpireByIDs expires the given subscriptions if they are still active.
+	ExpireByIDs(ids []uuid.UUID) (int64, error)
+	// FindToExtend returns the ACTIVE and FROZEN subscriptions of a gym, only
+	// those on planIDs when it is not empty.
+	FindToExtend(gymID uuid.UUID, planIDs []uuid.UUID) ([]*entities.Subscription, error)
 	// FindExpiredFreezes returns frozen subscriptions whose freeze period is over
 	// and that should go back to active.
 	FindExpiredFreezes() ([]*entities.Subscription, error)
+	// FindDueToStart returns the pending subscriptions whose start date is at or
+	// before `now`.
+	FindDueToStart(now time.Time) ([]*entities.Subscription, error)
+	// FindDueForAutoRenew returns active auto-renew subscriptions whose end date
+	// falls on or before `before`.
+	FindDueForAutoRenew(before time.Time) ([]*entities.Subscription, error)
 	// MarkRemindersSent flags many subscriptions as notified in one statement.
 	MarkRemindersSent(ids []uuid.UUID) error
+	// ConsumeVisit uses one entry of an active punch card. It reports false,
+	// without changing anything, when there is no entry left to use. It does
+	// not close the card when the entry was the last one.
+	ConsumeVisit(id uuid.UUID) (bool, error)
+	// AddPayment adds amount to what was paid of a subscription. It reports
+	// false, without changing anything, when that would pay more than is owed.
+	AddPayment(id uuid.UUID, amount float64) (bool, error)
 }
 
 // SubscriptionMemberRepository defines group membership repository interface
@@ -89,6 +109,8 @@ type SubscriptionMemberRepository interface {
 	FindActiveSubscriptionByUserID(userID uuid.UUID) (*entities.Subscription, error)
 	FindSubscriptionsByMemberUserID(userID uuid.UUID) ([]*entities.Subscription, error)
 	DeleteBySubscriptionID(subscriptionID uuid.UUID) error
+	// DeleteBySubscriptionAndUser removes one beneficiary from a group.
+	DeleteBySubscriptionAndUser(subscriptionID, userID uuid.UUID) error
 }
 
 // SubscriptionAuditLogRepository defines audit log repository interface
@@ -104,10 +126,94 @@ type PaymentRepository interface {
 	FindByUserID(userID uuid.UUID) ([]*entities.Payment, error)
 	FindByGymID(gymID uuid.UUID, limit, offset int) ([]*entities.Payment, error)
 	FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.Payment, error)
+	FindBySubscriptionID(subscriptionID uuid.UUID) ([]*entities.Payment, error)
+	// FindBySaleID returns the charges of a sale, one per tender, and none for
+	// a sale of total 0.
+	FindBySaleID(saleID uuid.UUID) ([]*entities.Payment, error)
+	// FindRefundsByDateRange returns the payments refunded between the local
+	// dates from and to (inclusive), whenever they were originally charged.
+	FindRefundsByDateRange(gymID uuid.UUID, from, to string) ([]*entities.Payment, error)
+	// FindByCashRegisterID returns the payments taken or refunded in a register
+	// session.
+	FindByCashRegisterID(sessionID uuid.UUID) ([]*entities.Payment, error)
 	Update(payment *entities.Payment) error
 	GetTotalRevenueByGymID(gymID uuid.UUID) (float64, error)
 }
 
+// CouponRepository defines coupon repository interface
+type CouponRepository interface {
+	Create(coupon *entities.Coupon) error
+	FindByID(id uuid.UUID) (*entities.Coupon, error)
+	// FindByCode looks up a code as typed at the counter. It returns nil, nil
+	// when the gym has no such coupon.
+	FindByCode(gymID uuid.UUID, code string) (*entities.Coupon, error)
+	FindByGymID(gymID uuid.UUID) ([]*entities.Coupon, error)
+	Update(coupon *entities.Coupon) error
+	// IncrementUses takes one use of the coupon, failing with ErrInvalidCoupon
+	// when MaxUses is already reached.
+	IncrementUses(id uuid.UUID) error
+	CreateRedemption(redemption *entities.CouponRedemption) error
+	CountRedemptionsByUser(couponID, userID uuid.UUID) (int64, error)
+	FindRedemptionsByCouponID(couponID uuid.UUID) ([]*entities.CouponRedemption, error)
+	// FindRedemptionsByDateRange returns the redemptions between the local
+	// dates from and to (inclusive).
+	FindRedemptionsByDateRange(gymID uuid.UUID, from, to string) ([]*entities.CouponRedemption, error)
+}
+
+// GymClosureRepository defines gym closure repository interface. Dates are
+// local YYYY-MM-DD strings, both ends included.
+type GymClosureRepository interface {
+	Create(closure *entities.GymClosure) error
+	FindByID(id uuid.UUID) (*entities.GymClosure, error)
+	// FindByDate returns nil, nil when the gym opens on date.
+	FindByDate(gymID uuid.UUID, date string) (*entities.GymClosure, error)
+	FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.GymClosure, error)
+	// FindUncompensated returns the closures up to date whose days were not
+	// given back to the members yet, oldest first.
+	FindUncompensated(gymID uuid.UUID, upTo string) ([]*entities.GymClosure, error)
+	MarkCompensated(id uuid.UUID, at time.Time) error
+	Delete(id uuid.UUID) error
+}
+
+// CashRegisterRepository defines cash register session repository interface
+type CashRegisterRepository interface {
+	Create(session *entities.CashRegisterSession) error
+	FindByID(id uuid.UUID) (*entities.CashRegisterSession, error)
+	// FindOpenByUser returns nil, nil when userID has no register open.
+	FindOpenByUser(userID uuid.UUID) (*entities.CashRegisterSession, error)
+	// FindByDateRange returns the sessions opened between the local dates from
+	// and to (inclusive), newest first.
+	FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.CashRegisterSession, error)
+	Update(session *entities.CashRegisterSession) error
+}
+
+// InvoiceRepository defines electronic invoice repository interface
+type InvoiceRepository interface {
+	// Create saves the invoice with its lines.
+	Create(invoice *entities.Invoice) error
+	// FindByID returns the invoice with its lines.
+	FindByID(id uuid.UUID) (*entities.Invoice, error)
+	// FindBySaleID and FindByPaymentID return nil, nil when the source has not
+	// been invoiced.
+	FindBySaleID(saleID uuid.UUID) (*entities.Invoice, error)
+	FindByPaymentID(paymentID uuid.UUID) (*entities.Invoice, error)
+	// FindByDateRange returns the invoices issued between the local dates from
+	// and to (inclusive), in numbering order, without their lines.
+	FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.Invoice, error)
+	Update(invoice *entities.Invoice) error
+}
+
+// InvoiceResolutionRepository defines DIAN numbering resolution repository interface
+type InvoiceResolutionRepository interface {
+	Create(resolution *entities.InvoiceResolution) error
+	// FindActiveByGym returns nil, nil when the gym has no active resolution.
+	FindActiveByGym(gymID uuid.UUID) (*entities.InvoiceResolution, error)
+	FindByGymID(gymID uuid.UUID) ([]*entities.InvoiceResolution, error)
+	// DeactivateByGym deactivates every resolution of the gym.
+	DeactivateByGym(gymID uuid.UUID) error
+	Update(resolution *entities.InvoiceResolution) error
+}
+
 // AccessLogRepository defines access log repository interface
 type AccessLogRepository interface {
 	Create(log *entities.AccessLog) error
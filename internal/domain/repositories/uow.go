@@ -11,8 +11,16 @@ type Repos struct {
 	Members       SubscriptionMemberRepository
 	Audit         SubscriptionAuditLogRepository
 	Products      ProductRepository
+	PayMethods    PaymentMethodRepository
 	Sales         SaleRepository
 	SaleDetails   SaleDetailRepository
+	SaleTenders   SaleTenderRepository
+	Payments      PaymentRepository
+	Coupons       CouponRepository
+	Closures      GymClosureRepository
+	Registers     CashRegisterRepository
+	Invoices      InvoiceRepository
+	Resolutions   InvoiceResolutionRepository
 }
 
 // UnitOfWork ejecuta una función dentro de una única transacción de base de datos.
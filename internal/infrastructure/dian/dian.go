@@ -0,0 +1,103 @@
+// Package dian produces Colombian electronic invoices: the UBL 2.1 document the
+// DIAN's Anexo Técnico 1.9 describes, its CUFE, and the submission to the DIAN.
+//
+// The XML is not signed here. The XAdES signature needs the gym's digital
+// certificate and is part of the submission: a real Submitter signs the
+// document before sending it.
+package dian
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+)
+
+// Submitter sends invoices to the DIAN.
+type Submitter interface {
+	// Submit sends the invoice and returns the DIAN's verdict on it. An error
+	// is a submission that did not get an answer and may be retried.
+	Submit(ctx context.Context, invoice *entities.Invoice) (*SubmitResult, error)
+}
+
+// SubmitResult is the DIAN's answer to a submission.
+type SubmitResult struct {
+	Accepted bool
+	TrackID  string
+	Message  string
+}
+
+// StubSubmitter accepts every invoice without sending it anywhere, for gyms
+// not yet enabled as electronic invoicers and for development.
+type StubSubmitter struct{}
+
+func NewStubSubmitter() *StubSubmitter {
+	return &StubSubmitter{}
+}
+
+func (s *StubSubmitter) Submit(_ context.Context, invoice *entities.Invoice) (*SubmitResult, error) {
+	return &SubmitResult{
+		Accepted: true,
+		TrackID:  "LOCAL-" + invoice.FullNumber,
+		Message:  "Validada localmente, no enviada a la DIAN",
+	}, nil
+}
+
+// CUFE is the Código Único de Factura Electrónica: the SHA-384 of the number,
+// the date, the amounts and the parties of the invoice, with the technical key
+// of its resolution. The DIAN recomputes it on reception, so every amount
+// must be formatted exactly as in the XML.
+func CUFE(inv *entities.Invoice, supplierNIT, technicalKey string, env entities.InvoiceEnvironment) string {
+	var b strings.Builder
+	b.WriteString(inv.FullNumber)
+	b.WriteString(inv.IssueDate)
+	b.WriteString(inv.IssueTime)
+	b.WriteString(amount(inv.LineExtension()))
+	b.WriteString("01" + amount(inv.TaxTotal)) // IVA
+	b.WriteString("04" + amount(0))            // INC
+	b.WriteString("03" + amount(0))            // ICA
+	b.WriteString(amount(inv.Total))
+	b.WriteString(supplierNIT)
+	b.WriteString(inv.CustomerDocument)
+	b.WriteString(technicalKey)
+	b.WriteString(string(env))
+	sum := sha512.Sum384([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// SplitNIT separates a tax ID as written on the gym ("900.123.456-7") into the
+// NIT and its check digit, computing the check digit when it is missing.
+func SplitNIT(taxID string) (nit, dv string) {
+	taxID = strings.NewReplacer(".", "", " ", "").Replace(taxID)
+	if i := strings.IndexByte(taxID, '-'); i >= 0 {
+		return taxID[:i], taxID[i+1:]
+	}
+	return taxID, CheckDigit(taxID)
+}
+
+// CheckDigit computes the DIAN check digit (dígito de verificación) of a NIT.
+func CheckDigit(nit string) string {
+	weights := []int{3, 7, 13, 17, 19, 23, 29, 37, 41, 43, 47, 53, 59, 67, 71}
+	sum := 0
+	for i := 0; i < len(nit) && i < len(weights); i++ {
+		c := nit[len(nit)-1-i]
+		if c < '0' || c > '9' {
+			return ""
+		}
+		sum += int(c-'0') * weights[i]
+	}
+	r := sum % 11
+	if r > 1 {
+		r = 11 - r
+	}
+	return fmt.Sprintf("%d", r)
+}
+
+// amount formats a value the way the UBL and the CUFE carry it: two decimals,
+// a dot, no thousands separator.
+func amount(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
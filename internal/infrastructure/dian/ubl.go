@@ -0,0 +1,221 @@
+package dian
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"text/template"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+)
+
+// QRURL is where the DIAN lets anyone check an invoice by its CUFE, printed as
+// a QR on the graphic representation.
+const QRURL = "https://catalogo-vpfe.dian.gov.co/document/searchqr?documentkey="
+
+// BuildXML generates the UBL 2.1 invoice (factura electrónica de venta) of inv,
+// issued by gym under res. inv must be numbered and carry its CUFE.
+func BuildXML(inv *entities.Invoice, gym *entities.Gym, res *entities.InvoiceResolution) ([]byte, error) {
+	nit, dv := SplitNIT(gym.TaxID)
+	legalName := gym.LegalName
+	if legalName == "" {
+		legalName = gym.Name
+	}
+	data := ublData{
+		Inv:        inv,
+		Res:        res,
+		Gym:        gym,
+		NIT:        nit,
+		DV:         dv,
+		LegalName:  legalName,
+		CustomerDV: "",
+		QR:         QRURL + inv.CUFE,
+	}
+	if inv.CustomerDocType == "31" {
+		data.CustomerDV = CheckDigit(inv.CustomerDocument)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := ublTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("generando UBL: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type ublData struct {
+	Inv        *entities.Invoice
+	Res        *entities.InvoiceResolution
+	Gym        *entities.Gym
+	NIT        string
+	DV         string
+	LegalName  string
+	CustomerDV string
+	QR         string
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+var ublTemplate = template.Must(template.New("ubl").Funcs(template.FuncMap{
+	"x":      xmlEscape,
+	"amount": amount,
+}).Parse(`<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2" xmlns:sts="dian:gov:co:facturaelectronica:Structures-2-1">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <sts:DianExtensions>
+          <sts:InvoiceControl>
+            <sts:InvoiceAuthorization>{{x .Res.ResolutionNumber}}</sts:InvoiceAuthorization>
+            <sts:AuthorizationPeriod>
+              <cbc:StartDate>{{.Res.ValidFrom}}</cbc:StartDate>
+              <cbc:EndDate>{{.Res.ValidTo}}</cbc:EndDate>
+            </sts:AuthorizationPeriod>
+            <sts:AuthorizedInvoices>
+              <sts:Prefix>{{x .Res.Prefix}}</sts:Prefix>
+              <sts:From>{{.Res.From}}</sts:From>
+              <sts:To>{{.Res.To}}</sts:To>
+            </sts:AuthorizedInvoices>
+          </sts:InvoiceControl>
+          <sts:InvoiceSource>
+            <cbc:IdentificationCode listAgencyID="6" listAgencyName="United Nations Economic Commission for Europe" listSchemeURI="urn:oasis:names:specification:ubl:codelist:gc:CountryIdentificationCode-2.1">CO</cbc:IdentificationCode>
+          </sts:InvoiceSource>
+          <sts:QRCode>{{x .QR}}</sts:QRCode>
+        </sts:DianExtensions>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>
+  <cbc:CustomizationID>10</cbc:CustomizationID>
+  <cbc:ProfileID>DIAN 2.1: Factura Electrónica de Venta</cbc:ProfileID>
+  <cbc:ProfileExecutionID>{{.Res.Environment}}</cbc:ProfileExecutionID>
+  <cbc:ID>{{x .Inv.FullNumber}}</cbc:ID>
+  <cbc:UUID schemeID="{{.Res.Environment}}" schemeName="CUFE-SHA384">{{.Inv.CUFE}}</cbc:UUID>
+  <cbc:IssueDate>{{.Inv.IssueDate}}</cbc:IssueDate>
+  <cbc:IssueTime>{{.Inv.IssueTime}}</cbc:IssueTime>
+  <cbc:InvoiceTypeCode>01</cbc:InvoiceTypeCode>
+  <cbc:DocumentCurrencyCode>{{x .Inv.Currency}}</cbc:DocumentCurrencyCode>
+  <cbc:LineCountNumeric>{{len .Inv.Lines}}</cbc:LineCountNumeric>
+  <cac:AccountingSupplierParty>
+    <cbc:AdditionalAccountID>1</cbc:AdditionalAccountID>
+    <cac:Party>
+      <cac:PartyName>
+        <cbc:Name>{{x .Gym.Name}}</cbc:Name>
+      </cac:PartyName>
+      <cac:PhysicalLocation>
+        <cac:Address>
+          <cbc:CityName>{{x .Gym.City}}</cbc:CityName>
+          <cbc:CountrySubentity>{{x .Gym.State}}</cbc:CountrySubentity>
+          <cac:AddressLine>
+            <cbc:Line>{{x .Gym.Address}}</cbc:Line>
+          </cac:AddressLine>
+          <cac:Country>
+            <cbc:IdentificationCode>CO</cbc:IdentificationCode>
+          </cac:Country>
+        </cac:Address>
+      </cac:PhysicalLocation>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .LegalName}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeAgencyName="CO, DIAN (Dirección de Impuestos y Aduanas Nacionales)" schemeID="{{.DV}}" schemeName="31">{{x .NIT}}</cbc:CompanyID>
+        <cbc:TaxLevelCode listName="48">R-99-PN</cbc:TaxLevelCode>
+        <cac:TaxScheme>
+          <cbc:ID>01</cbc:ID>
+          <cbc:Name>IVA</cbc:Name>
+        </cac:TaxScheme>
+      </cac:PartyTaxScheme>
+      <cac:PartyLegalEntity>
+        <cbc:RegistrationName>{{x .LegalName}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeID="{{.DV}}" schemeName="31">{{x .NIT}}</cbc:CompanyID>
+        <cac:CorporateRegistrationScheme>
+          <cbc:ID>{{x .Res.Prefix}}</cbc:ID>
+        </cac:CorporateRegistrationScheme>
+      </cac:PartyLegalEntity>
+      <cac:Contact>
+        <cbc:Telephone>{{x .Gym.Phone}}</cbc:Telephone>
+        <cbc:ElectronicMail>{{x .Gym.Email}}</cbc:ElectronicMail>
+      </cac:Contact>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:AccountingCustomerParty>
+    <cbc:AdditionalAccountID>2</cbc:AdditionalAccountID>
+    <cac:Party>
+      <cac:PartyIdentification>
+        <cbc:ID schemeName="{{.Inv.CustomerDocType}}"{{if .CustomerDV}} schemeID="{{.CustomerDV}}"{{end}}>{{x .Inv.CustomerDocument}}</cbc:ID>
+      </cac:PartyIdentification>
+      <cac:PartyTaxScheme>
+        <cbc:RegistrationName>{{x .Inv.CustomerName}}</cbc:RegistrationName>
+        <cbc:CompanyID schemeAgencyID="195" schemeName="{{.Inv.CustomerDocType}}"{{if .CustomerDV}} schemeID="{{.CustomerDV}}"{{end}}>{{x .Inv.CustomerDocument}}</cbc:CompanyID>
+        <cbc:TaxLevelCode listName="48">R-99-PN</cbc:TaxLevelCode>
+        <cac:TaxScheme>
+          <cbc:ID>ZZ</cbc:ID>
+          <cbc:Name>No aplica</cbc:Name>
+        </cac:TaxScheme>
+      </cac:PartyTaxScheme>
+      <cac:PartyLegalEntity>
+        <cbc:RegistrationName>{{x .Inv.CustomerName}}</cbc:RegistrationName>
+      </cac:PartyLegalEntity>{{if .Inv.CustomerEmail}}
+      <cac:Contact>
+        <cbc:ElectronicMail>{{x .Inv.CustomerEmail}}</cbc:ElectronicMail>
+      </cac:Contact>{{end}}
+    </cac:Party>
+  </cac:AccountingCustomerParty>
+  <cac:PaymentMeans>
+    <cbc:ID>1</cbc:ID>
+    <cbc:PaymentMeansCode>10</cbc:PaymentMeansCode>
+  </cac:PaymentMeans>
+  <cac:TaxTotal>
+    <cbc:TaxAmount currencyID="{{.Inv.Currency}}">{{amount .Inv.TaxTotal}}</cbc:TaxAmount>{{range .Inv.Taxes}}
+    <cac:TaxSubtotal>
+      <cbc:TaxableAmount currencyID="{{$.Inv.Currency}}">{{amount .Base}}</cbc:TaxableAmount>
+      <cbc:TaxAmount currencyID="{{$.Inv.Currency}}">{{amount .Tax}}</cbc:TaxAmount>
+      <cac:TaxCategory>
+        <cbc:Percent>{{amount .Rate}}</cbc:Percent>
+        <cac:TaxScheme>
+          <cbc:ID>01</cbc:ID>
+          <cbc:Name>IVA</cbc:Name>
+        </cac:TaxScheme>
+      </cac:TaxCategory>
+    </cac:TaxSubtotal>{{end}}
+  </cac:TaxTotal>
+  <cac:LegalMonetaryTotal>
+    <cbc:LineExtensionAmount currencyID="{{.Inv.Currency}}">{{amount .Inv.LineExtension}}</cbc:LineExtensionAmount>
+    <cbc:TaxExclusiveAmount currencyID="{{.Inv.Currency}}">{{amount .Inv.LineExtension}}</cbc:TaxExclusiveAmount>
+    <cbc:TaxInclusiveAmount currencyID="{{.Inv.Currency}}">{{amount .Inv.Total}}</cbc:TaxInclusiveAmount>
+    <cbc:PayableAmount currencyID="{{.Inv.Currency}}">{{amount .Inv.Total}}</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>{{range .Inv.Lines}}{{$cur := $.Inv.Currency}}
+  <cac:InvoiceLine>
+    <cbc:ID>{{.Position}}</cbc:ID>
+    <cbc:InvoicedQuantity unitCode="94">{{.Quantity}}</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="{{$cur}}">{{amount .Total}}</cbc:LineExtensionAmount>{{if .Discount}}
+    <cac:AllowanceCharge>
+      <cbc:ID>1</cbc:ID>
+      <cbc:ChargeIndicator>false</cbc:ChargeIndicator>
+      <cbc:AllowanceChargeReason>Descuento</cbc:AllowanceChargeReason>
+      <cbc:Amount currencyID="{{$cur}}">{{amount .Discount}}</cbc:Amount>
+    </cac:AllowanceCharge>{{end}}
+    <cac:TaxTotal>
+      <cbc:TaxAmount currencyID="{{$cur}}">{{amount .TaxAmount}}</cbc:TaxAmount>
+      <cac:TaxSubtotal>
+        <cbc:TaxableAmount currencyID="{{$cur}}">{{amount .Total}}</cbc:TaxableAmount>
+        <cbc:TaxAmount currencyID="{{$cur}}">{{amount .TaxAmount}}</cbc:TaxAmount>
+        <cac:TaxCategory>
+          <cbc:Percent>{{amount .TaxRate}}</cbc:Percent>
+          <cac:TaxScheme>
+            <cbc:ID>01</cbc:ID>
+            <cbc:Name>IVA</cbc:Name>
+          </cac:TaxScheme>
+        </cac:TaxCategory>
+      </cac:TaxSubtotal>
+    </cac:TaxTotal>
+    <cac:Item>
+      <cbc:Description>{{x .Description}}</cbc:Description>
+    </cac:Item>
+    <cac:Price>
+      <cbc:PriceAmount currencyID="{{$cur}}">{{amount .UnitPrice}}</cbc:PriceAmount>
+      <cbc:BaseQuantity unitCode="94">1</cbc:BaseQuantity>
+    </cac:Price>
+  </cac:InvoiceLine>{{end}}
+</Invoice>
+`))
@@ -0,0 +1,188 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// InvoiceDocument holds what the graphic representation of an electronic
+// invoice shows. The XML is the invoice; this is the copy the member gets.
+type InvoiceDocument struct {
+	GymName          string
+	LegalName        string
+	TaxID            string
+	Address          string
+	Phone            string
+	Email            string
+	Resolution       string // "Resolución DIAN 1876... del ... al ..., prefijo ... del ... al ..."
+	Number           string
+	IssueDate        string // DD/MM/YYYY HH:MM
+	CustomerName     string
+	CustomerDocument string
+	CustomerEmail    string
+	Currency         string
+	Lines            []InvoiceDocumentLine
+	Subtotal         float64
+	Discount         float64
+	TaxTotal         float64
+	Total            float64
+	CUFE             string
+	VerifyURL        string
+	Status           string
+}
+
+// InvoiceDocumentLine is a line of InvoiceDocument.
+type InvoiceDocumentLine struct {
+	Description string
+	Quantity    int
+	UnitPrice   float64
+	Discount    float64
+	TaxRate     float64
+	Total       float64
+}
+
+// BuildInvoicePDF renders the graphic representation of an electronic invoice,
+// with the header and tables of the daily-close PDF.
+func BuildInvoicePDF(doc *InvoiceDocument) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(14, 14, 14)
+	pdf.SetAutoPageBreak(true, 25)
+	pdf.AddPage()
+
+	W, _ := pdf.GetPageSize()
+	cw := W - 28
+
+	darkR, darkG, darkB := 17, 24, 39
+	blueR, blueG, blueB := 37, 99, 235
+
+	// ── Header ────────────────────────────────────────────────────────────────
+	pdf.SetFillColor(darkR, darkG, darkB)
+	pdf.Rect(0, 0, W, 40, "F")
+
+	legalName := doc.LegalName
+	if legalName == "" {
+		legalName = doc.GymName
+	}
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.SetXY(14, 7)
+	pdf.CellFormat(cw/2, 7, latin1(legalName), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(cw/2, 4.5, latin1("NIT "+doc.TaxID), "", 2, "L", false, 0, "")
+	pdf.CellFormat(cw/2, 4.5, latin1(doc.Address), "", 2, "L", false, 0, "")
+	pdf.CellFormat(cw/2, 4.5, latin1(doc.Phone+"  "+doc.Email), "", 2, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetXY(14+cw/2, 7)
+	pdf.CellFormat(cw/2, 6, latin1("FACTURA ELECTRÓNICA DE VENTA"), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(cw/2, 8, latin1(doc.Number), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(180, 220, 180)
+	pdf.CellFormat(cw/2, 5, latin1("Fecha de emisión: "+doc.IssueDate), "", 2, "R", false, 0, "")
+
+	// ── Customer ──────────────────────────────────────────────────────────────
+	pdf.SetY(46)
+	pdf.SetFillColor(243, 244, 246)
+	pdf.Rect(14, 46, cw, 16, "F")
+	pdf.SetTextColor(15, 15, 15)
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetXY(17, 48)
+	pdf.CellFormat(cw-6, 4, "ADQUIRIENTE", "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(cw-6, 4.5, latin1(doc.CustomerName+"  -  "+doc.CustomerDocument), "", 2, "L", false, 0, "")
+	if doc.CustomerEmail != "" {
+		pdf.CellFormat(cw-6, 4.5, latin1(doc.CustomerEmail), "", 2, "L", false, 0, "")
+	}
+
+	// ── Lines ─────────────────────────────────────────────────────────────────
+	type colSpec struct {
+		w     float64
+		align string
+	}
+	cols := []colSpec{{78, "L"}, {16, "C"}, {26, "R"}, {22, "R"}, {14, "R"}, {26, "R"}}
+	drawRow := func(cells []string, h float64, fill, bold bool) {
+		x := 14.0
+		y := pdf.GetY()
+		if bold {
+			pdf.SetFont("Helvetica", "B", 7)
+		} else {
+			pdf.SetFont("Helvetica", "", 7)
+		}
+		for i, c := range cells {
+			pdf.SetXY(x, y)
+			pdf.CellFormat(cols[i].w, h, latin1(c), "1", 0, cols[i].align, fill, 0, "")
+			x += cols[i].w
+		}
+		pdf.SetY(y + h)
+	}
+
+	pdf.SetY(68)
+	pdf.SetFillColor(blueR, blueG, blueB)
+	pdf.SetTextColor(255, 255, 255)
+	drawRow([]string{"Descripción", "Cant.", "Valor unit.", "Descuento", "IVA", "Total"}, 6, true, true)
+	pdf.SetTextColor(15, 15, 15)
+	pdf.SetFillColor(239, 246, 255)
+	for i, l := range doc.Lines {
+		if pdf.GetY() > 250 {
+			pdf.AddPage()
+		}
+		discount := "-"
+		if l.Discount != 0 {
+			discount = fmtAmt(l.Discount)
+		}
+		drawRow([]string{l.Description, fmt.Sprintf("%d", l.Quantity), fmtAmt(l.UnitPrice), discount,
+			fmt.Sprintf("%.0f%%", l.TaxRate), fmtAmt(l.Total)}, 6, i%2 == 1, false)
+	}
+
+	// ── Totals ────────────────────────────────────────────────────────────────
+	pdf.SetY(pdf.GetY() + 3)
+	totals := []struct {
+		label string
+		value float64
+	}{
+		{"Subtotal", doc.Subtotal},
+		{"Descuentos", -doc.Discount},
+		{"IVA", doc.TaxTotal},
+	}
+	for _, t := range totals {
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetX(14 + cw - 70)
+		pdf.CellFormat(40, 5, latin1(t.label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 5, fmtAmt(t.value), "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(darkR, darkG, darkB)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetX(14 + cw - 70)
+	pdf.CellFormat(40, 7, "TOTAL "+latin1(doc.Currency), "", 0, "R", true, 0, "")
+	pdf.CellFormat(30, 7, fmtAmt(doc.Total), "", 1, "R", true, 0, "")
+
+	// ── CUFE and resolution ───────────────────────────────────────────────────
+	pdf.SetY(pdf.GetY() + 8)
+	pdf.SetTextColor(55, 65, 81)
+	pdf.SetFont("Helvetica", "B", 7)
+	pdf.SetX(14)
+	pdf.CellFormat(cw, 4, "CUFE", "", 1, "L", false, 0, "")
+	pdf.SetFont("Courier", "", 7)
+	pdf.SetX(14)
+	pdf.MultiCell(cw, 3.5, doc.CUFE, "", "L", false)
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetX(14)
+	pdf.MultiCell(cw, 3.5, latin1("Consulte esta factura en "+doc.VerifyURL), "", "L", false)
+	pdf.SetY(pdf.GetY() + 2)
+	pdf.SetX(14)
+	pdf.MultiCell(cw, 3.5, latin1(doc.Resolution), "", "L", false)
+	if doc.Status != "" {
+		pdf.SetX(14)
+		pdf.MultiCell(cw, 3.5, latin1("Estado ante la DIAN: "+doc.Status), "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("generating invoice PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -18,22 +18,40 @@ import (
 // DailyCloseReport holds every piece of data needed to render the daily-close
 // email body, Excel workbook, and PDF summary.
 type DailyCloseReport struct {
-	Date              time.Time // start date
-	EndDate           time.Time // end date (same as Date for single-day)
-	GymName           string
-	Currency          string
-	TotalSalesAmount  float64 // net (after discount)
-	TotalSalesCount   int
-	SalesGross        float64 // bruto (before discount)
-	SalesDiscount     float64 // total discounts applied
-	TotalSubsAmount   float64
-	TotalSubsCount    int
-	TotalRevenue      float64
-	PaymentMethods    []PaymentMethodSummary
-	Plans             []PlanSummary
-	Products          []ProductSummary
-	SaleItems         []SaleLineItem
-	SubscriptionItems []SubscriptionLineItem
+	Date             time.Time // start date
+	EndDate          time.Time // end date (same as Date for single-day)
+	GymName          string
+	Currency         string
+	TotalSalesAmount float64 // net (after discount)
+	TotalSalesCount  int
+	SalesGross       float64 // bruto (before discount)
+	SalesDiscount    float64 // total discounts applied
+	TotalSubsAmount  float64 // includes InstallmentItems
+	TotalSubsCount   int
+	TotalRefunds     float64 // money given back in the period, as a positive amount
+	TotalRevenue     float64 // subscriptions + sales - refunds
+	// TotalCouponDiscount is what coupons took off in the period. It is already
+	// out of the subscription and sales amounts: informative, never subtracted.
+	TotalCouponDiscount float64
+	PaymentMethods      []PaymentMethodSummary
+	Plans               []PlanSummary
+	Products            []ProductSummary
+	SaleItems           []SaleLineItem
+	SubscriptionItems   []SubscriptionLineItem
+	InstallmentItems    []InstallmentLineItem
+	RefundItems         []RefundLineItem
+	CouponItems         []CouponLineItem
+	// Taxes is the IVA of what was charged in the period, by rate: the sales
+	// from their details, the membership charges at the rate of their plan.
+	// The IVA of the refunds is taken off at the rates it was charged at.
+	Taxes []TaxSummary
+}
+
+// TaxSummary is the taxable base and the IVA charged at one rate.
+type TaxSummary struct {
+	Rate float64
+	Base float64
+	Tax  float64
 }
 
 // PaymentMethodSummary aggregates totals by payment method, split by source.
@@ -45,6 +63,9 @@ type PaymentMethodSummary struct {
 	SubsCount  int
 	SalesTotal float64
 	SalesCount int
+	// Refunds are already subtracted from Total.
+	RefundsTotal float64
+	RefundsCount int
 }
 
 // SaleLineItem represents one completed sale in the detail list.
@@ -84,6 +105,38 @@ type SubscriptionLineItem struct {
 	CreatedAt     string
 }
 
+// InstallmentLineItem represents a charge on a subscription other than its
+// sale: a payment towards the balance of one sold in installments, possibly in
+// an earlier period, or a fee. It is listed with the subscriptions and counted
+// in their subtotal.
+type InstallmentLineItem struct {
+	MemberName    string
+	Concept       string // "Abono de saldo", "Congelamiento"...
+	PaymentMethod string
+	PaidAt        string
+	Amount        float64
+}
+
+// RefundLineItem represents one refund given in the period. Amount is negative:
+// it is money out of the till.
+type RefundLineItem struct {
+	MemberName    string
+	Description   string
+	Reason        string
+	PaymentMethod string
+	RefundedAt    string
+	Amount        float64
+}
+
+// CouponLineItem represents one coupon redemption in the period.
+type CouponLineItem struct {
+	Code       string
+	MemberName string
+	Concept    string // "Suscripcion" or "Venta"
+	RedeemedAt string
+	Amount     float64
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 // Excel — matches frontend handleExportExcel in AccountingReports.jsx
 // ──────────────────────────────────────────────────────────────────────────────
@@ -177,6 +230,15 @@ func BuildExcelReport(report *DailyCloseReport) ([]byte, error) {
 		f.SetCellStyle(sh, xlCell(7, row), xlCell(10, row), numSt)
 		row++
 	}
+	for _, in := range report.InstallmentItems {
+		f.SetCellValue(sh, xlCell(1, row), in.MemberName)
+		f.SetCellValue(sh, xlCell(2, row), in.Concept)
+		f.SetCellValue(sh, xlCell(3, row), in.PaymentMethod)
+		f.SetCellValue(sh, xlCell(4, row), in.PaidAt)
+		f.SetCellValue(sh, xlCell(10, row), in.Amount)
+		f.SetCellStyle(sh, xlCell(10, row), xlCell(10, row), numSt)
+		row++
+	}
 
 	// Subtotal suscripciones
 	f.SetCellValue(sh, xlCell(6, row), "SUBTOTAL SUSCRIPCIONES")
@@ -251,6 +313,102 @@ func BuildExcelReport(report *DailyCloseReport) ([]byte, error) {
 	f.SetCellStyle(sh, xlCell(2, row), xlCell(4, row), numSt)
 	row++
 
+	// ── Reembolsos ────────────────────────────────────────────────────────────
+	if len(report.RefundItems) > 0 {
+		row++ // blank
+		f.SetCellValue(sh, xlCell(1, row), "REEMBOLSOS")
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(10, row), sectionLabelSt)
+		f.MergeCell(sh, xlCell(1, row), xlCell(10, row))
+		row++
+
+		for i, h := range []string{"Usuario", "Concepto", "Motivo", "Metodo pago", "Fecha", "Monto"} {
+			f.SetCellValue(sh, xlCell(i+1, row), h)
+		}
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(6, row), blueHeaderSt)
+		row++
+
+		for _, r := range report.RefundItems {
+			f.SetCellValue(sh, xlCell(1, row), r.MemberName)
+			f.SetCellValue(sh, xlCell(2, row), r.Description)
+			f.SetCellValue(sh, xlCell(3, row), r.Reason)
+			f.SetCellValue(sh, xlCell(4, row), r.PaymentMethod)
+			f.SetCellValue(sh, xlCell(5, row), r.RefundedAt)
+			f.SetCellValue(sh, xlCell(6, row), r.Amount)
+			f.SetCellStyle(sh, xlCell(6, row), xlCell(6, row), numSt)
+			row++
+		}
+
+		f.SetCellValue(sh, xlCell(5, row), "SUBTOTAL REEMBOLSOS")
+		f.SetCellValue(sh, xlCell(6, row), -report.TotalRefunds)
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(6, row), subtotalSt)
+		row++
+		row++ // blank
+	}
+
+	// ── Cupones ───────────────────────────────────────────────────────────────
+	if len(report.CouponItems) > 0 {
+		f.SetCellValue(sh, xlCell(1, row), "CUPONES REDIMIDOS (DESCUENTO YA INCLUIDO EN LOS TOTALES)")
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(10, row), sectionLabelSt)
+		f.MergeCell(sh, xlCell(1, row), xlCell(10, row))
+		row++
+
+		for i, h := range []string{"Codigo", "Usuario", "Concepto", "Fecha", "Descuento"} {
+			f.SetCellValue(sh, xlCell(i+1, row), h)
+		}
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(5, row), blueHeaderSt)
+		row++
+
+		for _, cr := range report.CouponItems {
+			f.SetCellValue(sh, xlCell(1, row), cr.Code)
+			f.SetCellValue(sh, xlCell(2, row), cr.MemberName)
+			f.SetCellValue(sh, xlCell(3, row), cr.Concept)
+			f.SetCellValue(sh, xlCell(4, row), cr.RedeemedAt)
+			f.SetCellValue(sh, xlCell(5, row), cr.Amount)
+			f.SetCellStyle(sh, xlCell(5, row), xlCell(5, row), numSt)
+			row++
+		}
+
+		f.SetCellValue(sh, xlCell(4, row), "TOTAL CUPONES")
+		f.SetCellValue(sh, xlCell(5, row), report.TotalCouponDiscount)
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(5, row), subtotalSt)
+		row++
+		row++ // blank
+	}
+
+	// ── IVA por tarifa ────────────────────────────────────────────────────────
+	if len(report.Taxes) > 0 {
+		f.SetCellValue(sh, xlCell(1, row), "IVA POR TARIFA")
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(10, row), sectionLabelSt)
+		f.MergeCell(sh, xlCell(1, row), xlCell(10, row))
+		row++
+
+		for i, h := range []string{"Tarifa", "Base gravable", "IVA", "Total"} {
+			f.SetCellValue(sh, xlCell(i+1, row), h)
+		}
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(4, row), blueHeaderSt)
+		row++
+
+		var base, tax float64
+		for _, t := range report.Taxes {
+			f.SetCellValue(sh, xlCell(1, row), taxRateLabel(t.Rate))
+			f.SetCellValue(sh, xlCell(2, row), t.Base)
+			f.SetCellValue(sh, xlCell(3, row), t.Tax)
+			f.SetCellValue(sh, xlCell(4, row), t.Base+t.Tax)
+			f.SetCellStyle(sh, xlCell(2, row), xlCell(4, row), numSt)
+			base += t.Base
+			tax += t.Tax
+			row++
+		}
+
+		f.SetCellValue(sh, xlCell(1, row), "TOTAL")
+		f.SetCellValue(sh, xlCell(2, row), base)
+		f.SetCellValue(sh, xlCell(3, row), tax)
+		f.SetCellValue(sh, xlCell(4, row), base+tax)
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(4, row), subtotalSt)
+		row++
+		row++ // blank
+	}
+
 	// ── Métodos de pago ───────────────────────────────────────────────────────
 	if len(report.PaymentMethods) > 0 {
 		f.SetCellValue(sh, xlCell(1, row), "METODOS DE PAGO")
@@ -258,10 +416,10 @@ func BuildExcelReport(report *DailyCloseReport) ([]byte, error) {
 		f.MergeCell(sh, xlCell(1, row), xlCell(10, row))
 		row++
 
-		for i, h := range []string{"Metodo de pago", "Suscripciones", "Ventas inventario", "Total"} {
+		for i, h := range []string{"Metodo de pago", "Suscripciones", "Ventas inventario", "Reembolsos", "Total"} {
 			f.SetCellValue(sh, xlCell(i+1, row), h)
 		}
-		f.SetCellStyle(sh, xlCell(1, row), xlCell(4, row), blueHeaderSt)
+		f.SetCellStyle(sh, xlCell(1, row), xlCell(5, row), blueHeaderSt)
 		row++
 
 		grayRowSt, _ := f.NewStyle(&excelize.Style{
@@ -279,9 +437,10 @@ func BuildExcelReport(report *DailyCloseReport) ([]byte, error) {
 			f.SetCellValue(sh, xlCell(1, row), pm.Name)
 			f.SetCellValue(sh, xlCell(2, row), subsStr)
 			f.SetCellValue(sh, xlCell(3, row), salesStr)
-			f.SetCellValue(sh, xlCell(4, row), fmtAmt(pm.Total))
+			f.SetCellValue(sh, xlCell(4, row), refundsCell(pm))
+			f.SetCellValue(sh, xlCell(5, row), fmtAmt(pm.Total))
 			if i%2 == 0 {
-				f.SetCellStyle(sh, xlCell(1, row), xlCell(4, row), grayRowSt)
+				f.SetCellStyle(sh, xlCell(1, row), xlCell(5, row), grayRowSt)
 			}
 			row++
 		}
@@ -367,6 +526,9 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 		{"VENTAS INVENTARIO", fmtAmt(report.TotalSalesAmount), fmt.Sprintf("%d transaccion(es)", report.TotalSalesCount), blueR, blueG, blueB},
 		{"TOTAL GENERAL", fmtAmt(report.TotalRevenue), "Suscripciones + Ventas", darkR, darkG, darkB},
 	}
+	if report.TotalRefunds > 0 {
+		boxes[2].sub = "Suscripciones + Ventas - Reembolsos"
+	}
 	for i, b := range boxes {
 		bx := 14 + float64(i)*(boxW+3)
 		by := 46.0
@@ -389,57 +551,19 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 
 	pdf.SetY(76)
 
-	// ── Helpers ───────────────────────────────────────────────────────────────
-
-	drawSectionTitle := func(title string) {
-		y := pdf.GetY()
-		pdf.SetFillColor(243, 244, 246)
-		pdf.Rect(14, y, cw, 7, "F")
-		pdf.SetTextColor(15, 15, 15)
-		pdf.SetFont("Helvetica", "B", 8)
-		pdf.SetXY(17, y+1)
-		pdf.CellFormat(cw-3, 5, latin1(title), "", 1, "L", false, 0, "")
-		pdf.SetY(pdf.GetY() + 2)
-	}
-
-	type colSpec struct {
-		w     float64
-		align string
-	}
-
-	drawRow := func(cells []string, cols []colSpec, h float64, fillR, fillG, fillB, txtR, txtG, txtB int, fill, bold bool) {
-		x := 14.0
-		y := pdf.GetY()
-		if fill {
-			pdf.SetFillColor(fillR, fillG, fillB)
-		}
-		pdf.SetTextColor(txtR, txtG, txtB)
-		if bold {
-			pdf.SetFont("Helvetica", "B", 7)
-		} else {
-			pdf.SetFont("Helvetica", "", 7)
-		}
-		for i, c := range cells {
-			pdf.SetXY(x, y)
-			pdf.CellFormat(cols[i].w, h, latin1(c), "1", 0, cols[i].align, fill, 0, "")
-			x += cols[i].w
-		}
-		pdf.SetY(y + h)
-	}
-
 	// ── Section 1: Suscripciones ──────────────────────────────────────────────
-	drawSectionTitle("1. SUSCRIPCIONES DEL PERIODO")
+	pdfSectionTitle(pdf, cw, "1. SUSCRIPCIONES DEL PERIODO")
 
-	subCols := []colSpec{{55, "L"}, {35, "L"}, {40, "C"}, {28, "L"}, {24, "R"}}
+	subCols := []pdfCol{{55, "L"}, {35, "L"}, {40, "C"}, {28, "L"}, {24, "R"}}
 
 	// Header
-	drawRow(
+	pdfRow(pdf,
 		[]string{"Usuario / Grupo", "Plan", "Periodo", "Metodo", "Total"},
 		subCols, 6,
 		greenR, greenG, greenB, 255, 255, 255, true, true,
 	)
 
-	if len(report.SubscriptionItems) == 0 {
+	if len(report.SubscriptionItems) == 0 && len(report.InstallmentItems) == 0 {
 		pdf.SetFont("Helvetica", "I", 8)
 		pdf.SetTextColor(100, 100, 100)
 		pdf.SetX(14)
@@ -458,18 +582,28 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 			if s.GroupMembers != "" {
 				memberStr = s.MemberName + " (+ " + s.GroupMembers + ")"
 			}
-			drawRow(
+			pdfRow(pdf,
 				[]string{memberStr, s.PlanName, s.StartDate + " > " + s.EndDate, s.PaymentMethod, fmtAmt(s.TotalPaid)},
 				subCols, 6,
 				fillR, fillG, fillB, 15, 15, 15, fill, false,
 			)
 		}
+		for _, in := range report.InstallmentItems {
+			if pdf.GetY() > 262 {
+				pdf.AddPage()
+			}
+			pdfRow(pdf,
+				[]string{in.MemberName, in.Concept, in.PaidAt, in.PaymentMethod, fmtAmt(in.Amount)},
+				subCols, 6,
+				255, 255, 255, 15, 15, 15, false, false,
+			)
+		}
 		// Subtotal row
 		if pdf.GetY() > 262 {
 			pdf.AddPage()
 		}
-		subtotalCols := []colSpec{{158, "R"}, {24, "R"}}
-		drawRow(
+		subtotalCols := []pdfCol{{158, "R"}, {24, "R"}}
+		pdfRow(pdf,
 			[]string{"Subtotal suscripciones", fmtAmt(report.TotalSubsAmount)},
 			subtotalCols, 7,
 			209, 250, 229, greenR, greenG, greenB, true, true,
@@ -482,12 +616,12 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 			pdf.AddPage()
 		}
 		pdf.SetY(pdf.GetY() + 3)
-		planCols := []colSpec{{100, "L"}, {40, "C"}, {42, "R"}}
+		planCols := []pdfCol{{100, "L"}, {40, "C"}, {42, "R"}}
 		pdf.SetFont("Helvetica", "B", 7)
 		pdf.SetTextColor(greenR, greenG, greenB)
 		pdf.SetX(14)
 		pdf.CellFormat(cw, 5, "Desglose por plan", "", 1, "L", false, 0, "")
-		drawRow(
+		pdfRow(pdf,
 			[]string{"Plan", "Cantidad", "Total"},
 			planCols, 6,
 			greenR, greenG, greenB, 255, 255, 255, true, true,
@@ -501,7 +635,7 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 			if !fill {
 				fR, fG, fB = 255, 255, 255
 			}
-			drawRow(
+			pdfRow(pdf,
 				[]string{p.Name, fmt.Sprintf("%d", p.Qty), fmtAmt(p.Revenue)},
 				planCols, 6,
 				fR, fG, fB, 15, 15, 15, fill, false,
@@ -515,13 +649,13 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 	if pdf.GetY() > 220 {
 		pdf.AddPage()
 	}
-	drawSectionTitle("2. VENTAS DE INVENTARIO")
+	pdfSectionTitle(pdf, cw, "2. VENTAS DE INVENTARIO")
 
 	salesColW := cw / 4
-	salesCols := []colSpec{{salesColW, "C"}, {salesColW, "C"}, {salesColW, "C"}, {salesColW, "C"}}
+	salesCols := []pdfCol{{salesColW, "C"}, {salesColW, "C"}, {salesColW, "C"}, {salesColW, "C"}}
 
 	// Header
-	drawRow(
+	pdfRow(pdf,
 		[]string{"Transacciones", "Ingresos brutos", "Descuentos", "Ingresos netos"},
 		salesCols, 7,
 		blueR, blueG, blueB, 255, 255, 255, true, true,
@@ -557,10 +691,10 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 		if pdf.GetY() > 220 {
 			pdf.AddPage()
 		}
-		drawSectionTitle("2b. PRODUCTOS VENDIDOS")
+		pdfSectionTitle(pdf, cw, "2b. PRODUCTOS VENDIDOS")
 
-		prodCols := []colSpec{{100, "L"}, {40, "C"}, {42, "R"}}
-		drawRow(
+		prodCols := []pdfCol{{100, "L"}, {40, "C"}, {42, "R"}}
+		pdfRow(pdf,
 			[]string{"Producto", "Cantidad", "Total"},
 			prodCols, 6,
 			greenR, greenG, greenB, 255, 255, 255, true, true,
@@ -574,7 +708,7 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 			if !fill {
 				fR, fG, fB = 255, 255, 255
 			}
-			drawRow(
+			pdfRow(pdf,
 				[]string{p.Name, fmt.Sprintf("%d", p.Qty), fmtAmt(p.Revenue)},
 				prodCols, 6,
 				fR, fG, fB, 15, 15, 15, fill, false,
@@ -583,17 +717,89 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 		pdf.SetY(pdf.GetY() + 6)
 	}
 
+	// ── Section 2c: Reembolsos ────────────────────────────────────────────────
+	if len(report.RefundItems) > 0 {
+		if pdf.GetY() > 220 {
+			pdf.AddPage()
+		}
+		pdfSectionTitle(pdf, cw, "2c. REEMBOLSOS")
+
+		refundCols := []pdfCol{{50, "L"}, {60, "L"}, {26, "L"}, {22, "C"}, {24, "R"}}
+		pdfRow(pdf,
+			[]string{"Usuario", "Motivo", "Metodo", "Fecha", "Monto"},
+			refundCols, 6,
+			220, 38, 38, 255, 255, 255, true, true,
+		)
+		for i, r := range report.RefundItems {
+			if pdf.GetY() > 265 {
+				pdf.AddPage()
+			}
+			fill := i%2 == 1
+			fR, fG, fB := 254, 242, 242
+			if !fill {
+				fR, fG, fB = 255, 255, 255
+			}
+			pdfRow(pdf,
+				[]string{r.MemberName, r.Reason, r.PaymentMethod, r.RefundedAt, fmtAmt(r.Amount)},
+				refundCols, 6,
+				fR, fG, fB, 15, 15, 15, fill, false,
+			)
+		}
+		pdfRow(pdf,
+			[]string{"Subtotal reembolsos", fmtAmt(-report.TotalRefunds)},
+			[]pdfCol{{158, "R"}, {24, "R"}}, 7,
+			254, 226, 226, 220, 38, 38, true, true,
+		)
+		pdf.SetY(pdf.GetY() + 6)
+	}
+
+	// ── Section 2d: Cupones redimidos ─────────────────────────────────────────
+	if len(report.CouponItems) > 0 {
+		if pdf.GetY() > 220 {
+			pdf.AddPage()
+		}
+		pdfSectionTitle(pdf, cw, "2d. CUPONES REDIMIDOS")
+
+		couponCols := []pdfCol{{30, "L"}, {60, "L"}, {30, "L"}, {36, "C"}, {26, "R"}}
+		pdfRow(pdf,
+			[]string{"Codigo", "Usuario", "Concepto", "Fecha", "Descuento"},
+			couponCols, 6,
+			blueR, blueG, blueB, 255, 255, 255, true, true,
+		)
+		for i, cr := range report.CouponItems {
+			if pdf.GetY() > 265 {
+				pdf.AddPage()
+			}
+			fill := i%2 == 1
+			fR, fG, fB := 239, 246, 255
+			if !fill {
+				fR, fG, fB = 255, 255, 255
+			}
+			pdfRow(pdf,
+				[]string{cr.Code, cr.MemberName, cr.Concept, cr.RedeemedAt, fmtAmt(cr.Amount)},
+				couponCols, 6,
+				fR, fG, fB, 15, 15, 15, fill, false,
+			)
+		}
+		pdfRow(pdf,
+			[]string{"Total descuentos por cupon (ya incluidos en los totales)", fmtAmt(report.TotalCouponDiscount)},
+			[]pdfCol{{156, "R"}, {26, "R"}}, 7,
+			219, 234, 254, blueR, blueG, blueB, true, true,
+		)
+		pdf.SetY(pdf.GetY() + 6)
+	}
+
 	// ── Section 3: Desglose por método de pago ────────────────────────────────
 	if pdf.GetY() > 220 {
 		pdf.AddPage()
 	}
-	drawSectionTitle("3. DESGLOSE POR METODO DE PAGO")
+	pdfSectionTitle(pdf, cw, "3. DESGLOSE POR METODO DE PAGO")
 
-	pmCols := []colSpec{{50, "L"}, {46, "C"}, {46, "C"}, {40, "R"}}
+	pmCols := []pdfCol{{44, "L"}, {38, "C"}, {38, "C"}, {30, "C"}, {32, "R"}}
 
 	// Header
-	drawRow(
-		[]string{"Metodo de pago", "Suscripciones", "Ventas inventario", "Total"},
+	pdfRow(pdf,
+		[]string{"Metodo de pago", "Suscripciones", "Ventas inventario", "Reembolsos", "Total"},
 		pmCols, 7,
 		grayR, grayG, grayB, 255, 255, 255, true, true,
 	)
@@ -624,7 +830,7 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 			pdf.SetFillColor(255, 255, 255)
 		}
 		pdf.SetTextColor(15, 15, 15)
-		cells := []string{pm.Name, subsStr, salesStr, fmtAmt(pm.Total)}
+		cells := []string{pm.Name, subsStr, salesStr, refundsCell(pm), fmtAmt(pm.Total)}
 		for j, c := range cells {
 			pdf.SetXY(x, y)
 			if j == len(cells)-1 {
@@ -640,6 +846,42 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 
 	pdf.SetY(pdf.GetY() + 8)
 
+	// ── Section 4: IVA por tarifa ─────────────────────────────────────────────
+	if len(report.Taxes) > 0 {
+		if pdf.GetY() > 220 {
+			pdf.AddPage()
+		}
+		pdfSectionTitle(pdf, cw, "4. IVA POR TARIFA")
+
+		taxCols := []pdfCol{{44, "L"}, {46, "R"}, {46, "R"}, {46, "R"}}
+		pdfRow(pdf,
+			[]string{"Tarifa", "Base gravable", "IVA", "Total"},
+			taxCols, 6,
+			grayR, grayG, grayB, 255, 255, 255, true, true,
+		)
+		var base, tax float64
+		for i, t := range report.Taxes {
+			fill := i%2 == 1
+			fR, fG, fB := 249, 250, 251
+			if !fill {
+				fR, fG, fB = 255, 255, 255
+			}
+			pdfRow(pdf,
+				[]string{taxRateLabel(t.Rate), fmtAmt(t.Base), fmtAmt(t.Tax), fmtAmt(t.Base + t.Tax)},
+				taxCols, 6,
+				fR, fG, fB, 15, 15, 15, fill, false,
+			)
+			base += t.Base
+			tax += t.Tax
+		}
+		pdfRow(pdf,
+			[]string{"Total", fmtAmt(base), fmtAmt(tax), fmtAmt(base + tax)},
+			taxCols, 7,
+			243, 244, 246, 15, 15, 15, true, true,
+		)
+		pdf.SetY(pdf.GetY() + 8)
+	}
+
 	// ── Total General box ──────────────────────────────────────────────────────
 	if pdf.GetY() > 242 {
 		pdf.AddPage()
@@ -681,7 +923,63 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 		"", 0, "C", false, 0, "")
 
 	// ── Page numbers ──────────────────────────────────────────────────────────
-	_, pH := pdf.GetPageSize()
+	pdfPageNumbers(pdf)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("generating PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Helpers
+// ──────────────────────────────────────────────────────────────────────────────
+
+// pdfCol is the width and alignment of a column of a PDF table.
+type pdfCol struct {
+	w     float64
+	align string
+}
+
+// pdfSectionTitle draws the gray band that opens a section, cw wide.
+func pdfSectionTitle(pdf *fpdf.Fpdf, cw float64, title string) {
+	y := pdf.GetY()
+	pdf.SetFillColor(243, 244, 246)
+	pdf.Rect(14, y, cw, 7, "F")
+	pdf.SetTextColor(15, 15, 15)
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetXY(17, y+1)
+	pdf.CellFormat(cw-3, 5, latin1(title), "", 1, "L", false, 0, "")
+	pdf.SetY(pdf.GetY() + 2)
+}
+
+// pdfRow draws a bordered table row at the left margin, filled with the fill
+// colour when fill is set.
+func pdfRow(pdf *fpdf.Fpdf, cells []string, cols []pdfCol, h float64, fillR, fillG, fillB, txtR, txtG, txtB int, fill, bold bool) {
+	x := 14.0
+	y := pdf.GetY()
+	if fill {
+		pdf.SetFillColor(fillR, fillG, fillB)
+	}
+	pdf.SetTextColor(txtR, txtG, txtB)
+	if bold {
+		pdf.SetFont("Helvetica", "B", 7)
+	} else {
+		pdf.SetFont("Helvetica", "", 7)
+	}
+	for i, c := range cells {
+		pdf.SetXY(x, y)
+		pdf.CellFormat(cols[i].w, h, latin1(c), "1", 0, cols[i].align, fill, 0, "")
+		x += cols[i].w
+	}
+	pdf.SetY(y + h)
+}
+
+// pdfPageNumbers stamps "Pagina i de n" at the foot of every page, once the
+// document is complete.
+func pdfPageNumbers(pdf *fpdf.Fpdf) {
+	W, pH := pdf.GetPageSize()
 	pages := pdf.PageCount()
 	for i := 1; i <= pages; i++ {
 		pdf.SetPage(i)
@@ -690,17 +988,23 @@ func BuildPDFReport(report *DailyCloseReport) ([]byte, error) {
 		pdf.SetXY(W-14-40, pH-10)
 		pdf.CellFormat(40, 5, fmt.Sprintf("Pagina %d de %d", i, pages), "", 0, "R", false, 0, "")
 	}
+}
 
-	var buf bytes.Buffer
-	if err := pdf.Output(&buf); err != nil {
-		return nil, fmt.Errorf("generating PDF: %w", err)
+// taxRateLabel names an IVA rate in the tax breakdown.
+func taxRateLabel(rate float64) string {
+	if rate == 0 {
+		return "Excluido / 0%"
 	}
-	return buf.Bytes(), nil
+	return fmt.Sprintf("IVA %g%%", rate)
 }
 
-// ──────────────────────────────────────────────────────────────────────────────
-// Helpers
-// ──────────────────────────────────────────────────────────────────────────────
+// refundsCell renders the refunds column of the payment-method breakdown.
+func refundsCell(pm PaymentMethodSummary) string {
+	if pm.RefundsTotal == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%d)", fmtAmt(-pm.RefundsTotal), pm.RefundsCount)
+}
 
 // xlCell returns a cell reference like "A1" for column col (1-based) and row.
 func xlCell(col, row int) string {
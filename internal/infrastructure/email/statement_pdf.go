@@ -0,0 +1,270 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// MemberStatement holds what a member's account statement shows: what they
+// paid the gym in a period, the certificate asked for by an employer or for
+// the tax return.
+type MemberStatement struct {
+	GymName   string
+	LegalName string
+	TaxID     string
+	Address   string
+	Phone     string
+	Email     string
+	// Logo is the gym's logo, PNG or JPEG. Without it the letterhead is text
+	// only.
+	Logo              []byte
+	MemberName        string
+	MemberDocument    string // "CC 1020304050"
+	MemberEmail       string
+	From              string // DD/MM/YYYY
+	To                string
+	Currency          string
+	Subscriptions     []StatementSubscription
+	MembershipCharges []StatementLine // what was paid for the subscriptions
+	Purchases         []StatementLine
+	Refunds           []StatementLine // negative amounts
+	TotalPaid         float64
+	TotalRefunded     float64 // positive
+}
+
+// StatementSubscription is a subscription of the member in the period.
+type StatementSubscription struct {
+	PlanName  string
+	StartDate string // DD/MM/YYYY
+	EndDate   string
+	Status    string
+	Total     float64 // the price of the subscription, paid or not
+}
+
+// StatementLine is a movement of money on the statement.
+type StatementLine struct {
+	Date          string // DD/MM/YYYY
+	Concept       string
+	PaymentMethod string
+	Amount        float64
+}
+
+// BuildStatementPDF renders a member's account statement on the gym's
+// letterhead, with the tables of the daily-close PDF.
+func BuildStatementPDF(doc *MemberStatement) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(14, 14, 14)
+	pdf.SetAutoPageBreak(true, 25)
+	pdf.AddPage()
+
+	W, _ := pdf.GetPageSize()
+	cw := W - 28
+
+	darkR, darkG, darkB := 17, 24, 39
+	greenR, greenG, greenB := 16, 185, 129
+	blueR, blueG, blueB := 37, 99, 235
+
+	// ── Letterhead ────────────────────────────────────────────────────────────
+	textX := 14.0
+	if logoType := imageType(doc.Logo); logoType != "" {
+		opts := fpdf.ImageOptions{ImageType: logoType, ReadDpi: true}
+		pdf.RegisterImageOptionsReader("logo", opts, bytes.NewReader(doc.Logo))
+		if pdf.Ok() {
+			pdf.ImageOptions("logo", 14, 10, 0, 22, false, opts, 0, "")
+			textX = 14 + 34
+		} else {
+			// A logo fpdf cannot read is left out, not the statement.
+			pdf.ClearError()
+		}
+	}
+	legalName := doc.LegalName
+	if legalName == "" {
+		legalName = doc.GymName
+	}
+	pdf.SetTextColor(darkR, darkG, darkB)
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.SetXY(textX, 10)
+	pdf.CellFormat(cw/2, 7, latin1(legalName), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(55, 65, 81)
+	if doc.TaxID != "" {
+		pdf.CellFormat(cw/2, 4.5, latin1("NIT "+doc.TaxID), "", 2, "L", false, 0, "")
+	}
+	pdf.CellFormat(cw/2, 4.5, latin1(doc.Address), "", 2, "L", false, 0, "")
+	pdf.CellFormat(cw/2, 4.5, latin1(doc.Phone+"  "+doc.Email), "", 2, "L", false, 0, "")
+
+	pdf.SetTextColor(darkR, darkG, darkB)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetXY(14+cw/2, 10)
+	pdf.CellFormat(cw/2, 7, latin1("ESTADO DE CUENTA"), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(cw/2, 4.5, latin1("Periodo: "+doc.From+" - "+doc.To), "", 2, "R", false, 0, "")
+	pdf.CellFormat(cw/2, 4.5, "Generado: "+time.Now().Format("02/01/2006 15:04"), "", 2, "R", false, 0, "")
+
+	pdf.SetDrawColor(darkR, darkG, darkB)
+	pdf.SetLineWidth(0.6)
+	pdf.Line(14, 36, W-14, 36)
+	pdf.SetLineWidth(0.2)
+
+	// ── Member ────────────────────────────────────────────────────────────────
+	pdf.SetFillColor(243, 244, 246)
+	pdf.Rect(14, 40, cw, 16, "F")
+	pdf.SetTextColor(15, 15, 15)
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetXY(17, 42)
+	pdf.CellFormat(cw-6, 4, "SOCIO", "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	member := doc.MemberName
+	if doc.MemberDocument != "" {
+		member += "  -  " + doc.MemberDocument
+	}
+	pdf.CellFormat(cw-6, 4.5, latin1(member), "", 2, "L", false, 0, "")
+	if doc.MemberEmail != "" {
+		pdf.CellFormat(cw-6, 4.5, latin1(doc.MemberEmail), "", 2, "L", false, 0, "")
+	}
+	pdf.SetY(62)
+
+	empty := func(text string) {
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.SetX(14)
+		pdf.CellFormat(cw, 6, latin1(text), "", 1, "L", false, 0, "")
+	}
+	lines := func(lines []StatementLine, r, g, b int) {
+		cols := []pdfCol{{28, "C"}, {94, "L"}, {30, "L"}, {30, "R"}}
+		pdfRow(pdf, []string{"Fecha", "Concepto", "Metodo", "Monto"}, cols, 6, r, g, b, 255, 255, 255, true, true)
+		for i, l := range lines {
+			if pdf.GetY() > 262 {
+				pdf.AddPage()
+			}
+			pdfRow(pdf, []string{l.Date, l.Concept, l.PaymentMethod, fmtAmt(l.Amount)}, cols, 6,
+				249, 250, 251, 15, 15, 15, i%2 == 1, false)
+		}
+		pdf.SetY(pdf.GetY() + 6)
+	}
+
+	// ── Section 1: Suscripciones ──────────────────────────────────────────────
+	pdfSectionTitle(pdf, cw, "1. SUSCRIPCIONES")
+	if len(doc.Subscriptions) == 0 {
+		empty("Sin suscripciones en este periodo")
+		pdf.SetY(pdf.GetY() + 4)
+	} else {
+		cols := []pdfCol{{72, "L"}, {50, "C"}, {30, "C"}, {30, "R"}}
+		pdfRow(pdf, []string{"Plan", "Vigencia", "Estado", "Valor"}, cols, 6, greenR, greenG, greenB, 255, 255, 255, true, true)
+		for i, s := range doc.Subscriptions {
+			if pdf.GetY() > 262 {
+				pdf.AddPage()
+			}
+			pdfRow(pdf, []string{s.PlanName, s.StartDate + " > " + s.EndDate, s.Status, fmtAmt(s.Total)}, cols, 6,
+				240, 253, 244, 15, 15, 15, i%2 == 1, false)
+		}
+		pdf.SetY(pdf.GetY() + 4)
+	}
+	if len(doc.MembershipCharges) > 0 {
+		lines(doc.MembershipCharges, greenR, greenG, greenB)
+	}
+
+	// ── Section 2: Compras de productos ───────────────────────────────────────
+	if pdf.GetY() > 230 {
+		pdf.AddPage()
+	}
+	pdfSectionTitle(pdf, cw, "2. COMPRAS DE PRODUCTOS")
+	if len(doc.Purchases) == 0 {
+		empty("Sin compras en este periodo")
+		pdf.SetY(pdf.GetY() + 4)
+	} else {
+		lines(doc.Purchases, blueR, blueG, blueB)
+	}
+
+	// ── Section 3: Reembolsos y devoluciones ──────────────────────────────────
+	if len(doc.Refunds) > 0 {
+		if pdf.GetY() > 230 {
+			pdf.AddPage()
+		}
+		pdfSectionTitle(pdf, cw, "3. REEMBOLSOS Y DEVOLUCIONES")
+		lines(doc.Refunds, 220, 38, 38)
+	}
+
+	// ── Totals ────────────────────────────────────────────────────────────────
+	if pdf.GetY() > 240 {
+		pdf.AddPage()
+	}
+	totals := []struct {
+		label string
+		value float64
+	}{
+		{"Total pagado", doc.TotalPaid},
+		{"Reembolsos y devoluciones", -doc.TotalRefunded},
+	}
+	for _, t := range totals {
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(15, 15, 15)
+		pdf.SetX(14 + cw - 90)
+		pdf.CellFormat(60, 5, latin1(t.label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 5, fmtAmt(t.value), "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(darkR, darkG, darkB)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetX(14 + cw - 90)
+	pdf.CellFormat(60, 7, "NETO PAGADO "+latin1(doc.Currency), "", 0, "R", true, 0, "")
+	pdf.CellFormat(30, 7, fmtAmt(doc.TotalPaid-doc.TotalRefunded), "", 1, "R", true, 0, "")
+
+	pdf.SetY(pdf.GetY() + 8)
+	pdf.SetFont("Helvetica", "I", 7)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.SetX(14)
+	pdf.MultiCell(cw, 3.5, latin1(fmt.Sprintf(
+		"%s certifica que %s realizó los pagos aquí relacionados entre el %s y el %s. Los valores incluyen IVA cuando aplica.",
+		legalName, doc.MemberName, doc.From, doc.To)), "", "L", false)
+
+	pdfPageNumbers(pdf)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("generating statement PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// imageType tells the format of an image fpdf can embed from its first bytes:
+// "PNG", "JPG" or "" for anything else.
+func imageType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		return "PNG"
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "JPG"
+	}
+	return ""
+}
+
+// LoadLogo reads the image of a gym's LogoURL for a letterhead: an http(s) URL
+// or a data: URI carrying it in base64.
+func LoadLogo(ref string) ([]byte, error) {
+	if strings.HasPrefix(ref, "data:") {
+		i := strings.Index(ref, ";base64,")
+		if i < 0 {
+			return nil, fmt.Errorf("logo data URI is not base64")
+		}
+		return base64.StdEncoding.DecodeString(ref[i+len(";base64,"):])
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(ref)
+	if err != nil {
+		return nil, fmt.Errorf("fetching logo: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching logo: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+}
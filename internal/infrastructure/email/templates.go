@@ -21,6 +21,10 @@ type DailyCloseEmailData struct {
 	TotalSubsAmount  string
 	TotalSubsCount   int
 	TotalRevenue     string
+	TotalRefunds     string // already negative, e.g. "- $ 40.000"
+	TotalRefundCount int
+	TotalCoupons     string // discount given through coupons, already inside the totals
+	TotalCouponCount int
 	Currency         string
 	PaymentMethods   []PaymentMethodRow
 }
@@ -70,6 +74,20 @@ const dailyCloseTpl = `<!DOCTYPE html>
           <td style="padding:10px 14px;text-align:center">{{.TotalSubsCount}}</td>
           <td style="padding:10px 14px;text-align:right">{{.TotalSubsAmount}}</td>
         </tr>
+        {{if .TotalRefundCount}}
+        <tr style="border-bottom:1px solid #f3f4f6">
+          <td style="padding:10px 14px">Reembolsos</td>
+          <td style="padding:10px 14px;text-align:center">{{.TotalRefundCount}}</td>
+          <td style="padding:10px 14px;text-align:right;color:#dc2626">{{.TotalRefunds}}</td>
+        </tr>
+        {{end}}
+        {{if .TotalCouponCount}}
+        <tr style="border-bottom:1px solid #f3f4f6;color:#6b7280">
+          <td style="padding:10px 14px">Descuentos por cupon (ya incluidos)</td>
+          <td style="padding:10px 14px;text-align:center">{{.TotalCouponCount}}</td>
+          <td style="padding:10px 14px;text-align:right">{{.TotalCoupons}}</td>
+        </tr>
+        {{end}}
         <tr style="background:#ecfdf5;font-weight:700">
           <td style="padding:12px 14px;border-radius:0 0 0 6px;color:#065f46">{{if .IsRange}}TOTAL DEL PERIODO{{else}}TOTAL DEL DIA{{end}}</td>
           <td style="padding:12px 14px;text-align:center;color:#065f46">{{totalCount .TotalSalesCount .TotalSubsCount}}</td>
@@ -170,3 +188,162 @@ func RenderSubscriptionReminderEmail(data SubscriptionReminderEmailData) (string
 	}
 	return buf.String(), nil
 }
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Cash register close
+// ──────────────────────────────────────────────────────────────────────────────
+
+// CashRegisterCloseEmailData is the data contract for the register-close email,
+// sent to the DAILY_CLOSE recipients when a receptionist closes a shift.
+type CashRegisterCloseEmailData struct {
+	GymName      string
+	UserName     string
+	OpenedAt     string // formatted local date and hour, e.g. "15/01/2024 06:00"
+	ClosedAt     string
+	OpeningFloat string
+	CashIn       string
+	CashOut      string // already negative
+	ExpectedCash string
+	CountedCash  string
+	Variance     string
+	Short        bool // the variance is negative: cash is missing
+	Over         bool
+	Notes        string
+	Currency     string
+	Methods      []PaymentMethodRow
+}
+
+const cashRegisterCloseTpl = `<!DOCTYPE html>
+<html lang="es">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"></head>
+<body style="font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',sans-serif;
+             max-width:620px;margin:0 auto;padding:32px 24px;color:#1f2937;background:#f9fafb">
+  <div style="background:#fff;border-radius:12px;padding:32px;box-shadow:0 1px 3px rgba(0,0,0,.1)">
+
+    <div style="border-left:4px solid #10b981;padding-left:16px;margin-bottom:24px">
+      <h1 style="margin:0 0 4px;font-size:22px;color:#111827">{{.GymName}}</h1>
+      <p style="margin:0;font-size:14px;color:#6b7280">Cierre de caja de {{.UserName}} &mdash; {{.OpenedAt}} a {{.ClosedAt}}</p>
+    </div>
+
+    <table style="width:100%;border-collapse:collapse;margin-bottom:24px;font-size:14px">
+      <thead>
+        <tr style="background:#10b981;color:#fff">
+          <th style="padding:10px 14px;text-align:left;border-radius:6px 0 0 0">Efectivo</th>
+          <th style="padding:10px 14px;text-align:right;border-radius:0 6px 0 0">Total ({{.Currency}})</th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr style="border-bottom:1px solid #f3f4f6">
+          <td style="padding:10px 14px">Base inicial</td>
+          <td style="padding:10px 14px;text-align:right">{{.OpeningFloat}}</td>
+        </tr>
+        <tr style="border-bottom:1px solid #f3f4f6">
+          <td style="padding:10px 14px">Cobrado en efectivo</td>
+          <td style="padding:10px 14px;text-align:right">{{.CashIn}}</td>
+        </tr>
+        <tr style="border-bottom:1px solid #f3f4f6">
+          <td style="padding:10px 14px">Reembolsado en efectivo</td>
+          <td style="padding:10px 14px;text-align:right;color:#dc2626">{{.CashOut}}</td>
+        </tr>
+        <tr style="border-bottom:1px solid #f3f4f6;font-weight:600">
+          <td style="padding:10px 14px">Esperado en caja</td>
+          <td style="padding:10px 14px;text-align:right">{{.ExpectedCash}}</td>
+        </tr>
+        <tr style="border-bottom:1px solid #f3f4f6;font-weight:600">
+          <td style="padding:10px 14px">Contado</td>
+          <td style="padding:10px 14px;text-align:right">{{.CountedCash}}</td>
+        </tr>
+        <tr style="background:{{if .Short}}#fef2f2{{else}}#ecfdf5{{end}};font-weight:700">
+          <td style="padding:12px 14px;border-radius:0 0 0 6px">{{if .Short}}FALTANTE{{else if .Over}}SOBRANTE{{else}}CUADRE{{end}}</td>
+          <td style="padding:12px 14px;text-align:right;font-size:16px;border-radius:0 0 6px 0;color:{{if .Short}}#dc2626{{else}}#10b981{{end}}">{{.Variance}}</td>
+        </tr>
+      </tbody>
+    </table>
+
+    {{if .Methods}}
+    <h3 style="font-size:13px;font-weight:600;color:#374151;text-transform:uppercase;
+               letter-spacing:.05em;margin:0 0 10px">Cobros del turno por metodo de pago</h3>
+    <table style="width:100%;border-collapse:collapse;font-size:13px;margin-bottom:24px">
+      <thead>
+        <tr style="background:#f3f4f6">
+          <th style="padding:8px 12px;text-align:left;font-weight:600">Metodo</th>
+          <th style="padding:8px 12px;text-align:center;font-weight:600">Trans.</th>
+          <th style="padding:8px 12px;text-align:right;font-weight:600">Neto</th>
+        </tr>
+      </thead>
+      <tbody>
+        {{range .Methods}}
+        <tr style="border-bottom:1px solid #f3f4f6">
+          <td style="padding:8px 12px">{{.Name}}</td>
+          <td style="padding:8px 12px;text-align:center">{{.Count}}</td>
+          <td style="padding:8px 12px;text-align:right">{{.Total}}</td>
+        </tr>
+        {{end}}
+      </tbody>
+    </table>
+    {{end}}
+
+    {{if .Notes}}
+    <p style="margin:0 0 16px;font-size:13px"><strong>Observaciones:</strong> {{.Notes}}</p>
+    {{end}}
+
+    <p style="margin:0;font-size:12px;color:#9ca3af;border-top:1px solid #f3f4f6;padding-top:16px">
+      Este correo fue generado automaticamente por Sistema Gym-Go.
+    </p>
+  </div>
+</body>
+</html>`
+
+// RenderCashRegisterCloseEmail builds the HTML body for a register-close email.
+func RenderCashRegisterCloseEmail(data CashRegisterCloseEmailData) (string, error) {
+	t, err := template.New("cash_register_close").Parse(cashRegisterCloseTpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing cash register close template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing cash register close template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Member statement
+// ──────────────────────────────────────────────────────────────────────────────
+
+// MemberStatementEmailData is the data contract for the email that carries a
+// member's account statement as a PDF attachment.
+type MemberStatementEmailData struct {
+	GymName    string
+	MemberName string
+	From       string // DD/MM/YYYY
+	To         string
+}
+
+const memberStatementTpl = `<!DOCTYPE html>
+<html lang="es">
+<head><meta charset="UTF-8"></head>
+<body style="font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',sans-serif;
+             max-width:500px;margin:0 auto;padding:32px 24px;color:#1f2937">
+  <div style="background:#fff;border-radius:12px;padding:32px;box-shadow:0 1px 3px rgba(0,0,0,.1)">
+    <h2 style="color:#10b981;margin:0 0 16px">{{.GymName}}</h2>
+    <p>Hola <strong>{{.MemberName}}</strong>,</p>
+    <p>Adjuntamos tu estado de cuenta con los pagos realizados del
+       <strong>{{.From}}</strong> al <strong>{{.To}}</strong>.</p>
+    <p style="margin-top:32px;color:#6b7280;font-size:13px">&mdash; Equipo {{.GymName}}</p>
+  </div>
+</body>
+</html>`
+
+// RenderMemberStatementEmail builds the HTML body for a member statement email.
+func RenderMemberStatementEmail(data MemberStatementEmailData) (string, error) {
+	t, err := template.New("member_statement").Parse(memberStatementTpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing member statement template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing member statement template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+)
+
+// FakeSignatureHeader carries the signature of a FakeGateway webhook.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeGateway is a provider that charges nothing, for development and tests.
+// Its links go nowhere; a payment is confirmed by posting to the webhook the
+// body of Event, signed by Sign, e.g. with curl.
+type FakeGateway struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewFakeGateway creates a fake gateway whose links start with baseURL and
+// whose webhooks are signed with secret.
+func NewFakeGateway(baseURL, secret string) *FakeGateway {
+	return &FakeGateway{baseURL: strings.TrimRight(baseURL, "/"), secret: []byte(secret)}
+}
+
+func (g *FakeGateway) Method() entities.PaymentMethod {
+	return entities.PaymentMethodOnline
+}
+
+func (g *FakeGateway) CreateLink(ctx context.Context, req LinkRequest) (*Link, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("fake gateway: amount must be positive, got %.2f", req.Amount)
+	}
+	id := "fake-" + req.Reference
+	return &Link{ID: id, URL: g.baseURL + "/fake-checkout/" + id}, nil
+}
+
+// fakeEvent is the body of a FakeGateway webhook.
+type fakeEvent struct {
+	Reference     string      `json:"reference"`
+	TransactionID string      `json:"transaction_id"`
+	Status        EventStatus `json:"status"`
+	Amount        float64     `json:"amount"`
+}
+
+func (g *FakeGateway) ParseWebhook(ctx context.Context, header http.Header, body []byte) (*WebhookEvent, error) {
+	got, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(got, g.mac(body)) {
+		return nil, ErrInvalidSignature
+	}
+	var ev fakeEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, fmt.Errorf("fake gateway: decoding webhook: %w", err)
+	}
+	return &WebhookEvent{Reference: ev.Reference, TransactionID: ev.TransactionID, Status: ev.Status, Amount: ev.Amount}, nil
+}
+
+// Event builds the webhook body that reports the payment reference with status.
+func (g *FakeGateway) Event(reference string, status EventStatus, amount float64) []byte {
+	body, _ := json.Marshal(fakeEvent{
+		Reference:     reference,
+		TransactionID: "fake-tx-" + reference,
+		Status:        status,
+		Amount:        amount,
+	})
+	return body
+}
+
+// Sign returns the value of FakeSignatureHeader for body.
+func (g *FakeGateway) Sign(body []byte) string {
+	return hex.EncodeToString(g.mac(body))
+}
+
+func (g *FakeGateway) mac(body []byte) []byte {
+	m := hmac.New(sha256.New, g.secret)
+	m.Write(body)
+	return m.Sum(nil)
+}
@@ -0,0 +1,61 @@
+// Package gateway charges payments through an online payment provider: a
+// checkout link the member pays at, and a signed webhook the provider calls
+// back with the result.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+)
+
+// ErrInvalidSignature is returned by ParseWebhook for a call the provider did
+// not sign: anyone can reach the webhook URL.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// PaymentGateway is an online payment provider.
+type PaymentGateway interface {
+	// Method is the payment method the payments charged through it are
+	// recorded with.
+	Method() entities.PaymentMethod
+	// CreateLink opens a checkout for req and returns where to send the payer.
+	CreateLink(ctx context.Context, req LinkRequest) (*Link, error)
+	// ParseWebhook checks the signature of a webhook call and returns the
+	// payment it is about, or nil for a notification about anything else.
+	ParseWebhook(ctx context.Context, header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// LinkRequest is what a checkout link charges. Reference is our Payment.ID:
+// the provider hands it back in the webhook.
+type LinkRequest struct {
+	Reference  string
+	Title      string
+	Amount     float64
+	Currency   string
+	PayerEmail string
+}
+
+// Link is a checkout opened at the provider.
+type Link struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// EventStatus is the state of a payment at the provider, as told by a webhook.
+type EventStatus string
+
+const (
+	EventStatusApproved EventStatus = "approved"
+	EventStatusPending  EventStatus = "pending"
+	EventStatusRejected EventStatus = "rejected"
+)
+
+// WebhookEvent is the news about one payment.
+type WebhookEvent struct {
+	Reference     string
+	TransactionID string
+	Status        EventStatus
+	Amount        float64
+}
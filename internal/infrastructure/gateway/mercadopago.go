@@ -0,0 +1,202 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+)
+
+const mercadoPagoAPI = "https://api.mercadopago.com"
+
+// MercadoPagoConfig holds the credentials of a MercadoPago account.
+type MercadoPagoConfig struct {
+	AccessToken string
+	// WebhookSecret is the secret key of the webhook, from the integration's
+	// "Webhooks" page.
+	WebhookSecret string
+	// NotificationURL is where MercadoPago posts the webhook: the public URL of
+	// /api/v1/payments/webhook.
+	NotificationURL string
+	// BaseURL overrides the API, for tests. Empty is the real one.
+	BaseURL string
+}
+
+// MercadoPagoGateway charges through MercadoPago Checkout Pro.
+//
+// A link is a checkout preference whose external_reference is our payment.
+// The webhook only says "payment 123 changed" and is signed on that id, so
+// ParseWebhook reads the payment back from the API to learn its status, amount
+// and external_reference: what the body says is never trusted.
+type MercadoPagoGateway struct {
+	config MercadoPagoConfig
+	client *http.Client
+}
+
+// NewMercadoPagoGateway creates a MercadoPago gateway.
+func NewMercadoPagoGateway(config MercadoPagoConfig) *MercadoPagoGateway {
+	if config.BaseURL == "" {
+		config.BaseURL = mercadoPagoAPI
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &MercadoPagoGateway{
+		config: config,
+		// A provider that stops answering must not hold the request, or the
+		// webhook, for ever: the same lesson as the SMTP timeouts.
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *MercadoPagoGateway) Method() entities.PaymentMethod {
+	return entities.PaymentMethodMercadoPago
+}
+
+type mpPreferenceItem struct {
+	Title      string  `json:"title"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  float64 `json:"unit_price"`
+	CurrencyID string  `json:"currency_id,omitempty"`
+}
+
+type mpPreference struct {
+	Items             []mpPreferenceItem `json:"items"`
+	ExternalReference string             `json:"external_reference"`
+	NotificationURL   string             `json:"notification_url,omitempty"`
+	Payer             *mpPayer           `json:"payer,omitempty"`
+}
+
+type mpPayer struct {
+	Email string `json:"email"`
+}
+
+func (g *MercadoPagoGateway) CreateLink(ctx context.Context, req LinkRequest) (*Link, error) {
+	pref := mpPreference{
+		Items: []mpPreferenceItem{{
+			Title:      req.Title,
+			Quantity:   1,
+			UnitPrice:  req.Amount,
+			CurrencyID: req.Currency,
+		}},
+		ExternalReference: req.Reference,
+		NotificationURL:   g.config.NotificationURL,
+	}
+	if req.PayerEmail != "" {
+		pref.Payer = &mpPayer{Email: req.PayerEmail}
+	}
+
+	var created struct {
+		ID        string `json:"id"`
+		InitPoint string `json:"init_point"`
+	}
+	if err := g.call(ctx, http.MethodPost, "/checkout/preferences", pref, &created); err != nil {
+		return nil, fmt.Errorf("mercadopago: creating preference: %w", err)
+	}
+	return &Link{ID: created.ID, URL: created.InitPoint}, nil
+}
+
+func (g *MercadoPagoGateway) ParseWebhook(ctx context.Context, header http.Header, body []byte) (*WebhookEvent, error) {
+	var notification struct {
+		Type string `json:"type"`
+		Data struct {
+			ID json.RawMessage `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("mercadopago: decoding webhook: %w", err)
+	}
+	// data.id comes as a string or as a number depending on the topic.
+	dataID := strings.Trim(string(notification.Data.ID), `"`)
+	if !g.validSignature(header, dataID) {
+		return nil, ErrInvalidSignature
+	}
+	if notification.Type != "payment" || dataID == "" {
+		return nil, nil
+	}
+
+	var payment struct {
+		ID                int64   `json:"id"`
+		Status            string  `json:"status"`
+		ExternalReference string  `json:"external_reference"`
+		TransactionAmount float64 `json:"transaction_amount"`
+	}
+	if err := g.call(ctx, http.MethodGet, "/v1/payments/"+dataID, nil, &payment); err != nil {
+		return nil, fmt.Errorf("mercadopago: reading payment %s: %w", dataID, err)
+	}
+
+	status := EventStatusPending
+	switch payment.Status {
+	case "approved":
+		status = EventStatusApproved
+	case "rejected", "cancelled", "refunded", "charged_back":
+		status = EventStatusRejected
+	}
+	return &WebhookEvent{
+		Reference:     payment.ExternalReference,
+		TransactionID: fmt.Sprint(payment.ID),
+		Status:        status,
+		Amount:        payment.TransactionAmount,
+	}, nil
+}
+
+// validSignature checks the x-signature header, "ts=<unix>,v1=<hmac>", where
+// the HMAC-SHA256 is over "id:<data.id>;request-id:<x-request-id>;ts:<ts>;".
+func (g *MercadoPagoGateway) validSignature(header http.Header, dataID string) bool {
+	var ts, v1 string
+	for _, part := range strings.Split(header.Get("x-signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "ts":
+			ts = value
+		case "v1":
+			v1 = value
+		}
+	}
+	got, err := hex.DecodeString(v1)
+	if ts == "" || err != nil {
+		return false
+	}
+	manifest := fmt.Sprintf("id:%s;request-id:%s;ts:%s;", strings.ToLower(dataID), header.Get("x-request-id"), ts)
+	m := hmac.New(sha256.New, []byte(g.config.WebhookSecret))
+	m.Write([]byte(manifest))
+	return hmac.Equal(got, m.Sum(nil))
+}
+
+func (g *MercadoPagoGateway) call(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.config.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.config.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(payload)))
+	}
+	return json.Unmarshal(payload, out)
+}
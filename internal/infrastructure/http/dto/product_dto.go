@@ -14,10 +14,15 @@ import (
 //
 // Estos tags eran `validate:` — un tag que Gin no interpreta y que nadie había
 // registrado —, así que hasta ahora este endpoint aceptaba cuerpos vacíos.
+//
+// `tax_rate` es el % de IVA; `tax_excluded` indica que `unit_price` es antes de
+// IVA. Por defecto el precio ya lo incluye, que es lo que se cobra en la caja.
 type CreateProductRequest struct {
 	Name        string  `json:"name" binding:"required"`
 	Description string  `json:"description"`
 	UnitPrice   float64 `json:"unit_price" binding:"required,min=0"`
+	TaxRate     float64 `json:"tax_rate" binding:"min=0,lt=100"`
+	TaxExcluded bool    `json:"tax_excluded"`
 	Stock       int     `json:"stock" binding:"min=0"`
 	Status      string  `json:"status,omitempty"`
 }
@@ -27,20 +32,27 @@ type UpdateProductRequest struct {
 	Name        string  `json:"name,omitempty"`
 	Description string  `json:"description,omitempty"`
 	UnitPrice   float64 `json:"unit_price,omitempty" binding:"min=0"`
-	Stock       int     `json:"stock,omitempty" binding:"min=0"`
-	Status      string  `json:"status,omitempty"`
+	// Punteros: 0 y false son valores válidos, distintos de no enviarlos.
+	TaxRate     *float64 `json:"tax_rate,omitempty" binding:"omitempty,min=0,lt=100"`
+	TaxExcluded *bool    `json:"tax_excluded,omitempty"`
+	Stock       int      `json:"stock,omitempty" binding:"min=0"`
+	Status      string   `json:"status,omitempty"`
 }
 
 // ProductResponse representa la respuesta de un producto
 type ProductResponse struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	UnitPrice   float64   `json:"unit_price"`
-	Stock       int       `json:"stock"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	UnitPrice   float64 `json:"unit_price"`
+	TaxRate     float64 `json:"tax_rate"`
+	TaxExcluded bool    `json:"tax_excluded"`
+	// PriceWithTax es lo que se cobra por unidad, IVA incluido.
+	PriceWithTax float64   `json:"price_with_tax"`
+	Stock        int       `json:"stock"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // UpdateStockRequest representa la solicitud para fijar el stock de un producto.
@@ -73,6 +85,8 @@ func (r *CreateProductRequest) ToEntity() *entities.Product {
 		Name:        r.Name,
 		Description: r.Description,
 		UnitPrice:   r.UnitPrice,
+		TaxRate:     r.TaxRate,
+		TaxExcluded: r.TaxExcluded,
 		Stock:       r.Stock,
 		Status:      status,
 	}
@@ -81,14 +95,17 @@ func (r *CreateProductRequest) ToEntity() *entities.Product {
 // ToProductResponse convierte Product entity a ProductResponse
 func ToProductResponse(product *entities.Product) *ProductResponse {
 	return &ProductResponse{
-		ID:          product.ID.String(),
-		Name:        product.Name,
-		Description: product.Description,
-		UnitPrice:   product.UnitPrice,
-		Stock:       product.Stock,
-		Status:      string(product.Status),
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
+		ID:           product.ID.String(),
+		Name:         product.Name,
+		Description:  product.Description,
+		UnitPrice:    product.UnitPrice,
+		TaxRate:      product.TaxRate,
+		TaxExcluded:  product.TaxExcluded,
+		PriceWithTax: product.Gross(product.UnitPrice),
+		Stock:        product.Stock,
+		Status:       string(product.Status),
+		CreatedAt:    product.CreatedAt,
+		UpdatedAt:    product.UpdatedAt,
 	}
 }
 
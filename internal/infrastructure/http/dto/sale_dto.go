@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,7 +9,9 @@ import (
 	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
 )
 
-// SaleDetailRequest representa un detalle de venta en una solicitud
+// SaleDetailRequest representa un detalle de venta en una solicitud. Un
+// unit_price reemplaza el del producto y es lo que se cobra, IVA incluido,
+// como el price_with_tax del producto: no se le vuelve a sumar el IVA.
 type SaleDetailRequest struct {
 	ProductID string  `json:"product_id" binding:"required"`
 	Quantity  int     `json:"quantity" binding:"required,min=1"`
@@ -16,10 +19,21 @@ type SaleDetailRequest struct {
 	Discount  float64 `json:"discount,omitempty" binding:"min=0"`
 }
 
-// CreateSaleRequest representa la solicitud para crear una venta
+// SaleTenderRequest representa uno de los medios con que se paga una venta
+type SaleTenderRequest struct {
+	PaymentMethodID string  `json:"payment_method_id" binding:"required"`
+	Amount          float64 `json:"amount" binding:"required,gt=0"`
+}
+
+// CreateSaleRequest representa la solicitud para crear una venta. Se paga con
+// payment_method_id o, dividida entre varios medios, con tenders, cuya suma
+// debe ser el total.
 type CreateSaleRequest struct {
-	PaymentMethodID string              `json:"payment_method_id" binding:"required"`
+	PaymentMethodID string              `json:"payment_method_id,omitempty"`
+	Tenders         []SaleTenderRequest `json:"tenders,omitempty" binding:"omitempty,dive"`
 	Details         []SaleDetailRequest `json:"details" binding:"required,min=1"`
+	CouponCode      string              `json:"coupon_code,omitempty"`
+	MemberID        string              `json:"member_id,omitempty"` // Comprador, para cupones con límite por socio
 }
 
 // VoidSaleRequest representa la solicitud para anular una venta
@@ -27,18 +41,41 @@ type VoidSaleRequest struct {
 	Reason string `json:"reason,omitempty"`
 }
 
+// ReturnItemRequest representa las unidades de una línea de la venta que se devuelven
+type ReturnItemRequest struct {
+	DetailID string `json:"detail_id" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required,min=1"`
+}
+
+// ReturnSaleRequest representa la solicitud para devolver parte de una venta
+type ReturnSaleRequest struct {
+	Items []ReturnItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
 // SaleDetailResponse representa un detalle de venta en una respuesta
 type SaleDetailResponse struct {
-	ID          string           `json:"id"`
-	ProductID   string           `json:"product_id"`
-	ProductName string           `json:"product_name,omitempty"`
-	UnitPrice   float64          `json:"unit_price"`
-	Quantity    int              `json:"quantity"`
-	TotalPrice  float64          `json:"total_price"`
-	Discount    float64          `json:"discount"`
-	Subtotal    float64          `json:"subtotal"`
-	CreatedAt   time.Time        `json:"created_at"`
-	Product     *ProductResponse `json:"product,omitempty"`
+	ID          string  `json:"id"`
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name,omitempty"`
+	UnitPrice   float64 `json:"unit_price"`
+	Quantity    int     `json:"quantity"`
+	TotalPrice  float64 `json:"total_price"`
+	Discount    float64 `json:"discount"`
+	Subtotal    float64 `json:"subtotal"` // IVA incluido
+	TaxRate     float64 `json:"tax_rate"`
+	TaxBase     float64 `json:"tax_base"`
+	TaxAmount   float64 `json:"tax_amount"`
+	// ReturnedDetailID es, en una devolución, la línea de la venta devuelta
+	ReturnedDetailID *string          `json:"returned_detail_id,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	Product          *ProductResponse `json:"product,omitempty"`
+}
+
+// SaleTenderResponse representa un medio de pago de una venta
+type SaleTenderResponse struct {
+	PaymentMethodID   string  `json:"payment_method_id"`
+	PaymentMethodName string  `json:"payment_method_name,omitempty"`
+	Amount            float64 `json:"amount"`
 }
 
 // SaleResponse representa la respuesta de una venta
@@ -53,9 +90,12 @@ type SaleResponse struct {
 	PaymentMethodID   string               `json:"payment_method_id"`
 	PaymentMethodName string               `json:"payment_method_name,omitempty"`
 	VoidedSaleID      *string              `json:"voided_sale_id,omitempty"`
+	ReturnedSaleID    *string              `json:"returned_sale_id,omitempty"`
+	CouponCode        string               `json:"coupon_code,omitempty"`
 	CreatedAt         time.Time            `json:"created_at"`
 	UpdatedAt         time.Time            `json:"updated_at"`
 	Details           []SaleDetailResponse `json:"details,omitempty"`
+	Tenders           []SaleTenderResponse `json:"tenders,omitempty"`
 }
 
 // SaleReportRequest representa la solicitud para un reporte de ventas
@@ -65,14 +105,17 @@ type SaleReportRequest struct {
 	UserID    *string `json:"user_id,omitempty"`
 }
 
-// SaleReportResponse representa el reporte de ventas
+// SaleReportResponse representa el reporte de ventas, con su IVA por tarifa
 type SaleReportResponse struct {
-	TotalSales    float64   `json:"total_sales"`
-	TotalDiscount float64   `json:"total_discount"`
-	NetSales      float64   `json:"net_sales"`
-	SalesCount    int       `json:"sales_count"`
-	StartDate     time.Time `json:"start_date"`
-	EndDate       time.Time `json:"end_date"`
+	TotalSales    float64                 `json:"total_sales"`
+	TotalDiscount float64                 `json:"total_discount"`
+	NetSales      float64                 `json:"net_sales"`
+	SalesCount    int                     `json:"sales_count"`
+	TaxableBase   float64                 `json:"taxable_base"`
+	TaxTotal      float64                 `json:"tax_total"`
+	Taxes         []entities.TaxBreakdown `json:"taxes"`
+	StartDate     time.Time               `json:"start_date"`
+	EndDate       time.Time               `json:"end_date"`
 }
 
 // SaleProductReportResponse representa ventas agrupadas por producto
@@ -87,9 +130,25 @@ type SaleProductReportResponse struct {
 
 // ToEntity convierte CreateSaleRequest a Sale entity
 func (r *CreateSaleRequest) ToEntity(userID uuid.UUID) (*entities.Sale, error) {
-	paymentMethodID, err := uuid.Parse(r.PaymentMethodID)
-	if err != nil {
-		return nil, err
+	if r.PaymentMethodID == "" && len(r.Tenders) == 0 {
+		return nil, fmt.Errorf("se requiere payment_method_id o tenders")
+	}
+	var paymentMethodID uuid.UUID
+	if r.PaymentMethodID != "" {
+		id, err := uuid.Parse(r.PaymentMethodID)
+		if err != nil {
+			return nil, err
+		}
+		paymentMethodID = id
+	}
+
+	tenders := make([]entities.SaleTender, len(r.Tenders))
+	for i, t := range r.Tenders {
+		methodID, err := uuid.Parse(t.PaymentMethodID)
+		if err != nil {
+			return nil, err
+		}
+		tenders[i] = entities.SaleTender{PaymentMethodID: methodID, Amount: t.Amount}
 	}
 
 	details := make([]entities.SaleDetail, len(r.Details))
@@ -106,10 +165,22 @@ func (r *CreateSaleRequest) ToEntity(userID uuid.UUID) (*entities.Sale, error) {
 		}
 	}
 
+	var memberID *uuid.UUID
+	if r.MemberID != "" {
+		id, err := uuid.Parse(r.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		memberID = &id
+	}
+
 	return &entities.Sale{
 		UserID:          userID,
 		PaymentMethodID: paymentMethodID,
+		MemberID:        memberID,
+		CouponCode:      r.CouponCode,
 		Details:         details,
+		Tenders:         tenders,
 		Type:            entities.SaleTypeNormal,
 		Status:          entities.SaleStatusCompleted,
 	}, nil
@@ -123,6 +194,12 @@ func ToSaleResponse(sale *entities.Sale) *SaleResponse {
 		voidedSaleID = &id
 	}
 
+	var returnedSaleID *string
+	if sale.ReturnedSaleID != nil {
+		id := sale.ReturnedSaleID.String()
+		returnedSaleID = &id
+	}
+
 	response := &SaleResponse{
 		ID:              sale.ID.String(),
 		SaleDate:        sale.SaleDate,
@@ -133,6 +210,8 @@ func ToSaleResponse(sale *entities.Sale) *SaleResponse {
 		Status:          string(sale.Status),
 		PaymentMethodID: sale.PaymentMethodID.String(),
 		VoidedSaleID:    voidedSaleID,
+		ReturnedSaleID:  returnedSaleID,
+		CouponCode:      sale.CouponCode,
 		CreatedAt:       sale.CreatedAt,
 		UpdatedAt:       sale.UpdatedAt,
 	}
@@ -141,6 +220,17 @@ func ToSaleResponse(sale *entities.Sale) *SaleResponse {
 		response.PaymentMethodName = sale.PaymentMethod.Name
 	}
 
+	for _, tender := range sale.Tenders {
+		t := SaleTenderResponse{
+			PaymentMethodID: tender.PaymentMethodID.String(),
+			Amount:          tender.Amount,
+		}
+		if tender.PaymentMethod != nil {
+			t.PaymentMethodName = tender.PaymentMethod.Name
+		}
+		response.Tenders = append(response.Tenders, t)
+	}
+
 	if len(sale.Details) > 0 {
 		response.Details = make([]SaleDetailResponse, len(sale.Details))
 		for i, detail := range sale.Details {
@@ -161,9 +251,17 @@ func ToSaleDetailResponse(detail *entities.SaleDetail) SaleDetailResponse {
 		TotalPrice: detail.TotalPrice,
 		Discount:   detail.Discount,
 		Subtotal:   detail.Subtotal,
+		TaxRate:    detail.TaxRate,
+		TaxBase:    detail.TaxBase,
+		TaxAmount:  detail.TaxAmount,
 		CreatedAt:  detail.CreatedAt,
 	}
 
+	if detail.ReturnedDetailID != nil {
+		id := detail.ReturnedDetailID.String()
+		response.ReturnedDetailID = &id
+	}
+
 	if detail.Product != nil {
 		response.ProductName = detail.Product.Name
 		response.Product = ToProductResponse(detail.Product)
@@ -188,6 +286,9 @@ func ToSaleReportResponse(report repositories.SaleReport, startDate, endDate tim
 		TotalDiscount: report.TotalDiscount,
 		NetSales:      report.NetSales,
 		SalesCount:    report.SalesCount,
+		TaxableBase:   report.TaxableBase,
+		TaxTotal:      report.TaxTotal,
+		Taxes:         report.Taxes,
 		StartDate:     startDate,
 		EndDate:       endDate,
 	}
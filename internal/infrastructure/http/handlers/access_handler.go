@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -51,7 +52,7 @@ func (h *AccessHandler) CheckIn(c *gin.Context) {
 		method = entities.AccessLogMethod(req.Method)
 	}
 
-	accessLog, err := h.accessUseCase.RecordEntry(userID, gymID, method)
+	accessLog, err := h.accessUseCase.RecordEntry(userID, gymID, method, middleware.GetGymLocation(c))
 	if err != nil {
 		if accessLog != nil && accessLog.Status == entities.AccessLogStatusDenied {
 			c.JSON(http.StatusForbidden, gin.H{
@@ -167,9 +168,30 @@ func (h *AccessHandler) GetStats(c *gin.Context) {
 		return
 	}
 
+	// The range defaults to the last 30 days, today included.
+	today := time.Now().In(loc)
+	from := c.DefaultQuery("from", today.AddDate(0, 0, -29).Format("2006-01-02"))
+	to := c.DefaultQuery("to", today.Format("2006-01-02"))
+	fromDay, errFrom := time.Parse("2006-01-02", from)
+	toDay, errTo := time.Parse("2006-01-02", to)
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha inválido, use YYYY-MM-DD"})
+		return
+	}
+	if toDay.Before(fromDay) || toDay.Sub(fromDay) > 366*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El rango debe ir de from a to y no pasar de un año"})
+		return
+	}
+	stats, err := h.accessUseCase.GetStats(gymID, from, to, loc)
+	if err != nil {
+		RespondError(c, err, "Failed to get access stats")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
 			"today_count": int64(len(logs)),
+			"range":       stats,
 		},
 	})
 }
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+)
+
+type CashRegisterHandler struct {
+	registerUseCase *usecases.CashRegisterUseCase
+	notifUseCase    *usecases.NotificationUseCase
+}
+
+func NewCashRegisterHandler(registerUseCase *usecases.CashRegisterUseCase, notifUseCase *usecases.NotificationUseCase) *CashRegisterHandler {
+	return &CashRegisterHandler{
+		registerUseCase: registerUseCase,
+		notifUseCase:    notifUseCase,
+	}
+}
+
+type OpenCashRegisterRequest struct {
+	OpeningFloat float64 `json:"opening_float" binding:"min=0"`
+}
+
+type CloseCashRegisterRequest struct {
+	CountedCash *float64 `json:"counted_cash" binding:"required,min=0"`
+	Notes       string   `json:"notes"`
+}
+
+// Open opens a register for the logged-in user.
+func (h *CashRegisterHandler) Open(c *gin.Context) {
+	var req OpenCashRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	session, err := h.registerUseCase.OpenSession(gymID, userID, req.OpeningFloat, middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "Failed to open cash register")
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// Current returns the register the logged-in user has open, with what it
+// should hold so far. 404 when there is none.
+func (h *CashRegisterHandler) Current(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	summary, err := h.registerUseCase.GetCurrentSummary(userID)
+	if err != nil {
+		RespondError(c, err, "Failed to get cash register")
+		return
+	}
+	if summary == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No tiene una caja abierta"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// List returns the registers opened between from and to (YYYY-MM-DD), today
+// by default.
+func (h *CashRegisterHandler) List(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.registerUseCase.ListSessions(gymID, from, to)
+	if err != nil {
+		RespondError(c, err, "Failed to list cash registers")
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *CashRegisterHandler) Get(c *gin.Context) {
+	summary, ok := h.ownSummary(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// Close closes a register with the cash counted in the drawer and sends its
+// summary to the DAILY_CLOSE recipients. The email goes out after answering:
+// a slow SMTP server must not keep the receptionist waiting, and the register
+// is closed whether it arrives or not.
+func (h *CashRegisterHandler) Close(c *gin.Context) {
+	var req CloseCashRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	current, ok := h.ownSummary(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.registerUseCase.CloseSession(current.Session.ID, *req.CountedCash, req.Notes)
+	if err != nil {
+		RespondError(c, err, "Failed to close cash register")
+		return
+	}
+
+	loc := middleware.GetGymLocation(c)
+	go func() {
+		if err := h.notifUseCase.SendCashRegisterClose(summary, loc); err != nil {
+			log.Printf("⚠️  cierre de caja %s: no se envió el resumen: %v", summary.Session.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ownSummary loads the register in :id. A receptionist only sees and closes
+// their own; the admins, any register of the gym.
+func (h *CashRegisterHandler) ownSummary(c *gin.Context) (*usecases.CashRegisterSummary, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	summary, err := h.registerUseCase.GetSummary(id)
+	if err != nil || summary.Session.GymID.String() != c.GetString("gym_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cash register not found"})
+		return nil, false
+	}
+	role := c.GetString("role")
+	if role != "SUPER_ADMIN" && role != "ADMIN_GYM" && summary.Session.UserID.String() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return nil, false
+	}
+	return summary, true
+}
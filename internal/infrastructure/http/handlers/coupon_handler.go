@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	"github.com/sebastiancorrales/gym-go/pkg/timeutil"
+)
+
+type CouponHandler struct {
+	couponUseCase *usecases.CouponUseCase
+}
+
+func NewCouponHandler(couponUseCase *usecases.CouponUseCase) *CouponHandler {
+	return &CouponHandler{
+		couponUseCase: couponUseCase,
+	}
+}
+
+// CouponRequest is used both to create and to update a coupon: an update
+// replaces every rule of the coupon, which is what the edit form sends anyway.
+type CouponRequest struct {
+	Code        string               `json:"code" binding:"required"`
+	Description string               `json:"description"`
+	Kind        entities.CouponKind  `json:"kind" binding:"required"`
+	Value       float64              `json:"value" binding:"required,gt=0"`
+	Scope       entities.CouponScope `json:"scope"`
+	PlanIDs     []uuid.UUID          `json:"plan_ids"`
+	ProductIDs  []uuid.UUID          `json:"product_ids"`
+	// ValidFrom/ValidUntil are YYYY-MM-DD in the gym's timezone, both days
+	// included. Empty means no bound.
+	ValidFrom        string `json:"valid_from"`
+	ValidUntil       string `json:"valid_until"`
+	MaxUses          int    `json:"max_uses" binding:"min=0"`
+	MaxUsesPerMember int    `json:"max_uses_per_member" binding:"min=0"`
+}
+
+// apply copies the request onto coupon, resolving the dates in loc.
+func (r *CouponRequest) apply(coupon *entities.Coupon, loc *time.Location) error {
+	coupon.Code = entities.NormalizeCouponCode(r.Code)
+	coupon.Description = r.Description
+	coupon.Kind = r.Kind
+	coupon.Value = r.Value
+	coupon.Scope = r.Scope
+	if coupon.Scope == "" {
+		coupon.Scope = entities.CouponScopeAll
+	}
+	coupon.PlanIDs = r.PlanIDs
+	coupon.ProductIDs = r.ProductIDs
+	coupon.MaxUses = r.MaxUses
+	coupon.MaxUsesPerMember = r.MaxUsesPerMember
+
+	coupon.ValidFrom, coupon.ValidUntil = nil, nil
+	if r.ValidFrom != "" {
+		from, err := timeutil.ParseLocalDate(r.ValidFrom, loc)
+		if err != nil {
+			return err
+		}
+		coupon.ValidFrom = &from
+	}
+	if r.ValidUntil != "" {
+		until, err := timeutil.ParseLocalDateEndOfDay(r.ValidUntil, loc)
+		if err != nil {
+			return err
+		}
+		coupon.ValidUntil = &until
+	}
+	return nil
+}
+
+func (h *CouponHandler) Create(c *gin.Context) {
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+
+	coupon := entities.NewCoupon(gymID, req.Code, req.Kind, req.Value)
+	if err := req.apply(coupon, middleware.GetGymLocation(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha inválido, use YYYY-MM-DD"})
+		return
+	}
+	if err := h.couponUseCase.CreateCoupon(coupon); err != nil {
+		RespondError(c, err, "Failed to create coupon")
+		return
+	}
+
+	c.JSON(http.StatusCreated, coupon)
+}
+
+func (h *CouponHandler) List(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+
+	coupons, err := h.couponUseCase.ListCoupons(gymID)
+	if err != nil {
+		RespondError(c, err, "Failed to list coupons")
+		return
+	}
+
+	c.JSON(http.StatusOK, coupons)
+}
+
+func (h *CouponHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coupon, err := h.couponUseCase.GetCouponByID(id)
+	if err != nil || coupon.GymID.String() != c.GetString("gym_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+	if err := req.apply(coupon, middleware.GetGymLocation(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha inválido, use YYYY-MM-DD"})
+		return
+	}
+	if err := h.couponUseCase.UpdateCoupon(coupon); err != nil {
+		RespondError(c, err, "Failed to update coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": coupon})
+}
+
+func (h *CouponHandler) Deactivate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	coupon, err := h.couponUseCase.GetCouponByID(id)
+	if err != nil || coupon.GymID.String() != c.GetString("gym_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+	if err := h.couponUseCase.DeactivateCoupon(id); err != nil {
+		RespondError(c, err, "Failed to deactivate coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Coupon deactivated"})
+}
+
+// Redemptions lists every use of a coupon, newest first.
+func (h *CouponHandler) Redemptions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	coupon, err := h.couponUseCase.GetCouponByID(id)
+	if err != nil || coupon.GymID.String() != c.GetString("gym_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+	redemptions, err := h.couponUseCase.GetRedemptions(id)
+	if err != nil {
+		RespondError(c, err, "Failed to list redemptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, redemptions)
+}
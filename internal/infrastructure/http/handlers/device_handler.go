@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
 	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/printer"
 	"go.bug.st/serial"
 )
 
@@ -44,7 +45,8 @@ func (h *DeviceHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, devices)
 }
 
-// Create creates a new relay device
+// Create creates a new relay device, or a receipt printer when device_type
+// says so
 func (h *DeviceHandler) Create(c *gin.Context) {
 	gymID, ok := gymIDFromContext(c)
 	if !ok {
@@ -53,25 +55,48 @@ func (h *DeviceHandler) Create(c *gin.Context) {
 	}
 
 	var req struct {
-		Name     string `json:"name" binding:"required"`
-		Location string `json:"location"`
-		COMPort  string `json:"com_port"`
-		BaudRate int    `json:"baud_rate"`
-		Notes    string `json:"notes"`
+		Name       string              `json:"name" binding:"required"`
+		DeviceType entities.DeviceType `json:"device_type"`
+		Location   string              `json:"location"`
+		COMPort    string              `json:"com_port"`
+		BaudRate   int                 `json:"baud_rate"`
+		PaperWidth int                 `json:"paper_width"`
+		Notes      string              `json:"notes"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	deviceType := req.DeviceType
+	switch deviceType {
+	case "":
+		deviceType = entities.DeviceTypeRelay
+	case entities.DeviceTypeRelay, entities.DeviceTypeReceiptPrinter:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "device_type must be RELAY or RECEIPT_PRINTER"})
+		return
+	}
+	if req.PaperWidth != 0 && req.PaperWidth != 58 && req.PaperWidth != 80 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "paper_width must be 58 or 80"})
+		return
+	}
+	if printer.IsFilePort(req.COMPort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "com_port must be a serial port"})
+		return
+	}
+
 	baudRate := req.BaudRate
 	if baudRate == 0 {
 		baudRate = 9600
 	}
 
-	device := entities.NewDevice(gymID, req.Name, entities.DeviceTypeRelay, "", req.Location)
+	device := entities.NewDevice(gymID, req.Name, deviceType, "", req.Location)
 	device.COMPort = req.COMPort
 	device.BaudRate = baudRate
+	if deviceType == entities.DeviceTypeReceiptPrinter {
+		device.PaperWidth = req.PaperWidth
+	}
 	device.Notes = req.Notes
 
 	if err := h.repo.Create(device); err != nil {
@@ -96,12 +121,13 @@ func (h *DeviceHandler) Update(c *gin.Context) {
 	}
 
 	var req struct {
-		Name     string `json:"name"`
-		Location string `json:"location"`
-		COMPort  string `json:"com_port"`
-		BaudRate int    `json:"baud_rate"`
-		Notes    string `json:"notes"`
-		IsActive *bool  `json:"is_active"`
+		Name       string `json:"name"`
+		Location   string `json:"location"`
+		COMPort    string `json:"com_port"`
+		BaudRate   int    `json:"baud_rate"`
+		PaperWidth int    `json:"paper_width"`
+		Notes      string `json:"notes"`
+		IsActive   *bool  `json:"is_active"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -115,11 +141,22 @@ func (h *DeviceHandler) Update(c *gin.Context) {
 		device.Location = req.Location
 	}
 	if req.COMPort != "" {
+		if printer.IsFilePort(req.COMPort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "com_port must be a serial port"})
+			return
+		}
 		device.COMPort = req.COMPort
 	}
 	if req.BaudRate != 0 {
 		device.BaudRate = req.BaudRate
 	}
+	if req.PaperWidth != 0 && device.DeviceType == entities.DeviceTypeReceiptPrinter {
+		if req.PaperWidth != 58 && req.PaperWidth != 80 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "paper_width must be 58 or 80"})
+			return
+		}
+		device.PaperWidth = req.PaperWidth
+	}
 	if req.Notes != "" {
 		device.Notes = req.Notes
 	}
@@ -168,6 +205,12 @@ func (h *DeviceHandler) Trigger(c *gin.Context) {
 		return
 	}
 
+	// "OPEN\n" would come out of a receipt printer as a line of text.
+	if device.DeviceType == entities.DeviceTypeReceiptPrinter {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "device is a receipt printer, not a relay"})
+		return
+	}
+
 	if device.COMPort == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "device has no COM port configured"})
 		return
@@ -59,7 +59,9 @@ func RespondError(c *gin.Context, err error, fallbackMessage string) {
 		errors.Is(err, apperrors.ErrInvalidQuantity),
 		errors.Is(err, apperrors.ErrInvalidPrice),
 		errors.Is(err, apperrors.ErrInvalidDiscount),
-		errors.Is(err, apperrors.ErrDiscountExceedsTotal):
+		errors.Is(err, apperrors.ErrDiscountExceedsTotal),
+		errors.Is(err, apperrors.ErrFreezeNotAllowed),
+		errors.Is(err, apperrors.ErrInvalidCoupon):
 		status = http.StatusBadRequest
 		message = err.Error()
 
@@ -71,7 +73,7 @@ func RespondError(c *gin.Context, err error, fallbackMessage string) {
 		status = http.StatusForbidden
 		message = err.Error()
 
-	case errors.Is(err, apperrors.ErrConflict):
+	case errors.Is(err, apperrors.ErrConflict), errors.Is(err, apperrors.ErrInvalidTransition):
 		status = http.StatusConflict
 		message = err.Error()
 	}
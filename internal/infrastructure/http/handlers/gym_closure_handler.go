@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+)
+
+type GymClosureHandler struct {
+	closureUseCase *usecases.GymClosureUseCase
+}
+
+func NewGymClosureHandler(closureUseCase *usecases.GymClosureUseCase) *GymClosureHandler {
+	return &GymClosureHandler{
+		closureUseCase: closureUseCase,
+	}
+}
+
+type CreateGymClosureRequest struct {
+	Date   string `json:"date" binding:"required"` // YYYY-MM-DD, local to the gym
+	Reason string `json:"reason"`
+}
+
+// List returns the closures between from and to (YYYY-MM-DD, both included).
+// By default, from today to a year ahead.
+func (h *GymClosureHandler) List(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+
+	today := time.Now().In(middleware.GetGymLocation(c))
+	from := c.DefaultQuery("from", today.Format("2006-01-02"))
+	to := c.DefaultQuery("to", today.AddDate(1, 0, 0).Format("2006-01-02"))
+	closures, err := h.closureUseCase.ListClosures(gymID, from, to)
+	if err != nil {
+		RespondError(c, err, "Failed to list closures")
+		return
+	}
+
+	c.JSON(http.StatusOK, closures)
+}
+
+func (h *GymClosureHandler) Create(c *gin.Context) {
+	var req CreateGymClosureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	createdBy, _ := uuid.Parse(c.GetString("user_id"))
+
+	closure, err := entities.NewGymClosure(gymID, req.Date, req.Reason, createdBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.closureUseCase.CreateClosure(closure); err != nil {
+		RespondError(c, err, "Failed to create closure")
+		return
+	}
+
+	c.JSON(http.StatusCreated, closure)
+}
+
+func (h *GymClosureHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	closure, err := h.closureUseCase.GetClosureByID(id)
+	if err != nil || closure.GymID.String() != c.GetString("gym_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Closure not found"})
+		return
+	}
+	if err := h.closureUseCase.DeleteClosure(id); err != nil {
+		RespondError(c, err, "Failed to delete closure")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Closure deleted"})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
 	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
 )
 
@@ -39,6 +40,14 @@ type UpdateGymRequest struct {
 	SMTPUsername *string `json:"smtp_username"`
 	SMTPPassword *string `json:"smtp_password"`
 	SMTPFrom     *string `json:"smtp_from"`
+	// Cancellation refunds
+	RefundPolicy     *entities.RefundPolicy `json:"refund_policy"`
+	RefundWindowDays *int                   `json:"refund_window_days"`
+	// Overdue installment balances
+	OverdueBalancePolicy    *entities.OverdueBalancePolicy `json:"overdue_balance_policy"`
+	OverdueBalanceGraceDays *int                           `json:"overdue_balance_grace_days"`
+	// Closed days
+	ClosureCompensation *entities.ClosureCompensation `json:"closure_compensation"`
 }
 
 func (h *GymHandler) Get(c *gin.Context) {
@@ -135,6 +144,41 @@ func (h *GymHandler) Update(c *gin.Context) {
 	if req.SMTPFrom != nil {
 		gym.SMTPFrom = *req.SMTPFrom
 	}
+	if req.RefundPolicy != nil {
+		if !req.RefundPolicy.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refund_policy must be NONE, FULL or PRORATED"})
+			return
+		}
+		gym.RefundPolicy = *req.RefundPolicy
+	}
+	if req.RefundWindowDays != nil {
+		if *req.RefundWindowDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refund_window_days cannot be negative"})
+			return
+		}
+		gym.RefundWindowDays = *req.RefundWindowDays
+	}
+	if req.OverdueBalancePolicy != nil {
+		if !req.OverdueBalancePolicy.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "overdue_balance_policy must be NONE, WARN or DENY"})
+			return
+		}
+		gym.OverdueBalancePolicy = *req.OverdueBalancePolicy
+	}
+	if req.OverdueBalanceGraceDays != nil {
+		if *req.OverdueBalanceGraceDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "overdue_balance_grace_days cannot be negative"})
+			return
+		}
+		gym.OverdueBalanceGraceDays = *req.OverdueBalanceGraceDays
+	}
+	if req.ClosureCompensation != nil {
+		if !req.ClosureCompensation.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "closure_compensation must be NONE or EXTEND"})
+			return
+		}
+		gym.ClosureCompensation = *req.ClosureCompensation
+	}
 
 	gym.UpdatedAt = time.Now()
 
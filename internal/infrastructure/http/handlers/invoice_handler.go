@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+)
+
+type InvoiceHandler struct {
+	invoiceUseCase *usecases.InvoiceUseCase
+}
+
+func NewInvoiceHandler(invoiceUseCase *usecases.InvoiceUseCase) *InvoiceHandler {
+	return &InvoiceHandler{invoiceUseCase: invoiceUseCase}
+}
+
+// CreateResolution registers the DIAN numbering resolution the gym invoices
+// with from now on.
+func (h *InvoiceHandler) CreateResolution(c *gin.Context) {
+	var req usecases.ResolutionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+
+	res, err := h.invoiceUseCase.CreateResolution(gymID, req)
+	if err != nil {
+		RespondError(c, err, "Failed to create invoice resolution")
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *InvoiceHandler) ListResolutions(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+
+	resolutions, err := h.invoiceUseCase.ListResolutions(gymID)
+	if err != nil {
+		RespondError(c, err, "Failed to list invoice resolutions")
+		return
+	}
+
+	c.JSON(http.StatusOK, resolutions)
+}
+
+// InvoiceSale issues the invoice of a sale, or returns the one it already has.
+func (h *InvoiceHandler) InvoiceSale(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	saleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sale ID"})
+		return
+	}
+
+	inv, err := h.invoiceUseCase.InvoiceSale(c.Request.Context(), gymID, saleID, middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "Failed to invoice sale")
+		return
+	}
+
+	c.JSON(http.StatusOK, inv)
+}
+
+// InvoicePayment issues the invoice of a membership payment, or returns the one
+// it already has.
+func (h *InvoiceHandler) InvoicePayment(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	inv, err := h.invoiceUseCase.InvoicePayment(c.Request.Context(), gymID, paymentID, middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "Failed to invoice payment")
+		return
+	}
+
+	c.JSON(http.StatusOK, inv)
+}
+
+// InvoicePending issues the invoices missing between from and to (YYYY-MM-DD),
+// today by default.
+func (h *InvoiceHandler) InvoicePending(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.invoiceUseCase.InvoicePending(c.Request.Context(), gymID, from, to, middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "Failed to issue pending invoices")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// List returns the invoices issued between from and to (YYYY-MM-DD), today by
+// default.
+func (h *InvoiceHandler) List(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	invoices, err := h.invoiceUseCase.ListInvoices(gymID, from, to)
+	if err != nil {
+		RespondError(c, err, "Failed to list invoices")
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+func (h *InvoiceHandler) Get(c *gin.Context) {
+	inv, ok := h.ownInvoice(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
+
+// XML downloads the UBL document of an invoice.
+func (h *InvoiceHandler) XML(c *gin.Context) {
+	inv, ok := h.ownInvoice(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xml"`, inv.FullNumber))
+	c.Data(http.StatusOK, "application/xml", []byte(inv.XML))
+}
+
+// PDF downloads the graphic representation of an invoice.
+func (h *InvoiceHandler) PDF(c *gin.Context) {
+	inv, ok := h.ownInvoice(c)
+	if !ok {
+		return
+	}
+
+	pdf, err := h.invoiceUseCase.GetPDF(inv.ID)
+	if err != nil {
+		RespondError(c, err, "Failed to render invoice")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, inv.FullNumber))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// Submit sends again an invoice the DIAN has not accepted.
+func (h *InvoiceHandler) Submit(c *gin.Context) {
+	own, ok := h.ownInvoice(c)
+	if !ok {
+		return
+	}
+
+	inv, err := h.invoiceUseCase.Submit(c.Request.Context(), own.ID)
+	if err != nil {
+		RespondError(c, err, "Failed to submit invoice")
+		return
+	}
+
+	c.JSON(http.StatusOK, inv)
+}
+
+// ownInvoice loads the invoice of the :id param, answering 404 for one of
+// another gym.
+func (h *InvoiceHandler) ownInvoice(c *gin.Context) (*entities.Invoice, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return nil, false
+	}
+
+	inv, err := h.invoiceUseCase.GetInvoice(id)
+	if err != nil {
+		RespondError(c, err, "Invoice not found")
+		return nil, false
+	}
+	if inv.GymID.String() != c.GetString("gym_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return nil, false
+	}
+	return inv, true
+}
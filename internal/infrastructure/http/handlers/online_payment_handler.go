@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/gateway"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+	"github.com/sebastiancorrales/gym-go/pkg/timeutil"
+	"gorm.io/gorm"
+)
+
+type OnlinePaymentHandler struct {
+	onlinePaymentUseCase *usecases.OnlinePaymentUseCase
+}
+
+func NewOnlinePaymentHandler(onlinePaymentUseCase *usecases.OnlinePaymentUseCase) *OnlinePaymentHandler {
+	return &OnlinePaymentHandler{
+		onlinePaymentUseCase: onlinePaymentUseCase,
+	}
+}
+
+type SubscriptionCheckoutRequest struct {
+	UserID            string   `json:"user_id" binding:"required"`
+	PlanID            string   `json:"plan_id" binding:"required"`
+	CouponCode        string   `json:"coupon_code"`
+	AdditionalMembers []string `json:"additional_members"`
+	StartDate         string   `json:"start_date"` // YYYY-MM-DD, gym time; empty starts it once paid
+}
+
+// Checkout sells a subscription to be paid online and returns the link the
+// member pays it at.
+func (h *OnlinePaymentHandler) Checkout(c *gin.Context) {
+	var req SubscriptionCheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	planID, err := uuid.Parse(req.PlanID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+
+	additionalIDs := make([]uuid.UUID, 0, len(req.AdditionalMembers))
+	for _, idStr := range req.AdditionalMembers {
+		if id, err := uuid.Parse(idStr); err == nil {
+			additionalIDs = append(additionalIDs, id)
+		}
+	}
+
+	loc := middleware.GetGymLocation(c)
+	var startDate time.Time
+	if req.StartDate != "" {
+		startDate, err = timeutil.ParseLocalDate(req.StartDate, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha inválido, use YYYY-MM-DD"})
+			return
+		}
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+
+	checkout, err := h.onlinePaymentUseCase.CreateSubscriptionCheckout(c.Request.Context(), userID, planID, gymID,
+		req.CouponCode, additionalIDs, startDate, changedByID, c.GetString("user_name"), loc)
+	if err != nil {
+		RespondError(c, err, "Failed to create checkout")
+		return
+	}
+
+	c.JSON(http.StatusCreated, checkout)
+}
+
+// Webhook receives the gateway's notifications. It is public: the signature
+// is what authenticates the call.
+//
+// Anything other than 2xx makes the gateway retry, so only what a retry can
+// fix is answered as an error: a bad signature and a failure on our side. A
+// payment we do not know or cannot confirm any more (its subscription was
+// cancelled, it was paid short) is logged for someone to look at and
+// acknowledged.
+func (h *OnlinePaymentHandler) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid body"})
+		return
+	}
+
+	event, err := h.onlinePaymentUseCase.HandleWebhook(c.Request.Context(), c.Request.Header, body)
+	switch {
+	case errors.Is(err, gateway.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	case errors.Is(err, apperrors.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, apperrors.ErrConflict), errors.Is(err, apperrors.ErrInvalidInput):
+		log.Printf("⚠️  webhook de pago ignorado (referencia %q, transacción %q): %v", event.Reference, event.TransactionID, err)
+		c.JSON(http.StatusOK, gin.H{"received": true, "ignored": err.Error()})
+		return
+	case err != nil:
+		RespondError(c, err, "Failed to process webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+)
+
+type PaymentHandler struct {
+	paymentUseCase *usecases.PaymentUseCase
+}
+
+func NewPaymentHandler(paymentUseCase *usecases.PaymentUseCase) *PaymentHandler {
+	return &PaymentHandler{
+		paymentUseCase: paymentUseCase,
+	}
+}
+
+// dateRange reads from/to (YYYY-MM-DD, local to the gym) off the query, today
+// by default.
+func dateRange(c *gin.Context) (from, to string, ok bool) {
+	today := time.Now().In(middleware.GetGymLocation(c)).Format("2006-01-02")
+	return dateRangeSince(c, today)
+}
+
+// dateRangeSince is dateRange with from defaulting to since instead of today.
+func dateRangeSince(c *gin.Context, since string) (from, to string, ok bool) {
+	today := time.Now().In(middleware.GetGymLocation(c)).Format("2006-01-02")
+	from = c.DefaultQuery("from", since)
+	to = c.DefaultQuery("to", today)
+	_, errFrom := time.Parse("2006-01-02", from)
+	_, errTo := time.Parse("2006-01-02", to)
+	if errFrom != nil || errTo != nil || to < from {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rango de fechas inválido, use from y to en formato YYYY-MM-DD"})
+		return "", "", false
+	}
+	return from, to, true
+}
+
+// List returns the payments charged between from and to.
+func (h *PaymentHandler) List(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	payments, err := h.paymentUseCase.ListPayments(gymID, from, to)
+	if err != nil {
+		RespondError(c, err, "Failed to list payments")
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+// Revenue answers how much money came in between from and to, by method and
+// by what was paid for, net of refunds.
+func (h *PaymentHandler) Revenue(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	revenue, err := h.paymentUseCase.GetRevenue(gymID, from, to)
+	if err != nil {
+		RespondError(c, err, "Failed to get revenue")
+		return
+	}
+
+	c.JSON(http.StatusOK, revenue)
+}
@@ -31,6 +31,11 @@ func NewPaymentMethodHandler(paymentMethodUseCase *usecases.PaymentMethodUseCase
 // @Success 201 {object} dto.PaymentMethodResponse
 // @Router /payment-methods [post]
 func (h *PaymentMethodHandler) CreatePaymentMethod(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	var req dto.CreatePaymentMethodRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -43,7 +48,7 @@ func (h *PaymentMethodHandler) CreatePaymentMethod(c *gin.Context) {
 
 	method := req.ToEntity()
 
-	if err := h.paymentMethodUseCase.CreatePaymentMethod(c.Request.Context(), method); err != nil {
+	if err := h.paymentMethodUseCase.CreatePaymentMethod(c.Request.Context(), gymID, method); err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Error al crear método de pago",
@@ -64,6 +69,11 @@ func (h *PaymentMethodHandler) CreatePaymentMethod(c *gin.Context) {
 // @Success 200 {object} dto.PaymentMethodResponse
 // @Router /payment-methods/{id} [get]
 func (h *PaymentMethodHandler) GetPaymentMethod(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -74,7 +84,7 @@ func (h *PaymentMethodHandler) GetPaymentMethod(c *gin.Context) {
 		return
 	}
 
-	method, err := h.paymentMethodUseCase.GetPaymentMethodByID(c.Request.Context(), id)
+	method, err := h.paymentMethodUseCase.GetPaymentMethodByID(c.Request.Context(), gymID, id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, dto.ErrorResponse{
 			Error:   "Not Found",
@@ -104,13 +114,18 @@ func (h *PaymentMethodHandler) GetPaymentMethod(c *gin.Context) {
 // @Success 200 {array} dto.PaymentMethodResponse
 // @Router /payment-methods [get]
 func (h *PaymentMethodHandler) GetAllPaymentMethods(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	var status *entities.PaymentMethodStatus
 	if statusParam := c.Query("status"); statusParam != "" {
 		s := entities.PaymentMethodStatus(statusParam)
 		status = &s
 	}
 
-	methods, err := h.paymentMethodUseCase.GetAllPaymentMethods(c.Request.Context(), status)
+	methods, err := h.paymentMethodUseCase.GetAllPaymentMethods(c.Request.Context(), gymID, status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
@@ -134,6 +149,11 @@ func (h *PaymentMethodHandler) GetAllPaymentMethods(c *gin.Context) {
 // @Success 200 {object} dto.PaymentMethodResponse
 // @Router /payment-methods/{id} [put]
 func (h *PaymentMethodHandler) UpdatePaymentMethod(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -155,7 +175,7 @@ func (h *PaymentMethodHandler) UpdatePaymentMethod(c *gin.Context) {
 	}
 
 	// Obtener método de pago existente
-	method, err := h.paymentMethodUseCase.GetPaymentMethodByID(c.Request.Context(), id)
+	method, err := h.paymentMethodUseCase.GetPaymentMethodByID(c.Request.Context(), gymID, id)
 	if err != nil || method == nil {
 		c.JSON(http.StatusNotFound, dto.ErrorResponse{
 			Error:   "Not Found",
@@ -175,7 +195,7 @@ func (h *PaymentMethodHandler) UpdatePaymentMethod(c *gin.Context) {
 		method.Status = entities.PaymentMethodStatus(req.Status)
 	}
 
-	if err := h.paymentMethodUseCase.UpdatePaymentMethod(c.Request.Context(), method); err != nil {
+	if err := h.paymentMethodUseCase.UpdatePaymentMethod(c.Request.Context(), gymID, method); err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Error al actualizar método de pago",
@@ -195,6 +215,11 @@ func (h *PaymentMethodHandler) UpdatePaymentMethod(c *gin.Context) {
 // @Success 204
 // @Router /payment-methods/{id} [delete]
 func (h *PaymentMethodHandler) DeletePaymentMethod(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -205,12 +230,8 @@ func (h *PaymentMethodHandler) DeletePaymentMethod(c *gin.Context) {
 		return
 	}
 
-	if err := h.paymentMethodUseCase.DeletePaymentMethod(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Error:   "Internal Server Error",
-			Message: "Error al eliminar método de pago",
-			Details: map[string]string{"detail": err.Error()},
-		})
+	if err := h.paymentMethodUseCase.DeletePaymentMethod(c.Request.Context(), gymID, id); err != nil {
+		RespondError(c, err, "Error al eliminar método de pago")
 		return
 	}
 
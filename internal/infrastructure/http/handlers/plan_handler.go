@@ -27,8 +27,18 @@ type CreatePlanRequest struct {
 	DurationDays  int     `json:"duration_days" binding:"required,min=1"`
 	Price         float64 `json:"price" binding:"required,min=0"`
 	EnrollmentFee float64 `json:"enrollment_fee"`
+	// TaxRate is the IVA %; tax_excluded says price and fees are before it.
+	TaxRate       float64 `json:"tax_rate"`
+	TaxExcluded   bool    `json:"tax_excluded"`
 	MaxMembers    int     `json:"max_members"`
 	BillingMode   string  `json:"billing_mode"`
+	// VisitAllowance > 0 makes it a punch card; duration_days is then how long
+	// the visits stay valid.
+	VisitAllowance int `json:"visit_allowance" binding:"min=0"`
+	// AccessWindows restricts entry to some days/hours; empty means any time.
+	AccessWindows []entities.AccessWindow `json:"access_windows"`
+	// Freeze limits how much a member may freeze; zeros mean no limit.
+	Freeze entities.FreezePolicy `json:"freeze"`
 }
 
 func (h *PlanHandler) Create(c *gin.Context) {
@@ -52,11 +62,16 @@ func (h *PlanHandler) Create(c *gin.Context) {
 		req.DurationDays,
 		req.Price,
 		req.EnrollmentFee,
+		req.TaxRate,
+		req.TaxExcluded,
 		req.MaxMembers,
 		req.BillingMode,
+		req.VisitAllowance,
+		req.AccessWindows,
+		req.Freeze,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create plan"})
+		RespondError(c, err, "Failed to create plan")
 		return
 	}
 
@@ -104,6 +119,16 @@ type UpdatePlanRequest struct {
 	EnrollmentFee float64 `json:"enrollment_fee"`
 	MaxMembers    int     `json:"max_members"`
 	BillingMode   string  `json:"billing_mode"`
+	// Pointers so that 0 and false can be told apart from not sending them.
+	TaxRate     *float64 `json:"tax_rate"`
+	TaxExcluded *bool    `json:"tax_excluded"`
+	// Pointer so that 0 (back to a time-based plan) can be told apart from not
+	// sending the field.
+	VisitAllowance *int `json:"visit_allowance" binding:"omitempty,min=0"`
+	// Pointer for the same reason: an empty list removes the restriction.
+	AccessWindows *[]entities.AccessWindow `json:"access_windows"`
+	// Pointer as well: the policy is replaced as a whole when sent.
+	Freeze *entities.FreezePolicy `json:"freeze"`
 }
 
 func (h *PlanHandler) Update(c *gin.Context) {
@@ -146,10 +171,25 @@ func (h *PlanHandler) Update(c *gin.Context) {
 	if req.BillingMode == "30_DAYS" || req.BillingMode == "CALENDAR_MONTH" {
 		plan.BillingMode = entities.BillingMode(req.BillingMode)
 	}
+	if req.VisitAllowance != nil {
+		plan.VisitAllowance = *req.VisitAllowance
+	}
+	if req.AccessWindows != nil {
+		plan.AccessWindows = *req.AccessWindows
+	}
+	if req.Freeze != nil {
+		plan.Freeze = *req.Freeze
+	}
+	if req.TaxRate != nil {
+		plan.TaxRate = *req.TaxRate
+	}
+	if req.TaxExcluded != nil {
+		plan.TaxExcluded = *req.TaxExcluded
+	}
 	plan.UpdatedAt = time.Now()
 
 	if err := h.planUseCase.UpdatePlan(plan); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update plan"})
+		RespondError(c, err, "Failed to update plan")
 		return
 	}
 
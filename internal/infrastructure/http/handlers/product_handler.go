@@ -31,6 +31,11 @@ func NewProductHandler(productUseCase *usecases.ProductUseCase) *ProductHandler
 // @Success 201 {object} dto.ProductResponse
 // @Router /products [post]
 func (h *ProductHandler) CreateProduct(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	var req dto.CreateProductRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -43,7 +48,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 
 	product := req.ToEntity()
 
-	if err := h.productUseCase.CreateProduct(c.Request.Context(), product); err != nil {
+	if err := h.productUseCase.CreateProduct(c.Request.Context(), gymID, product); err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Error al crear producto",
@@ -64,6 +69,11 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 // @Success 200 {object} dto.ProductResponse
 // @Router /products/{id} [get]
 func (h *ProductHandler) GetProduct(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -74,7 +84,7 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productUseCase.GetProductByID(c.Request.Context(), id)
+	product, err := h.productUseCase.GetProductByID(c.Request.Context(), gymID, id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, dto.ErrorResponse{
 			Error:   "Not Found",
@@ -104,13 +114,18 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 // @Success 200 {array} dto.ProductResponse
 // @Router /products [get]
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	var status *entities.ProductStatus
 	if statusParam := c.Query("status"); statusParam != "" {
 		s := entities.ProductStatus(statusParam)
 		status = &s
 	}
 
-	products, err := h.productUseCase.GetAllProducts(c.Request.Context(), status)
+	products, err := h.productUseCase.GetAllProducts(c.Request.Context(), gymID, status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
@@ -134,6 +149,11 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 // @Success 200 {object} dto.ProductResponse
 // @Router /products/{id} [put]
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -155,7 +175,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	}
 
 	// Obtener producto existente
-	product, err := h.productUseCase.GetProductByID(c.Request.Context(), id)
+	product, err := h.productUseCase.GetProductByID(c.Request.Context(), gymID, id)
 	if err != nil || product == nil {
 		c.JSON(http.StatusNotFound, dto.ErrorResponse{
 			Error:   "Not Found",
@@ -174,6 +194,12 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	if req.UnitPrice > 0 {
 		product.UnitPrice = req.UnitPrice
 	}
+	if req.TaxRate != nil {
+		product.TaxRate = *req.TaxRate
+	}
+	if req.TaxExcluded != nil {
+		product.TaxExcluded = *req.TaxExcluded
+	}
 	if req.Stock >= 0 {
 		product.Stock = req.Stock
 	}
@@ -181,7 +207,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		product.Status = entities.ProductStatus(req.Status)
 	}
 
-	if err := h.productUseCase.UpdateProduct(c.Request.Context(), product); err != nil {
+	if err := h.productUseCase.UpdateProduct(c.Request.Context(), gymID, product); err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Error al actualizar producto",
@@ -201,6 +227,11 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 // @Success 204
 // @Router /products/{id} [delete]
 func (h *ProductHandler) DeleteProduct(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -211,12 +242,8 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.productUseCase.DeleteProduct(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
-			Error:   "Internal Server Error",
-			Message: "Error al eliminar producto",
-			Details: map[string]string{"detail": err.Error()},
-		})
+	if err := h.productUseCase.DeleteProduct(c.Request.Context(), gymID, id); err != nil {
+		RespondError(c, err, "Error al eliminar producto")
 		return
 	}
 
@@ -231,6 +258,11 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 // @Success 200 {array} dto.ProductResponse
 // @Router /products/search [get]
 func (h *ProductHandler) SearchProducts(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	searchTerm := c.Query("q")
 	if searchTerm == "" {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -240,7 +272,7 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 		return
 	}
 
-	products, err := h.productUseCase.SearchProducts(c.Request.Context(), searchTerm)
+	products, err := h.productUseCase.SearchProducts(c.Request.Context(), gymID, searchTerm)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
@@ -264,6 +296,11 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 // @Success 200 {object} dto.ProductResponse
 // @Router /products/{id}/stock [patch]
 func (h *ProductHandler) UpdateStock(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -284,13 +321,13 @@ func (h *ProductHandler) UpdateStock(c *gin.Context) {
 		return
 	}
 
-	if err := h.productUseCase.SetProductStock(c.Request.Context(), id, *req.Stock); err != nil {
+	if err := h.productUseCase.SetProductStock(c.Request.Context(), gymID, id, *req.Stock); err != nil {
 		RespondError(c, err, "Error al actualizar stock")
 		return
 	}
 
 	// Obtener producto actualizado
-	product, err := h.productUseCase.GetProductByID(c.Request.Context(), id)
+	product, err := h.productUseCase.GetProductByID(c.Request.Context(), gymID, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
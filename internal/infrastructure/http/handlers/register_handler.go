@@ -113,11 +113,18 @@ func (h *RegisterHandler) Register(c *gin.Context) {
 
 	// Gym and its admin in one transaction. This replaces a hand-written rollback
 	// (delete the gym if the user insert failed) which itself could fail, leaving
-	// an orphan gym with no way to log into it.
+	// an orphan gym with no way to log into it. The payment methods go with them:
+	// each gym has its own, and without any the POS cannot sell.
+	methods := entities.DefaultPaymentMethods(gym.ID)
 	if err := h.uow.Do(c.Request.Context(), func(r repositories.Repos) error {
 		if err := r.Gyms.Create(gym); err != nil {
 			return err
 		}
+		for i := range methods {
+			if err := r.PayMethods.Create(c.Request.Context(), &methods[i]); err != nil {
+				return err
+			}
+		}
 		return r.Users.Create(admin)
 	}); err != nil {
 		RespondError(c, err, "Error al crear el gimnasio y su administrador")
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
@@ -9,6 +12,7 @@ import (
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/dto"
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
 	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
 )
 
 // localDateStr formats time in the given location as "YYYY-MM-DD"
@@ -19,13 +23,15 @@ func localHourStr(t time.Time, loc *time.Location) string { return t.In(loc).For
 
 // SaleHandler maneja las peticiones HTTP relacionadas con ventas
 type SaleHandler struct {
-	saleUseCase *usecases.SaleUseCase
+	saleUseCase    *usecases.SaleUseCase
+	receiptUseCase *usecases.ReceiptUseCase
 }
 
 // NewSaleHandler crea una nueva instancia de SaleHandler
-func NewSaleHandler(saleUseCase *usecases.SaleUseCase) *SaleHandler {
+func NewSaleHandler(saleUseCase *usecases.SaleUseCase, receiptUseCase *usecases.ReceiptUseCase) *SaleHandler {
 	return &SaleHandler{
-		saleUseCase: saleUseCase,
+		saleUseCase:    saleUseCase,
+		receiptUseCase: receiptUseCase,
 	}
 }
 
@@ -38,6 +44,11 @@ func NewSaleHandler(saleUseCase *usecases.SaleUseCase) *SaleHandler {
 // @Success 201 {object} dto.SaleResponse
 // @Router /sales [post]
 func (h *SaleHandler) CreateSale(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	var req dto.CreateSaleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -83,15 +94,56 @@ func (h *SaleHandler) CreateSale(c *gin.Context) {
 	sale.Date = localDateStr(now, saleLoc)
 	sale.Hour = localHourStr(now, saleLoc)
 
-	if err := h.saleUseCase.CreateSale(c.Request.Context(), sale); err != nil {
+	if err := h.saleUseCase.CreateSale(c.Request.Context(), gymID, sale); err != nil {
 		RespondError(c, err, "Error al crear venta")
 		return
 	}
 
+	// El recibo sale solo, después de responder: una impresora sin papel o
+	// desconectada no puede dejar la venta sin confirmar. Se reimprime con
+	// POST /sales/:id/print.
+	saleID := sale.ID
+	go func() {
+		if err := h.receiptUseCase.PrintSale(context.Background(), gymID, saleID); err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+			log.Printf("⚠️  venta %s: no se imprimió el recibo: %v", saleID, err)
+		}
+	}()
+
 	response := dto.ToSaleResponse(sale)
 	c.JSON(http.StatusCreated, response)
 }
 
+// PrintSale imprime (o reimprime) el recibo de una venta
+// @Summary Imprimir recibo de venta
+// @Tags ventas
+// @Produce json
+// @Param id path string true "ID de la venta (UUID)"
+// @Success 200 {object} map[string]string
+// @Router /sales/{id}/print [post]
+func (h *SaleHandler) PrintSale(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "ID inválido",
+			Details: map[string]string{"detail": err.Error()},
+		})
+		return
+	}
+
+	if err := h.receiptUseCase.PrintSale(c.Request.Context(), gymID, id); err != nil {
+		RespondError(c, err, "Error al imprimir el recibo")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recibo impreso"})
+}
+
 // GetSale obtiene una venta por su ID
 // @Summary Obtener venta
 // @Tags ventas
@@ -100,6 +152,11 @@ func (h *SaleHandler) CreateSale(c *gin.Context) {
 // @Success 200 {object} dto.SaleResponse
 // @Router /sales/{id} [get]
 func (h *SaleHandler) GetSale(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -110,7 +167,7 @@ func (h *SaleHandler) GetSale(c *gin.Context) {
 		return
 	}
 
-	sale, err := h.saleUseCase.GetSaleByID(c.Request.Context(), id)
+	sale, err := h.saleUseCase.GetSaleByID(c.Request.Context(), gymID, id)
 	if err != nil {
 		// Antes cualquier error salía como 404 "Venta no encontrada", incluido un
 		// bloqueo de base de datos. RespondError sólo devuelve 404 cuando la venta
@@ -130,7 +187,12 @@ func (h *SaleHandler) GetSale(c *gin.Context) {
 // @Success 200 {array} dto.SaleResponse
 // @Router /sales [get]
 func (h *SaleHandler) GetAllSales(c *gin.Context) {
-	sales, err := h.saleUseCase.GetAllSales(c.Request.Context())
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
+	sales, err := h.saleUseCase.GetAllSales(c.Request.Context(), gymID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
@@ -154,6 +216,11 @@ func (h *SaleHandler) GetAllSales(c *gin.Context) {
 // @Success 200 {object} dto.SaleResponse
 // @Router /sales/{id}/void [post]
 func (h *SaleHandler) VoidSale(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
@@ -184,7 +251,7 @@ func (h *SaleHandler) VoidSale(c *gin.Context) {
 		return
 	}
 
-	voidSale, err := h.saleUseCase.VoidSale(c.Request.Context(), id, userID, middleware.GetGymLocation(c))
+	voidSale, err := h.saleUseCase.VoidSale(c.Request.Context(), gymID, id, userID, middleware.GetGymLocation(c))
 	if err != nil {
 		RespondError(c, err, "Error al anular venta")
 		return
@@ -194,6 +261,74 @@ func (h *SaleHandler) VoidSale(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// ReturnSale devuelve parte de una venta: las unidades indicadas de sus líneas
+// vuelven al inventario y su dinero al cliente, en un documento de devolución
+// ligado a la venta
+// @Summary Devolver productos de una venta
+// @Tags ventas
+// @Accept json
+// @Produce json
+// @Param id path string true "ID de la venta"
+// @Param items body dto.ReturnSaleRequest true "Líneas y cantidades devueltas"
+// @Success 201 {object} dto.SaleResponse
+// @Router /sales/{id}/return [post]
+func (h *SaleHandler) ReturnSale(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "ID inválido",
+			Details: map[string]string{"detail": err.Error()},
+		})
+		return
+	}
+
+	var req dto.ReturnSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Solicitud inválida",
+			Details: map[string]string{"detail": err.Error()},
+		})
+		return
+	}
+	items := make([]usecases.ReturnItem, len(req.Items))
+	for i, item := range req.Items {
+		detailID, err := uuid.Parse(item.DetailID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Bad Request",
+				Message: "ID de línea inválido",
+				Details: map[string]string{"detail": err.Error()},
+			})
+			return
+		}
+		items[i] = usecases.ReturnItem{DetailID: detailID, Quantity: item.Quantity}
+	}
+
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Usuario no autenticado",
+		})
+		return
+	}
+
+	returnSale, err := h.saleUseCase.ReturnSale(c.Request.Context(), gymID, id, userID, items, middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "Error al registrar la devolución")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.ToSaleResponse(returnSale))
+}
+
 // GetSalesByDateRange obtiene ventas por rango de fechas
 // @Summary Ventas por rango de fechas
 // @Tags ventas
@@ -204,6 +339,11 @@ func (h *SaleHandler) VoidSale(c *gin.Context) {
 // @Success 200 {array} dto.SaleResponse
 // @Router /sales/by-date [get]
 func (h *SaleHandler) GetSalesByDateRange(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	startDateStr := c.Query("start_date")
 	endDateStr := c.Query("end_date")
 
@@ -220,7 +360,7 @@ func (h *SaleHandler) GetSalesByDateRange(c *gin.Context) {
 		}
 	}
 
-	sales, err := h.saleUseCase.GetSalesByDateRange(c.Request.Context(), startDateStr, endDateStr, userID)
+	sales, err := h.saleUseCase.GetSalesByDateRange(c.Request.Context(), gymID, startDateStr, endDateStr, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Internal Server Error", Message: "Error al obtener ventas", Details: map[string]string{"detail": err.Error()}})
 		return
@@ -239,6 +379,11 @@ func (h *SaleHandler) GetSalesByDateRange(c *gin.Context) {
 // @Success 200 {object} dto.SaleReportResponse
 // @Router /sales/report [get]
 func (h *SaleHandler) GetSalesReport(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	startDateStr := c.Query("start_date")
 	endDateStr := c.Query("end_date")
 
@@ -255,7 +400,7 @@ func (h *SaleHandler) GetSalesReport(c *gin.Context) {
 		}
 	}
 
-	reports, err := h.saleUseCase.GetSalesReport(c.Request.Context(), startDateStr, endDateStr, userID)
+	reports, err := h.saleUseCase.GetSalesReport(c.Request.Context(), gymID, startDateStr, endDateStr, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Internal Server Error", Message: "Error al generar reporte", Details: map[string]string{"detail": err.Error()}})
 		return
@@ -281,6 +426,11 @@ func (h *SaleHandler) GetSalesReport(c *gin.Context) {
 // @Success 200 {array} dto.SaleProductReportResponse
 // @Router /sales/report/by-product [get]
 func (h *SaleHandler) GetSalesReportByProduct(c *gin.Context) {
+	gymID, ok := mustGymID(c)
+	if !ok {
+		return
+	}
+
 	startDateStr := c.Query("start_date")
 	endDateStr := c.Query("end_date")
 
@@ -289,7 +439,7 @@ func (h *SaleHandler) GetSalesReportByProduct(c *gin.Context) {
 		return
 	}
 
-	reports, err := h.saleUseCase.GetSalesReportByProduct(c.Request.Context(), startDateStr, endDateStr)
+	reports, err := h.saleUseCase.GetSalesReportByProduct(c.Request.Context(), gymID, startDateStr, endDateStr)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+)
+
+type StatementHandler struct {
+	statementUseCase *usecases.StatementUseCase
+}
+
+func NewStatementHandler(statementUseCase *usecases.StatementUseCase) *StatementHandler {
+	return &StatementHandler{statementUseCase: statementUseCase}
+}
+
+// Get downloads the account statement of a member between from and to
+// (YYYY-MM-DD), from the start of the year by default. With ?email=true it is
+// also sent to the member.
+func (h *StatementHandler) Get(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	loc := middleware.GetGymLocation(c)
+	yearStart := time.Date(time.Now().In(loc).Year(), 1, 1, 0, 0, 0, 0, loc).Format("2006-01-02")
+	from, to, ok := dateRangeSince(c, yearStart)
+	if !ok {
+		return
+	}
+	sendEmail := c.Query("email") == "true"
+
+	pdf, err := h.statementUseCase.GeneratePDF(c.Request.Context(), gymID, memberID, from, to, loc, sendEmail)
+	if err != nil {
+		RespondError(c, err, "Failed to generate statement")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="estado_de_cuenta_%s_%s.pdf"`, from, to))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
 	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
 	"github.com/sebastiancorrales/gym-go/pkg/timeutil"
 )
 
@@ -18,17 +20,20 @@ type SubscriptionHandler struct {
 	subscriptionUseCase *usecases.SubscriptionUseCase
 	userUseCase         *usecases.UserUseCase
 	planUseCase         *usecases.PlanUseCase
+	receiptUseCase      *usecases.ReceiptUseCase
 }
 
 func NewSubscriptionHandler(
 	subscriptionUseCase *usecases.SubscriptionUseCase,
 	userUseCase *usecases.UserUseCase,
 	planUseCase *usecases.PlanUseCase,
+	receiptUseCase *usecases.ReceiptUseCase,
 ) *SubscriptionHandler {
 	return &SubscriptionHandler{
 		subscriptionUseCase: subscriptionUseCase,
 		userUseCase:         userUseCase,
 		planUseCase:         planUseCase,
+		receiptUseCase:      receiptUseCase,
 	}
 }
 
@@ -43,14 +48,27 @@ type SubscriptionResponse struct {
 	User    *entities.User `json:"user,omitempty"`
 	Plan    *entities.Plan `json:"plan,omitempty"`
 	Members []MemberInfo   `json:"members,omitempty"`
+	// VisitsRemaining is only present on punch cards.
+	VisitsRemaining *int `json:"visits_remaining,omitempty"`
+	// Balance is only present on subscriptions sold in installments.
+	Balance *float64 `json:"balance,omitempty"`
 }
 
 type CreateSubscriptionRequest struct {
 	UserID            string   `json:"user_id" binding:"required"`
 	PlanID            string   `json:"plan_id" binding:"required"`
 	Discount          float64  `json:"discount"`
+	CouponCode        string   `json:"coupon_code"`
 	PaymentMethod     string   `json:"payment_method"`
 	AdditionalMembers []string `json:"additional_members"`
+	// StartDate (YYYY-MM-DD, gym time) defers the start of the period. Empty
+	// starts it now.
+	StartDate string `json:"start_date"`
+	// BalanceDueDate (YYYY-MM-DD, gym time) sells the plan in installments:
+	// the member pays DownPayment now and the rest by that day. Empty charges
+	// the plan in full.
+	DownPayment    float64 `json:"down_payment" binding:"min=0"`
+	BalanceDueDate string  `json:"balance_due_date"`
 }
 
 func (h *SubscriptionHandler) Create(c *gin.Context) {
@@ -89,15 +107,62 @@ func (h *SubscriptionHandler) Create(c *gin.Context) {
 	}
 
 	loc := middleware.GetGymLocation(c)
-	subscription, err := h.subscriptionUseCase.CreateSubscription(userID, planID, gymID, req.Discount, req.PaymentMethod, additionalIDs, loc)
+	var startDate time.Time
+	if req.StartDate != "" {
+		startDate, err = timeutil.ParseLocalDate(req.StartDate, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha inválido, use YYYY-MM-DD"})
+			return
+		}
+	}
+	var installments *usecases.Installments
+	if req.BalanceDueDate != "" {
+		dueDate, err := timeutil.ParseLocalDateEndOfDay(req.BalanceDueDate, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de fecha inválido, use YYYY-MM-DD"})
+			return
+		}
+		installments = &usecases.Installments{DownPayment: req.DownPayment, DueDate: dueDate}
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	subscription, err := h.subscriptionUseCase.CreateSubscription(userID, planID, gymID, req.Discount, req.CouponCode, req.PaymentMethod, additionalIDs, startDate, installments, changedByID, c.GetString("user_name"), loc)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		RespondError(c, err, "No se pudo crear la suscripción")
 		return
 	}
 
+	// The receipt is printed after answering, like the one of a sale: a
+	// printer out of paper must not fail the sale of the plan.
+	go func() {
+		if err := h.receiptUseCase.PrintSubscription(gymID, subscription.ID, loc); err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+			log.Printf("⚠️  suscripción %s: no se imprimió el recibo: %v", subscription.ID, err)
+		}
+	}()
+
 	c.JSON(http.StatusCreated, subscription)
 }
 
+// Print prints again the receipt of a subscription.
+func (h *SubscriptionHandler) Print(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+
+	if err := h.receiptUseCase.PrintSubscription(gymID, id, middleware.GetGymLocation(c)); err != nil {
+		RespondError(c, err, "Failed to print receipt")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Receipt printed"})
+}
+
 func (h *SubscriptionHandler) List(c *gin.Context) {
 	gymIDStr := c.GetString("gym_id")
 	gymID, err := uuid.Parse(gymIDStr)
@@ -211,6 +276,14 @@ func (h *SubscriptionHandler) buildSubscriptionResponses(
 			User:         usersByID[sub.UserID],
 			Plan:         plansByID[sub.PlanID],
 		}
+		if sub.IsVisitBased() {
+			left := sub.VisitsLeft()
+			subResp.VisitsRemaining = &left
+		}
+		if sub.BalanceDueDate != nil {
+			balance := sub.Balance()
+			subResp.Balance = &balance
+		}
 
 		for _, m := range membersBySub[sub.ID] {
 			subResp.Members = append(subResp.Members, MemberInfo{
@@ -235,12 +308,18 @@ func (h *SubscriptionHandler) Cancel(c *gin.Context) {
 	}
 	var req struct {
 		Reason string `json:"reason"`
+		Refund bool   `json:"refund"`
 	}
 	_ = c.ShouldBindJSON(&req)
 	userIDStr := c.GetString("user_id")
 	cancelledBy, _ := uuid.Parse(userIDStr)
-	if err := h.subscriptionUseCase.CancelSubscription(id, req.Reason, cancelledBy); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel subscription"})
+	refunds, err := h.subscriptionUseCase.CancelSubscription(id, req.Reason, cancelledBy, c.GetString("user_name"), req.Refund, middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "Failed to cancel subscription")
+		return
+	}
+	if len(refunds) > 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Subscription cancelled", "refunds": refunds})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription cancelled"})
@@ -256,6 +335,7 @@ func (h *SubscriptionHandler) Renew(c *gin.Context) {
 	var req struct {
 		PlanID            string   `json:"plan_id" binding:"required"`
 		Discount          float64  `json:"discount"`
+		CouponCode        string   `json:"coupon_code"`
 		PaymentMethod     string   `json:"payment_method"`
 		AdditionalMembers []string `json:"additional_members"`
 	}
@@ -277,14 +357,222 @@ func (h *SubscriptionHandler) Renew(c *gin.Context) {
 	gymIDStr := c.GetString("gym_id")
 	gymID, _ := uuid.Parse(gymIDStr)
 	renewLoc := middleware.GetGymLocation(c)
-	newSub, err := h.subscriptionUseCase.RenewSubscription(id, planID, gymID, req.Discount, req.PaymentMethod, additionalIDs, renewLoc)
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	newSub, err := h.subscriptionUseCase.RenewSubscription(id, planID, gymID, req.Discount, req.CouponCode, req.PaymentMethod, additionalIDs, changedByID, c.GetString("user_name"), renewLoc)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		RespondError(c, err, "No se pudo renovar la suscripción")
 		return
 	}
 	c.JSON(http.StatusCreated, newSub)
 }
 
+// ChangePlan moves a subscription to another plan, crediting the unused days of
+// the current one against the new price.
+func (h *SubscriptionHandler) ChangePlan(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	var req struct {
+		PlanID            string   `json:"plan_id" binding:"required"`
+		PaymentMethod     string   `json:"payment_method"`
+		AdditionalMembers []string `json:"additional_members"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	planID, err := uuid.Parse(req.PlanID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+	additionalIDs := make([]uuid.UUID, 0, len(req.AdditionalMembers))
+	for _, idStr := range req.AdditionalMembers {
+		uid, err := uuid.Parse(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid member ID: " + idStr})
+			return
+		}
+		additionalIDs = append(additionalIDs, uid)
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	changedByName := c.GetString("user_name")
+	newSub, err := h.subscriptionUseCase.ChangePlan(id, planID, additionalIDs, req.PaymentMethod, changedByID, changedByName, middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "No se pudo cambiar el plan")
+		return
+	}
+	c.JSON(http.StatusCreated, newSub)
+}
+
+// Transfer hands the rest of a subscription to another member of the gym.
+func (h *SubscriptionHandler) Transfer(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	var req struct {
+		UserID        string  `json:"user_id" binding:"required"`
+		Fee           float64 `json:"fee" binding:"min=0"`
+		PaymentMethod string  `json:"payment_method"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	toUserID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	received, err := h.subscriptionUseCase.TransferSubscription(id, toUserID, req.Fee, req.PaymentMethod, changedByID, c.GetString("user_name"), middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "No se pudo traspasar la suscripción")
+		return
+	}
+	c.JSON(http.StatusCreated, received)
+}
+
+// ListMembers returns the holder and beneficiaries of a group subscription.
+func (h *SubscriptionHandler) ListMembers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	members, err := h.subscriptionUseCase.GetSubscriptionMembers(id)
+	if err != nil {
+		RespondError(c, err, "No se pudieron obtener los beneficiarios")
+		return
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(members))
+	for _, m := range members {
+		userIDs = append(userIDs, m.UserID)
+	}
+	usersByID, err := h.userUseCase.GetUsersByIDs(userIDs)
+	if err != nil {
+		RespondError(c, err, "No se pudieron obtener los beneficiarios")
+		return
+	}
+
+	response := make([]MemberInfo, 0, len(members))
+	for _, m := range members {
+		response = append(response, MemberInfo{
+			UserID:    m.UserID,
+			IsPrimary: m.IsPrimary,
+			User:      usersByID[m.UserID],
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddMember adds a beneficiary to a group subscription.
+func (h *SubscriptionHandler) AddMember(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := h.subscriptionUseCase.AddGroupMember(id, userID, changedByID, c.GetString("user_name")); err != nil {
+		RespondError(c, err, "No se pudo agregar el beneficiario")
+		return
+	}
+	h.ListMembers(c)
+}
+
+// RemoveMember takes a beneficiary out of a group subscription.
+func (h *SubscriptionHandler) RemoveMember(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := h.subscriptionUseCase.RemoveGroupMember(id, userID, changedByID, c.GetString("user_name")); err != nil {
+		RespondError(c, err, "No se pudo retirar el beneficiario")
+		return
+	}
+	h.ListMembers(c)
+}
+
+// ReplaceMember swaps the beneficiary in the path for the one in the body.
+func (h *SubscriptionHandler) ReplaceMember(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	oldUserID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	newUserID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := h.subscriptionUseCase.ReplaceGroupMember(id, oldUserID, newUserID, changedByID, c.GetString("user_name")); err != nil {
+		RespondError(c, err, "No se pudo reemplazar el beneficiario")
+		return
+	}
+	h.ListMembers(c)
+}
+
+// SetAutoRenew turns automatic renewal on or off. The hourly job renews the
+// subscriptions that have it on before they expire.
+func (h *SubscriptionHandler) SetAutoRenew(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	var req struct {
+		AutoRenew *bool `json:"auto_renew" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := h.subscriptionUseCase.SetAutoRenew(id, *req.AutoRenew, changedByID, c.GetString("user_name")); err != nil {
+		RespondError(c, err, "No se pudo actualizar la renovación automática")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Renovación automática actualizada", "auto_renew": *req.AutoRenew})
+}
+
 func (h *SubscriptionHandler) UpdateDates(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
@@ -317,7 +605,7 @@ func (h *SubscriptionHandler) UpdateDates(c *gin.Context) {
 	changedByID, _ := uuid.Parse(changedByIDStr)
 	changedByName := c.GetString("user_name")
 	if err := h.subscriptionUseCase.UpdateDates(id, start, end, changedByID, changedByName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		RespondError(c, err, "Failed to update subscription dates")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Fechas actualizadas"})
@@ -338,6 +626,86 @@ func (h *SubscriptionHandler) GetAuditLog(c *gin.Context) {
 	c.JSON(http.StatusOK, logs)
 }
 
+// Extend gives every running subscription of the gym some days more, after a
+// closure. With dry_run it only reports how many would be extended.
+func (h *SubscriptionHandler) Extend(c *gin.Context) {
+	gymID, err := uuid.Parse(c.GetString("gym_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gym ID"})
+		return
+	}
+	var req struct {
+		Days    int         `json:"days" binding:"required,min=1,max=365"`
+		PlanIDs []uuid.UUID `json:"plan_ids"`
+		Reason  string      `json:"reason"`
+		DryRun  bool        `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	result, err := h.subscriptionUseCase.ExtendSubscriptions(gymID, req.Days, req.PlanIDs, req.Reason, req.DryRun, changedByID, c.GetString("user_name"))
+	if err != nil {
+		RespondError(c, err, "No se pudieron extender las suscripciones")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RecordPayment registers a payment towards the balance of a subscription sold
+// in installments.
+func (h *SubscriptionHandler) RecordPayment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	var req struct {
+		Amount float64 `json:"amount" binding:"required,gt=0"`
+		// PaymentMethod defaults to the method the subscription was sold with.
+		PaymentMethod string `json:"payment_method"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	payment, err := h.subscriptionUseCase.RecordPayment(id, req.Amount, req.PaymentMethod, changedByID, c.GetString("user_name"), middleware.GetGymLocation(c))
+	if err != nil {
+		RespondError(c, err, "Failed to record payment")
+		return
+	}
+	c.JSON(http.StatusCreated, payment)
+}
+
+// ListPayments lists what has been paid towards a subscription, with what is
+// still owed.
+func (h *SubscriptionHandler) ListPayments(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+	sub, err := h.subscriptionUseCase.GetSubscription(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	payments, err := h.subscriptionUseCase.GetPayments(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":            sub.TotalPaid,
+		"amount_paid":      sub.AmountPaid,
+		"balance":          sub.Balance(),
+		"balance_due_date": sub.BalanceDueDate,
+		"payments":         payments,
+	})
+}
+
 func (h *SubscriptionHandler) Freeze(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
@@ -348,13 +716,17 @@ func (h *SubscriptionHandler) Freeze(c *gin.Context) {
 	var req struct {
 		Days   int    `json:"days" binding:"required,min=1"`
 		Reason string `json:"reason"`
+		// PaymentMethod is how the plan's freeze fee is paid, if it has one.
+		// Defaults to the method the subscription was sold with.
+		PaymentMethod string `json:"payment_method"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.subscriptionUseCase.FreezeSubscription(id, req.Days, req.Reason); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to freeze subscription"})
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := h.subscriptionUseCase.FreezeSubscription(id, req.Days, req.Reason, req.PaymentMethod, changedByID, c.GetString("user_name"), middleware.GetGymLocation(c)); err != nil {
+		RespondError(c, err, "Failed to freeze subscription")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription frozen"})
@@ -367,8 +739,9 @@ func (h *SubscriptionHandler) Unfreeze(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
 		return
 	}
-	if err := h.subscriptionUseCase.UnfreezeSubscription(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfreeze subscription"})
+	changedByID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := h.subscriptionUseCase.UnfreezeSubscription(id, changedByID, c.GetString("user_name")); err != nil {
+		RespondError(c, err, "Failed to unfreeze subscription")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription unfrozen"})
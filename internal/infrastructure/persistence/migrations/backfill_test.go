@@ -0,0 +1,192 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// TestBackfillPayments_LedgersWhatWasChargedBeforeIt seeds the rows of a gym
+// from before the payments ledger: a subscription, a sale, a voided sale and
+// its void, plus an installment subscription and a sale that already have
+// their payments. The backfill charges each missing one on its day and to
+// the seller's gym unless the sale has its own, refunds the voided sale on the
+// day of its void, and a second run adds nothing.
+func TestBackfillPayments_LedgersWhatWasChargedBeforeIt(t *testing.T) {
+	db := freshMigratedDB(t)
+
+	gym := entities.NewGym("Gym", "gym@test.local", "3000000000")
+	seller := entities.NewUser(gym.ID, "caja@test.local", "Caja", "Uno", entities.RoleRecepcionista)
+	member := entities.NewUser(gym.ID, "socio@test.local", "Ana", "Pérez", entities.RoleMember)
+	method := &entities.SalePaymentMethod{ID: uuid.New(), GymID: gym.ID, Name: "Efectivo", Type: entities.PaymentTypeCash, Status: entities.PaymentMethodStatusActive}
+	seed(t, db, gym, seller, member, method)
+
+	old := &entities.Subscription{ID: uuid.New(), GymID: gym.ID, UserID: member.ID, Status: entities.SubscriptionStatusActive,
+		TotalPaid: 100000, AmountPaid: 100000, PaymentMethod: "CASH", Date: "2025-03-01", Hour: "10:00"}
+	due := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	owing := &entities.Subscription{ID: uuid.New(), GymID: gym.ID, UserID: member.ID, Status: entities.SubscriptionStatusActive,
+		TotalPaid: 100000, AmountPaid: 50000, BalanceDueDate: &due, PaymentMethod: "CASH", Date: "2025-03-01", Hour: "11:00"}
+	sale := newSale(seller.ID, method.ID, 6000, "2025-03-01")
+	voided := newSale(seller.ID, method.ID, 4000, "2025-03-01")
+	voided.Status = entities.SaleStatusVoided
+	void := newSale(seller.ID, method.ID, -4000, "2025-03-02")
+	void.Type, void.VoidedSaleID = entities.SaleTypeVoid, &voided.ID
+	ledgered := newSale(seller.ID, method.ID, 3000, "2025-03-01")
+	charge := entities.NewPayment(gym.ID, uuid.Nil, seller.ID, 3000, "COP", "Efectivo", entities.PaymentTypeProduct, "Venta")
+	charge.SaleID = &ledgered.ID
+	charge.Complete("")
+	// A sale that already has its gym keeps it, whatever the seller's is.
+	branch := entities.NewGym("Sede Sur", "sur@test.local", "3000000001")
+	scoped := newSale(seller.ID, method.ID, 2000, "2025-03-01")
+	scoped.GymID = branch.ID
+	seed(t, db, branch, old, owing, sale, voided, void, ledgered, charge, scoped)
+
+	for run := 0; run < 2; run++ {
+		if err := backfillPayments(db); err != nil {
+			t.Fatalf("backfillPayments (vuelta %d): %v", run+1, err)
+		}
+	}
+
+	paymentsOf := func(column string, id uuid.UUID) []entities.Payment {
+		t.Helper()
+		var payments []entities.Payment
+		if err := db.Where(column+" = ?", id).Find(&payments).Error; err != nil {
+			t.Fatalf("leyendo pagos: %v", err)
+		}
+		return payments
+	}
+
+	if ps := paymentsOf("subscription_id", old.ID); len(ps) != 1 || ps[0].Amount != 100000 || ps[0].Date != "2025-03-01" || !ps[0].IsCompleted() {
+		t.Errorf("pagos de la suscripción vieja = %+v, want uno de 100000 completado el 2025-03-01", ps)
+	}
+	if ps := paymentsOf("subscription_id", owing.ID); len(ps) != 0 {
+		t.Errorf("la suscripción a cuotas recibió %d pagos, want ninguno", len(ps))
+	}
+	if ps := paymentsOf("sale_id", sale.ID); len(ps) != 1 || ps[0].Amount != 6000 || ps[0].GymID != gym.ID || string(ps[0].PaymentMethod) != "Efectivo" {
+		t.Errorf("pagos de la venta = %+v, want uno de 6000 en Efectivo del gimnasio del vendedor", ps)
+	}
+	if ps := paymentsOf("sale_id", voided.ID); len(ps) != 1 || !ps[0].IsRefunded() || ps[0].RefundedAmount != 4000 || ps[0].RefundDate != "2025-03-02" {
+		t.Errorf("pagos de la venta anulada = %+v, want uno de 4000 reembolsado el 2025-03-02", ps)
+	}
+	if ps := paymentsOf("sale_id", void.ID); len(ps) != 0 {
+		t.Errorf("la anulación recibió %d pagos, want ninguno", len(ps))
+	}
+	if ps := paymentsOf("sale_id", scoped.ID); len(ps) != 1 || ps[0].GymID != branch.ID {
+		t.Errorf("pagos de la venta con gimnasio = %+v, want uno de la sede sur", ps)
+	}
+	if ps := paymentsOf("sale_id", ledgered.ID); len(ps) != 1 {
+		t.Errorf("la venta que ya tenía pago tiene %d, want 1", len(ps))
+	}
+}
+
+// TestBackfillGymScope_CopiesWhatTwoGymsShared seeds two gyms from before
+// gym_id, sharing a payment method and three products: one both sold, one only
+// the second sold and one nobody sold. The second gym's product stays its own;
+// the other two, like the method, stay with the oldest gym and the second gets
+// a copy without stock that its sales and coupons move to. A second run adds
+// nothing.
+func TestBackfillGymScope_CopiesWhatTwoGymsShared(t *testing.T) {
+	db := freshMigratedDB(t)
+
+	first := entities.NewGym("Sede Norte", "norte@test.local", "3000000000")
+	second := entities.NewGym("Sede Sur", "sur@test.local", "3000000001")
+	second.CreatedAt = first.CreatedAt.Add(time.Hour)
+	northSeller := entities.NewUser(first.ID, "norte.caja@test.local", "Caja", "Norte", entities.RoleRecepcionista)
+	southSeller := entities.NewUser(second.ID, "sur.caja@test.local", "Caja", "Sur", entities.RoleRecepcionista)
+	method := &entities.SalePaymentMethod{ID: uuid.New(), Name: "Efectivo", Type: entities.PaymentTypeCash, Status: entities.PaymentMethodStatusActive}
+	product := func(name string) *entities.Product {
+		return &entities.Product{ID: uuid.New(), Name: name, UnitPrice: 2000, Stock: 10, Status: entities.ProductStatusActive}
+	}
+	water, towel, protein := product("Agua"), product("Toalla"), product("Proteína")
+	seed(t, db, first, second, northSeller, southSeller, method, water, towel, protein)
+
+	northSale := newSale(northSeller.ID, method.ID, 2000, "2025-03-01")
+	southSale := newSale(southSeller.ID, method.ID, 4000, "2025-03-01")
+	northWater := &entities.SaleDetail{ID: uuid.New(), SaleID: northSale.ID, ProductID: water.ID, Quantity: 1}
+	southWater := &entities.SaleDetail{ID: uuid.New(), SaleID: southSale.ID, ProductID: water.ID, Quantity: 1}
+	southProtein := &entities.SaleDetail{ID: uuid.New(), SaleID: southSale.ID, ProductID: protein.ID, Quantity: 1}
+	coupon := entities.NewCoupon(second.ID, "AGUA10", entities.CouponKindPercent, 10)
+	coupon.ProductIDs = []uuid.UUID{water.ID, protein.ID}
+	seed(t, db, northSale, southSale, northWater, southWater, southProtein, coupon)
+
+	for run := 0; run < 2; run++ {
+		if err := backfillGymScope(db); err != nil {
+			t.Fatalf("backfillGymScope (vuelta %d): %v", run+1, err)
+		}
+	}
+
+	productsOf := func(gymID uuid.UUID) map[string]entities.Product {
+		t.Helper()
+		var products []entities.Product
+		if err := db.Where("gym_id = ?", gymID).Find(&products).Error; err != nil {
+			t.Fatalf("leyendo productos: %v", err)
+		}
+		byName := make(map[string]entities.Product, len(products))
+		for _, p := range products {
+			byName[p.Name] = p
+		}
+		return byName
+	}
+	north, south := productsOf(first.ID), productsOf(second.ID)
+	if len(north) != 2 || north["Agua"].ID != water.ID || north["Toalla"].ID != towel.ID || north["Agua"].Stock != 10 {
+		t.Errorf("productos de la sede norte = %+v, want Agua y Toalla originales, con su stock", north)
+	}
+	if len(south) != 3 || south["Proteína"].ID != protein.ID || south["Agua"].ID == water.ID || south["Toalla"].ID == towel.ID ||
+		south["Agua"].Stock != 0 || south["Proteína"].Stock != 10 {
+		t.Errorf("productos de la sede sur = %+v, want su Proteína y copias sin stock de Agua y Toalla", south)
+	}
+
+	var details []entities.SaleDetail
+	if err := db.Find(&details).Error; err != nil {
+		t.Fatalf("leyendo detalles: %v", err)
+	}
+	want := map[uuid.UUID]uuid.UUID{northWater.ID: water.ID, southWater.ID: south["Agua"].ID, southProtein.ID: protein.ID}
+	for _, d := range details {
+		if d.ProductID != want[d.ID] {
+			t.Errorf("detalle %s apunta a %s, want %s", d.ID, d.ProductID, want[d.ID])
+		}
+	}
+
+	var stored entities.Coupon
+	if err := db.First(&stored, "id = ?", coupon.ID).Error; err != nil {
+		t.Fatalf("leyendo cupón: %v", err)
+	}
+	if len(stored.ProductIDs) != 2 || stored.ProductIDs[0] != south["Agua"].ID || stored.ProductIDs[1] != protein.ID {
+		t.Errorf("productos del cupón = %v, want la copia del Agua y la Proteína", stored.ProductIDs)
+	}
+
+	var methods []entities.SalePaymentMethod
+	if err := db.Where("name = ?", "Efectivo").Find(&methods).Error; err != nil {
+		t.Fatalf("leyendo métodos de pago: %v", err)
+	}
+	if len(methods) == 2 && methods[0].ID != method.ID {
+		methods[0], methods[1] = methods[1], methods[0]
+	}
+	if len(methods) != 2 || methods[0].ID != method.ID || methods[0].GymID != first.ID || methods[1].GymID != second.ID {
+		t.Fatalf("métodos de pago = %+v, want el original en la sede norte y una copia en la sur", methods)
+	}
+	var moved entities.Sale
+	if err := db.First(&moved, "id = ?", southSale.ID).Error; err != nil {
+		t.Fatalf("leyendo venta: %v", err)
+	}
+	if moved.GymID != second.ID || moved.PaymentMethodID != methods[1].ID {
+		t.Errorf("venta de la sede sur = gym %s, método %s; want %s y la copia %s", moved.GymID, moved.PaymentMethodID, second.ID, methods[1].ID)
+	}
+}
+
+func newSale(sellerID, methodID uuid.UUID, total float64, date string) *entities.Sale {
+	return &entities.Sale{ID: uuid.New(), UserID: sellerID, PaymentMethodID: methodID, Total: total,
+		Type: entities.SaleTypeNormal, Status: entities.SaleStatusCompleted, Date: date, Hour: "12:00"}
+}
+
+func seed(t *testing.T, db *gorm.DB, rows ...interface{}) {
+	t.Helper()
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("sembrando %T: %v", row, err)
+		}
+	}
+}
@@ -13,7 +13,7 @@ import (
 // the hot paths resolve through an index instead of scanning the table.
 //
 // This matters beyond raw speed: a scan holds its read lock for longer, and the
-// hourly MarkExpiredSubscriptions scan held a WRITE lock over the whole
+// hourly expiry used to be an UPDATE whose scan held a WRITE lock over the whole
 // subscriptions table. It also guards against someone changing a WHERE clause
 // and silently losing the index.
 func TestHotQueriesUseIndexes(t *testing.T) {
@@ -50,8 +50,8 @@ func TestHotQueriesUseIndexes(t *testing.T) {
 			args:  []interface{}{"00000000-0000-0000-0000-000000000000", "ACTIVE"},
 		},
 		{
-			name:  "auto-expirar suscripciones (cada hora, bajo lock de escritura)",
-			query: "UPDATE subscriptions SET status = 'EXPIRED' WHERE status = ? AND end_date < ?",
+			name:  "suscripciones a vencer (cada hora)",
+			query: "SELECT * FROM subscriptions WHERE status = ? AND end_date < ?",
 			args:  []interface{}{"ACTIVE", "2026-01-01"},
 		},
 		{
@@ -69,6 +69,11 @@ func TestHotQueriesUseIndexes(t *testing.T) {
 			query: "SELECT * FROM sales WHERE date >= ? AND date <= ?",
 			args:  []interface{}{"2026-01-01", "2026-01-31"},
 		},
+		{
+			name:  "ventas del gym por rango de fecha",
+			query: "SELECT * FROM sales WHERE gym_id = ? AND date >= ? AND date <= ?",
+			args:  []interface{}{"00000000-0000-0000-0000-000000000000", "2026-01-01", "2026-01-31"},
+		},
 		{
 			name:  "miembros de una suscripcion grupal",
 			query: "SELECT * FROM subscription_members WHERE subscription_id = ?",
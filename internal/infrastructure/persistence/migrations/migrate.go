@@ -29,11 +29,19 @@ func Migrate(db *gorm.DB) error {
 		&entities.SalePaymentMethod{},
 		&entities.Sale{},
 		&entities.SaleDetail{},
+		&entities.SaleTender{},
 		&entities.Class{},
 		&entities.Attendance{},
 		&entities.SubscriptionMember{},
 		&entities.SubscriptionAuditLog{},
 		&entities.NotificationRecipient{},
+		&entities.Coupon{},
+		&entities.CouponRedemption{},
+		&entities.GymClosure{},
+		&entities.CashRegisterSession{},
+		&entities.InvoiceResolution{},
+		&entities.Invoice{},
+		&entities.InvoiceLine{},
 	)
 
 	if err != nil {
@@ -47,6 +55,29 @@ func Migrate(db *gorm.DB) error {
 		log.Printf("⚠️  backfillDateHour: %v", err)
 	}
 
+	if err := backfillAuditEvents(db); err != nil {
+		log.Printf("⚠️  backfillAuditEvents: %v", err)
+	}
+
+	if err := backfillAmountPaid(db); err != nil {
+		log.Printf("⚠️  backfillAmountPaid: %v", err)
+	}
+
+	// After backfillAmountPaid: the charge of an old subscription is its
+	// AmountPaid.
+	if err := backfillPayments(db); err != nil {
+		log.Printf("⚠️  backfillPayments: %v", err)
+	}
+
+	if err := backfillTaxBase(db); err != nil {
+		log.Printf("⚠️  backfillTaxBase: %v", err)
+	}
+
+	// After backfillPayments: the ledger is where most sales find their gym.
+	if err := backfillGymScope(db); err != nil {
+		log.Printf("⚠️  backfillGymScope: %v", err)
+	}
+
 	return nil
 }
 
@@ -178,6 +209,317 @@ func backfillOrphanSales(tx *gorm.DB, gyms []entities.Gym) error {
 	return nil
 }
 
+// backfillAuditEvents types the audit entries written before the log had events.
+// Until then the only writer was the manual date edit, so that is what they all
+// are. A single UPDATE that matches nothing in steady state.
+func backfillAuditEvents(db *gorm.DB) error {
+	return db.Model(&entities.SubscriptionAuditLog{}).
+		Where("event IS NULL OR event = ''").
+		Update("event", entities.AuditEventDatesEdited).Error
+}
+
+// backfillAmountPaid marks the subscriptions sold before installments existed as
+// paid in full, which they were. Installment sales always carry a due date, so
+// a single UPDATE that matches nothing in steady state tells them apart.
+func backfillAmountPaid(db *gorm.DB) error {
+	return db.Model(&entities.Subscription{}).
+		Where("amount_paid = 0 AND total_paid > 0 AND balance_due_date IS NULL").
+		Update("amount_paid", gorm.Expr("total_paid")).Error
+}
+
+// backfillTaxBase gives the sale details sold before IVA existed their taxable
+// base: all of the line, at rate 0, which is how they were charged. A single
+// UPDATE that matches nothing in steady state; a line at 0% always has its
+// base, so the only ones left without are these.
+func backfillTaxBase(db *gorm.DB) error {
+	return db.Model(&entities.SaleDetail{}).
+		Where("tax_rate = 0 AND tax_base = 0 AND tax_amount = 0 AND subtotal <> 0").
+		Update("tax_base", gorm.Expr("subtotal")).Error
+}
+
+// backfillGymScope gives their gym to the sales, products and payment methods
+// from before they had gym_id, when a second gym on the same install shared the
+// first one's inventory and saw its sales. In steady state the three counts
+// come back zero.
+//
+// A sale is the gym's its payments were charged to; without any (voids, old
+// sales left out of the ledger) it is its original sale's or its seller's, and
+// with a single gym it is that gym's. A product is the gym's that sold it. A
+// payment method, or a product several gyms sold or none did, was shared by
+// every gym until now: it stays with the oldest gym and is copied to each of
+// the others, whose sales and coupons are moved to their copy. The copies start
+// with no stock, since there was one shelf and it was already counted once.
+//
+// A sale that cannot be told apart is logged and left alone rather than
+// guessed, as in backfillOrphanSales.
+func backfillGymScope(db *gorm.DB) error {
+	unscoped := "gym_id IS NULL OR gym_id = ?"
+	var sales, products, methods int64
+	if err := db.Model(&entities.Sale{}).Where(unscoped, uuid.Nil).Count(&sales).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&entities.Product{}).Where(unscoped, uuid.Nil).Count(&products).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&entities.SalePaymentMethod{}).Where(unscoped, uuid.Nil).Count(&methods).Error; err != nil {
+		return err
+	}
+	if sales == 0 && products == 0 && methods == 0 {
+		return nil
+	}
+
+	var gyms []entities.Gym
+	if err := db.Order("created_at ASC").Find(&gyms).Error; err != nil {
+		return err
+	}
+	if len(gyms) == 0 {
+		return nil // a fresh install: the first gym registers with its own
+	}
+
+	log.Printf("🔄 backfillGymScope: %d ventas, %d productos y %d métodos de pago sin gimnasio, asignándolos...",
+		sales, products, methods)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// ── Sales ─────────────────────────────────────────────────────────────
+		steps := []string{
+			`UPDATE sales SET gym_id = (SELECT p.gym_id FROM payments p WHERE p.sale_id = sales.id LIMIT 1)
+			 WHERE (gym_id IS NULL OR gym_id = @nil) AND EXISTS (
+			   SELECT 1 FROM payments p WHERE p.sale_id = sales.id AND p.gym_id <> @nil)`,
+			`UPDATE sales SET gym_id = (SELECT o.gym_id FROM sales o WHERE o.id = COALESCE(sales.voided_sale_id, sales.returned_sale_id))
+			 WHERE (gym_id IS NULL OR gym_id = @nil) AND EXISTS (
+			   SELECT 1 FROM sales o WHERE o.id = COALESCE(sales.voided_sale_id, sales.returned_sale_id) AND o.gym_id <> @nil)`,
+			`UPDATE sales SET gym_id = (SELECT u.gym_id FROM users u WHERE u.id = sales.user_id)
+			 WHERE (gym_id IS NULL OR gym_id = @nil) AND EXISTS (
+			   SELECT 1 FROM users u WHERE u.id = sales.user_id AND u.gym_id <> @nil)`,
+		}
+		if len(gyms) == 1 {
+			steps = append(steps, `UPDATE sales SET gym_id = @gym WHERE gym_id IS NULL OR gym_id = @nil`)
+		}
+
+		// ── Products ──────────────────────────────────────────────────────────
+		if len(gyms) == 1 {
+			steps = append(steps, `UPDATE products SET gym_id = @gym WHERE gym_id IS NULL OR gym_id = @nil`)
+		} else {
+			steps = append(steps, `UPDATE products SET gym_id = (
+			   SELECT s.gym_id FROM sale_details sd JOIN sales s ON s.id = sd.sale_id
+			   WHERE sd.product_id = products.id AND s.gym_id <> @nil LIMIT 1)
+			 WHERE (gym_id IS NULL OR gym_id = @nil) AND (
+			   SELECT COUNT(DISTINCT s.gym_id) FROM sale_details sd JOIN sales s ON s.id = sd.sale_id
+			   WHERE sd.product_id = products.id AND s.gym_id <> @nil) = 1`)
+		}
+		args := map[string]interface{}{"nil": uuid.Nil, "gym": gyms[0].ID}
+		for _, step := range steps {
+			if err := tx.Exec(step, args).Error; err != nil {
+				return err
+			}
+		}
+
+		// ── Shared products ───────────────────────────────────────────────────
+		var sharedProducts []entities.Product
+		if err := tx.Where(unscoped, uuid.Nil).Find(&sharedProducts).Error; err != nil {
+			return err
+		}
+		copies := make(map[uuid.UUID]map[uuid.UUID]uuid.UUID) // gym → original → copy
+		for _, p := range sharedProducts {
+			for _, gym := range gyms[1:] {
+				copied := p
+				copied.ID = uuid.New()
+				copied.GymID = gym.ID
+				copied.Stock = 0
+				if err := tx.Create(&copied).Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(`UPDATE sale_details SET product_id = ? WHERE product_id = ? AND sale_id IN (
+						SELECT id FROM sales WHERE gym_id = ?)`, copied.ID, p.ID, gym.ID).Error; err != nil {
+					return err
+				}
+				if copies[gym.ID] == nil {
+					copies[gym.ID] = make(map[uuid.UUID]uuid.UUID)
+				}
+				copies[gym.ID][p.ID] = copied.ID
+			}
+			if err := tx.Model(&entities.Product{}).Where("id = ?", p.ID).
+				Update("gym_id", gyms[0].ID).Error; err != nil {
+				return err
+			}
+		}
+		if len(sharedProducts) > 0 {
+			var coupons []entities.Coupon
+			if err := tx.Where("gym_id <> ?", gyms[0].ID).Find(&coupons).Error; err != nil {
+				return err
+			}
+			for _, c := range coupons {
+				moved := false
+				for i, id := range c.ProductIDs {
+					if copied, ok := copies[c.GymID][id]; ok {
+						c.ProductIDs[i], moved = copied, true
+					}
+				}
+				if !moved {
+					continue
+				}
+				if err := tx.Model(&c).Select("product_ids").Updates(&c).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// ── Payment methods ───────────────────────────────────────────────────
+		var shared []entities.SalePaymentMethod
+		if err := tx.Where(unscoped, uuid.Nil).Find(&shared).Error; err != nil {
+			return err
+		}
+		for _, pm := range shared {
+			for _, gym := range gyms[1:] {
+				copied := pm
+				copied.ID = uuid.New()
+				copied.GymID = gym.ID
+				if err := tx.Create(&copied).Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(`UPDATE sales SET payment_method_id = ? WHERE payment_method_id = ? AND gym_id = ?`,
+					copied.ID, pm.ID, gym.ID).Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(`UPDATE sale_tenders SET payment_method_id = ? WHERE payment_method_id = ? AND sale_id IN (
+						SELECT id FROM sales WHERE gym_id = ?)`, copied.ID, pm.ID, gym.ID).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&entities.SalePaymentMethod{}).Where("id = ?", pm.ID).
+				Update("gym_id", gyms[0].ID).Error; err != nil {
+				return err
+			}
+		}
+
+		var leftSales int64
+		if err := tx.Model(&entities.Sale{}).Where(unscoped, uuid.Nil).Count(&leftSales).Error; err != nil {
+			return err
+		}
+		if leftSales > 0 {
+			log.Printf("⚠️  backfillGymScope: %d ventas sin gimnasio: hay %d gimnasios y no se sabe "+
+				"de cuál son. No aparecen en ninguno hasta asignarles gym_id a mano.", leftSales, len(gyms))
+		}
+
+		log.Println("✅ backfillGymScope completed")
+		return nil
+	})
+}
+
+// backfillPayments writes into the payments ledger the subscriptions and sales
+// charged before it existed, so the daily close and the revenue reports, which
+// only read the ledger, still see them. Each gets the payment it would get today:
+// dated on its day, refunded on the day of its void for a voided sale.
+//
+// Installment sales are left out: they have had their payments from the start,
+// and the payments of their balance are in their AmountPaid. In steady state
+// both lookups come back empty.
+func backfillPayments(db *gorm.DB) error {
+	var subs []entities.Subscription
+	if err := db.Where(`amount_paid > 0 AND balance_due_date IS NULL AND NOT EXISTS (
+			SELECT 1 FROM payments p WHERE p.subscription_id = subscriptions.id AND p.payment_type = ?)`,
+		entities.PaymentTypeSubscription).Find(&subs).Error; err != nil {
+		return err
+	}
+	var sales []entities.Sale
+	if err := db.Where(`type = ? AND total > 0 AND NOT EXISTS (
+			SELECT 1 FROM payments p WHERE p.sale_id = sales.id)`,
+		entities.SaleTypeNormal).Find(&sales).Error; err != nil {
+		return err
+	}
+	if len(subs) == 0 && len(sales) == 0 {
+		return nil
+	}
+
+	log.Printf("🔄 backfillPayments: %d suscripciones y %d ventas sin pago, registrándolos...", len(subs), len(sales))
+
+	var gyms []entities.Gym
+	if err := db.Find(&gyms).Error; err != nil {
+		return err
+	}
+	currencies := make(map[uuid.UUID]string, len(gyms))
+	for _, g := range gyms {
+		currencies[g.ID] = g.Currency
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, sub := range subs {
+			p := entities.NewPayment(sub.GymID, sub.UserID, uuid.Nil, sub.AmountPaid, currencies[sub.GymID],
+				entities.PaymentMethod(sub.PaymentMethod), entities.PaymentTypeSubscription, "Suscripción")
+			p.SubscriptionID = &sub.ID
+			p.PaymentDate, p.Date, p.Hour = sub.CreatedAt, sub.Date, sub.Hour
+			p.Complete("")
+			if err := tx.Create(p).Error; err != nil {
+				return err
+			}
+		}
+
+		// A sale is its gym_id's. The ones from before gym_id have none yet
+		// (backfillGymScope runs after this and reads the gym off these very
+		// payments), so theirs is the seller's, as in backfillDateHour. A sale
+		// whose seller is gone only has a gym when there is just one.
+		methods := make(map[uuid.UUID]string)
+		var pms []entities.SalePaymentMethod
+		if err := tx.Find(&pms).Error; err != nil {
+			return err
+		}
+		for _, pm := range pms {
+			methods[pm.ID] = pm.Name
+		}
+		skipped := 0
+		for _, sale := range sales {
+			var seller entities.User
+			gymID := uuid.Nil
+			if err := tx.Where("id = ?", sale.UserID).Limit(1).Find(&seller).Error; err != nil {
+				return err
+			}
+			switch {
+			case sale.GymID != uuid.Nil:
+				gymID = sale.GymID
+			case seller.ID != uuid.Nil && seller.GymID != uuid.Nil:
+				gymID = seller.GymID
+			case len(gyms) == 1:
+				gymID = gyms[0].ID
+			default:
+				skipped++
+				continue
+			}
+
+			p := entities.NewPayment(gymID, uuid.Nil, sale.UserID, sale.Total, currencies[gymID],
+				entities.PaymentMethod(methods[sale.PaymentMethodID]), entities.PaymentTypeProduct, "Venta")
+			if sale.MemberID != nil {
+				p.UserID = *sale.MemberID
+			}
+			p.SaleID = &sale.ID
+			p.PaymentDate, p.Date, p.Hour = sale.SaleDate, sale.Date, sale.Hour
+			p.Complete("")
+			if sale.Status == entities.SaleStatusVoided {
+				var void entities.Sale
+				if err := tx.Where("voided_sale_id = ?", sale.ID).Limit(1).Find(&void).Error; err != nil {
+					return err
+				}
+				if err := p.Refund(p.Amount, "Anulación de venta"); err != nil {
+					return err
+				}
+				p.RefundedAt, p.RefundDate = &sale.SaleDate, sale.Date
+				if void.ID != uuid.Nil {
+					p.RefundedAt, p.RefundDate = &void.SaleDate, void.Date
+				}
+			}
+			if err := tx.Create(p).Error; err != nil {
+				return err
+			}
+		}
+		if skipped > 0 {
+			log.Printf("⚠️  backfillPayments: %d ventas cuyo vendedor ya no existe se dejan fuera del libro de pagos: "+
+				"hay %d gimnasios y no se sabe de cuál son.", skipped, len(gyms))
+		}
+
+		log.Println("✅ backfillPayments completed")
+		return nil
+	})
+}
+
 // Seed creates initial data
 func Seed(db *gorm.DB) error {
 	log.Println("🌱 Seeding database...")
@@ -216,38 +558,19 @@ func Seed(db *gorm.DB) error {
 		log.Println("   Default Password: admin123 (CHANGE THIS!)")
 	}
 
-	// Check if payment methods exist
-	var paymentMethodCount int64
-	db.Model(&entities.SalePaymentMethod{}).Count(&paymentMethodCount)
-
-	if paymentMethodCount == 0 {
-		// Create default payment methods
-		paymentMethods := []entities.SalePaymentMethod{
-			{
-				ID:     uuid.New(),
-				Name:   "Efectivo",
-				Type:   entities.PaymentTypeCash,
-				Status: entities.PaymentMethodStatusActive,
-			},
-			{
-				ID:     uuid.New(),
-				Name:   "Tarjeta",
-				Type:   entities.PaymentTypeCard,
-				Status: entities.PaymentMethodStatusActive,
-			},
-			{
-				ID:     uuid.New(),
-				Name:   "Transferencia",
-				Type:   entities.PaymentTypeTransfer,
-				Status: entities.PaymentMethodStatusActive,
-			},
-		}
-
-		for _, pm := range paymentMethods {
+	// Every gym sells with its own payment methods: the ones without any get
+	// the defaults.
+	var gyms []entities.Gym
+	if err := db.Where(`NOT EXISTS (
+			SELECT 1 FROM sale_payment_methods pm WHERE pm.gym_id = gyms.id)`).Find(&gyms).Error; err != nil {
+		log.Printf("⚠️ Failed to look up gyms without payment methods: %v", err)
+	}
+	for _, gym := range gyms {
+		for _, pm := range entities.DefaultPaymentMethods(gym.ID) {
 			if err := db.Create(&pm).Error; err != nil {
-				log.Printf("⚠️ Failed to create payment method %s: %v", pm.Name, err)
+				log.Printf("⚠️ Failed to create payment method %s for %s: %v", pm.Name, gym.Name, err)
 			} else {
-				log.Printf("✅ Payment method created: %s", pm.Name)
+				log.Printf("✅ Payment method created for %s: %s", gym.Name, pm.Name)
 			}
 		}
 	}
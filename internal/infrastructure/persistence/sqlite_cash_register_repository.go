@@ -0,0 +1,56 @@
+package persistence
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// SQLiteCashRegisterRepository implements CashRegisterRepository for SQLite
+type SQLiteCashRegisterRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteCashRegisterRepository creates a new SQLite cash register repository
+func NewSQLiteCashRegisterRepository(db *gorm.DB) *SQLiteCashRegisterRepository {
+	return &SQLiteCashRegisterRepository{db: db}
+}
+
+func (r *SQLiteCashRegisterRepository) Create(session *entities.CashRegisterSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *SQLiteCashRegisterRepository) FindByID(id uuid.UUID) (*entities.CashRegisterSession, error) {
+	var session entities.CashRegisterSession
+	err := r.db.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SQLiteCashRegisterRepository) FindOpenByUser(userID uuid.UUID) (*entities.CashRegisterSession, error) {
+	var session entities.CashRegisterSession
+	err := r.db.Where("user_id = ? AND status = ?", userID, entities.CashRegisterStatusOpen).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SQLiteCashRegisterRepository) FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.CashRegisterSession, error) {
+	var sessions []*entities.CashRegisterSession
+	err := r.db.Where("gym_id = ? AND date >= ? AND date <= ?", gymID, from, to).
+		Order("opened_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SQLiteCashRegisterRepository) Update(session *entities.CashRegisterSession) error {
+	return r.db.Save(session).Error
+}
@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// SQLiteCouponRepository implements CouponRepository for SQLite
+type SQLiteCouponRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteCouponRepository creates a new SQLite coupon repository
+func NewSQLiteCouponRepository(db *gorm.DB) *SQLiteCouponRepository {
+	return &SQLiteCouponRepository{db: db}
+}
+
+func (r *SQLiteCouponRepository) Create(coupon *entities.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+func (r *SQLiteCouponRepository) FindByID(id uuid.UUID) (*entities.Coupon, error) {
+	var coupon entities.Coupon
+	err := r.db.Where("id = ?", id).First(&coupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *SQLiteCouponRepository) FindByCode(gymID uuid.UUID, code string) (*entities.Coupon, error) {
+	var coupon entities.Coupon
+	err := r.db.Where("gym_id = ? AND code = ?", gymID, entities.NormalizeCouponCode(code)).First(&coupon).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *SQLiteCouponRepository) FindByGymID(gymID uuid.UUID) ([]*entities.Coupon, error) {
+	var coupons []*entities.Coupon
+	err := r.db.Where("gym_id = ?", gymID).Order("created_at DESC").Find(&coupons).Error
+	return coupons, err
+}
+
+func (r *SQLiteCouponRepository) Update(coupon *entities.Coupon) error {
+	coupon.UpdatedAt = time.Now().UTC().Round(0)
+	return r.db.Save(coupon).Error
+}
+
+// IncrementUses uses the same conditional UPDATE as DecrementStock: reading
+// times_used and writing it back would let two tills both redeem the last use.
+func (r *SQLiteCouponRepository) IncrementUses(id uuid.UUID) error {
+	res := r.db.Model(&entities.Coupon{}).
+		Where("id = ? AND (max_uses = 0 OR times_used < max_uses)", id).
+		Updates(map[string]interface{}{
+			"times_used": gorm.Expr("times_used + 1"),
+			"updated_at": time.Now().UTC().Round(0),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperrors.ErrInvalidCoupon
+	}
+	return nil
+}
+
+func (r *SQLiteCouponRepository) CreateRedemption(redemption *entities.CouponRedemption) error {
+	return r.db.Create(redemption).Error
+}
+
+func (r *SQLiteCouponRepository) CountRedemptionsByUser(couponID, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&entities.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", couponID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *SQLiteCouponRepository) FindRedemptionsByCouponID(couponID uuid.UUID) ([]*entities.CouponRedemption, error) {
+	var redemptions []*entities.CouponRedemption
+	err := r.db.Where("coupon_id = ?", couponID).Order("created_at DESC").Find(&redemptions).Error
+	return redemptions, err
+}
+
+func (r *SQLiteCouponRepository) FindRedemptionsByDateRange(gymID uuid.UUID, from, to string) ([]*entities.CouponRedemption, error) {
+	var redemptions []*entities.CouponRedemption
+	err := r.db.Where("gym_id = ? AND date >= ? AND date <= ?", gymID, from, to).
+		Order("created_at ASC").
+		Find(&redemptions).Error
+	return redemptions, err
+}
@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// SQLiteGymClosureRepository implements GymClosureRepository for SQLite
+type SQLiteGymClosureRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteGymClosureRepository creates a new SQLite gym closure repository
+func NewSQLiteGymClosureRepository(db *gorm.DB) *SQLiteGymClosureRepository {
+	return &SQLiteGymClosureRepository{db: db}
+}
+
+func (r *SQLiteGymClosureRepository) Create(closure *entities.GymClosure) error {
+	return r.db.Create(closure).Error
+}
+
+func (r *SQLiteGymClosureRepository) FindByID(id uuid.UUID) (*entities.GymClosure, error) {
+	var closure entities.GymClosure
+	err := r.db.Where("id = ?", id).First(&closure).Error
+	if err != nil {
+		return nil, err
+	}
+	return &closure, nil
+}
+
+func (r *SQLiteGymClosureRepository) FindByDate(gymID uuid.UUID, date string) (*entities.GymClosure, error) {
+	var closure entities.GymClosure
+	err := r.db.Where("gym_id = ? AND date = ?", gymID, date).First(&closure).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &closure, nil
+}
+
+func (r *SQLiteGymClosureRepository) FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.GymClosure, error) {
+	var closures []*entities.GymClosure
+	err := r.db.Where("gym_id = ? AND date >= ? AND date <= ?", gymID, from, to).
+		Order("date ASC").
+		Find(&closures).Error
+	return closures, err
+}
+
+func (r *SQLiteGymClosureRepository) FindUncompensated(gymID uuid.UUID, upTo string) ([]*entities.GymClosure, error) {
+	var closures []*entities.GymClosure
+	err := r.db.Where("gym_id = ? AND date <= ? AND compensated_at IS NULL", gymID, upTo).
+		Order("date ASC").
+		Find(&closures).Error
+	return closures, err
+}
+
+func (r *SQLiteGymClosureRepository) MarkCompensated(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&entities.GymClosure{}).Where("id = ?", id).Update("compensated_at", at).Error
+}
+
+func (r *SQLiteGymClosureRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&entities.GymClosure{}, "id = ?", id).Error
+}
@@ -33,11 +33,17 @@ func (r *SQLiteGymRepository) Update(gym *entities.Gym) error {
 			name=?, legal_name=?, tax_id=?, address=?, city=?, state=?, country=?,
 			postal_code=?, phone=?, email=?, logo_url=?, timezone=?, locale=?, currency=?,
 			status=?, smtp_host=?, smtp_port=?, smtp_username=?, smtp_password=?, smtp_from=?,
+			refund_policy=?, refund_window_days=?,
+			overdue_balance_policy=?, overdue_balance_grace_days=?,
+			closure_compensation=?,
 			updated_at=?
 		WHERE id=?`,
 		gym.Name, gym.LegalName, gym.TaxID, gym.Address, gym.City, gym.State, gym.Country,
 		gym.PostalCode, gym.Phone, gym.Email, gym.LogoURL, gym.Timezone, gym.Locale, gym.Currency,
 		gym.Status, gym.SMTPHost, gym.SMTPPort, gym.SMTPUsername, gym.SMTPPassword, gym.SMTPFrom,
+		gym.RefundPolicy, gym.RefundWindowDays,
+		gym.OverdueBalancePolicy, gym.OverdueBalanceGraceDays,
+		gym.ClosureCompensation,
 		gym.UpdatedAt, gym.ID.String(),
 	).Error
 }
@@ -48,7 +54,7 @@ func (r *SQLiteGymRepository) Delete(id uuid.UUID) error {
 
 func (r *SQLiteGymRepository) List(limit, offset int) ([]*entities.Gym, error) {
 	var gyms []*entities.Gym
-	err := r.db.Limit(limit).Offset(offset).Find(&gyms).Error
+	err := r.db.Order("created_at, id").Limit(limit).Offset(offset).Find(&gyms).Error
 	return gyms, err
 }
 
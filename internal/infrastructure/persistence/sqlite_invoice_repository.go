@@ -0,0 +1,120 @@
+package persistence
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// SQLiteInvoiceRepository implements InvoiceRepository for SQLite
+type SQLiteInvoiceRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteInvoiceRepository creates a new SQLite invoice repository
+func NewSQLiteInvoiceRepository(db *gorm.DB) *SQLiteInvoiceRepository {
+	return &SQLiteInvoiceRepository{db: db}
+}
+
+// Create saves the invoice and its lines. Callers outside a unit of work get
+// both or neither all the same.
+func (r *SQLiteInvoiceRepository) Create(invoice *entities.Invoice) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+		if len(invoice.Lines) == 0 {
+			return nil
+		}
+		return tx.Create(&invoice.Lines).Error
+	})
+}
+
+func (r *SQLiteInvoiceRepository) FindByID(id uuid.UUID) (*entities.Invoice, error) {
+	var invoice entities.Invoice
+	if err := r.db.Where("id = ?", id).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("invoice_id = ?", id).Order("position ASC").Find(&invoice.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *SQLiteInvoiceRepository) FindBySaleID(saleID uuid.UUID) (*entities.Invoice, error) {
+	return r.findOne("sale_id = ?", saleID)
+}
+
+func (r *SQLiteInvoiceRepository) FindByPaymentID(paymentID uuid.UUID) (*entities.Invoice, error) {
+	return r.findOne("payment_id = ?", paymentID)
+}
+
+func (r *SQLiteInvoiceRepository) findOne(query string, arg uuid.UUID) (*entities.Invoice, error) {
+	var invoice entities.Invoice
+	err := r.db.Where(query, arg).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(invoice.ID)
+}
+
+func (r *SQLiteInvoiceRepository) FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.Invoice, error) {
+	var invoices []*entities.Invoice
+	err := r.db.Where("gym_id = ? AND issue_date >= ? AND issue_date <= ?", gymID, from, to).
+		Order("prefix ASC, number ASC").
+		Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *SQLiteInvoiceRepository) Update(invoice *entities.Invoice) error {
+	return r.db.Save(invoice).Error
+}
+
+// SQLiteInvoiceResolutionRepository implements InvoiceResolutionRepository for SQLite
+type SQLiteInvoiceResolutionRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteInvoiceResolutionRepository creates a new SQLite resolution repository
+func NewSQLiteInvoiceResolutionRepository(db *gorm.DB) *SQLiteInvoiceResolutionRepository {
+	return &SQLiteInvoiceResolutionRepository{db: db}
+}
+
+func (r *SQLiteInvoiceResolutionRepository) Create(resolution *entities.InvoiceResolution) error {
+	return r.db.Create(resolution).Error
+}
+
+func (r *SQLiteInvoiceResolutionRepository) FindActiveByGym(gymID uuid.UUID) (*entities.InvoiceResolution, error) {
+	var resolution entities.InvoiceResolution
+	err := r.db.Where("gym_id = ? AND active = ?", gymID, true).
+		Order("created_at DESC").
+		First(&resolution).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resolution, nil
+}
+
+func (r *SQLiteInvoiceResolutionRepository) FindByGymID(gymID uuid.UUID) ([]*entities.InvoiceResolution, error) {
+	var resolutions []*entities.InvoiceResolution
+	err := r.db.Where("gym_id = ?", gymID).Order("created_at DESC").Find(&resolutions).Error
+	return resolutions, err
+}
+
+func (r *SQLiteInvoiceResolutionRepository) DeactivateByGym(gymID uuid.UUID) error {
+	return r.db.Model(&entities.InvoiceResolution{}).
+		Where("gym_id = ? AND active = ?", gymID, true).
+		Update("active", false).Error
+}
+
+func (r *SQLiteInvoiceResolutionRepository) Update(resolution *entities.InvoiceResolution) error {
+	return r.db.Save(resolution).Error
+}
@@ -37,10 +37,10 @@ func (r *SQLitePaymentMethodRepository) GetByID(ctx context.Context, id uuid.UUI
 	return &method, nil
 }
 
-// GetAll retrieves all payment methods, optionally filtered by status
-func (r *SQLitePaymentMethodRepository) GetAll(ctx context.Context, status *entities.PaymentMethodStatus) ([]entities.SalePaymentMethod, error) {
+// GetAll retrieves the payment methods of a gym, optionally filtered by status
+func (r *SQLitePaymentMethodRepository) GetAll(ctx context.Context, gymID uuid.UUID, status *entities.PaymentMethodStatus) ([]entities.SalePaymentMethod, error) {
 	var methods []entities.SalePaymentMethod
-	query := r.db.WithContext(ctx)
+	query := r.db.WithContext(ctx).Where("gym_id = ?", gymID)
 
 	if status != nil {
 		query = query.Where("status = ?", *status)
@@ -0,0 +1,100 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// SQLitePaymentRepository implements PaymentRepository for SQLite
+type SQLitePaymentRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLitePaymentRepository creates a new SQLite payment repository
+func NewSQLitePaymentRepository(db *gorm.DB) *SQLitePaymentRepository {
+	return &SQLitePaymentRepository{db: db}
+}
+
+func (r *SQLitePaymentRepository) Create(payment *entities.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *SQLitePaymentRepository) FindByID(id uuid.UUID) (*entities.Payment, error) {
+	var payment entities.Payment
+	err := r.db.Where("id = ?", id).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *SQLitePaymentRepository) FindByUserID(userID uuid.UUID) ([]*entities.Payment, error) {
+	var payments []*entities.Payment
+	err := r.db.Where("user_id = ?", userID).Order("payment_date DESC").Find(&payments).Error
+	return payments, err
+}
+
+func (r *SQLitePaymentRepository) FindByGymID(gymID uuid.UUID, limit, offset int) ([]*entities.Payment, error) {
+	var payments []*entities.Payment
+	err := r.db.Where("gym_id = ?", gymID).
+		Limit(limit).Offset(offset).
+		Order("payment_date DESC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *SQLitePaymentRepository) FindByDateRange(gymID uuid.UUID, from, to string) ([]*entities.Payment, error) {
+	var payments []*entities.Payment
+	err := r.db.Where("gym_id = ? AND date >= ? AND date <= ?", gymID, from, to).
+		Order("payment_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *SQLitePaymentRepository) FindBySubscriptionID(subscriptionID uuid.UUID) ([]*entities.Payment, error) {
+	var payments []*entities.Payment
+	err := r.db.Where("subscription_id = ?", subscriptionID).Order("payment_date ASC").Find(&payments).Error
+	return payments, err
+}
+
+func (r *SQLitePaymentRepository) FindBySaleID(saleID uuid.UUID) ([]*entities.Payment, error) {
+	var payments []*entities.Payment
+	err := r.db.Where("sale_id = ?", saleID).Order("created_at ASC").Find(&payments).Error
+	return payments, err
+}
+
+func (r *SQLitePaymentRepository) FindRefundsByDateRange(gymID uuid.UUID, from, to string) ([]*entities.Payment, error) {
+	var payments []*entities.Payment
+	err := r.db.Where("gym_id = ? AND refund_date >= ? AND refund_date <= ?", gymID, from, to).
+		Order("refunded_at ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *SQLitePaymentRepository) FindByCashRegisterID(sessionID uuid.UUID) ([]*entities.Payment, error) {
+	var payments []*entities.Payment
+	err := r.db.Where("cash_register_id = ? OR refund_cash_register_id = ?", sessionID, sessionID).
+		Order("payment_date ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *SQLitePaymentRepository) Update(payment *entities.Payment) error {
+	payment.UpdatedAt = time.Now().UTC().Round(0)
+	return r.db.Save(payment).Error
+}
+
+// GetTotalRevenueByGymID is what the gym kept: every charge that went through,
+// less whatever was refunded from it.
+func (r *SQLitePaymentRepository) GetTotalRevenueByGymID(gymID uuid.UUID) (float64, error) {
+	var total float64
+	err := r.db.Model(&entities.Payment{}).
+		Select("COALESCE(SUM(amount - refunded_amount), 0)").
+		Where("gym_id = ? AND status IN ?", gymID,
+			[]entities.PaymentStatus{entities.PaymentStatusCompleted, entities.PaymentStatusRefunded}).
+		Scan(&total).Error
+	return total, err
+}
@@ -39,10 +39,10 @@ func (r *SQLiteProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*e
 	return &product, nil
 }
 
-// GetAll retrieves all products, optionally filtered by status
-func (r *SQLiteProductRepository) GetAll(ctx context.Context, status *entities.ProductStatus) ([]entities.Product, error) {
+// GetAll retrieves the products of a gym, optionally filtered by status
+func (r *SQLiteProductRepository) GetAll(ctx context.Context, gymID uuid.UUID, status *entities.ProductStatus) ([]entities.Product, error) {
 	var products []entities.Product
-	query := r.db.WithContext(ctx)
+	query := r.db.WithContext(ctx).Where("gym_id = ?", gymID)
 
 	if status != nil {
 		query = query.Where("status = ?", *status)
@@ -119,11 +119,12 @@ func (r *SQLiteProductRepository) IncrementStock(ctx context.Context, productID
 	return r.UpdateStock(ctx, productID, qty)
 }
 
-// Search searches for products by name or description
-func (r *SQLiteProductRepository) Search(ctx context.Context, searchTerm string) ([]entities.Product, error) {
+// Search searches the products of a gym by name or description
+func (r *SQLiteProductRepository) Search(ctx context.Context, gymID uuid.UUID, searchTerm string) ([]entities.Product, error) {
 	var products []entities.Product
 	searchPattern := "%" + searchTerm + "%"
 	err := r.db.WithContext(ctx).
+		Where("gym_id = ?", gymID).
 		Where("name LIKE ? OR description LIKE ?", searchPattern, searchPattern).
 		Order("created_at DESC").
 		Find(&products).Error
@@ -2,7 +2,6 @@ package persistence
 
 import (
 	"context"
-	"log"
 
 	"github.com/google/uuid"
 	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
@@ -40,9 +39,10 @@ func (r *SQLiteSaleRepository) GetByID(ctx context.Context, id uuid.UUID) (*enti
 
 // GetAll retrieves the most recent sales, capped. `sales` only ever grows, so an
 // unbounded SELECT here would return the whole history to serve GET /sales.
-func (r *SQLiteSaleRepository) GetAll(ctx context.Context) ([]entities.Sale, error) {
+func (r *SQLiteSaleRepository) GetAll(ctx context.Context, gymID uuid.UUID) ([]entities.Sale, error) {
 	var sales []entities.Sale
 	err := r.db.WithContext(ctx).
+		Where("gym_id = ?", gymID).
 		Order("sale_date DESC").
 		Limit(maxSalesRows).
 		Find(&sales).Error
@@ -55,12 +55,13 @@ func (r *SQLiteSaleRepository) Update(ctx context.Context, sale *entities.Sale)
 	return r.db.WithContext(ctx).Save(sale).Error
 }
 
-// GetByDateRange retrieves sales within a date range using the local date field
-func (r *SQLiteSaleRepository) GetByDateRange(ctx context.Context, startDate, endDate string, userID *uuid.UUID) ([]entities.Sale, error) {
+// GetByDateRange retrieves the sales of a gym within a date range using the
+// local date field
+func (r *SQLiteSaleRepository) GetByDateRange(ctx context.Context, gymID uuid.UUID, startDate, endDate string, userID *uuid.UUID) ([]entities.Sale, error) {
 	var sales []entities.Sale
 
 	query := r.db.WithContext(ctx).
-		Where("date >= ? AND date <= ?", startDate, endDate)
+		Where("gym_id = ? AND date >= ? AND date <= ?", gymID, startDate, endDate)
 
 	if userID != nil {
 		query = query.Where("user_id = ?", *userID)
@@ -70,22 +71,30 @@ func (r *SQLiteSaleRepository) GetByDateRange(ctx context.Context, startDate, en
 	return sales, err
 }
 
+// GetReturns retrieves the return documents of a sale
+func (r *SQLiteSaleRepository) GetReturns(ctx context.Context, saleID uuid.UUID) ([]entities.Sale, error) {
+	var sales []entities.Sale
+	err := r.db.WithContext(ctx).
+		Where("returned_sale_id = ? AND type = ?", saleID, entities.SaleTypeReturn).
+		Order("sale_date ASC").
+		Find(&sales).Error
+	return sales, err
+}
+
 // GetSalesReport generates a sales report for a date range
-func (r *SQLiteSaleRepository) GetSalesReport(ctx context.Context, startDate, endDate string, userID *uuid.UUID) ([]repositories.SaleReport, error) {
+func (r *SQLiteSaleRepository) GetSalesReport(ctx context.Context, gymID uuid.UUID, startDate, endDate string, userID *uuid.UUID) ([]repositories.SaleReport, error) {
 	var reports []repositories.SaleReport
 
-	log.Printf("🔍 DEBUG GetSalesReport - startDate: %v, endDate: %v", startDate, endDate)
-
 	query := r.db.WithContext(ctx).
 		Model(&entities.Sale{}).
 		Select(`
 			COALESCE(SUM(CASE WHEN type = 'normal' THEN total ELSE 0 END), 0) as total_sales,
 			COALESCE(SUM(CASE WHEN type = 'normal' THEN total_discount ELSE 0 END), 0) as total_discount,
 			COALESCE(SUM(CASE WHEN type = 'normal' THEN total ELSE 0 END) -
-			         SUM(CASE WHEN type = 'void' THEN ABS(total) ELSE 0 END), 0) as net_sales,
+			         SUM(CASE WHEN type IN ('void', 'return') THEN ABS(total) ELSE 0 END), 0) as net_sales,
 			COUNT(CASE WHEN type = 'normal' THEN 1 END) as sales_count
 		`).
-		Where("date >= ? AND date <= ?", startDate, endDate).
+		Where("gym_id = ? AND date >= ? AND date <= ?", gymID, startDate, endDate).
 		Where("status = ?", entities.SaleStatusCompleted)
 
 	if userID != nil {
@@ -93,15 +102,43 @@ func (r *SQLiteSaleRepository) GetSalesReport(ctx context.Context, startDate, en
 	}
 
 	err := query.Scan(&reports).Error
-	log.Printf("🔍 DEBUG GetSalesReport - Found %d reports, error: %v", len(reports), err)
-	if len(reports) > 0 {
-		log.Printf("🔍 DEBUG GetSalesReport - Report: %+v", reports[0])
+	if err != nil || len(reports) == 0 {
+		return reports, err
 	}
-	return reports, err
+
+	// IVA by rate, from the details: a sale can mix products of several rates.
+	// A return gives its IVA back; a void needs nothing, its sale is no longer
+	// completed.
+	taxQuery := r.db.WithContext(ctx).
+		Table("sale_details sd").
+		Select(`
+			sd.tax_rate as rate,
+			COALESCE(SUM(sd.tax_base), 0) as base,
+			COALESCE(SUM(sd.tax_amount), 0) as tax
+		`).
+		Joins("JOIN sales s ON sd.sale_id = s.id").
+		Where("s.gym_id = ? AND s.date >= ? AND s.date <= ?", gymID, startDate, endDate).
+		Where("s.status = ?", entities.SaleStatusCompleted).
+		Where("s.type IN ?", []entities.SaleType{entities.SaleTypeNormal, entities.SaleTypeReturn})
+	if userID != nil {
+		taxQuery = taxQuery.Where("s.user_id = ?", *userID)
+	}
+	var taxes []entities.TaxBreakdown
+	if err := taxQuery.Group("sd.tax_rate").Order("sd.tax_rate").Scan(&taxes).Error; err != nil {
+		return nil, err
+	}
+	reports[0].Taxes = taxes
+	for _, t := range taxes {
+		reports[0].TaxableBase += t.Base
+		reports[0].TaxTotal += t.Tax
+	}
+	return reports, nil
 }
 
-// GetSalesReportByProduct generates a sales report grouped by product
-func (r *SQLiteSaleRepository) GetSalesReportByProduct(ctx context.Context, startDate, endDate string) ([]repositories.SaleProductReport, error) {
+// GetSalesReportByProduct generates a sales report grouped by product. Units
+// returned are taken off: a return's amounts are already negative, its
+// quantities are not.
+func (r *SQLiteSaleRepository) GetSalesReportByProduct(ctx context.Context, gymID uuid.UUID, startDate, endDate string) ([]repositories.SaleProductReport, error) {
 	var reports []repositories.SaleProductReport
 
 	err := r.db.WithContext(ctx).
@@ -109,16 +146,16 @@ func (r *SQLiteSaleRepository) GetSalesReportByProduct(ctx context.Context, star
 		Select(`
 			sd.product_id,
 			p.name as product_name,
-			COALESCE(SUM(sd.quantity), 0) as quantity_sold,
+			COALESCE(SUM(CASE WHEN s.type = 'return' THEN -sd.quantity ELSE sd.quantity END), 0) as quantity_sold,
 			COALESCE(SUM(sd.total_price), 0) as total_revenue,
 			COALESCE(SUM(sd.discount), 0) as total_discount,
 			COALESCE(SUM(sd.subtotal), 0) as net_revenue
 		`).
 		Joins("JOIN sales s ON sd.sale_id = s.id").
 		Joins("JOIN products p ON sd.product_id = p.id").
-		Where("s.date >= ? AND s.date <= ?", startDate, endDate).
+		Where("s.gym_id = ? AND s.date >= ? AND s.date <= ?", gymID, startDate, endDate).
 		Where("s.status = ?", entities.SaleStatusCompleted).
-		Where("s.type = ?", entities.SaleTypeNormal).
+		Where("s.type IN ?", []entities.SaleType{entities.SaleTypeNormal, entities.SaleTypeReturn}).
 		Group("sd.product_id, p.name").
 		Order("net_revenue DESC").
 		Scan(&reports).Error
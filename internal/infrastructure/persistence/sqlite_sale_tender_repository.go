@@ -0,0 +1,39 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// SQLiteSaleTenderRepository implements SaleTenderRepository for SQLite
+type SQLiteSaleTenderRepository struct {
+	db *gorm.DB
+}
+
+// NewSQLiteSaleTenderRepository creates a new SQLiteSaleTenderRepository
+func NewSQLiteSaleTenderRepository(db *gorm.DB) repositories.SaleTenderRepository {
+	return &SQLiteSaleTenderRepository{db: db}
+}
+
+// CreateBatch creates the tenders of a sale. Their IDs are set by the caller,
+// so that a retried transaction inserts the same rows.
+func (r *SQLiteSaleTenderRepository) CreateBatch(ctx context.Context, tenders []entities.SaleTender) error {
+	if len(tenders) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&tenders).Error
+}
+
+// GetBySaleID retrieves the tenders of a sale
+func (r *SQLiteSaleTenderRepository) GetBySaleID(ctx context.Context, saleID uuid.UUID) ([]entities.SaleTender, error) {
+	var tenders []entities.SaleTender
+	err := r.db.WithContext(ctx).
+		Where("sale_id = ?", saleID).
+		Order("amount DESC").
+		Find(&tenders).Error
+	return tenders, err
+}
@@ -83,3 +83,8 @@ func (r *SQLiteSubscriptionMemberRepository) DeleteBySubscriptionID(subscription
 	return r.db.Where("subscription_id = ?", subscriptionID).
 		Delete(&entities.SubscriptionMember{}).Error
 }
+
+func (r *SQLiteSubscriptionMemberRepository) DeleteBySubscriptionAndUser(subscriptionID, userID uuid.UUID) error {
+	return r.db.Where("subscription_id = ? AND user_id = ?", subscriptionID, userID).
+		Delete(&entities.SubscriptionMember{}).Error
+}
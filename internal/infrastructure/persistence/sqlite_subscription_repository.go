@@ -67,11 +67,51 @@ func (r *SQLiteSubscriptionRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&entities.Subscription{}, id).Error
 }
 
-func (r *SQLiteSubscriptionRepository) MarkExpiredSubscriptions() (int64, error) {
-	result := r.db.Model(&entities.Subscription{}).
-		Where("status = ? AND end_date < ?", entities.SubscriptionStatusActive, time.Now()).
-		Update("status", entities.SubscriptionStatusExpired)
-	return result.RowsAffected, result.Error
+func (r *SQLiteSubscriptionRepository) FindToExpire(now time.Time) ([]*entities.Subscription, error) {
+	var subscriptions []*entities.Subscription
+	err := r.db.Where("status = ? AND end_date < ?", entities.SubscriptionStatusActive, now).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *SQLiteSubscriptionRepository) FindToExtend(gymID uuid.UUID, planIDs []uuid.UUID) ([]*entities.Subscription, error) {
+	var subscriptions []*entities.Subscription
+	q := r.db.Where("gym_id = ? AND status IN ?", gymID,
+		[]entities.SubscriptionStatus{entities.SubscriptionStatusActive, entities.SubscriptionStatusFrozen})
+	if len(planIDs) > 0 {
+		q = q.Where("plan_id IN ?", planIDs)
+	}
+	err := q.Order("end_date ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ExpireByIDs expires the given subscriptions, in batches like MarkRemindersSent.
+// The status guard keeps a subscription renewed or cancelled in the meantime
+// from being expired on top.
+func (r *SQLiteSubscriptionRepository) ExpireByIDs(ids []uuid.UUID) (int64, error) {
+	const batchSize = 500
+	now := time.Now().UTC().Round(0)
+
+	var expired int64
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		result := r.db.Model(&entities.Subscription{}).
+			Where("id IN ? AND status = ?", ids[start:end], entities.SubscriptionStatusActive).
+			Updates(map[string]interface{}{
+				"status":     entities.SubscriptionStatusExpired,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return expired, result.Error
+		}
+		expired += result.RowsAffected
+	}
+
+	return expired, nil
 }
 
 // MarkRemindersSent flags the given subscriptions as already reminded, in batches
@@ -103,6 +143,37 @@ func (r *SQLiteSubscriptionRepository) MarkRemindersSent(ids []uuid.UUID) error
 	return nil
 }
 
+// ConsumeVisit uses one entry of a punch card in a single conditional UPDATE, the
+// same way DecrementStock guards the last unit of a product: two check-ins racing
+// for the last entry cannot both get it. Closing a card whose last entry this
+// was is left to the caller, through Subscription.Expire and its audit entry.
+func (r *SQLiteSubscriptionRepository) ConsumeVisit(id uuid.UUID) (bool, error) {
+	result := r.db.Exec(`UPDATE subscriptions
+		SET visits_used = visits_used + 1,
+		    updated_at  = ?
+		WHERE id = ? AND status = ? AND visit_allowance > 0 AND visits_used < visit_allowance`,
+		time.Now().UTC().Round(0), id, entities.SubscriptionStatusActive)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// AddPayment credits a partial payment with a conditional UPDATE, like
+// ConsumeVisit: the balance is checked against the row, so two tills taking the
+// last payment of the same member cannot both get it through.
+func (r *SQLiteSubscriptionRepository) AddPayment(id uuid.UUID, amount float64) (bool, error) {
+	result := r.db.Exec(`UPDATE subscriptions
+		SET amount_paid = amount_paid + ?,
+		    updated_at  = ?
+		WHERE id = ? AND amount_paid + ? <= total_paid`,
+		amount, time.Now().UTC().Round(0), id, amount)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // FindExpiredFreezes returns frozen subscriptions whose freeze period has already
 // elapsed. Rows are returned rather than updated in bulk because reactivating
 // involves reclaiming unused freeze days, which is business logic on the entity.
@@ -115,12 +186,34 @@ func (r *SQLiteSubscriptionRepository) FindExpiredFreezes() ([]*entities.Subscri
 	return subs, err
 }
 
+// FindDueToStart returns the future-dated subscriptions whose day has come. Those
+// still awaiting their online payment are not due whatever the day.
+func (r *SQLiteSubscriptionRepository) FindDueToStart(now time.Time) ([]*entities.Subscription, error) {
+	var subs []*entities.Subscription
+	err := r.db.
+		Where("status = ? AND start_date <= ? AND awaiting_payment = ?", entities.SubscriptionStatusPending, now, false).
+		Find(&subs).Error
+	return subs, err
+}
+
+// FindDueForAutoRenew returns the auto-renew subscriptions that end before the
+// given instant. It leads with (status, end_date) so it rides idx_subs_status_end
+// like the expiry pass that runs right after it.
+func (r *SQLiteSubscriptionRepository) FindDueForAutoRenew(before time.Time) ([]*entities.Subscription, error) {
+	var subs []*entities.Subscription
+	err := r.db.
+		Where("status = ? AND end_date <= ? AND auto_renew = ?",
+			entities.SubscriptionStatusActive, before, true).
+		Find(&subs).Error
+	return subs, err
+}
+
 func (r *SQLiteSubscriptionRepository) FindByGymIDWithFilters(gymID uuid.UUID, filter repositories.SubscriptionFilter, limit, offset int) ([]*entities.Subscription, error) {
 	var subscriptions []*entities.Subscription
 	q := r.db.Where("gym_id = ?", gymID)
 	switch filter.Status {
 	case "INACTIVE":
-		q = q.Where("status IN ?", []string{"EXPIRED", "CANCELLED", "SUSPENDED"})
+		q = q.Where("status IN ?", []string{"EXPIRED", "CANCELLED", "SUSPENDED", "PLAN_CHANGED", "TRANSFERRED"})
 	case "":
 		// no status filter
 	default:
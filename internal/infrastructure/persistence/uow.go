@@ -39,7 +39,15 @@ func reposOn(tx *gorm.DB) repositories.Repos {
 		Members:       NewSQLiteSubscriptionMemberRepository(tx),
 		Audit:         NewSQLiteSubscriptionAuditLogRepository(tx),
 		Products:      NewSQLiteProductRepository(tx),
+		PayMethods:    NewSQLitePaymentMethodRepository(tx),
 		Sales:         NewSQLiteSaleRepository(tx),
 		SaleDetails:   NewSQLiteSaleDetailRepository(tx),
+		SaleTenders:   NewSQLiteSaleTenderRepository(tx),
+		Payments:      NewSQLitePaymentRepository(tx),
+		Coupons:       NewSQLiteCouponRepository(tx),
+		Closures:      NewSQLiteGymClosureRepository(tx),
+		Registers:     NewSQLiteCashRegisterRepository(tx),
+		Invoices:      NewSQLiteInvoiceRepository(tx),
+		Resolutions:   NewSQLiteInvoiceResolutionRepository(tx),
 	}
 }
@@ -0,0 +1,155 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// ESC/POS commands, the subset every thermal printer of the front desk takes.
+var (
+	cmdInit        = []byte{0x1b, 0x40}       // ESC @
+	cmdCodePage850 = []byte{0x1b, 0x74, 0x02} // ESC t 2: PC850, the one with the Spanish letters
+	cmdCut         = []byte{0x1d, 0x56, 0x42, 0x03}
+)
+
+// Align is the justification of the following lines.
+type Align byte
+
+const (
+	AlignLeft   Align = 0
+	AlignCenter Align = 1
+	AlignRight  Align = 2
+)
+
+// Receipt builds the ESC/POS bytes of a receipt, line by line, for a paper
+// of the given number of columns.
+type Receipt struct {
+	buf  bytes.Buffer
+	cols int
+}
+
+// NewReceipt starts a receipt: resets the printer and selects PC850.
+func NewReceipt(cols int) *Receipt {
+	r := &Receipt{cols: cols}
+	r.buf.Write(cmdInit)
+	r.buf.Write(cmdCodePage850)
+	return r
+}
+
+// Align sets the justification of the lines that follow.
+func (r *Receipt) Align(a Align) *Receipt {
+	r.buf.Write([]byte{0x1b, 0x61, byte(a)})
+	return r
+}
+
+// Bold turns emphasis on or off.
+func (r *Receipt) Bold(on bool) *Receipt {
+	r.buf.Write([]byte{0x1b, 0x45, flag(on)})
+	return r
+}
+
+// Big switches to double width and height. Half as many columns fit.
+func (r *Receipt) Big(on bool) *Receipt {
+	size := byte(0x00)
+	if on {
+		size = 0x11
+	}
+	r.buf.Write([]byte{0x1d, 0x21, size})
+	return r
+}
+
+// Line prints a line of text, wrapped at the paper width.
+func (r *Receipt) Line(s string) *Receipt {
+	for _, l := range wrap(s, r.cols) {
+		r.buf.Write(cp850(l))
+		r.buf.WriteByte('\n')
+	}
+	return r
+}
+
+// Pair prints left and right on the same line, right flushed to the edge.
+// A left too long for both gets its own line.
+func (r *Receipt) Pair(left, right string) *Receipt {
+	gap := r.cols - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if gap < 1 {
+		r.Line(left)
+		gap = r.cols - utf8.RuneCountInString(right)
+		left = ""
+		if gap < 0 {
+			gap = 0
+		}
+	}
+	return r.Line(left + strings.Repeat(" ", gap) + right)
+}
+
+// Rule prints a dashed line across the paper.
+func (r *Receipt) Rule() *Receipt {
+	return r.Line(strings.Repeat("-", r.cols))
+}
+
+// Feed prints n blank lines.
+func (r *Receipt) Feed(n int) *Receipt {
+	for i := 0; i < n; i++ {
+		r.buf.WriteByte('\n')
+	}
+	return r
+}
+
+// Bytes ends the receipt, feeding it past the cutter and cutting it, and
+// returns what to send to the printer.
+func (r *Receipt) Bytes() []byte {
+	r.buf.Write(cmdCut)
+	return r.buf.Bytes()
+}
+
+func flag(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// wrap splits s in lines of at most cols characters, breaking at spaces when
+// it can.
+func wrap(s string, cols int) []string {
+	runes := []rune(s)
+	if cols <= 0 || len(runes) <= cols {
+		return []string{s}
+	}
+	var lines []string
+	for len(runes) > cols {
+		cut := cols
+		for i := cols; i > 0; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+	}
+	return append(lines, string(runes))
+}
+
+// cp850 converts a UTF-8 string to code page 850, as latin1 does for the PDFs.
+func cp850(s string) []byte {
+	replacer := map[rune]byte{
+		'á': 0xa0, 'é': 0x82, 'í': 0xa1, 'ó': 0xa2, 'ú': 0xa3,
+		'Á': 0xb5, 'É': 0x90, 'Í': 0xd6, 'Ó': 0xe0, 'Ú': 0xe9,
+		'ñ': 0xa4, 'Ñ': 0xa5, 'ü': 0x81, 'Ü': 0x9a,
+		'¡': 0xad, '¿': 0xa8, '°': 0xf8,
+	}
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 128:
+			b = append(b, byte(r))
+		case replacer[r] != 0:
+			b = append(b, replacer[r])
+		default:
+			b = append(b, '?')
+		}
+	}
+	return b
+}
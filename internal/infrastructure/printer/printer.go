@@ -0,0 +1,95 @@
+// Package printer prints receipts on the thermal printers at the front desk,
+// ESC/POS printers hanging from a serial port like the turnstile relays.
+package printer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"go.bug.st/serial"
+)
+
+// Printer sends a rendered receipt to a printer.
+type Printer interface {
+	Print(data []byte) error
+}
+
+// IsFilePort reports whether port names a file rather than a serial port.
+// Receipts are never written to a file named by a device: any admin of a gym
+// sets its COM port, and the server would append to whatever path it gave.
+func IsFilePort(port string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(port)), "file:")
+}
+
+// Open returns the printer of a RECEIPT_PRINTER device.
+func Open(device *entities.Device) (Printer, error) {
+	switch {
+	case device.COMPort == "":
+		return nil, fmt.Errorf("la impresora %s no tiene puerto configurado", device.Name)
+	case IsFilePort(device.COMPort):
+		return nil, fmt.Errorf("la impresora %s no tiene un puerto serie: %s", device.Name, device.COMPort)
+	}
+	baudRate := device.BaudRate
+	if baudRate == 0 {
+		baudRate = 9600
+	}
+	return &SerialPrinter{Port: device.COMPort, BaudRate: baudRate}, nil
+}
+
+// Columns is how many characters of the normal font fit in a line of the
+// device's paper.
+func Columns(device *entities.Device) int {
+	if device.PaperWidth == 58 {
+		return 32
+	}
+	return 48
+}
+
+// SerialPrinter is a printer on a COM port. The port is opened for each
+// receipt, as DeviceHandler.Trigger does with the relays: holding it open
+// would lock out the vendor's own tools.
+type SerialPrinter struct {
+	Port     string
+	BaudRate int
+}
+
+func (p *SerialPrinter) Print(data []byte) error {
+	port, err := serial.Open(p.Port, &serial.Mode{BaudRate: p.BaudRate})
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", p.Port, err)
+	}
+	defer port.Close()
+	if _, err := port.Write(data); err != nil {
+		return fmt.Errorf("write to %s failed: %w", p.Port, err)
+	}
+	return nil
+}
+
+// FilePrinter appends every receipt to a file: a fake printer for the tests
+// and for trying the receipts out without the hardware. It is never opened
+// from a device (see IsFilePort).
+type FilePrinter struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFilePrinter(path string) *FilePrinter {
+	return &FilePrinter{Path: path}
+}
+
+func (p *FilePrinter) Print(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, err := os.OpenFile(p.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,164 @@
+package printer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/email"
+)
+
+// RenderSale renders the receipt of a sale. The sale comes as GetSaleByID
+// returns it, with the products of its details and its tenders loaded.
+func RenderSale(gym *entities.Gym, sale *entities.Sale, cols int) []byte {
+	r := NewReceipt(cols)
+	header(r, gym)
+
+	r.Align(AlignLeft)
+	r.Pair("Venta", shortID(sale.ID.String()))
+	r.Pair("Fecha", saleDate(sale))
+	r.Rule()
+	for _, d := range sale.Details {
+		name := "Producto"
+		if d.Product != nil {
+			name = d.Product.Name
+		}
+		r.Line(name)
+		r.Pair(fmt.Sprintf("  %d x %s", d.Quantity, email.FmtAmt(d.UnitPrice)), email.FmtAmt(d.TotalPrice))
+		if d.Discount != 0 {
+			r.Pair("  Descuento", email.FmtAmt(-d.Discount))
+		}
+	}
+	r.Rule()
+	if sale.TotalDiscount != 0 {
+		r.Pair("Descuentos", email.FmtAmt(-sale.TotalDiscount))
+	}
+	if sale.CouponCode != "" {
+		r.Pair("Cupón", sale.CouponCode)
+	}
+	r.Bold(true).Pair("TOTAL", email.FmtAmt(sale.Total)).Bold(false)
+	var taxes []entities.TaxBreakdown
+	for _, d := range sale.Details {
+		taxes = entities.AddToBreakdown(taxes, d.TaxRate, d.TaxBase, d.TaxAmount)
+	}
+	taxLines(r, taxes)
+	for _, t := range sale.Tenders {
+		method := "Pago"
+		if t.PaymentMethod != nil {
+			method = t.PaymentMethod.Name
+		}
+		r.Pair(method, email.FmtAmt(t.Amount))
+	}
+	if sale.Status == entities.SaleStatusVoided {
+		r.Feed(1).Align(AlignCenter).Bold(true).Line("*** VENTA ANULADA ***").Bold(false)
+	}
+
+	footer(r)
+	return r.Bytes()
+}
+
+// RenderSubscription renders the receipt of a new subscription: the plan,
+// the dates it runs and what was paid for it.
+func RenderSubscription(gym *entities.Gym, sub *entities.Subscription, plan *entities.Plan, member *entities.User, loc *time.Location, cols int) []byte {
+	if loc == nil {
+		loc = time.UTC
+	}
+	r := NewReceipt(cols)
+	header(r, gym)
+
+	r.Align(AlignLeft)
+	r.Pair("Suscripción", shortID(sub.ID.String()))
+	r.Pair("Fecha", sub.CreatedAt.In(loc).Format("02/01/2006 15:04"))
+	if member != nil {
+		r.Pair("Socio", member.FullName())
+		if member.DocumentNumber != "" {
+			r.Pair("Documento", member.DocumentNumber)
+		}
+	}
+	r.Rule()
+	if plan != nil {
+		r.Bold(true).Line(plan.Name).Bold(false)
+	}
+	r.Pair("Desde", sub.StartDate.In(loc).Format("02/01/2006"))
+	r.Pair("Hasta", sub.EndDate.In(loc).Format("02/01/2006"))
+	r.Rule()
+	r.Pair("Plan", email.FmtAmt(sub.PricePaid))
+	if sub.EnrollmentFeePaid != 0 {
+		r.Pair("Inscripción", email.FmtAmt(sub.EnrollmentFeePaid))
+	}
+	if sub.DiscountApplied != 0 {
+		r.Pair("Descuento", email.FmtAmt(-sub.DiscountApplied))
+	}
+	r.Bold(true).Pair("TOTAL", email.FmtAmt(sub.TotalPaid)).Bold(false)
+	base, tax := entities.SplitTax(sub.TotalPaid, sub.TaxRate)
+	taxLines(r, []entities.TaxBreakdown{{Rate: sub.TaxRate, Base: base, Tax: tax}})
+	if sub.PaymentMethod != "" {
+		r.Pair("Pagado ("+sub.PaymentMethod+")", email.FmtAmt(sub.AmountPaid))
+	} else {
+		r.Pair("Pagado", email.FmtAmt(sub.AmountPaid))
+	}
+	if balance := sub.Balance(); balance > 0 {
+		r.Pair("Saldo pendiente", email.FmtAmt(balance))
+		if sub.BalanceDueDate != nil {
+			r.Pair("Pagar antes de", sub.BalanceDueDate.In(loc).Format("02/01/2006"))
+		}
+	}
+
+	footer(r)
+	return r.Bytes()
+}
+
+// header prints the gym as the receipt's letterhead.
+func header(r *Receipt, gym *entities.Gym) {
+	r.Align(AlignCenter)
+	r.Big(true).Bold(true).Line(gym.Name).Bold(false).Big(false)
+	if gym.LegalName != "" && gym.LegalName != gym.Name {
+		r.Line(gym.LegalName)
+	}
+	if gym.TaxID != "" {
+		r.Line("NIT " + gym.TaxID)
+	}
+	if gym.Address != "" {
+		r.Line(gym.Address)
+	}
+	if gym.Phone != "" {
+		r.Line("Tel. " + gym.Phone)
+	}
+	r.Feed(1)
+}
+
+// taxLines prints the IVA included in the total, by rate. Nothing when no rate
+// has IVA.
+func taxLines(r *Receipt, taxes []entities.TaxBreakdown) {
+	for _, t := range taxes {
+		if t.Tax == 0 {
+			continue
+		}
+		r.Pair(fmt.Sprintf("  Base IVA %g%%", t.Rate), email.FmtAmt(t.Base))
+		r.Pair(fmt.Sprintf("  IVA %g%%", t.Rate), email.FmtAmt(t.Tax))
+	}
+}
+
+func footer(r *Receipt) {
+	r.Feed(1).Align(AlignCenter).Line("¡Gracias por su compra!")
+	r.Line("Este documento no es una factura electrónica")
+	r.Feed(2)
+}
+
+// saleDate is the local date and hour the sale was stored with.
+func saleDate(sale *entities.Sale) string {
+	if d, err := time.Parse("2006-01-02", sale.Date); err == nil {
+		return strings.TrimSpace(d.Format("02/01/2006") + " " + sale.Hour)
+	}
+	return sale.SaleDate.Format("02/01/2006 15:04")
+}
+
+// shortID is the first block of a UUID, enough to find the record from the
+// paper.
+func shortID(id string) string {
+	if i := strings.IndexByte(id, '-'); i > 0 {
+		return strings.ToUpper(id[:i])
+	}
+	return id
+}
@@ -1,7 +1,9 @@
 package usecases
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +17,10 @@ type AccessUseCase struct {
 	userRepo         repositories.UserRepository
 	subscriptionRepo repositories.SubscriptionRepository
 	memberRepo       repositories.SubscriptionMemberRepository
+	planRepo         repositories.PlanRepository
+	gymRepo          repositories.GymRepository
+	closureRepo      repositories.GymClosureRepository
+	uow              repositories.UnitOfWork
 }
 
 func NewAccessUseCase(
@@ -22,17 +28,26 @@ func NewAccessUseCase(
 	userRepo repositories.UserRepository,
 	subscriptionRepo repositories.SubscriptionRepository,
 	memberRepo repositories.SubscriptionMemberRepository,
+	planRepo repositories.PlanRepository,
+	gymRepo repositories.GymRepository,
+	closureRepo repositories.GymClosureRepository,
+	uow repositories.UnitOfWork,
 ) *AccessUseCase {
 	return &AccessUseCase{
 		accessLogRepo:    accessLogRepo,
 		userRepo:         userRepo,
 		subscriptionRepo: subscriptionRepo,
 		memberRepo:       memberRepo,
+		planRepo:         planRepo,
+		gymRepo:          gymRepo,
+		closureRepo:      closureRepo,
+		uow:              uow,
 	}
 }
 
-// RecordEntry records a gym entry
-func (uc *AccessUseCase) RecordEntry(userID, gymID uuid.UUID, method entities.AccessLogMethod) (*entities.AccessLog, error) {
+// RecordEntry records a gym entry. loc is the gym's timezone: plan access
+// windows are read off the local clock, not UTC.
+func (uc *AccessUseCase) RecordEntry(userID, gymID uuid.UUID, method entities.AccessLogMethod, loc *time.Location) (*entities.AccessLog, error) {
 	// Verify user exists
 	user, err := uc.userRepo.FindByID(userID)
 	if err != nil {
@@ -49,14 +64,48 @@ func (uc *AccessUseCase) RecordEntry(userID, gymID uuid.UUID, method entities.Ac
 		return accessLog, nil
 	}
 
+	// On a closed day no member gets in, whatever their subscription. Staff
+	// still does, above: maintenance days are when they are there.
+	closure, err := uc.closureRepo.FindByDate(gymID, time.Now().In(loc).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	if closure != nil {
+		accessLog := entities.NewAccessLog(gymID, userID, entities.AccessLogTypeEntry, method)
+		accessLog.Deny(closure.DenialReason())
+		uc.accessLogRepo.Create(accessLog)
+		return accessLog, errors.New("gym closed")
+	}
+
 	// Check if user has active subscription (direct or via group membership)
 	subscription, err := uc.subscriptionRepo.FindActiveByUserID(userID)
 	if err != nil || subscription == nil {
 		// Check if user is a member of an active group subscription
 		subscription, err = uc.memberRepo.FindActiveSubscriptionByUserID(userID)
 	}
+	if err != nil || subscription == nil {
+		// A period whose start has come but that the hourly job has not reached
+		// yet starts now: the member is at the door on the day they paid for.
+		if due, dueErr := uc.activateDue(userID); dueErr != nil {
+			return nil, dueErr
+		} else if due != nil {
+			subscription, err = due, nil
+		}
+	}
 	if err != nil || subscription == nil {
 		accessLog := entities.NewAccessLog(gymID, userID, entities.AccessLogTypeEntry, method)
+		if card := uc.exhaustedPunchCard(userID); card != nil {
+			accessLog.Deny(fmt.Sprintf("Sin entradas disponibles: ya se usaron las %d entradas del plan", card.VisitAllowance))
+			accessLog.SubscriptionID = &card.ID
+			uc.accessLogRepo.Create(accessLog)
+			return accessLog, errors.New("no visits left")
+		}
+		if upcoming := uc.upcomingSubscription(userID); upcoming != nil {
+			accessLog.Deny("La suscripción empieza el " + upcoming.StartDate.In(loc).Format("02/01/2006"))
+			accessLog.SubscriptionID = &upcoming.ID
+			uc.accessLogRepo.Create(accessLog)
+			return accessLog, errors.New("subscription not started yet")
+		}
 		accessLog.Deny("No active subscription")
 		uc.accessLogRepo.Create(accessLog)
 		return accessLog, errors.New("no active subscription")
@@ -70,9 +119,70 @@ func (uc *AccessUseCase) RecordEntry(userID, gymID uuid.UUID, method entities.Ac
 		return accessLog, errors.New("subscription expired or inactive")
 	}
 
+	// Off-peak and weekend plans only let the member in during their windows.
+	plan, err := uc.planRepo.FindByID(subscription.PlanID)
+	if err != nil {
+		accessLog := entities.NewAccessLog(gymID, userID, entities.AccessLogTypeEntry, method)
+		accessLog.Deny("No se encontró el plan de la suscripción")
+		accessLog.SubscriptionID = &subscription.ID
+		uc.accessLogRepo.Create(accessLog)
+		return accessLog, fmt.Errorf("plan de la suscripción: %w", err)
+	}
+	if now := time.Now().In(loc); !plan.AllowsEntryAt(now) {
+		accessLog := entities.NewAccessLog(gymID, userID, entities.AccessLogTypeEntry, method)
+		accessLog.Deny(fmt.Sprintf("Fuera del horario del plan '%s' (%s): %s a las %s",
+			plan.Name, plan.AccessSchedule(), entities.WeekdayName(now.Weekday()), now.Format("15:04")))
+		accessLog.SubscriptionID = &subscription.ID
+		uc.accessLogRepo.Create(accessLog)
+		return accessLog, errors.New("outside plan access hours")
+	}
+
+	// A balance still owed past its due date and the gym's grace days: the gym
+	// decides whether the member is turned away or let in with a warning.
+	var warning string
+	if subscription.Balance() > 0 {
+		gym, err := uc.gymRepo.FindByID(subscription.GymID)
+		if err != nil {
+			return nil, err
+		}
+		if subscription.BalanceOverdue(time.Now(), gym.OverdueBalanceGraceDays) {
+			reason := fmt.Sprintf("Saldo pendiente de %.0f, vencido el %s",
+				subscription.Balance(), subscription.BalanceDueDate.In(loc).Format("02/01/2006"))
+			switch gym.OverdueBalancePolicy {
+			case entities.OverdueBalanceDeny:
+				accessLog := entities.NewAccessLog(gymID, userID, entities.AccessLogTypeEntry, method)
+				accessLog.Deny(reason)
+				accessLog.SubscriptionID = &subscription.ID
+				uc.accessLogRepo.Create(accessLog)
+				return accessLog, errors.New("overdue balance")
+			case entities.OverdueBalanceIgnore:
+			default:
+				warning = reason
+			}
+		}
+	}
+
 	// Grant access
 	accessLog := entities.NewAccessLog(gymID, userID, entities.AccessLogTypeEntry, method)
 	accessLog.Grant()
+	accessLog.Warning = warning
+
+	// A punch card pays for the entry with one of its visits. The repository
+	// takes it conditionally, so two readers racing for the last visit cannot
+	// both get in.
+	if subscription.IsVisitBased() {
+		ok, err := uc.consumeVisit(subscription.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			accessLog.Deny(fmt.Sprintf("Sin entradas disponibles: ya se usaron las %d entradas del plan", subscription.VisitAllowance))
+			accessLog.SubscriptionID = &subscription.ID
+			uc.accessLogRepo.Create(accessLog)
+			return accessLog, errors.New("no visits left")
+		}
+		accessLog.Notes = fmt.Sprintf("Entrada %d de %d", subscription.VisitsUsed+1, subscription.VisitAllowance)
+	}
 
 	// Store subscription ID
 	accessLog.SubscriptionID = &subscription.ID
@@ -84,6 +194,132 @@ func (uc *AccessUseCase) RecordEntry(userID, gymID uuid.UUID, method entities.Ac
 	return accessLog, nil
 }
 
+// consumeVisit takes one entry of a punch card and, when it was the last one,
+// closes the card in the same transaction: EXPIRED through the transition
+// table, ending now, with its audit entry. Left ACTIVE with no entries it would
+// block selling the member a new one, and its end date would still be weeks
+// away for the next renewal to chain from. It reports false when no entry was
+// left to take.
+func (uc *AccessUseCase) consumeVisit(id uuid.UUID) (bool, error) {
+	consumed := false
+	err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		ok, err := r.Subscriptions.ConsumeVisit(id)
+		if err != nil || !ok {
+			consumed = false
+			return err
+		}
+		consumed = true
+
+		card, err := r.Subscriptions.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if !card.VisitsExhausted() {
+			return nil
+		}
+		before := card.Snapshot()
+		if err := card.Expire(); err != nil {
+			return err
+		}
+		card.EndDate = card.UpdatedAt
+		if err := r.Subscriptions.Update(card); err != nil {
+			return err
+		}
+		return r.Audit.Create(entities.NewSubscriptionAuditLog(card.ID, entities.AuditEventExpired, before, card.Snapshot(),
+			uuid.Nil, entities.SystemActor, fmt.Sprintf("Vencida: se usaron las %d entradas", card.VisitAllowance)))
+	})
+	return consumed, err
+}
+
+// exhaustedPunchCard returns the member's most recent subscription when it is a
+// punch card with no visits left, so a denied entry can say why instead of a
+// bare "no active subscription": using the last visit closes the card.
+func (uc *AccessUseCase) exhaustedPunchCard(userID uuid.UUID) *entities.Subscription {
+	var latest *entities.Subscription
+	if subs, err := uc.subscriptionRepo.FindByUserID(userID); err == nil && len(subs) > 0 {
+		latest = subs[0]
+	}
+	if subs, err := uc.memberRepo.FindSubscriptionsByMemberUserID(userID); err == nil {
+		for _, s := range subs {
+			if latest == nil || s.CreatedAt.After(latest.CreatedAt) {
+				latest = s
+			}
+		}
+	}
+	if latest != nil && latest.VisitsExhausted() {
+		return latest
+	}
+	return nil
+}
+
+// upcomingSubscription returns the member's earliest paid subscription that has
+// not started yet, directly or as a group beneficiary, or nil.
+func (uc *AccessUseCase) upcomingSubscription(userID uuid.UUID) *entities.Subscription {
+	var subs []*entities.Subscription
+	if own, err := uc.subscriptionRepo.FindByUserID(userID); err == nil {
+		subs = append(subs, own...)
+	}
+	if groups, err := uc.memberRepo.FindSubscriptionsByMemberUserID(userID); err == nil {
+		subs = append(subs, groups...)
+	}
+
+	now := time.Now()
+	var next *entities.Subscription
+	for _, s := range subs {
+		if s.StartsLater(now) && (next == nil || s.StartDate.Before(next.StartDate)) {
+			next = s
+		}
+	}
+	return next
+}
+
+// activateDue activates the member's pending subscription, own or group, whose
+// start date has passed, the way ActivateDueSubscriptions would on its next
+// pass, with its audit entry. It returns nil when there is none.
+func (uc *AccessUseCase) activateDue(userID uuid.UUID) (*entities.Subscription, error) {
+	var subs []*entities.Subscription
+	if own, err := uc.subscriptionRepo.FindByUserID(userID); err == nil {
+		subs = append(subs, own...)
+	}
+	if groups, err := uc.memberRepo.FindSubscriptionsByMemberUserID(userID); err == nil {
+		subs = append(subs, groups...)
+	}
+
+	now := time.Now()
+	for _, s := range subs {
+		if s.Status != entities.SubscriptionStatusPending || s.AwaitingPayment || now.Before(s.StartDate) || !now.Before(s.EndDate) {
+			continue
+		}
+		var activated *entities.Subscription
+		err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+			// Re-read inside the transaction: two readers at the door at once
+			// must not both activate it.
+			sub, err := r.Subscriptions.FindByID(s.ID)
+			if err != nil {
+				return err
+			}
+			before := sub.Snapshot()
+			if !sub.ActivateIfDue(now) {
+				activated = nil
+				return nil
+			}
+			if err := r.Subscriptions.Update(sub); err != nil {
+				return err
+			}
+			activated = sub
+			return r.Audit.Create(entities.NewSubscriptionAuditLog(sub.ID, entities.AuditEventActivated, before, sub.Snapshot(),
+				uuid.Nil, entities.SystemActor, "Inicio del periodo al registrar la entrada"))
+		})
+		if err != nil {
+			return nil, err
+		}
+		if activated != nil {
+			return activated, nil
+		}
+	}
+	return nil, nil
+}
+
 // RecordExit records a gym exit
 func (uc *AccessUseCase) RecordExit(userID, gymID uuid.UUID) (*entities.AccessLog, error) {
 	accessLog := entities.NewAccessLog(gymID, userID, entities.AccessLogTypeExit, entities.AccessLogMethodManual)
@@ -103,6 +339,71 @@ func (uc *AccessUseCase) GetTodayAccessByGym(gymID uuid.UUID, loc *time.Location
 	return uc.accessLogRepo.FindByDateRange(gymID, start, end)
 }
 
+// AccessStats summarises the entries of a range of local days. Days the gym
+// was closed are left out, of the days and of the average: a holiday would
+// otherwise read as the emptiest day of the month.
+type AccessStats struct {
+	From         string           `json:"from"`
+	To           string           `json:"to"`
+	Entries      int              `json:"entries"`
+	OpenDays     int              `json:"open_days"`
+	ClosedDays   []string         `json:"closed_days"`
+	DailyAverage float64          `json:"daily_average"`
+	ByDay        []AccessDayCount `json:"by_day"`
+}
+
+type AccessDayCount struct {
+	Date    string `json:"date"`
+	Entries int    `json:"entries"`
+}
+
+// GetStats counts the granted entries from one local day to another, both
+// included.
+func (uc *AccessUseCase) GetStats(gymID uuid.UUID, from, to string, loc *time.Location) (*AccessStats, error) {
+	start, err := timeutil.ParseLocalDate(from, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, err := timeutil.ParseLocalDateEndOfDay(to, loc)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := uc.accessLogRepo.FindByDateRange(gymID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	closures, err := uc.closureRepo.FindByDateRange(gymID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &AccessStats{From: from, To: to, ClosedDays: []string{}, ByDay: []AccessDayCount{}}
+	closed := make(map[string]bool, len(closures))
+	for _, c := range closures {
+		closed[c.Date] = true
+		stats.ClosedDays = append(stats.ClosedDays, c.Date)
+	}
+	perDay := make(map[string]int)
+	for _, l := range logs {
+		if l.AccessType == entities.AccessLogTypeEntry && l.Status == entities.AccessLogStatusGranted {
+			perDay[l.AccessTime.In(loc).Format("2006-01-02")]++
+		}
+	}
+	for day := start.In(loc); !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if closed[date] {
+			continue
+		}
+		stats.OpenDays++
+		stats.Entries += perDay[date]
+		stats.ByDay = append(stats.ByDay, AccessDayCount{Date: date, Entries: perDay[date]})
+	}
+	if stats.OpenDays > 0 {
+		stats.DailyAverage = float64(stats.Entries) / float64(stats.OpenDays)
+	}
+	return stats, nil
+}
+
 // GetAccessHistory gets access history for a gym with pagination
 func (uc *AccessUseCase) GetAccessHistory(gymID uuid.UUID, limit, offset int) ([]*entities.AccessLog, error) {
 	return uc.accessLogRepo.FindByGymID(gymID, limit, offset)
@@ -0,0 +1,294 @@
+package usecases_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// TestPunchCard_DeniesOnceVisitsRunOut sells a two-entry card and walks it to
+// the turnstile three times: the third must be refused with a reason reception
+// can read out, and the card must be closed so a new one can be sold.
+func TestPunchCard_DeniesOnceVisitsRunOut(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+
+	plan.VisitAllowance = 2
+	if err := persistence.NewSQLitePlanRepository(db).Update(plan); err != nil {
+		t.Fatalf("actualizando plan: %v", err)
+	}
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	accessUC := newAccessUseCase(db)
+	for i := 1; i <= 2; i++ {
+		if _, err := accessUC.RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC); err != nil {
+			t.Fatalf("entrada %d: %v", i, err)
+		}
+	}
+
+	log, err := accessUC.RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC)
+	if err == nil || log.IsGranted() {
+		t.Fatal("la tercera entrada de una tarjeta de 2 debería negarse")
+	}
+	if !strings.Contains(log.DenialReason, "Sin entradas disponibles") {
+		t.Errorf("DenialReason = %q, want el motivo de entradas agotadas", log.DenialReason)
+	}
+
+	card, err := subUC.GetSubscriptionsByUser(memberID)
+	if err != nil || len(card) != 1 || card[0].ID != sub.ID {
+		t.Fatalf("GetSubscriptionsByUser = %v, %v", card, err)
+	}
+	if card[0].VisitsUsed != 2 || card[0].Status != entities.SubscriptionStatusExpired {
+		t.Errorf("tarjeta usada %d veces en estado %s, want 2 y EXPIRED", card[0].VisitsUsed, card[0].Status)
+	}
+
+	logs, err := subUC.GetAuditLog(sub.ID)
+	if err != nil {
+		t.Fatalf("GetAuditLog: %v", err)
+	}
+	expired := 0
+	for _, l := range logs {
+		if l.Event == entities.AuditEventExpired && l.ChangedByName == entities.SystemActor {
+			expired++
+		}
+	}
+	if expired != 1 {
+		t.Errorf("entradas de auditoría de vencimiento = %d, want 1", expired)
+	}
+}
+
+// TestAccessWindow_DeniesOutsideThePlanSchedule also covers the round trip of
+// the windows through their JSON column.
+func TestAccessWindow_DeniesOutsideThePlanSchedule(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+
+	today := time.Now().UTC().Weekday()
+	plan.AccessWindows = []entities.AccessWindow{{Days: []time.Weekday{(today + 1) % 7}}}
+	if err := persistence.NewSQLitePlanRepository(db).Update(plan); err != nil {
+		t.Fatalf("actualizando plan: %v", err)
+	}
+	if _, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	log, err := newAccessUseCase(db).RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC)
+	if err == nil || log.IsGranted() {
+		t.Fatal("la entrada fuera del día del plan debería negarse")
+	}
+	if !strings.Contains(log.DenialReason, "Fuera del horario") {
+		t.Errorf("DenialReason = %q, want el motivo de horario", log.DenialReason)
+	}
+}
+
+// TestFutureStart_DeniesUntilTheJobActivatesIt sells a plan that starts in two
+// days: the member is told when it starts, and the hourly pass leaves it alone
+// until then.
+func TestFutureStart_DeniesUntilTheJobActivatesIt(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+
+	start := time.Now().Add(48 * time.Hour)
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, start, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if sub.Status != entities.SubscriptionStatusPending || !sub.StartDate.Equal(start) {
+		t.Fatalf("suscripción %s desde %v, want PENDING desde %v", sub.Status, sub.StartDate, start)
+	}
+
+	log, err := newAccessUseCase(db).RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC)
+	if err == nil || log.IsGranted() {
+		t.Fatal("la entrada antes del inicio debería negarse")
+	}
+	if want := "empieza el " + start.UTC().Format("02/01/2006"); !strings.Contains(log.DenialReason, want) {
+		t.Errorf("DenialReason = %q, want que diga %q", log.DenialReason, want)
+	}
+
+	if n, err := subUC.ActivateDueSubscriptions(); err != nil || n != 0 {
+		t.Fatalf("ActivateDueSubscriptions antes de tiempo = %d (err %v), want 0", n, err)
+	}
+
+	sub.StartDate = time.Now().Add(-time.Minute)
+	if err := subUC.UpdateSubscription(sub); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	if n, err := subUC.ActivateDueSubscriptions(); err != nil || n != 1 {
+		t.Fatalf("ActivateDueSubscriptions = %d (err %v), want 1", n, err)
+	}
+	if _, err := newAccessUseCase(db).RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC); err != nil {
+		t.Errorf("entrada tras la activación: %v", err)
+	}
+}
+
+// TestFutureStart_StartsAtTheDoorAndCannotBeStacked covers the two gaps around
+// a future-dated sale: a second one over the same days is refused, and once its
+// day comes the member gets in without waiting for the hourly job.
+func TestFutureStart_StartsAtTheDoorAndCannotBeStacked(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Now().Add(48*time.Hour), nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if _, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Now().Add(72*time.Hour), nil, memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("segunda suscripción sobre los mismos días: err = %v, want ErrConflict", err)
+	}
+
+	sub.StartDate = time.Now().Add(-time.Minute)
+	if err := subUC.UpdateSubscription(sub); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	log, err := newAccessUseCase(db).RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC)
+	if err != nil || !log.IsGranted() {
+		t.Fatalf("entrada el día del inicio: %v", err)
+	}
+	started, _ := subUC.GetSubscription(sub.ID)
+	if started.Status != entities.SubscriptionStatusActive || started.ActivatedAt == nil {
+		t.Errorf("suscripción %s (activada %v), want ACTIVE", started.Status, started.ActivatedAt)
+	}
+	if n, err := subUC.ActivateDueSubscriptions(); err != nil || n != 0 {
+		t.Errorf("ActivateDueSubscriptions tras la entrada = %d (err %v), want 0", n, err)
+	}
+}
+
+// TestOverdueBalance_WarnsOrDeniesPerGymPolicy sells half a plan on credit and
+// lets the due date pass: the gym's default only warns reception, DENY turns the
+// member away, and paying off the balance lets them in clean.
+func TestOverdueBalance_WarnsOrDeniesPerGymPolicy(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+
+	installments := &usecases.Installments{DownPayment: 50000, DueDate: time.Now().Add(time.Hour)}
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, installments, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if sub.AmountPaid != 50000 || sub.Balance() != 50000 {
+		t.Fatalf("pagado %v saldo %v, want 50000 y 50000", sub.AmountPaid, sub.Balance())
+	}
+
+	overdue := time.Now().AddDate(0, 0, -3)
+	sub.BalanceDueDate = &overdue
+	if err := subUC.UpdateSubscription(sub); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+
+	accessUC := newAccessUseCase(db)
+	log, err := accessUC.RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC)
+	if err != nil || !strings.Contains(log.Warning, "Saldo pendiente de 50000") {
+		t.Fatalf("con la política por defecto: err = %v, warning = %q", err, log.Warning)
+	}
+
+	gym, _ := gymRepo.FindByID(plan.GymID)
+	gym.OverdueBalancePolicy = entities.OverdueBalanceDeny
+	if err := gymRepo.Update(gym); err != nil {
+		t.Fatalf("actualizando gimnasio: %v", err)
+	}
+	if log, err := accessUC.RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC); err == nil || log.IsGranted() {
+		t.Fatal("con DENY la entrada con saldo vencido debería negarse")
+	}
+
+	if _, err := subUC.RecordPayment(sub.ID, 60000, "", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Fatalf("abono mayor que el saldo: err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := subUC.RecordPayment(sub.ID, 50000, "TRANSFER", memberID, "test", time.UTC); err != nil {
+		t.Fatalf("RecordPayment: %v", err)
+	}
+	log, err = accessUC.RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, time.UTC)
+	if err != nil || log.Warning != "" {
+		t.Fatalf("saldo pagado: err = %v, warning = %q", err, log.Warning)
+	}
+
+	payments, err := subUC.GetPayments(sub.ID)
+	if err != nil || len(payments) != 2 {
+		t.Fatalf("pagos de la suscripción = %d (err %v), want el inicial y el abono", len(payments), err)
+	}
+}
+
+// TestClosedDay_DeniesEntryAndGivesTheDayBack closes the gym today: the member
+// is turned away with the reason, the day drops out of the stats, and the job
+// gives it back once, under the gym's EXTEND setting, even with more gyms than
+// fit in a page before it.
+func TestClosedDay_DeniesEntryAndGivesTheDayBack(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	closureRepo := persistence.NewSQLiteGymClosureRepository(db)
+	closureUC := usecases.NewGymClosureUseCase(closureRepo, gymRepo, persistence.NewUnitOfWork(db))
+
+	// El job lee los gimnasios por páginas: este queda detrás de una página
+	// completa de otros.
+	for i := 0; i < 100; i++ {
+		other := entities.NewGym(fmt.Sprintf("Gym %d", i), fmt.Sprintf("gym%d@test.local", i), "3000000000")
+		if err := gymRepo.Create(other); err != nil {
+			t.Fatalf("creando gimnasio %d: %v", i, err)
+		}
+	}
+	gym, _ := gymRepo.FindByID(plan.GymID)
+	gym.ClosureCompensation = entities.ClosureCompensationExtend
+	gym.CreatedAt = time.Now().Add(time.Hour)
+	if err := gymRepo.Update(gym); err != nil {
+		t.Fatalf("actualizando gimnasio: %v", err)
+	}
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	bogota, _ := time.LoadLocation("America/Bogota")
+	today := time.Now().In(bogota).Format("2006-01-02")
+	closure, _ := entities.NewGymClosure(plan.GymID, today, "Festivo", memberID)
+	if err := closureUC.CreateClosure(closure); err != nil {
+		t.Fatalf("CreateClosure: %v", err)
+	}
+
+	accessUC := newAccessUseCase(db)
+	log, err := accessUC.RecordEntry(memberID, plan.GymID, entities.AccessLogMethodManual, bogota)
+	if err == nil || log.IsGranted() || !strings.Contains(log.DenialReason, "Festivo") {
+		t.Fatalf("entrada en día cerrado: %+v (err %v), want negada con el motivo", log, err)
+	}
+	stats, err := accessUC.GetStats(plan.GymID, today, today, bogota)
+	if err != nil || stats.OpenDays != 0 || len(stats.ClosedDays) != 1 {
+		t.Fatalf("GetStats = %+v (err %v), want el día fuera de las estadísticas", stats, err)
+	}
+
+	for run := 0; run < 2; run++ {
+		if _, err := closureUC.CompensateClosures(time.Now()); err != nil {
+			t.Fatalf("CompensateClosures: %v", err)
+		}
+	}
+	stored, _ := persistence.NewSQLiteSubscriptionRepository(db).FindByID(sub.ID)
+	if want := sub.EndDate.AddDate(0, 0, 1); !stored.EndDate.Equal(want) {
+		t.Errorf("EndDate = %v, want %v: un día más, una sola vez", stored.EndDate, want)
+	}
+	if err := closureUC.DeleteClosure(closure.ID); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("borrar un cierre compensado: err = %v, want ErrConflict", err)
+	}
+}
+
+func newAccessUseCase(db *gorm.DB) *usecases.AccessUseCase {
+	return usecases.NewAccessUseCase(
+		persistence.NewSQLiteAccessLogRepository(db),
+		persistence.NewSQLiteUserRepository(db),
+		persistence.NewSQLiteSubscriptionRepository(db),
+		persistence.NewSQLiteSubscriptionMemberRepository(db),
+		persistence.NewSQLitePlanRepository(db),
+		persistence.NewSQLiteGymRepository(db),
+		persistence.NewSQLiteGymClosureRepository(db),
+		persistence.NewUnitOfWork(db),
+	)
+}
@@ -0,0 +1,203 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// CashRegisterUseCase opens and closes the receptionists' shifts at the till.
+// The payments are tied to the open session by the use cases that take them,
+// through takenAtRegister and refundedAtRegister.
+type CashRegisterUseCase struct {
+	registerRepo repositories.CashRegisterRepository
+	paymentRepo  repositories.PaymentRepository
+	userRepo     repositories.UserRepository
+	uow          repositories.UnitOfWork
+}
+
+func NewCashRegisterUseCase(registerRepo repositories.CashRegisterRepository, paymentRepo repositories.PaymentRepository, userRepo repositories.UserRepository, uow repositories.UnitOfWork) *CashRegisterUseCase {
+	return &CashRegisterUseCase{
+		registerRepo: registerRepo,
+		paymentRepo:  paymentRepo,
+		userRepo:     userRepo,
+		uow:          uow,
+	}
+}
+
+// CashRegisterSummary is a session with what went through it: the figures of
+// the drawer, and the payments by method, cash or not.
+type CashRegisterSummary struct {
+	Session  *entities.CashRegisterSession `json:"session"`
+	CashIn   float64                       `json:"cash_in"`
+	CashOut  float64                       `json:"cash_out"`
+	Expected float64                       `json:"expected_cash"`
+	ByMethod []RevenueLine                 `json:"by_method"`
+	Payments []*entities.Payment           `json:"payments"`
+}
+
+// OpenSession opens a register for userID with openingFloat in the drawer. A
+// user with a register already open has to close it first.
+func (uc *CashRegisterUseCase) OpenSession(gymID, userID uuid.UUID, openingFloat float64, loc *time.Location) (*entities.CashRegisterSession, error) {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	session, err := entities.NewCashRegisterSession(gymID, userID, user.FullName(), openingFloat, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+	}
+
+	// The check and the insert in one transaction: two clicks on "open" must not
+	// leave the user with two drawers.
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		open, err := r.Registers.FindOpenByUser(userID)
+		if err != nil {
+			return err
+		}
+		if open != nil {
+			return fmt.Errorf("%w: ya tiene una caja abierta desde el %s a las %s", apperrors.ErrConflict, open.Date, open.Hour)
+		}
+		return r.Registers.Create(session)
+	}); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetCurrentSummary returns the register userID has open with its figures so
+// far, or nil when there is none.
+func (uc *CashRegisterUseCase) GetCurrentSummary(userID uuid.UUID) (*CashRegisterSummary, error) {
+	session, err := uc.registerRepo.FindOpenByUser(userID)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	return uc.summarize(session)
+}
+
+func (uc *CashRegisterUseCase) GetSummary(id uuid.UUID) (*CashRegisterSummary, error) {
+	session, err := uc.registerRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return uc.summarize(session)
+}
+
+func (uc *CashRegisterUseCase) ListSessions(gymID uuid.UUID, from, to string) ([]*entities.CashRegisterSession, error) {
+	return uc.registerRepo.FindByDateRange(gymID, from, to)
+}
+
+// CloseSession closes a register with the cash counted in the drawer, and
+// returns its summary with the expected cash and the variance.
+func (uc *CashRegisterUseCase) CloseSession(id uuid.UUID, counted float64, notes string) (*CashRegisterSummary, error) {
+	var summary *CashRegisterSummary
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		session, err := r.Registers.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if !session.IsOpen() {
+			return fmt.Errorf("%w: la caja ya se cerró", apperrors.ErrConflict)
+		}
+		payments, err := r.Payments.FindByCashRegisterID(session.ID)
+		if err != nil {
+			return err
+		}
+		summary = summarizeRegister(session, payments)
+		if err := session.Close(summary.CashIn, summary.CashOut, counted, notes); err != nil {
+			return fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+		}
+		return r.Registers.Update(session)
+	}); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (uc *CashRegisterUseCase) summarize(session *entities.CashRegisterSession) (*CashRegisterSummary, error) {
+	payments, err := uc.paymentRepo.FindByCashRegisterID(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeRegister(session, payments), nil
+}
+
+// summarizeRegister adds up the payments of a session. A payment taken in the
+// session counts in full even if it was refunded later: the refund is cash out
+// of the drawer it was given from, this one or another shift's.
+func summarizeRegister(session *entities.CashRegisterSession, payments []*entities.Payment) *CashRegisterSummary {
+	summary := &CashRegisterSummary{Session: session, Payments: payments}
+	byMethod := make(map[string]*RevenueLine)
+	for _, p := range payments {
+		name := string(p.PaymentMethod)
+		if name == "" {
+			name = "Otro"
+		}
+		if _, ok := byMethod[name]; !ok {
+			byMethod[name] = &RevenueLine{Name: name}
+		}
+		l := byMethod[name]
+
+		if p.CashRegisterID != nil && *p.CashRegisterID == session.ID && p.IsReturn() {
+			// A return of goods is given out of the drawer it was taken at.
+			l.Refunded -= p.Amount
+			l.Net += p.Amount
+			if p.IsCash() {
+				summary.CashOut -= p.Amount
+			}
+			continue
+		}
+		if p.CashRegisterID != nil && *p.CashRegisterID == session.ID && (p.IsCompleted() || p.IsRefunded()) {
+			l.Count++
+			l.Charged += p.Amount
+			l.Net += p.Amount
+			if p.IsCash() {
+				summary.CashIn += p.Amount
+			}
+		}
+		if p.RefundCashRegisterID != nil && *p.RefundCashRegisterID == session.ID && p.IsRefunded() {
+			l.Refunded += p.RefundedAmount
+			l.Net -= p.RefundedAmount
+			if p.IsCash() {
+				summary.CashOut += p.RefundedAmount
+			}
+		}
+	}
+	summary.Expected = session.OpeningFloat + summary.CashIn - summary.CashOut
+	summary.ByMethod = sortedRevenueLines(byMethod)
+	return summary
+}
+
+// takenAtRegister ties a payment to the register its ProcessedBy has open, if
+// any. Called inside the transaction that records the payment; payments nobody
+// took at a till (an automatic renewal) stay out of every register.
+func takenAtRegister(r repositories.Repos, payment *entities.Payment) error {
+	if payment.ProcessedBy == uuid.Nil {
+		return nil
+	}
+	session, err := r.Registers.FindOpenByUser(payment.ProcessedBy)
+	if err != nil || session == nil {
+		return err
+	}
+	payment.CashRegisterID = &session.ID
+	return nil
+}
+
+// refundedAtRegister ties the refund of a payment to the register userID has
+// open, the drawer the money is given back from.
+func refundedAtRegister(r repositories.Repos, payment *entities.Payment, userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return nil
+	}
+	session, err := r.Registers.FindOpenByUser(userID)
+	if err != nil || session == nil {
+		return err
+	}
+	payment.RefundCashRegisterID = &session.ID
+	return nil
+}
@@ -0,0 +1,73 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// TestCashRegister_ClosesWithTheVarianceOfTheShift opens a register, sells
+// twice in cash and voids one of the sales. The drawer should hold the float
+// plus what is left of the sales; counting less is a negative variance.
+func TestCashRegister_ClosesWithTheVarianceOfTheShift(t *testing.T) {
+	db := newTestDB(t)
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, uuid.Nil, 10)
+	registerUC := usecases.NewCashRegisterUseCase(persistence.NewSQLiteCashRegisterRepository(db),
+		persistence.NewSQLitePaymentRepository(db), persistence.NewSQLiteUserRepository(db), persistence.NewUnitOfWork(db))
+
+	session, err := registerUC.OpenSession(uuid.Nil, sellerID, 50000, time.UTC)
+	if err != nil {
+		t.Fatalf("OpenSession: %v", err)
+	}
+	if _, err := registerUC.OpenSession(uuid.Nil, sellerID, 0, time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("segunda apertura: err = %v, want ErrConflict", err)
+	}
+
+	sell := func(qty int) *entities.Sale {
+		sale := &entities.Sale{
+			UserID:          sellerID,
+			PaymentMethodID: paymentMethodID,
+			Details: []entities.SaleDetail{{
+				ProductID: product.ID,
+				UnitPrice: product.UnitPrice,
+				Quantity:  qty,
+			}},
+		}
+		if err := saleUC.CreateSale(context.Background(), uuid.Nil, sale); err != nil {
+			t.Fatalf("CreateSale: %v", err)
+		}
+		return sale
+	}
+	sell(3)
+	voided := sell(1)
+	if _, err := saleUC.VoidSale(context.Background(), uuid.Nil, voided.ID, sellerID, time.UTC); err != nil {
+		t.Fatalf("VoidSale: %v", err)
+	}
+
+	summary, err := registerUC.CloseSession(session.ID, 55000, "")
+	if err != nil {
+		t.Fatalf("CloseSession: %v", err)
+	}
+	closed := summary.Session
+	if closed.CashIn != 8000 || closed.CashOut != 2000 || closed.ExpectedCash != 56000 || closed.Variance != -1000 {
+		t.Errorf("cierre = entra %.0f, sale %.0f, esperado %.0f, diferencia %.0f; want 8000, 2000, 56000, -1000",
+			closed.CashIn, closed.CashOut, closed.ExpectedCash, closed.Variance)
+	}
+
+	if _, err := registerUC.CloseSession(session.ID, 55000, ""); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("segundo cierre: err = %v, want ErrConflict", err)
+	}
+	// Closed, the next sale is no longer the shift's.
+	after := sell(1)
+	payments, _ := persistence.NewSQLitePaymentRepository(db).FindBySaleID(after.ID)
+	if len(payments) != 1 || payments[0].CashRegisterID != nil {
+		t.Errorf("venta tras el cierre: payments = %+v, want uno sin caja", payments)
+	}
+}
@@ -0,0 +1,113 @@
+package usecases
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// CouponUseCase manages the gym's promo codes. Redeeming them is not done here
+// but by the subscription and sale use cases, inside the transaction of the
+// purchase they discount (see lookupCoupon and redeemCoupon).
+type CouponUseCase struct {
+	couponRepo repositories.CouponRepository
+}
+
+func NewCouponUseCase(couponRepo repositories.CouponRepository) *CouponUseCase {
+	return &CouponUseCase{
+		couponRepo: couponRepo,
+	}
+}
+
+func (uc *CouponUseCase) CreateCoupon(coupon *entities.Coupon) error {
+	coupon.Code = entities.NormalizeCouponCode(coupon.Code)
+	if err := coupon.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+	}
+	if existing, err := uc.couponRepo.FindByCode(coupon.GymID, coupon.Code); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("%w: ya existe un cupón con el código %s", apperrors.ErrDuplicate, coupon.Code)
+	}
+	return uc.couponRepo.Create(coupon)
+}
+
+func (uc *CouponUseCase) GetCouponByID(id uuid.UUID) (*entities.Coupon, error) {
+	return uc.couponRepo.FindByID(id)
+}
+
+func (uc *CouponUseCase) ListCoupons(gymID uuid.UUID) ([]*entities.Coupon, error) {
+	return uc.couponRepo.FindByGymID(gymID)
+}
+
+// UpdateCoupon saves an edited coupon. A new code must not be taken by another
+// coupon of the gym, the same rule CreateCoupon applies.
+func (uc *CouponUseCase) UpdateCoupon(coupon *entities.Coupon) error {
+	coupon.Code = entities.NormalizeCouponCode(coupon.Code)
+	if err := coupon.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+	}
+	if existing, err := uc.couponRepo.FindByCode(coupon.GymID, coupon.Code); err != nil {
+		return err
+	} else if existing != nil && existing.ID != coupon.ID {
+		return fmt.Errorf("%w: ya existe un cupón con el código %s", apperrors.ErrDuplicate, coupon.Code)
+	}
+	return uc.couponRepo.Update(coupon)
+}
+
+// DeactivateCoupon stops a coupon from being redeemed. Coupons are never
+// deleted: their redemptions still point at them.
+func (uc *CouponUseCase) DeactivateCoupon(id uuid.UUID) error {
+	coupon, err := uc.couponRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	coupon.Status = "INACTIVE"
+	return uc.couponRepo.Update(coupon)
+}
+
+func (uc *CouponUseCase) GetRedemptions(couponID uuid.UUID) ([]*entities.CouponRedemption, error) {
+	return uc.couponRepo.FindRedemptionsByCouponID(couponID)
+}
+
+// lookupCoupon resolves a code typed at the counter to a coupon that can be
+// redeemed at now. It fails with ErrInvalidCoupon, and the reason, otherwise.
+func lookupCoupon(repo repositories.CouponRepository, gymID uuid.UUID, code string, now time.Time) (*entities.Coupon, error) {
+	coupon, err := repo.FindByCode(gymID, code)
+	if err != nil {
+		return nil, err
+	}
+	if coupon == nil {
+		return nil, fmt.Errorf("%w: el cupón %s no existe", apperrors.ErrInvalidCoupon, entities.NormalizeCouponCode(code))
+	}
+	if err := coupon.CheckUsable(now); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// redeemCoupon records a redemption inside the transaction of the purchase it
+// discounts. Both limits are checked here, against the database, and not when
+// the coupon was looked up: between the two another till may have used it.
+func redeemCoupon(r repositories.Repos, coupon *entities.Coupon, redemption *entities.CouponRedemption) error {
+	if coupon.MaxUsesPerMember > 0 && redemption.UserID != nil {
+		used, err := r.Coupons.CountRedemptionsByUser(coupon.ID, *redemption.UserID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(coupon.MaxUsesPerMember) {
+			return fmt.Errorf("%w: el socio ya usó el cupón %s las %d veces permitidas", apperrors.ErrInvalidCoupon, coupon.Code, coupon.MaxUsesPerMember)
+		}
+	}
+	if err := r.Coupons.IncrementUses(coupon.ID); errors.Is(err, apperrors.ErrInvalidCoupon) {
+		return fmt.Errorf("%w: el cupón %s ya no tiene usos disponibles", err, coupon.Code)
+	} else if err != nil {
+		return err
+	}
+	return r.Coupons.CreateRedemption(redemption)
+}
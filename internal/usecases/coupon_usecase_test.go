@@ -0,0 +1,93 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// TestCoupon_DiscountsSubscriptionsAndSalesWithinItsLimits redeems one coupon
+// on a subscription and on a sale: the discount lands in TotalPaid and in the
+// sale lines, each use is recorded, and both limits are enforced.
+func TestCoupon_DiscountsSubscriptionsAndSalesWithinItsLimits(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, plan.GymID, 10)
+	couponRepo := persistence.NewSQLiteCouponRepository(db)
+
+	coupon := entities.NewCoupon(plan.GymID, "verano", entities.CouponKindPercent, 25)
+	coupon.MaxUses = 2
+	coupon.MaxUsesPerMember = 1
+	if err := couponRepo.Create(coupon); err != nil {
+		t.Fatalf("creando cupón: %v", err)
+	}
+
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "Verano ", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	// 25% del plan, nunca de la matrícula.
+	if want := plan.Price*0.75 + plan.EnrollmentFee; sub.TotalPaid != want || sub.CouponCode != "VERANO" {
+		t.Errorf("TotalPaid = %v con cupón %q, want %v con VERANO", sub.TotalPaid, sub.CouponCode, want)
+	}
+
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		MemberID:        &memberID,
+		CouponCode:      "VERANO",
+		Details:         []entities.SaleDetail{{ProductID: product.ID, Quantity: 2}},
+	}
+	err = saleUC.CreateSale(context.Background(), plan.GymID, sale)
+	if !errors.Is(err, apperrors.ErrInvalidCoupon) {
+		t.Fatalf("segundo uso del mismo socio: err = %v, want ErrInvalidCoupon", err)
+	}
+
+	other := seedMember(t, db, plan.GymID, "otro@test.local")
+	sale.MemberID = &other
+	sale.Details = []entities.SaleDetail{{ProductID: product.ID, Quantity: 2}}
+	if err := saleUC.CreateSale(context.Background(), plan.GymID, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+	if sale.Total != 3000 || sale.TotalDiscount != 1000 {
+		t.Errorf("venta total %v descuento %v, want 3000 y 1000", sale.Total, sale.TotalDiscount)
+	}
+
+	third := seedMember(t, db, plan.GymID, "tercero@test.local")
+	sale.MemberID = &third
+	sale.Details = []entities.SaleDetail{{ProductID: product.ID, Quantity: 1}}
+	if err := saleUC.CreateSale(context.Background(), plan.GymID, sale); !errors.Is(err, apperrors.ErrInvalidCoupon) {
+		t.Fatalf("tercer uso con máximo de 2: err = %v, want ErrInvalidCoupon", err)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	redemptions, err := couponRepo.FindRedemptionsByDateRange(plan.GymID, today, today)
+	if err != nil || len(redemptions) != 2 {
+		t.Fatalf("redenciones del día = %d (err %v), want 2", len(redemptions), err)
+	}
+	stored, _ := couponRepo.FindByID(coupon.ID)
+	if stored.TimesUsed != 2 {
+		t.Errorf("TimesUsed = %d, want 2", stored.TimesUsed)
+	}
+
+	couponUC := usecases.NewCouponUseCase(couponRepo)
+	winter := entities.NewCoupon(plan.GymID, "invierno", entities.CouponKindPercent, 10)
+	if err := couponUC.CreateCoupon(winter); err != nil {
+		t.Fatalf("CreateCoupon: %v", err)
+	}
+	winter.Code = " verano"
+	if err := couponUC.UpdateCoupon(winter); !errors.Is(err, apperrors.ErrDuplicate) {
+		t.Errorf("renombrar con el código de otro cupón: err = %v, want ErrDuplicate", err)
+	}
+	winter.Code = "invierno"
+	winter.Value = 15
+	if err := couponUC.UpdateCoupon(winter); err != nil {
+		t.Errorf("editar conservando su propio código: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package usecases
+
+import (
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/printer"
+)
+
+// BuildDailyCloseReport lets the tests check the figures of the daily close
+// without an SMTP server to send them to.
+var BuildDailyCloseReport = (*NotificationUseCase).buildDailyCloseReport
+
+// PrintTo makes uc print on p whatever receipt printer the gym has configured.
+func (uc *ReceiptUseCase) PrintTo(p printer.Printer) {
+	uc.open = func(*entities.Device) (printer.Printer, error) { return p, nil }
+}
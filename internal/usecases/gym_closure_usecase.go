@@ -0,0 +1,148 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+	"github.com/sebastiancorrales/gym-go/pkg/timeutil"
+)
+
+// gymPageSize is how many gyms CompensateClosures reads at a time.
+const gymPageSize = 100
+
+// GymClosureUseCase manages the days a gym is closed. Check-ins read the
+// calendar through AccessUseCase; here it is kept, and the days are given back
+// to the members when the gym asks for it.
+type GymClosureUseCase struct {
+	closureRepo repositories.GymClosureRepository
+	gymRepo     repositories.GymRepository
+	uow         repositories.UnitOfWork
+}
+
+func NewGymClosureUseCase(closureRepo repositories.GymClosureRepository, gymRepo repositories.GymRepository, uow repositories.UnitOfWork) *GymClosureUseCase {
+	return &GymClosureUseCase{
+		closureRepo: closureRepo,
+		gymRepo:     gymRepo,
+		uow:         uow,
+	}
+}
+
+func (uc *GymClosureUseCase) CreateClosure(closure *entities.GymClosure) error {
+	if existing, err := uc.closureRepo.FindByDate(closure.GymID, closure.Date); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("%w: el gimnasio ya está cerrado el %s", apperrors.ErrDuplicate, closure.Date)
+	}
+	return uc.closureRepo.Create(closure)
+}
+
+func (uc *GymClosureUseCase) GetClosureByID(id uuid.UUID) (*entities.GymClosure, error) {
+	return uc.closureRepo.FindByID(id)
+}
+
+func (uc *GymClosureUseCase) ListClosures(gymID uuid.UUID, from, to string) ([]*entities.GymClosure, error) {
+	return uc.closureRepo.FindByDateRange(gymID, from, to)
+}
+
+// DeleteClosure opens the gym again on a day it was going to close. Once the
+// day was given back to the members it can no longer be deleted.
+func (uc *GymClosureUseCase) DeleteClosure(id uuid.UUID) error {
+	closure, err := uc.closureRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if closure.CompensatedAt != nil {
+		return fmt.Errorf("%w: el cierre del %s ya se compensó a los socios", apperrors.ErrConflict, closure.Date)
+	}
+	return uc.closureRepo.Delete(id)
+}
+
+// CompensateClosures gives every member one more day per closed day, in the
+// gyms whose ClosureCompensation is EXTEND. It is run by the hourly job and
+// picks the closures up once their day has come in the gym's timezone, so a
+// subscription sold after the closure was announced is compensated too.
+//
+// Only subscriptions ACTIVE through the closed day are extended: a frozen one
+// already gets its end date pushed by the freeze, and one that ended before
+// the closure lost nothing. Each closure is a transaction of its own, with an
+// audit entry per subscription and the closure marked as compensated, so a run
+// that fails halfway never gives a day twice. Gyms are read a page at a time,
+// all of them. Returns how many subscriptions were extended.
+func (uc *GymClosureUseCase) CompensateClosures(now time.Time) (int, error) {
+	extended := 0
+	var firstErr error
+	for offset := 0; ; offset += gymPageSize {
+		gyms, err := uc.gymRepo.List(gymPageSize, offset)
+		if err != nil {
+			return extended, err
+		}
+		for _, gym := range gyms {
+			if gym.ClosureCompensation != entities.ClosureCompensationExtend {
+				continue
+			}
+			loc := timeutil.LoadLocationOrUTC(gym.Timezone)
+			closures, err := uc.closureRepo.FindUncompensated(gym.ID, now.In(loc).Format("2006-01-02"))
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			for _, closure := range closures {
+				n, err := uc.compensate(gym.ID, closure, loc)
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					continue
+				}
+				extended += n
+			}
+		}
+		if len(gyms) < gymPageSize {
+			return extended, firstErr
+		}
+	}
+}
+
+func (uc *GymClosureUseCase) compensate(gymID uuid.UUID, closure *entities.GymClosure, loc *time.Location) (int, error) {
+	start, end := closure.Bounds(loc)
+	day, _ := time.Parse("2006-01-02", closure.Date)
+	description := "Gimnasio cerrado el " + day.Format("02/01/2006")
+	if closure.Reason != "" {
+		description += ": " + closure.Reason
+	}
+
+	extended := 0
+	err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		extended = 0
+		subs, err := r.Subscriptions.FindToExtend(gymID, nil)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			if sub.Status != entities.SubscriptionStatusActive || sub.StartDate.After(end) || sub.EndDate.Before(start) {
+				continue
+			}
+			before := sub.Snapshot()
+			if err := sub.Extend(1); err != nil {
+				return err
+			}
+			if err := r.Subscriptions.Update(sub); err != nil {
+				return err
+			}
+			log := entities.NewSubscriptionAuditLog(sub.ID, entities.AuditEventExtended, before, sub.Snapshot(), uuid.Nil, entities.SystemActor, description)
+			if err := r.Audit.Create(log); err != nil {
+				return err
+			}
+			extended++
+		}
+		return r.Closures.MarkCompensated(closure.ID, timeutil.NowUTC())
+	})
+	return extended, err
+}
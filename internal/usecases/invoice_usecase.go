@@ -0,0 +1,489 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/dian"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/email"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// InvoiceUseCase issues the DIAN electronic invoices of the sales of the shop
+// and of the membership payments, numbered from the gym's active resolution.
+type InvoiceUseCase struct {
+	invoiceRepo      repositories.InvoiceRepository
+	resolutionRepo   repositories.InvoiceResolutionRepository
+	gymRepo          repositories.GymRepository
+	saleRepo         repositories.SaleRepository
+	saleDetailRepo   repositories.SaleDetailRepository
+	productRepo      repositories.ProductRepository
+	paymentRepo      repositories.PaymentRepository
+	subscriptionRepo repositories.SubscriptionRepository
+	planRepo         repositories.PlanRepository
+	userRepo         repositories.UserRepository
+	submitter        dian.Submitter
+	uow              repositories.UnitOfWork
+}
+
+func NewInvoiceUseCase(
+	invoiceRepo repositories.InvoiceRepository,
+	resolutionRepo repositories.InvoiceResolutionRepository,
+	gymRepo repositories.GymRepository,
+	saleRepo repositories.SaleRepository,
+	saleDetailRepo repositories.SaleDetailRepository,
+	productRepo repositories.ProductRepository,
+	paymentRepo repositories.PaymentRepository,
+	subscriptionRepo repositories.SubscriptionRepository,
+	planRepo repositories.PlanRepository,
+	userRepo repositories.UserRepository,
+	submitter dian.Submitter,
+	uow repositories.UnitOfWork,
+) *InvoiceUseCase {
+	return &InvoiceUseCase{
+		invoiceRepo:      invoiceRepo,
+		resolutionRepo:   resolutionRepo,
+		gymRepo:          gymRepo,
+		saleRepo:         saleRepo,
+		saleDetailRepo:   saleDetailRepo,
+		productRepo:      productRepo,
+		paymentRepo:      paymentRepo,
+		subscriptionRepo: subscriptionRepo,
+		planRepo:         planRepo,
+		userRepo:         userRepo,
+		submitter:        submitter,
+		uow:              uow,
+	}
+}
+
+// ResolutionInput is a numbering resolution as the DIAN issued it.
+type ResolutionInput struct {
+	ResolutionNumber string                      `json:"resolution_number" binding:"required"`
+	Prefix           string                      `json:"prefix"`
+	From             int64                       `json:"from" binding:"required,min=1"`
+	To               int64                       `json:"to" binding:"required,min=1"`
+	ValidFrom        string                      `json:"valid_from" binding:"required"`
+	ValidTo          string                      `json:"valid_to" binding:"required"`
+	TechnicalKey     string                      `json:"technical_key" binding:"required"`
+	Environment      entities.InvoiceEnvironment `json:"environment"`
+}
+
+// CreateResolution registers a new numbering resolution for the gym, which
+// replaces the one active until now.
+func (uc *InvoiceUseCase) CreateResolution(gymID uuid.UUID, in ResolutionInput) (*entities.InvoiceResolution, error) {
+	res, err := entities.NewInvoiceResolution(gymID, in.ResolutionNumber, strings.TrimSpace(in.Prefix), in.From, in.To,
+		in.ValidFrom, in.ValidTo, in.TechnicalKey, in.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+	}
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Resolutions.DeactivateByGym(gymID); err != nil {
+			return err
+		}
+		return r.Resolutions.Create(res)
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (uc *InvoiceUseCase) ListResolutions(gymID uuid.UUID) ([]*entities.InvoiceResolution, error) {
+	return uc.resolutionRepo.FindByGymID(gymID)
+}
+
+// InvoiceSale issues the invoice of a sale of the shop, to the member it was
+// sold to or to the final consumer, for what was kept of it: units returned
+// (see SaleUseCase.ReturnSale) are left out. A sale already invoiced returns
+// its invoice.
+func (uc *InvoiceUseCase) InvoiceSale(ctx context.Context, gymID, saleID uuid.UUID, loc *time.Location) (*entities.Invoice, error) {
+	if inv, err := uc.invoiceRepo.FindBySaleID(saleID); err != nil || inv != nil {
+		if inv != nil && inv.GymID != gymID {
+			return nil, apperrors.ErrNotFound
+		}
+		return inv, err
+	}
+	sale, err := uc.saleRepo.GetByID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	if sale == nil || sale.GymID != gymID {
+		return nil, apperrors.ErrNotFound
+	}
+	if !sale.IsNormal() || sale.Status != entities.SaleStatusCompleted {
+		return nil, fmt.Errorf("%w: solo se factura una venta completada, no una anulada ni una anulación", apperrors.ErrConflict)
+	}
+	if sale.Total <= 0 {
+		return nil, fmt.Errorf("%w: la venta no tiene valor a facturar", apperrors.ErrInvalidInput)
+	}
+	details, err := uc.saleDetailRepo.GetBySaleID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	returnedQty, returnedSubtotal, err := uc.returnedOf(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only what the customer kept is invoiced: the units returned and the
+	// money given back for them come off each line.
+	lines := make([]entities.InvoiceLine, 0, len(details))
+	for _, d := range details {
+		kept := d.Quantity - returnedQty[d.ID]
+		if kept <= 0 {
+			continue
+		}
+		description := d.ProductID.String()
+		if product, err := uc.productRepo.GetByID(ctx, d.ProductID); err == nil && product != nil {
+			description = product.Name
+		}
+		discount := d.Discount
+		if returnedQty[d.ID] > 0 {
+			discount = math.Max(0, math.Round((float64(kept)*d.UnitPrice-(d.Subtotal+returnedSubtotal[d.ID]))*100)/100)
+		}
+		lines = append(lines, entities.TaxedInvoiceLine(description, kept, d.UnitPrice, discount, d.TaxRate))
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: la venta se devolvió entera, no queda nada por facturar", apperrors.ErrConflict)
+	}
+
+	gym, err := uc.invoicingGym(gymID)
+	if err != nil {
+		return nil, err
+	}
+	inv := entities.NewInvoice(gymID, entities.InvoiceSourceSale, gym.Currency, lines)
+	inv.SaleID = &sale.ID
+	if sale.MemberID != nil {
+		uc.billTo(inv, *sale.MemberID)
+	}
+	return uc.issue(ctx, gym, inv, loc)
+}
+
+// returnedOf adds up, by line of the sale saleID, the units its returns gave
+// back and their subtotal, which is negative.
+func (uc *InvoiceUseCase) returnedOf(ctx context.Context, saleID uuid.UUID) (map[uuid.UUID]int, map[uuid.UUID]float64, error) {
+	returns, err := uc.saleRepo.GetReturns(ctx, saleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	returnIDs := make([]uuid.UUID, len(returns))
+	for i := range returns {
+		returnIDs[i] = returns[i].ID
+	}
+	returnedDetails, err := uc.saleDetailRepo.GetBySaleIDs(ctx, returnIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	qty := make(map[uuid.UUID]int)
+	subtotal := make(map[uuid.UUID]float64)
+	for _, lines := range returnedDetails {
+		for _, d := range lines {
+			if d.ReturnedDetailID != nil {
+				qty[*d.ReturnedDetailID] += d.Quantity
+				subtotal[*d.ReturnedDetailID] += d.Subtotal
+			}
+		}
+	}
+	return qty, subtotal, nil
+}
+
+// InvoicePayment issues the invoice of a membership payment: a subscription, a
+// payment of its balance, a freeze fee. The sales of the shop are invoiced
+// with their sale. A payment already invoiced returns its invoice.
+func (uc *InvoiceUseCase) InvoicePayment(ctx context.Context, gymID, paymentID uuid.UUID, loc *time.Location) (*entities.Invoice, error) {
+	if inv, err := uc.invoiceRepo.FindByPaymentID(paymentID); err != nil || inv != nil {
+		if inv != nil && inv.GymID != gymID {
+			return nil, apperrors.ErrNotFound
+		}
+		return inv, err
+	}
+	payment, err := uc.paymentRepo.FindByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.GymID != gymID {
+		return nil, apperrors.ErrNotFound
+	}
+	if payment.PaymentType == entities.PaymentTypeProduct {
+		return nil, fmt.Errorf("%w: el pago es de una venta, se factura con la venta", apperrors.ErrInvalidInput)
+	}
+	if !payment.IsCompleted() {
+		return nil, fmt.Errorf("%w: solo se factura un pago completado", apperrors.ErrConflict)
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	description := payment.Description
+	if payment.SubscriptionID != nil {
+		if sub, err := uc.subscriptionRepo.FindByID(*payment.SubscriptionID); err == nil {
+			if plan, err := uc.planRepo.FindByID(sub.PlanID); err == nil {
+				description = fmt.Sprintf("%s - %s (%s a %s)", description, plan.Name,
+					sub.StartDate.In(loc).Format("02/01/2006"), sub.EndDate.In(loc).Format("02/01/2006"))
+			}
+		}
+	}
+
+	gym, err := uc.invoicingGym(gymID)
+	if err != nil {
+		return nil, err
+	}
+	inv := entities.NewInvoice(gymID, entities.InvoiceSourcePayment, payment.Currency, []entities.InvoiceLine{
+		entities.TaxedInvoiceLine(description, 1, payment.Amount, 0, payment.TaxRate),
+	})
+	if inv.Currency == "" {
+		inv.Currency = gym.Currency
+	}
+	inv.PaymentID = &payment.ID
+	if payment.UserID != uuid.Nil {
+		uc.billTo(inv, payment.UserID)
+	}
+	return uc.issue(ctx, gym, inv, loc)
+}
+
+// PendingInvoices is the outcome of InvoicePending: what was issued and what
+// could not be, by source.
+type PendingInvoices struct {
+	Issued []*entities.Invoice `json:"issued"`
+	Failed map[string]string   `json:"failed,omitempty"`
+}
+
+// InvoicePending issues the invoices of every sale and membership payment
+// charged between the local dates from and to that has none yet. One that
+// fails does not stop the rest.
+func (uc *InvoiceUseCase) InvoicePending(ctx context.Context, gymID uuid.UUID, from, to string, loc *time.Location) (*PendingInvoices, error) {
+	payments, err := uc.paymentRepo.FindByDateRange(gymID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	result := &PendingInvoices{Failed: make(map[string]string)}
+	seenSales := make(map[uuid.UUID]bool)
+	for _, p := range payments {
+		// A return of goods is money given back, not a sale to invoice: its
+		// credit note is not issued here.
+		if !p.IsCompleted() || p.IsReturn() {
+			continue
+		}
+		var inv *entities.Invoice
+		var source string
+		switch {
+		case p.PaymentType == entities.PaymentTypeProduct && p.SaleID != nil:
+			// A sale paid with several tenders has a payment per tender.
+			if seenSales[*p.SaleID] {
+				continue
+			}
+			seenSales[*p.SaleID] = true
+			source = "sale:" + p.SaleID.String()
+			if existing, err := uc.invoiceRepo.FindBySaleID(*p.SaleID); err != nil || existing != nil {
+				continue
+			}
+			inv, err = uc.InvoiceSale(ctx, gymID, *p.SaleID, loc)
+		case p.PaymentType != entities.PaymentTypeProduct:
+			source = "payment:" + p.ID.String()
+			if existing, err := uc.invoiceRepo.FindByPaymentID(p.ID); err != nil || existing != nil {
+				continue
+			}
+			inv, err = uc.InvoicePayment(ctx, gymID, p.ID, loc)
+		default:
+			continue
+		}
+		if err != nil {
+			result.Failed[source] = err.Error()
+			continue
+		}
+		result.Issued = append(result.Issued, inv)
+	}
+	return result, nil
+}
+
+// Submit sends again an invoice the DIAN has not accepted.
+func (uc *InvoiceUseCase) Submit(ctx context.Context, id uuid.UUID) (*entities.Invoice, error) {
+	inv, err := uc.invoiceRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.IsAccepted() {
+		return nil, fmt.Errorf("%w: la factura %s ya fue aceptada", apperrors.ErrConflict, inv.FullNumber)
+	}
+	if err := uc.submit(ctx, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (uc *InvoiceUseCase) GetInvoice(id uuid.UUID) (*entities.Invoice, error) {
+	return uc.invoiceRepo.FindByID(id)
+}
+
+func (uc *InvoiceUseCase) ListInvoices(gymID uuid.UUID, from, to string) ([]*entities.Invoice, error) {
+	return uc.invoiceRepo.FindByDateRange(gymID, from, to)
+}
+
+// GetPDF renders the graphic representation of an invoice.
+func (uc *InvoiceUseCase) GetPDF(id uuid.UUID) ([]byte, error) {
+	inv, err := uc.invoiceRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	gym, err := uc.gymRepo.FindByID(inv.GymID)
+	if err != nil {
+		return nil, err
+	}
+	res := &entities.InvoiceResolution{}
+	if all, err := uc.resolutionRepo.FindByGymID(inv.GymID); err == nil {
+		for _, r := range all {
+			if r.ID == inv.ResolutionID {
+				res = r
+			}
+		}
+	}
+
+	nit, dv := dian.SplitNIT(gym.TaxID)
+	issued, _ := time.Parse("2006-01-02 15:04:05-07:00", inv.IssueDate+" "+inv.IssueTime)
+	doc := &email.InvoiceDocument{
+		GymName:   gym.Name,
+		LegalName: gym.LegalName,
+		TaxID:     nit + "-" + dv,
+		Address:   strings.TrimSpace(gym.Address + " " + gym.City),
+		Phone:     gym.Phone,
+		Email:     gym.Email,
+		Resolution: fmt.Sprintf("Resolución DIAN %s del %s al %s, prefijo %s del %d al %d",
+			res.ResolutionNumber, res.ValidFrom, res.ValidTo, res.Prefix, res.From, res.To),
+		Number:           inv.FullNumber,
+		IssueDate:        issued.Format("02/01/2006 15:04"),
+		CustomerName:     inv.CustomerName,
+		CustomerDocument: inv.CustomerDocument,
+		CustomerEmail:    inv.CustomerEmail,
+		Currency:         inv.Currency,
+		Subtotal:         inv.Subtotal,
+		Discount:         inv.Discount,
+		TaxTotal:         inv.TaxTotal,
+		Total:            inv.Total,
+		CUFE:             inv.CUFE,
+		VerifyURL:        dian.QRURL + inv.CUFE,
+		Status:           string(inv.Status),
+	}
+	for _, l := range inv.Lines {
+		doc.Lines = append(doc.Lines, email.InvoiceDocumentLine{
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			UnitPrice:   l.UnitPrice,
+			Discount:    l.Discount,
+			TaxRate:     l.TaxRate,
+			Total:       l.Total + l.TaxAmount,
+		})
+	}
+	return email.BuildInvoicePDF(doc)
+}
+
+// invoicingGym returns the gym if it can issue invoices: without its NIT there
+// is no invoice the DIAN takes.
+func (uc *InvoiceUseCase) invoicingGym(gymID uuid.UUID) (*entities.Gym, error) {
+	gym, err := uc.gymRepo.FindByID(gymID)
+	if err != nil {
+		return nil, err
+	}
+	if gym.TaxID == "" {
+		return nil, fmt.Errorf("%w: configure el NIT del gimnasio antes de facturar", apperrors.ErrInvalidInput)
+	}
+	if gym.Currency == "" {
+		gym.Currency = "COP"
+	}
+	return gym, nil
+}
+
+// billTo issues the invoice to a member with a document on file; without one
+// it stays with the final consumer.
+func (uc *InvoiceUseCase) billTo(inv *entities.Invoice, userID uuid.UUID) {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil || strings.TrimSpace(user.DocumentNumber) == "" {
+		return
+	}
+	inv.CustomerID = &user.ID
+	inv.CustomerName = user.FullName()
+	inv.CustomerDocType = dianDocumentType(user.DocumentType)
+	inv.CustomerDocument = strings.TrimSpace(user.DocumentNumber)
+	inv.CustomerEmail = user.Email
+}
+
+// dianDocumentType maps the document types reception types in to the DIAN's
+// codes (tabla 13.2.1 del anexo técnico). Cédula de ciudadanía by default.
+func dianDocumentType(docType string) string {
+	switch strings.ToUpper(strings.TrimSpace(docType)) {
+	case "RC":
+		return "11"
+	case "TI":
+		return "12"
+	case "TE":
+		return "21"
+	case "CE":
+		return "22"
+	case "NIT":
+		return "31"
+	case "PP", "PASAPORTE":
+		return "41"
+	case "PEP":
+		return "47"
+	}
+	return "13"
+}
+
+// issue numbers inv from the active resolution of the gym, generates its CUFE
+// and its XML and saves it, then submits it. The number is taken in the same
+// transaction that saves the invoice, so that a failure leaves no gap in the
+// numbering. A submission that fails leaves the invoice ISSUED, to be sent
+// again with Submit.
+func (uc *InvoiceUseCase) issue(ctx context.Context, gym *entities.Gym, inv *entities.Invoice, loc *time.Location) (*entities.Invoice, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	issuedAt := time.Now().In(loc).Truncate(time.Second)
+	nit, _ := dian.SplitNIT(gym.TaxID)
+
+	if err := uc.uow.Do(ctx, func(r repositories.Repos) error {
+		res, err := r.Resolutions.FindActiveByGym(gym.ID)
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			return fmt.Errorf("%w: el gimnasio no tiene una resolución de facturación activa", apperrors.ErrConflict)
+		}
+		number, err := res.Take(issuedAt.Format("2006-01-02"))
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperrors.ErrConflict, err)
+		}
+		inv.Numbered(res, number, issuedAt)
+		inv.CUFE = dian.CUFE(inv, nit, res.TechnicalKey, res.Environment)
+		xml, err := dian.BuildXML(inv, gym, res)
+		if err != nil {
+			return err
+		}
+		inv.XML = string(xml)
+		if err := r.Resolutions.Update(res); err != nil {
+			return err
+		}
+		return r.Invoices.Create(inv)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := uc.submit(ctx, inv); err != nil {
+		log.Printf("⚠️  invoice %s: submission failed, left ISSUED: %v", inv.FullNumber, err)
+	}
+	return inv, nil
+}
+
+func (uc *InvoiceUseCase) submit(ctx context.Context, inv *entities.Invoice) error {
+	result, err := uc.submitter.Submit(ctx, inv)
+	if err != nil {
+		return err
+	}
+	inv.Submitted(result.Accepted, result.TrackID, result.Message)
+	return uc.invoiceRepo.Update(inv)
+}
@@ -0,0 +1,215 @@
+package usecases_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/dian"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// TestInvoiceSale_NumbersInOrderOnceAndWithItsCUFE invoices two sales. They take
+// consecutive numbers of the resolution, invoicing a sale again returns the same
+// invoice, and the XML carries the number and the CUFE.
+func TestInvoiceSale_NumbersInOrderOnceAndWithItsCUFE(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	gym := entities.NewGym("Gym Test", "gym@test.local", "3000000000")
+	gym.LegalName = "Gym Test S.A.S."
+	gym.TaxID = "800197268"
+	if err := gymRepo.Create(gym); err != nil {
+		t.Fatalf("creando gym: %v", err)
+	}
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, gym.ID, 10)
+	invoiceUC := newInvoiceUseCase(db)
+
+	sell := func() *entities.Sale {
+		sale := &entities.Sale{
+			UserID:          sellerID,
+			PaymentMethodID: paymentMethodID,
+			Details:         []entities.SaleDetail{{ProductID: product.ID, UnitPrice: product.UnitPrice, Quantity: 2}},
+		}
+		if err := saleUC.CreateSale(ctx, gym.ID, sale); err != nil {
+			t.Fatalf("CreateSale: %v", err)
+		}
+		return sale
+	}
+	first, second := sell(), sell()
+
+	if _, err := invoiceUC.InvoiceSale(ctx, gym.ID, first.ID, time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("sin resolución: err = %v, want ErrConflict", err)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if _, err := invoiceUC.CreateResolution(gym.ID, usecases.ResolutionInput{
+		ResolutionNumber: "18760000001", Prefix: "SETP", From: 990000000, To: 995000000,
+		ValidFrom: today, ValidTo: today, TechnicalKey: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c",
+	}); err != nil {
+		t.Fatalf("CreateResolution: %v", err)
+	}
+
+	inv, err := invoiceUC.InvoiceSale(ctx, gym.ID, first.ID, time.UTC)
+	if err != nil {
+		t.Fatalf("InvoiceSale: %v", err)
+	}
+	if inv.FullNumber != "SETP990000000" || inv.Total != first.Total || !inv.IsAccepted() {
+		t.Errorf("factura = %s por %.0f (%s), want SETP990000000 por %.0f aceptada", inv.FullNumber, inv.Total, inv.Status, first.Total)
+	}
+	if len(inv.CUFE) != 96 || !strings.Contains(inv.XML, "<cbc:ID>SETP990000000</cbc:ID>") || !strings.Contains(inv.XML, inv.CUFE) {
+		t.Errorf("CUFE %q o XML sin número y CUFE", inv.CUFE)
+	}
+	if !strings.Contains(inv.XML, `schemeID="4" schemeName="31">800197268<`) {
+		t.Errorf("el XML no trae el NIT con su dígito de verificación 4")
+	}
+
+	again, err := invoiceUC.InvoiceSale(ctx, gym.ID, first.ID, time.UTC)
+	if err != nil || again.ID != inv.ID {
+		t.Errorf("refacturar la venta: %v, %v; want la misma factura", again, err)
+	}
+	next, err := invoiceUC.InvoiceSale(ctx, gym.ID, second.ID, time.UTC)
+	if err != nil || next.Number != inv.Number+1 {
+		t.Errorf("segunda venta: %v, %v; want el número %d", next, err, inv.Number+1)
+	}
+
+	pdf, err := invoiceUC.GetPDF(inv.ID)
+	if err != nil || !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Errorf("GetPDF: %v", err)
+	}
+}
+
+// TestInvoiceSale_OnlyByTheGymOfTheSale checks that another gym can neither
+// invoice a sale nor read its invoice, directly or through InvoicePending.
+func TestInvoiceSale_OnlyByTheGymOfTheSale(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	invoiceUC := newInvoiceUseCase(db)
+	today := time.Now().UTC().Format("2006-01-02")
+
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	owner := entities.NewGym("Gym Dueño", "dueno@test.local", "3000000000")
+	other := entities.NewGym("Otro Gym", "otro@test.local", "3000000001")
+	for _, gym := range []*entities.Gym{owner, other} {
+		gym.LegalName, gym.TaxID = gym.Name+" S.A.S.", "800197268"
+		if err := gymRepo.Create(gym); err != nil {
+			t.Fatalf("creando gym: %v", err)
+		}
+		if _, err := invoiceUC.CreateResolution(gym.ID, usecases.ResolutionInput{
+			ResolutionNumber: "18760000001", Prefix: "SETP", From: 990000000, To: 995000000,
+			ValidFrom: today, ValidTo: today, TechnicalKey: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c",
+		}); err != nil {
+			t.Fatalf("CreateResolution: %v", err)
+		}
+	}
+
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, owner.ID, 10)
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Details:         []entities.SaleDetail{{ProductID: product.ID, UnitPrice: product.UnitPrice, Quantity: 1}},
+	}
+	if err := saleUC.CreateSale(ctx, owner.ID, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+
+	if _, err := invoiceUC.InvoiceSale(ctx, other.ID, sale.ID, time.UTC); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("otro gym factura la venta: err = %v, want ErrNotFound", err)
+	}
+	pending, err := invoiceUC.InvoicePending(ctx, other.ID, today, today, time.UTC)
+	if err != nil || len(pending.Issued) != 0 || len(pending.Failed) != 0 {
+		t.Errorf("pendientes del otro gym = %+v (err %v), want nada", pending, err)
+	}
+
+	pending, err = invoiceUC.InvoicePending(ctx, owner.ID, today, today, time.UTC)
+	if err != nil || len(pending.Issued) != 1 || pending.Issued[0].GymID != owner.ID {
+		t.Fatalf("pendientes del dueño = %+v (err %v), want la factura de la venta", pending, err)
+	}
+	if _, err := invoiceUC.InvoiceSale(ctx, other.ID, sale.ID, time.UTC); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("otro gym pide la factura de la venta: err = %v, want ErrNotFound", err)
+	}
+}
+
+// TestInvoiceSale_LeavesOutWhatWasReturned invoices a sale after part of it
+// was returned: a line given back whole is left out, one given back in part is
+// invoiced for the units and the money kept, and a sale returned whole has
+// nothing to invoice.
+func TestInvoiceSale_LeavesOutWhatWasReturned(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	invoiceUC := newInvoiceUseCase(db)
+	today := time.Now().UTC().Format("2006-01-02")
+
+	gym := entities.NewGym("Gym Test", "gym@test.local", "3000000000")
+	gym.LegalName, gym.TaxID = "Gym Test S.A.S.", "800197268"
+	if err := persistence.NewSQLiteGymRepository(db).Create(gym); err != nil {
+		t.Fatalf("creando gym: %v", err)
+	}
+	if _, err := invoiceUC.CreateResolution(gym.ID, usecases.ResolutionInput{
+		ResolutionNumber: "18760000001", Prefix: "SETP", From: 990000000, To: 995000000,
+		ValidFrom: today, ValidTo: today, TechnicalKey: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c",
+	}); err != nil {
+		t.Fatalf("CreateResolution: %v", err)
+	}
+	saleUC, water, paymentMethodID, sellerID := seedPOS(t, db, gym.ID, 10)
+	towel := &entities.Product{ID: uuid.New(), GymID: gym.ID, Name: "Toalla", UnitPrice: 11900, TaxRate: 19,
+		Stock: 5, Status: entities.ProductStatusActive}
+	if err := persistence.NewSQLiteProductRepository(db).Create(ctx, towel); err != nil {
+		t.Fatalf("creando producto: %v", err)
+	}
+
+	sell := func(details ...entities.SaleDetail) *entities.Sale {
+		sale := &entities.Sale{UserID: sellerID, PaymentMethodID: paymentMethodID, Details: details}
+		if err := saleUC.CreateSale(ctx, gym.ID, sale); err != nil {
+			t.Fatalf("CreateSale: %v", err)
+		}
+		return sale
+	}
+	giveBack := func(sale *entities.Sale, items ...usecases.ReturnItem) *entities.Sale {
+		returned, err := saleUC.ReturnSale(ctx, gym.ID, sale.ID, sellerID, items, time.UTC)
+		if err != nil {
+			t.Fatalf("ReturnSale: %v", err)
+		}
+		return returned
+	}
+
+	// 3 aguas con 300 de descuento y una toalla: vuelven una agua y la toalla.
+	discounted := entities.SaleDetail{ProductID: water.ID, UnitPrice: water.UnitPrice, Quantity: 3, Discount: 300}
+	discounted.CalculateSubtotal()
+	sale := sell(discounted, entities.SaleDetail{ProductID: towel.ID, Quantity: 1})
+	returned := giveBack(sale,
+		usecases.ReturnItem{DetailID: sale.Details[0].ID, Quantity: 1},
+		usecases.ReturnItem{DetailID: sale.Details[1].ID, Quantity: 1})
+
+	inv, err := invoiceUC.InvoiceSale(ctx, gym.ID, sale.ID, time.UTC)
+	if err != nil {
+		t.Fatalf("InvoiceSale: %v", err)
+	}
+	if len(inv.Lines) != 1 || inv.Lines[0].Quantity != 2 || inv.Lines[0].TaxRate != 0 {
+		t.Fatalf("líneas = %+v, want solo las 2 aguas que quedaron", inv.Lines)
+	}
+	if want := sale.Total + returned.Total; inv.Total != want || inv.TaxTotal != 0 {
+		t.Errorf("factura por %.0f con IVA %.0f, want %.0f sin IVA", inv.Total, inv.TaxTotal, want)
+	}
+
+	whole := sell(entities.SaleDetail{ProductID: water.ID, Quantity: 1})
+	giveBack(whole, usecases.ReturnItem{DetailID: whole.Details[0].ID, Quantity: 1})
+	if _, err := invoiceUC.InvoiceSale(ctx, gym.ID, whole.ID, time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("venta devuelta entera: err = %v, want ErrConflict", err)
+	}
+}
+
+func newInvoiceUseCase(db *gorm.DB) *usecases.InvoiceUseCase {
+	return usecases.NewInvoiceUseCase(persistence.NewSQLiteInvoiceRepository(db), persistence.NewSQLiteInvoiceResolutionRepository(db),
+		persistence.NewSQLiteGymRepository(db), persistence.NewSQLiteSaleRepository(db), persistence.NewSQLiteSaleDetailRepository(db), persistence.NewSQLiteProductRepository(db),
+		persistence.NewSQLitePaymentRepository(db), persistence.NewSQLiteSubscriptionRepository(db), persistence.NewSQLitePlanRepository(db),
+		persistence.NewSQLiteUserRepository(db), dian.NewStubSubmitter(), persistence.NewUnitOfWork(db))
+}
@@ -26,6 +26,8 @@ type NotificationUseCase struct {
 	userRepo          repositories.UserRepository
 	paymentMethodRepo repositories.PaymentMethodRepository
 	productRepo       repositories.ProductRepository
+	paymentRepo       repositories.PaymentRepository
+	couponRepo        repositories.CouponRepository
 	emailSender       *email.Sender
 }
 
@@ -40,6 +42,8 @@ func NewNotificationUseCase(
 	userRepo repositories.UserRepository,
 	paymentMethodRepo repositories.PaymentMethodRepository,
 	productRepo repositories.ProductRepository,
+	paymentRepo repositories.PaymentRepository,
+	couponRepo repositories.CouponRepository,
 	emailSender *email.Sender,
 ) *NotificationUseCase {
 	return &NotificationUseCase{
@@ -52,6 +56,8 @@ func NewNotificationUseCase(
 		userRepo:          userRepo,
 		paymentMethodRepo: paymentMethodRepo,
 		productRepo:       productRepo,
+		paymentRepo:       paymentRepo,
+		couponRepo:        couponRepo,
 		emailSender:       emailSender,
 	}
 }
@@ -133,6 +139,10 @@ func (uc *NotificationUseCase) SendDailyClose(gymID uuid.UUID, startDate, endDat
 		TotalSubsAmount:  email.FmtAmt(report.TotalSubsAmount),
 		TotalSubsCount:   report.TotalSubsCount,
 		TotalRevenue:     email.FmtAmt(report.TotalRevenue),
+		TotalRefunds:     email.FmtAmt(-report.TotalRefunds),
+		TotalRefundCount: len(report.RefundItems),
+		TotalCoupons:     email.FmtAmt(report.TotalCouponDiscount),
+		TotalCouponCount: len(report.CouponItems),
 		Currency:         currency,
 	}
 	for _, pm := range report.PaymentMethods {
@@ -163,10 +173,6 @@ func (uc *NotificationUseCase) SendDailyClose(gymID uuid.UUID, startDate, endDat
 }
 
 // buildDailyCloseReport aggregates sales and subscription data for the given date range.
-//
-// NOTE: Sales are fetched by date range only (no gym filter) because the Sale
-// entity does not carry a gym_id. For single-gym installations this is correct.
-// If multi-gym support is needed in the future, add gym_id to the Sale entity.
 func (uc *NotificationUseCase) buildDailyCloseReport(
 	gymID uuid.UUID,
 	gym *entities.Gym,
@@ -179,15 +185,61 @@ func (uc *NotificationUseCase) buildDailyCloseReport(
 	startStr := startDate.In(loc).Format("2006-01-02")
 	endStr := endDate.In(loc).Format("2006-01-02")
 
-	sales, err := uc.saleRepo.GetByDateRange(ctx, startStr, endStr, nil)
+	// Every amount is read off the payments ledger: a charge counts on the day
+	// it was made and a refund on the day the money went back, which is what
+	// the till holds tonight. Sales and subscriptions are only looked up for
+	// the detail of their lines.
+	payments, err := uc.paymentRepo.FindByDateRange(gymID, startStr, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("fetching payments: %w", err)
+	}
+
+	refunds, err := uc.paymentRepo.FindRefundsByDateRange(gymID, startStr, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("fetching refunds: %w", err)
+	}
+
+	sales, err := uc.saleRepo.GetByDateRange(ctx, gymID, startStr, endStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("fetching sales: %w", err)
 	}
+	salesByID := make(map[uuid.UUID]*entities.Sale, len(sales))
+	for i := range sales {
+		salesByID[sales[i].ID] = &sales[i]
+	}
 
 	subs, err := uc.subscriptionRepo.FindByGymIDAndDateRange(gymID, startStr, endStr)
 	if err != nil {
 		return nil, fmt.Errorf("fetching subscriptions: %w", err)
 	}
+	subsByID := make(map[uuid.UUID]*entities.Subscription, len(subs))
+	for _, sub := range subs {
+		subsByID[sub.ID] = sub
+	}
+
+	// A charge refunded since still came in on its day: the refund is listed
+	// on its own.
+	var saleCharges, subCharges, otherCharges, saleReturns []*entities.Payment
+	for _, p := range payments {
+		if !p.IsCompleted() && !p.IsRefunded() {
+			continue
+		}
+		switch {
+		case p.IsReturn():
+			saleReturns = append(saleReturns, p)
+		case p.PaymentType == entities.PaymentTypeProduct && p.SaleID != nil && salesByID[*p.SaleID] != nil:
+			saleCharges = append(saleCharges, p)
+		case p.PaymentType == entities.PaymentTypeSubscription && p.SubscriptionID != nil && subsByID[*p.SubscriptionID] != nil:
+			subCharges = append(subCharges, p)
+		default:
+			otherCharges = append(otherCharges, p)
+		}
+	}
+
+	redemptions, err := uc.couponRepo.FindRedemptionsByDateRange(gymID, startStr, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("fetching coupon redemptions: %w", err)
+	}
 
 	currency := gym.Currency
 	if currency == "" {
@@ -220,17 +272,37 @@ func (uc *NotificationUseCase) buildDailyCloseReport(
 	// closing the till. Payment methods already had a cache; this extends the same
 	// idea to everything else, bringing the whole report down to a handful of
 	// queries.
-	saleIDs := make([]uuid.UUID, 0, len(sales))
-	for _, s := range sales {
-		saleIDs = append(saleIDs, s.ID)
+	// A sale paid with several tenders has a charge for each: the money goes
+	// to each method, the sale and its products are counted once.
+	saleIDs := make([]uuid.UUID, 0, len(saleCharges))
+	chargesBySale := make(map[uuid.UUID][]*entities.Payment, len(saleCharges))
+	for _, p := range saleCharges {
+		if _, ok := chargesBySale[*p.SaleID]; !ok {
+			saleIDs = append(saleIDs, *p.SaleID)
+		}
+		chargesBySale[*p.SaleID] = append(chargesBySale[*p.SaleID], p)
+	}
+	// The lines of the returns too: their units come off the products sold
+	// and their IVA off the IVA charged. Those of the sales refunded, for
+	// the IVA of the money given back.
+	detailSaleIDs := append([]uuid.UUID(nil), saleIDs...)
+	for _, p := range saleReturns {
+		if p.SaleID != nil {
+			detailSaleIDs = append(detailSaleIDs, *p.SaleID)
+		}
 	}
-	detailsBySale, err := uc.saleDetailRepo.GetBySaleIDs(ctx, saleIDs)
+	for _, p := range refunds {
+		if p.SaleID != nil {
+			detailSaleIDs = append(detailSaleIDs, *p.SaleID)
+		}
+	}
+	detailsBySale, err := uc.saleDetailRepo.GetBySaleIDs(ctx, detailSaleIDs)
 	if err != nil {
 		return nil, fmt.Errorf("loading sale details: %w", err)
 	}
 
 	productNames := make(map[uuid.UUID]string)
-	if products, err := uc.productRepo.GetAll(ctx, nil); err == nil {
+	if products, err := uc.productRepo.GetAll(ctx, gymID, nil); err == nil {
 		for _, p := range products {
 			productNames[p.ID] = p.Name
 		}
@@ -243,9 +315,23 @@ func (uc *NotificationUseCase) buildDailyCloseReport(
 		}
 	}
 
-	subUserIDs := make([]uuid.UUID, 0, len(subs))
-	for _, sub := range subs {
-		subUserIDs = append(subUserIDs, sub.UserID)
+	subUserIDs := make([]uuid.UUID, 0, len(subCharges)+len(otherCharges)+len(refunds))
+	for _, p := range subCharges {
+		subUserIDs = append(subUserIDs, p.UserID)
+	}
+	for _, p := range otherCharges {
+		subUserIDs = append(subUserIDs, p.UserID)
+	}
+	for _, p := range refunds {
+		subUserIDs = append(subUserIDs, p.UserID)
+	}
+	for _, p := range saleReturns {
+		subUserIDs = append(subUserIDs, p.UserID)
+	}
+	for _, cr := range redemptions {
+		if cr.UserID != nil {
+			subUserIDs = append(subUserIDs, *cr.UserID)
+		}
 	}
 	memberNames := make(map[uuid.UUID]string)
 	if users, err := uc.userRepo.FindByIDs(subUserIDs); err == nil {
@@ -253,6 +339,15 @@ func (uc *NotificationUseCase) buildDailyCloseReport(
 			memberNames[u.ID] = u.FirstName + " " + u.LastName
 		}
 	}
+	memberName := func(userID uuid.UUID) string {
+		if userID == uuid.Nil {
+			return "-"
+		}
+		if name, ok := memberNames[userID]; ok {
+			return name
+		}
+		return userID.String()
+	}
 
 	// Separate aggregation for subs vs sales per payment method
 	pmMap := make(map[string]*email.PaymentMethodSummary)
@@ -267,66 +362,85 @@ func (uc *NotificationUseCase) buildDailyCloseReport(
 		return pmMap[key]
 	}
 
+	// IVA by rate: of the sales from their details, a sale can mix rates; of
+	// the membership charges at the rate each was charged at.
+	var taxes []entities.TaxBreakdown
+
+	addProduct := func(d entities.SaleDetail, qty int) {
+		productName, ok := productNames[d.ProductID]
+		if !ok {
+			productName = d.ProductID.String()
+		}
+		key := strings.ToLower(strings.TrimSpace(productName))
+		if _, ok := productMap[key]; !ok {
+			productMap[key] = &email.ProductSummary{Name: productName}
+		}
+		productMap[key].Qty += qty
+		productMap[key].Revenue += d.Subtotal
+		taxes = entities.AddToBreakdown(taxes, d.TaxRate, d.TaxBase, d.TaxAmount)
+	}
+
 	// ── Sales ─────────────────────────────────────────────────────────────────
-	for _, s := range sales {
-		if s.Status != entities.SaleStatusCompleted || s.Type != entities.SaleTypeNormal {
-			continue
+	for _, saleID := range saleIDs {
+		s := salesByID[saleID]
+
+		amount := 0.0
+		pmNames := make([]string, 0, len(chargesBySale[saleID]))
+		for _, p := range chargesBySale[saleID] {
+			pmName := string(p.PaymentMethod)
+			if pmName == "" {
+				pmName = pmLookup(s.PaymentMethodID)
+			}
+			pm := ensurePM(pmName)
+			pm.SalesTotal += p.Amount
+			pm.SalesCount++
+			pm.Total += p.Amount
+			pm.Count++
+
+			amount += p.Amount
+			pmNames = append(pmNames, pmName)
 		}
 
-		report.TotalSalesAmount += s.Total
-		report.SalesGross += s.Total + s.TotalDiscount
+		report.TotalSalesAmount += amount
+		report.SalesGross += amount + s.TotalDiscount
 		report.SalesDiscount += s.TotalDiscount
 		report.TotalSalesCount++
 
-		pmName := pmLookup(s.PaymentMethodID)
-		pm := ensurePM(pmName)
-		pm.SalesTotal += s.Total
-		pm.SalesCount++
-		pm.Total += s.Total
-		pm.Count++
-
 		itemCount := 0
 		for _, d := range detailsBySale[s.ID] {
 			itemCount += d.Quantity
-			productName, ok := productNames[d.ProductID]
-			if !ok {
-				productName = d.ProductID.String()
-			}
-			key := strings.ToLower(strings.TrimSpace(productName))
-			if _, ok := productMap[key]; !ok {
-				productMap[key] = &email.ProductSummary{Name: productName}
-			}
-			productMap[key].Qty += d.Quantity
-			productMap[key].Revenue += d.Subtotal
+			addProduct(d, d.Quantity)
 		}
 
 		report.SaleItems = append(report.SaleItems, email.SaleLineItem{
 			Time:          s.SaleDate.In(loc).Format("15:04"),
 			ItemCount:     itemCount,
-			PaymentMethod: pmName,
-			Amount:        s.Total,
+			PaymentMethod: strings.Join(pmNames, " + "),
+			Amount:        amount,
 		})
 	}
 
 	// ── Subscriptions ─────────────────────────────────────────────────────────
-	for _, sub := range subs {
-		report.TotalSubsAmount += sub.TotalPaid
+	// The charge of a subscription sold in installments is its down payment;
+	// the rest is counted on the days it is paid (see Other charges below).
+	for _, p := range subCharges {
+		sub := subsByID[*p.SubscriptionID]
+		collected := p.Amount
+		report.TotalSubsAmount += collected
 		report.TotalSubsCount++
+		base, tax := entities.SplitTax(collected, p.TaxRate)
+		taxes = entities.AddToBreakdown(taxes, p.TaxRate, base, tax)
 
-		pmName := sub.PaymentMethod
+		pmName := string(p.PaymentMethod)
 		if pmName == "" {
 			pmName = "Suscripcion"
 		}
 		pm := ensurePM(pmName)
-		pm.SubsTotal += sub.TotalPaid
+		pm.SubsTotal += collected
 		pm.SubsCount++
-		pm.Total += sub.TotalPaid
+		pm.Total += collected
 		pm.Count++
 
-		memberName, ok := memberNames[sub.UserID]
-		if !ok {
-			memberName = sub.UserID.String()
-		}
 		planName, ok := planNames[sub.PlanID]
 		if !ok {
 			planName = sub.PlanID.String()
@@ -338,10 +452,10 @@ func (uc *NotificationUseCase) buildDailyCloseReport(
 			planMap[planKey] = &email.PlanSummary{Name: planName}
 		}
 		planMap[planKey].Qty++
-		planMap[planKey].Revenue += sub.TotalPaid
+		planMap[planKey].Revenue += collected
 
 		report.SubscriptionItems = append(report.SubscriptionItems, email.SubscriptionLineItem{
-			MemberName:    memberName,
+			MemberName:    memberName(sub.UserID),
 			PlanName:      planName,
 			StartDate:     sub.StartDate.In(loc).Format("02/01/2006"),
 			EndDate:       sub.EndDate.In(loc).Format("02/01/2006"),
@@ -349,12 +463,152 @@ func (uc *NotificationUseCase) buildDailyCloseReport(
 			Price:         sub.PricePaid,
 			EnrollmentFee: sub.EnrollmentFeePaid,
 			Discount:      sub.DiscountApplied,
-			TotalPaid:     sub.TotalPaid,
+			TotalPaid:     collected,
 			CreatedAt:     sub.CreatedAt.In(loc).Format("02/01/2006"),
 		})
 	}
 
-	report.TotalRevenue = report.TotalSalesAmount + report.TotalSubsAmount
+	// ── Other charges ─────────────────────────────────────────────────────────
+	// Payments of a balance, freeze fees: money in on a subscription that was
+	// not sold in the period, listed and counted with the subscriptions.
+	for _, p := range otherCharges {
+		report.TotalSubsAmount += p.Amount
+		base, tax := entities.SplitTax(p.Amount, p.TaxRate)
+		taxes = entities.AddToBreakdown(taxes, p.TaxRate, base, tax)
+
+		pmName := string(p.PaymentMethod)
+		if pmName == "" {
+			pmName = "Suscripcion"
+		}
+		pm := ensurePM(pmName)
+		pm.SubsTotal += p.Amount
+		pm.SubsCount++
+		pm.Total += p.Amount
+		pm.Count++
+
+		concept := p.Description
+		if p.PaymentType == entities.PaymentTypeInstallment {
+			concept = "Abono de saldo"
+		}
+		report.InstallmentItems = append(report.InstallmentItems, email.InstallmentLineItem{
+			MemberName:    memberName(p.UserID),
+			Concept:       concept,
+			PaymentMethod: pmName,
+			PaidAt:        p.PaymentDate.In(loc).Format("02/01/2006 15:04"),
+			Amount:        p.Amount,
+		})
+	}
+
+	// ── Returns ───────────────────────────────────────────────────────────────
+	// Goods given back on a sale: a negative line with the refunds, and their
+	// units and IVA off the products sold. Their lines are counted once, not
+	// per method the money went back through.
+	returnCounted := make(map[uuid.UUID]bool)
+	for _, p := range saleReturns {
+		report.TotalRefunds -= p.Amount
+
+		pmName := string(p.PaymentMethod)
+		if pmName == "" {
+			pmName = "Otro"
+		}
+		pm := ensurePM(pmName)
+		pm.RefundsTotal -= p.Amount
+		pm.RefundsCount++
+		pm.Total += p.Amount
+
+		if p.SaleID != nil && !returnCounted[*p.SaleID] {
+			returnCounted[*p.SaleID] = true
+			for _, d := range detailsBySale[*p.SaleID] {
+				addProduct(d, -d.Quantity)
+			}
+		}
+
+		report.RefundItems = append(report.RefundItems, email.RefundLineItem{
+			MemberName:    memberName(p.UserID),
+			Description:   p.Description,
+			PaymentMethod: pmName,
+			RefundedAt:    p.PaymentDate.In(loc).Format("02/01/2006 15:04"),
+			Amount:        p.Amount,
+		})
+	}
+
+	// ── Refunds ───────────────────────────────────────────────────────────────
+	// The IVA of the money given back comes off the IVA charged, as the sales
+	// report leaves voided sales out: a sale's at the rates of its lines,
+	// shared out by their subtotals, a membership's at the rate it was
+	// charged at.
+	for _, p := range refunds {
+		report.TotalRefunds += p.RefundedAmount
+
+		var details []entities.SaleDetail
+		saleTotal := 0.0
+		if p.SaleID != nil {
+			details = detailsBySale[*p.SaleID]
+		}
+		for _, d := range details {
+			saleTotal += d.Subtotal
+		}
+		if saleTotal > 0 {
+			for _, d := range details {
+				base, tax := entities.SplitTax(p.RefundedAmount*d.Subtotal/saleTotal, d.TaxRate)
+				taxes = entities.AddToBreakdown(taxes, d.TaxRate, -base, -tax)
+			}
+		} else {
+			base, tax := entities.SplitTax(p.RefundedAmount, p.TaxRate)
+			taxes = entities.AddToBreakdown(taxes, p.TaxRate, -base, -tax)
+		}
+
+		pmName := string(p.PaymentMethod)
+		if pmName == "" {
+			pmName = "Suscripcion"
+		}
+		pm := ensurePM(pmName)
+		pm.RefundsTotal += p.RefundedAmount
+		pm.RefundsCount++
+		pm.Total -= p.RefundedAmount
+
+		refundTime := ""
+		if p.RefundedAt != nil {
+			refundTime = p.RefundedAt.In(loc).Format("02/01/2006 15:04")
+		}
+		report.RefundItems = append(report.RefundItems, email.RefundLineItem{
+			MemberName:    memberName(p.UserID),
+			Description:   p.Description,
+			Reason:        p.RefundReason,
+			PaymentMethod: pmName,
+			RefundedAt:    refundTime,
+			Amount:        -p.RefundedAmount,
+		})
+	}
+
+	for _, t := range taxes {
+		report.Taxes = append(report.Taxes, email.TaxSummary{Rate: t.Rate, Base: t.Base, Tax: t.Tax})
+	}
+
+	// ── Coupons ───────────────────────────────────────────────────────────────
+	// Informative only: the discount is already out of the subscription and
+	// sale amounts above, so it is not subtracted again.
+	for _, cr := range redemptions {
+		report.TotalCouponDiscount += cr.Amount
+
+		redeemedBy := "-"
+		if cr.UserID != nil {
+			redeemedBy = memberName(*cr.UserID)
+		}
+		concept := "Venta"
+		if cr.SubscriptionID != nil {
+			concept = "Suscripcion"
+		}
+		report.CouponItems = append(report.CouponItems, email.CouponLineItem{
+			Code:       cr.Code,
+			MemberName: redeemedBy,
+			Concept:    concept,
+			RedeemedAt: cr.CreatedAt.In(loc).Format("02/01/2006 15:04"),
+			Amount:     cr.Amount,
+		})
+	}
+
+	report.TotalRevenue = report.TotalSalesAmount + report.TotalSubsAmount - report.TotalRefunds
 
 	// Plans sorted by quantity descending
 	for _, p := range planMap {
@@ -470,6 +724,110 @@ func (uc *NotificationUseCase) SendExpiringReminders(gymID uuid.UUID) (sent, err
 	return sent, errors, nil
 }
 
+// ──────────────────────────────────────────────────────────────────────────────
+// Cash register close
+// ──────────────────────────────────────────────────────────────────────────────
+
+// SendCashRegisterClose sends the summary of a closed register to the gym's
+// DAILY_CLOSE recipients: the same people who get the daily close want to know
+// which shift came up short.
+func (uc *NotificationUseCase) SendCashRegisterClose(summary *CashRegisterSummary, loc *time.Location) error {
+	session := summary.Session
+	if session.ClosedAt == nil {
+		return fmt.Errorf("the register of %s is still open", session.UserName)
+	}
+	gym, err := uc.gymRepo.FindByID(session.GymID)
+	if err != nil {
+		return fmt.Errorf("loading gym: %w", err)
+	}
+
+	sender := uc.resolvedSender(gym)
+	if !sender.IsConfigured() {
+		return fmt.Errorf("SMTP not configured for gym %q — configure SMTP in gym settings", gym.Name)
+	}
+
+	recipients, err := uc.recipientRepo.FindActiveByGymIDAndType(session.GymID, entities.NotificationTypeDailyClose)
+	if err != nil {
+		return fmt.Errorf("loading recipients: %w", err)
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no active DAILY_CLOSE recipients configured for gym %q", gym.Name)
+	}
+
+	currency := gym.Currency
+	if currency == "" {
+		currency = "COP"
+	}
+	data := email.CashRegisterCloseEmailData{
+		GymName:      gym.Name,
+		UserName:     session.UserName,
+		OpenedAt:     session.OpenedAt.In(loc).Format("02/01/2006 15:04"),
+		ClosedAt:     session.ClosedAt.In(loc).Format("02/01/2006 15:04"),
+		OpeningFloat: email.FmtAmt(session.OpeningFloat),
+		CashIn:       email.FmtAmt(session.CashIn),
+		CashOut:      email.FmtAmt(-session.CashOut),
+		ExpectedCash: email.FmtAmt(session.ExpectedCash),
+		CountedCash:  email.FmtAmt(session.CountedCash),
+		Variance:     email.FmtAmt(session.Variance),
+		Short:        session.Variance < 0,
+		Over:         session.Variance > 0,
+		Notes:        session.Notes,
+		Currency:     currency,
+	}
+	for _, m := range summary.ByMethod {
+		data.Methods = append(data.Methods, email.PaymentMethodRow{
+			Name:  m.Name,
+			Total: email.FmtAmt(m.Net),
+			Count: m.Count,
+		})
+	}
+
+	htmlBody, err := email.RenderCashRegisterCloseEmail(data)
+	if err != nil {
+		return fmt.Errorf("rendering email: %w", err)
+	}
+
+	toEmails := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		toEmails = append(toEmails, r.Email)
+	}
+	subject := fmt.Sprintf("%s - Cierre de caja de %s (%s)", gym.Name, session.UserName, data.ClosedAt)
+	return sender.Send(toEmails, subject, htmlBody)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Member statement
+// ──────────────────────────────────────────────────────────────────────────────
+
+// SendMemberStatement emails a member their account statement. from and to are
+// the period as printed on it (DD/MM/YYYY).
+func (uc *NotificationUseCase) SendMemberStatement(gym *entities.Gym, member *entities.User, from, to string, pdf []byte) error {
+	sender := uc.resolvedSender(gym)
+	if !sender.IsConfigured() {
+		return fmt.Errorf("SMTP not configured for gym %q — configure SMTP in gym settings", gym.Name)
+	}
+
+	htmlBody, err := email.RenderMemberStatementEmail(email.MemberStatementEmailData{
+		GymName:    gym.Name,
+		MemberName: member.FirstName,
+		From:       from,
+		To:         to,
+	})
+	if err != nil {
+		return fmt.Errorf("rendering email: %w", err)
+	}
+
+	filename := fmt.Sprintf("estado_de_cuenta_%s_%s.pdf",
+		strings.ReplaceAll(from, "/", "-"), strings.ReplaceAll(to, "/", "-"))
+	attachments := []email.Attachment{{
+		Filename:    filename,
+		ContentType: "application/pdf",
+		Data:        pdf,
+	}}
+	subject := fmt.Sprintf("%s - Estado de cuenta del %s al %s", gym.Name, from, to)
+	return sender.SendWithAttachments([]string{member.Email}, subject, htmlBody, attachments)
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 // Recipient management (thin wrappers over the repository)
 // ──────────────────────────────────────────────────────────────────────────────
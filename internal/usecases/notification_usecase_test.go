@@ -0,0 +1,145 @@
+package usecases_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	"gorm.io/gorm"
+)
+
+// TestDailyClose_AddsUpAMixedDay closes a day with a subscription sold and
+// refunded, another sold in installments with a balance payment, a sale and a
+// voided sale: every charge counts on its day, the refunds come off once with
+// their IVA, and what the payment methods hold adds up to the revenue.
+func TestDailyClose_AddsUpAMixedDay(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	saleUC, water, paymentMethodID, sellerID := seedPOS(t, db, plan.GymID, 10)
+
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	gym, _ := gymRepo.FindByID(plan.GymID)
+	gym.RefundPolicy = entities.RefundPolicyFull
+	if err := gymRepo.Update(gym); err != nil {
+		t.Fatalf("actualizando gimnasio: %v", err)
+	}
+
+	// 80000 + 20000 de matrícula, de los que se devuelven los 80000 del plan.
+	refunded, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if _, err := subUC.CancelSubscription(refunded.ID, "", memberID, "test", true, time.UTC); err != nil {
+		t.Fatalf("CancelSubscription: %v", err)
+	}
+
+	// 50000 de cuota inicial y un abono de 20000.
+	debtorID := seedMember(t, db, plan.GymID, "abonos@test.local")
+	installments := &usecases.Installments{DownPayment: 50000, DueDate: time.Now().AddDate(0, 0, 15)}
+	owing, err := subUC.CreateSubscription(debtorID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, installments, debtorID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription a cuotas: %v", err)
+	}
+	if _, err := subUC.RecordPayment(owing.ID, 20000, "CASH", debtorID, "test", time.UTC); err != nil {
+		t.Fatalf("RecordPayment: %v", err)
+	}
+
+	now := time.Now().UTC()
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Date:            now.Format("2006-01-02"),
+		Hour:            now.Format("15:04"),
+		Details:         []entities.SaleDetail{{ProductID: water.ID, UnitPrice: water.UnitPrice, Quantity: 3}},
+	}
+	if err := saleUC.CreateSale(ctx, plan.GymID, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+
+	// 11900 con 1900 de IVA, anulada: ni su dinero ni su IVA quedan en el día.
+	towel := &entities.Product{ID: uuid.New(), GymID: plan.GymID, Name: "Toalla", UnitPrice: 10000, TaxRate: 19,
+		TaxExcluded: true, Stock: 5, Status: entities.ProductStatusActive}
+	if err := persistence.NewSQLiteProductRepository(db).Create(ctx, towel); err != nil {
+		t.Fatalf("creando producto: %v", err)
+	}
+	voided := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Date:            now.Format("2006-01-02"),
+		Hour:            now.Format("15:04"),
+		Details:         []entities.SaleDetail{{ProductID: towel.ID, Quantity: 1}},
+	}
+	if err := saleUC.CreateSale(ctx, plan.GymID, voided); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+	if _, err := saleUC.VoidSale(ctx, plan.GymID, voided.ID, sellerID, time.UTC); err != nil {
+		t.Fatalf("VoidSale: %v", err)
+	}
+
+	report, err := usecases.BuildDailyCloseReport(newNotificationUseCase(db), plan.GymID, gym, now, now, time.UTC)
+	if err != nil {
+		t.Fatalf("buildDailyCloseReport: %v", err)
+	}
+
+	if report.TotalSalesAmount != 17900 || report.TotalSalesCount != 2 {
+		t.Errorf("ventas = %.0f en %d, want 17900 en 2", report.TotalSalesAmount, report.TotalSalesCount)
+	}
+	if report.TotalSubsAmount != 170000 || report.TotalSubsCount != 2 || len(report.InstallmentItems) != 1 {
+		t.Errorf("suscripciones = %.0f en %d, abonos %d; want 170000 en 2 y 1 abono",
+			report.TotalSubsAmount, report.TotalSubsCount, len(report.InstallmentItems))
+	}
+	if report.TotalRefunds != 91900 || len(report.RefundItems) != 2 {
+		t.Errorf("reembolsos = %.0f en %d, want 91900 en 2", report.TotalRefunds, len(report.RefundItems))
+	}
+	if report.TotalRevenue != 96000 {
+		t.Errorf("ingreso neto = %.0f, want 6000 + 170000 - 80000 = 96000", report.TotalRevenue)
+	}
+
+	held := 0.0
+	for _, pm := range report.PaymentMethods {
+		held += pm.Total
+	}
+	if held != report.TotalRevenue {
+		t.Errorf("los métodos de pago suman %.0f, want el ingreso neto %.0f", held, report.TotalRevenue)
+	}
+	if len(report.Plans) != 1 || report.Plans[0].Qty != 2 {
+		t.Errorf("planes = %+v, want Mensual vendido 2 veces", report.Plans)
+	}
+	for _, product := range report.Products {
+		if product.Name == water.Name && product.Qty != 3 {
+			t.Errorf("botellas de agua vendidas = %d, want 3", product.Qty)
+		}
+	}
+
+	// Lo mismo que el reporte de ventas, que deja fuera la venta anulada, más
+	// las suscripciones, que no llevan IVA.
+	taxes := map[float64][2]float64{}
+	for _, tx := range report.Taxes {
+		taxes[tx.Rate] = [2]float64{tx.Base, tx.Tax}
+	}
+	if taxes[0] != [2]float64{96000, 0} || taxes[19] != [2]float64{0, 0} {
+		t.Errorf("IVA del día = %+v, want base 96000 sin IVA y nada al 19%%", report.Taxes)
+	}
+}
+
+func newNotificationUseCase(db *gorm.DB) *usecases.NotificationUseCase {
+	return usecases.NewNotificationUseCase(
+		persistence.NewSQLiteNotificationRecipientRepository(db),
+		persistence.NewSQLiteSaleRepository(db),
+		persistence.NewSQLiteSaleDetailRepository(db),
+		persistence.NewSQLiteSubscriptionRepository(db),
+		persistence.NewSQLitePlanRepository(db),
+		persistence.NewSQLiteGymRepository(db),
+		persistence.NewSQLiteUserRepository(db),
+		persistence.NewSQLitePaymentMethodRepository(db),
+		persistence.NewSQLiteProductRepository(db),
+		persistence.NewSQLitePaymentRepository(db),
+		persistence.NewSQLiteCouponRepository(db),
+		nil,
+	)
+}
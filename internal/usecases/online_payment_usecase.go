@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/gateway"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// OnlinePaymentUseCase sells subscriptions paid through an online gateway: it
+// opens the checkout link and, when the gateway calls back, confirms the
+// payment and starts the subscription.
+type OnlinePaymentUseCase struct {
+	gateway             gateway.PaymentGateway
+	subscriptionUseCase *SubscriptionUseCase
+	paymentRepo         repositories.PaymentRepository
+	planRepo            repositories.PlanRepository
+	userRepo            repositories.UserRepository
+}
+
+func NewOnlinePaymentUseCase(gw gateway.PaymentGateway, subscriptionUseCase *SubscriptionUseCase, paymentRepo repositories.PaymentRepository, planRepo repositories.PlanRepository, userRepo repositories.UserRepository) *OnlinePaymentUseCase {
+	return &OnlinePaymentUseCase{
+		gateway:             gw,
+		subscriptionUseCase: subscriptionUseCase,
+		paymentRepo:         paymentRepo,
+		planRepo:            planRepo,
+		userRepo:            userRepo,
+	}
+}
+
+// Checkout is a subscription waiting to be paid at URL.
+type Checkout struct {
+	Subscription *entities.Subscription `json:"subscription"`
+	Payment      *entities.Payment      `json:"payment"`
+	URL          string                 `json:"url"`
+}
+
+// CreateSubscriptionCheckout sells a plan to be paid online and returns the
+// link to pay it at. The link is asked for once the sale is saved, outside its
+// transaction; if the gateway fails the sale is cancelled, so no subscription
+// is left waiting for a payment nobody can make.
+func (uc *OnlinePaymentUseCase) CreateSubscriptionCheckout(ctx context.Context, userID, planID, gymID uuid.UUID, couponCode string, additionalMemberIDs []uuid.UUID, startDate time.Time, changedByID uuid.UUID, changedByName string, loc *time.Location) (*Checkout, error) {
+	sub, payment, err := uc.subscriptionUseCase.CreateSubscriptionAwaitingPayment(userID, planID, gymID, couponCode,
+		string(uc.gateway.Method()), additionalMemberIDs, startDate, changedByID, changedByName, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	req := gateway.LinkRequest{
+		Reference: payment.ID.String(),
+		Title:     "Suscripción",
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+	}
+	if plan, err := uc.planRepo.FindByID(planID); err == nil {
+		req.Title = "Suscripción " + plan.Name
+	}
+	if user, err := uc.userRepo.FindByID(userID); err == nil {
+		req.PayerEmail = user.Email
+	}
+
+	link, err := uc.gateway.CreateLink(ctx, req)
+	if err != nil {
+		payment.Cancel()
+		if uErr := uc.paymentRepo.Update(payment); uErr != nil {
+			log.Printf("⚠️  checkout %s: cancelando el pago: %v", payment.ID, uErr)
+		}
+		if _, cErr := uc.subscriptionUseCase.CancelSubscription(sub.ID, "No se pudo generar el link de pago", changedByID, changedByName, false, loc); cErr != nil {
+			log.Printf("⚠️  checkout %s: cancelando la suscripción %s: %v", payment.ID, sub.ID, cErr)
+		}
+		return nil, fmt.Errorf("generando el link de pago: %w", err)
+	}
+
+	payment.ExternalReference = link.ID
+	if err := uc.paymentRepo.Update(payment); err != nil {
+		return nil, err
+	}
+	return &Checkout{Subscription: sub, Payment: payment, URL: link.URL}, nil
+}
+
+// HandleWebhook processes a call from the gateway. Only an approved payment
+// changes anything; a rejected one stays pending, since the member may try
+// again on the same link. It returns the payment the event was about, if any.
+func (uc *OnlinePaymentUseCase) HandleWebhook(ctx context.Context, header http.Header, body []byte) (*gateway.WebhookEvent, error) {
+	event, err := uc.gateway.ParseWebhook(ctx, header, body)
+	if err != nil || event == nil || event.Status != gateway.EventStatusApproved {
+		return event, err
+	}
+
+	paymentID, err := uuid.Parse(event.Reference)
+	if err != nil {
+		return event, fmt.Errorf("%w: referencia de pago %q", apperrors.ErrNotFound, event.Reference)
+	}
+	_, err = uc.subscriptionUseCase.ConfirmOnlinePayment(paymentID, event.TransactionID, event.Amount)
+	return event, err
+}
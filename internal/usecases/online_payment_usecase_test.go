@@ -0,0 +1,71 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/gateway"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+)
+
+// TestCheckout_WebhookConfirmsThePaymentAndStartsTheSubscription follows an
+// online sale: the subscription waits, unpaid, until a signed webhook says the
+// payment was approved. A forged call changes nothing and the gateway's
+// retries confirm nothing twice.
+func TestCheckout_WebhookConfirmsThePaymentAndStartsTheSubscription(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	paymentRepo := persistence.NewSQLitePaymentRepository(db)
+	fake := gateway.NewFakeGateway("http://localhost:8080", "secreto")
+	onlineUC := usecases.NewOnlinePaymentUseCase(fake, subUC, paymentRepo,
+		persistence.NewSQLitePlanRepository(db), persistence.NewSQLiteUserRepository(db))
+	loc, _ := time.LoadLocation("America/Bogota")
+
+	checkout, err := onlineUC.CreateSubscriptionCheckout(context.Background(), memberID, plan.ID, plan.GymID, "", nil, time.Time{}, uuid.Nil, "", loc)
+	if err != nil {
+		t.Fatalf("CreateSubscriptionCheckout: %v", err)
+	}
+	sub, payment := checkout.Subscription, checkout.Payment
+	if checkout.URL == "" || sub.Status != entities.SubscriptionStatusPending || !sub.AwaitingPayment || payment.Amount != 100000 {
+		t.Fatalf("checkout = %q, %s awaiting=%v, pago %.0f; want link, PENDING esperando el pago de 100000",
+			checkout.URL, sub.Status, sub.AwaitingPayment, payment.Amount)
+	}
+	if n, _ := subUC.ActivateDueSubscriptions(); n != 0 {
+		t.Errorf("ActivateDueSubscriptions activó %d sin pago, want 0", n)
+	}
+
+	body := fake.Event(payment.ID.String(), gateway.EventStatusApproved, payment.Amount)
+	forged := http.Header{}
+	forged.Set(gateway.FakeSignatureHeader, "00")
+	if _, err := onlineUC.HandleWebhook(context.Background(), forged, body); !errors.Is(err, gateway.ErrInvalidSignature) {
+		t.Fatalf("webhook sin firma válida: err = %v, want ErrInvalidSignature", err)
+	}
+
+	signed := http.Header{}
+	signed.Set(gateway.FakeSignatureHeader, fake.Sign(body))
+	for i := 0; i < 2; i++ {
+		if _, err := onlineUC.HandleWebhook(context.Background(), signed, body); err != nil {
+			t.Fatalf("webhook, entrega %d: %v", i+1, err)
+		}
+	}
+
+	sub, _ = subUC.GetSubscription(sub.ID)
+	if sub.Status != entities.SubscriptionStatusActive || sub.AwaitingPayment || sub.AmountPaid != 100000 {
+		t.Errorf("suscripción = %s awaiting=%v pagado %.0f, want ACTIVE pagada", sub.Status, sub.AwaitingPayment, sub.AmountPaid)
+	}
+	payment, _ = paymentRepo.FindByID(payment.ID)
+	if !payment.IsCompleted() || payment.Date == "" || payment.TransactionID == "" {
+		t.Errorf("pago = %s fecha %q tx %q, want COMPLETED con fecha y transacción", payment.Status, payment.Date, payment.TransactionID)
+	}
+	var confirmations int64
+	db.Model(&entities.SubscriptionAuditLog{}).Where("subscription_id = ? AND event = ?", sub.ID, entities.AuditEventPaymentRecorded).Count(&confirmations)
+	if confirmations != 1 {
+		t.Errorf("confirmaciones en la auditoría = %d, want 1 pese a la entrega repetida", confirmations)
+	}
+}
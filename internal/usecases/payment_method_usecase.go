@@ -22,14 +22,15 @@ func NewPaymentMethodUseCase(paymentMethodRepo repositories.PaymentMethodReposit
 	}
 }
 
-// CreatePaymentMethod creates a new payment method
-func (uc *PaymentMethodUseCase) CreatePaymentMethod(ctx context.Context, method *entities.SalePaymentMethod) error {
+// CreatePaymentMethod creates a new payment method of gymID
+func (uc *PaymentMethodUseCase) CreatePaymentMethod(ctx context.Context, gymID uuid.UUID, method *entities.SalePaymentMethod) error {
 	if method.Name == "" {
 		return errors.ErrInvalidInput
 	}
 
 	// Set default values
 	method.ID = uuid.New()
+	method.GymID = gymID
 	if method.Status == "" {
 		method.Status = entities.PaymentMethodStatusActive
 	}
@@ -39,18 +40,27 @@ func (uc *PaymentMethodUseCase) CreatePaymentMethod(ctx context.Context, method
 	return uc.paymentMethodRepo.Create(ctx, method)
 }
 
-// GetPaymentMethodByID retrieves a payment method by ID
-func (uc *PaymentMethodUseCase) GetPaymentMethodByID(ctx context.Context, id uuid.UUID) (*entities.SalePaymentMethod, error) {
-	return uc.paymentMethodRepo.GetByID(ctx, id)
+// GetPaymentMethodByID retrieves a payment method of gymID by ID. One of
+// another gym is ErrNotFound, as if it did not exist.
+func (uc *PaymentMethodUseCase) GetPaymentMethodByID(ctx context.Context, gymID, id uuid.UUID) (*entities.SalePaymentMethod, error) {
+	method, err := uc.paymentMethodRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if method == nil || method.GymID != gymID {
+		return nil, errors.ErrNotFound
+	}
+	return method, nil
 }
 
-// GetAllPaymentMethods retrieves all payment methods, optionally filtered by status
-func (uc *PaymentMethodUseCase) GetAllPaymentMethods(ctx context.Context, status *entities.PaymentMethodStatus) ([]entities.SalePaymentMethod, error) {
-	return uc.paymentMethodRepo.GetAll(ctx, status)
+// GetAllPaymentMethods retrieves the payment methods of gymID, optionally
+// filtered by status
+func (uc *PaymentMethodUseCase) GetAllPaymentMethods(ctx context.Context, gymID uuid.UUID, status *entities.PaymentMethodStatus) ([]entities.SalePaymentMethod, error) {
+	return uc.paymentMethodRepo.GetAll(ctx, gymID, status)
 }
 
-// UpdatePaymentMethod updates an existing payment method
-func (uc *PaymentMethodUseCase) UpdatePaymentMethod(ctx context.Context, method *entities.SalePaymentMethod) error {
+// UpdatePaymentMethod updates an existing payment method of gymID
+func (uc *PaymentMethodUseCase) UpdatePaymentMethod(ctx context.Context, gymID uuid.UUID, method *entities.SalePaymentMethod) error {
 	if method.ID == uuid.Nil {
 		return errors.ErrInvalidInput
 	}
@@ -59,30 +69,24 @@ func (uc *PaymentMethodUseCase) UpdatePaymentMethod(ctx context.Context, method
 	}
 
 	// Check if payment method exists
-	existing, err := uc.paymentMethodRepo.GetByID(ctx, method.ID)
+	existing, err := uc.GetPaymentMethodByID(ctx, gymID, method.ID)
 	if err != nil {
 		return err
 	}
-	if existing == nil {
-		return errors.ErrNotFound
-	}
 
+	method.GymID = existing.GymID
 	method.UpdatedAt = time.Now().UTC().Round(0)
 	method.CreatedAt = existing.CreatedAt // Preserve creation date
 
 	return uc.paymentMethodRepo.Update(ctx, method)
 }
 
-// DeletePaymentMethod deletes a payment method
-func (uc *PaymentMethodUseCase) DeletePaymentMethod(ctx context.Context, id uuid.UUID) error {
+// DeletePaymentMethod deletes a payment method of gymID
+func (uc *PaymentMethodUseCase) DeletePaymentMethod(ctx context.Context, gymID, id uuid.UUID) error {
 	// Check if payment method exists
-	existing, err := uc.paymentMethodRepo.GetByID(ctx, id)
-	if err != nil {
+	if _, err := uc.GetPaymentMethodByID(ctx, gymID, id); err != nil {
 		return err
 	}
-	if existing == nil {
-		return errors.ErrNotFound
-	}
 
 	return uc.paymentMethodRepo.Delete(ctx, id)
 }
@@ -0,0 +1,116 @@
+package usecases
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+)
+
+// PaymentUseCase reads the payments ledger. Payments are written by the use
+// cases that charge them, inside the transaction of what they pay for.
+type PaymentUseCase struct {
+	paymentRepo repositories.PaymentRepository
+}
+
+func NewPaymentUseCase(paymentRepo repositories.PaymentRepository) *PaymentUseCase {
+	return &PaymentUseCase{
+		paymentRepo: paymentRepo,
+	}
+}
+
+// ListPayments returns the payments charged between the local dates from and
+// to, both included.
+func (uc *PaymentUseCase) ListPayments(gymID uuid.UUID, from, to string) ([]*entities.Payment, error) {
+	return uc.paymentRepo.FindByDateRange(gymID, from, to)
+}
+
+// Revenue is the money in and out of a range of local days, the same figures
+// as the daily close: charges count on the day they were made, refunds on the
+// day the money went back.
+type Revenue struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Charged  float64       `json:"charged"`
+	Refunded float64       `json:"refunded"`
+	Net      float64       `json:"net"`
+	ByMethod []RevenueLine `json:"by_method"`
+	ByType   []RevenueLine `json:"by_type"`
+}
+
+// RevenueLine is one method or one type of payment within Revenue.
+type RevenueLine struct {
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Charged  float64 `json:"charged"`
+	Refunded float64 `json:"refunded"`
+	Net      float64 `json:"net"`
+}
+
+func (uc *PaymentUseCase) GetRevenue(gymID uuid.UUID, from, to string) (*Revenue, error) {
+	charges, err := uc.paymentRepo.FindByDateRange(gymID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := uc.paymentRepo.FindRefundsByDateRange(gymID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	revenue := &Revenue{From: from, To: to}
+	byMethod := make(map[string]*RevenueLine)
+	byType := make(map[string]*RevenueLine)
+	line := func(lines map[string]*RevenueLine, name string) *RevenueLine {
+		if name == "" {
+			name = "Otro"
+		}
+		if _, ok := lines[name]; !ok {
+			lines[name] = &RevenueLine{Name: name}
+		}
+		return lines[name]
+	}
+
+	for _, p := range charges {
+		if !p.IsCompleted() && !p.IsRefunded() {
+			continue
+		}
+		if p.IsReturn() {
+			// Goods given back: money out on the day of the return, as a
+			// refund is.
+			revenue.Refunded -= p.Amount
+			for _, l := range []*RevenueLine{line(byMethod, string(p.PaymentMethod)), line(byType, string(p.PaymentType))} {
+				l.Refunded -= p.Amount
+				l.Net += p.Amount
+			}
+			continue
+		}
+		revenue.Charged += p.Amount
+		for _, l := range []*RevenueLine{line(byMethod, string(p.PaymentMethod)), line(byType, string(p.PaymentType))} {
+			l.Count++
+			l.Charged += p.Amount
+			l.Net += p.Amount
+		}
+	}
+	for _, p := range refunds {
+		revenue.Refunded += p.RefundedAmount
+		for _, l := range []*RevenueLine{line(byMethod, string(p.PaymentMethod)), line(byType, string(p.PaymentType))} {
+			l.Refunded += p.RefundedAmount
+			l.Net -= p.RefundedAmount
+		}
+	}
+	revenue.Net = revenue.Charged - revenue.Refunded
+
+	revenue.ByMethod = sortedRevenueLines(byMethod)
+	revenue.ByType = sortedRevenueLines(byType)
+	return revenue, nil
+}
+
+func sortedRevenueLines(lines map[string]*RevenueLine) []RevenueLine {
+	sorted := make([]RevenueLine, 0, len(lines))
+	for _, l := range lines {
+		sorted = append(sorted, *l)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Net > sorted[j].Net })
+	return sorted
+}
@@ -1,9 +1,12 @@
 package usecases
 
 import (
+	"fmt"
+
 	"github.com/google/uuid"
 	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
 	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
 )
 
 type PlanUseCase struct {
@@ -16,16 +19,26 @@ func NewPlanUseCase(planRepo repositories.PlanRepository) *PlanUseCase {
 	}
 }
 
-func (uc *PlanUseCase) CreatePlan(gymID uuid.UUID, name, description string, durationDays int, price, enrollmentFee float64, maxMembers int, billingMode string) (*entities.Plan, error) {
+func (uc *PlanUseCase) CreatePlan(gymID uuid.UUID, name, description string, durationDays int, price, enrollmentFee, taxRate float64, taxExcluded bool, maxMembers int, billingMode string, visitAllowance int, accessWindows []entities.AccessWindow, freeze entities.FreezePolicy) (*entities.Plan, error) {
 	plan := entities.NewPlan(gymID, name, durationDays, price)
 	plan.Description = description
 	plan.EnrollmentFee = enrollmentFee
+	plan.TaxRate = taxRate
+	plan.TaxExcluded = taxExcluded
+	if visitAllowance > 0 {
+		plan.VisitAllowance = visitAllowance
+	}
 	if maxMembers > 0 {
 		plan.MaxMembers = maxMembers
 	}
 	if billingMode == "30_DAYS" || billingMode == "CALENDAR_MONTH" {
 		plan.BillingMode = entities.BillingMode(billingMode)
 	}
+	plan.AccessWindows = accessWindows
+	plan.Freeze = freeze
+	if err := plan.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+	}
 
 	if err := uc.planRepo.Create(plan); err != nil {
 		return nil, err
@@ -60,6 +73,9 @@ func (uc *PlanUseCase) ListActivePlansByGym(gymID uuid.UUID) ([]*entities.Plan,
 }
 
 func (uc *PlanUseCase) UpdatePlan(plan *entities.Plan) error {
+	if err := plan.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+	}
 	return uc.planRepo.Update(plan)
 }
 
@@ -2,6 +2,7 @@ package usecases
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,20 +23,24 @@ func NewProductUseCase(productRepo repositories.ProductRepository) *ProductUseCa
 	}
 }
 
-// CreateProduct creates a new product
-func (uc *ProductUseCase) CreateProduct(ctx context.Context, product *entities.Product) error {
+// CreateProduct creates a new product in the inventory of gymID
+func (uc *ProductUseCase) CreateProduct(ctx context.Context, gymID uuid.UUID, product *entities.Product) error {
 	if product.Name == "" {
 		return errors.ErrInvalidInput
 	}
 	if product.UnitPrice < 0 {
 		return errors.ErrInvalidPrice
 	}
+	if err := entities.ValidateTaxRate(product.TaxRate); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+	}
 	if product.Stock < 0 {
 		return errors.ErrInvalidQuantity
 	}
 
 	// Set default values
 	product.ID = uuid.New()
+	product.GymID = gymID
 	if product.Status == "" {
 		product.Status = entities.ProductStatusActive
 	}
@@ -45,18 +50,26 @@ func (uc *ProductUseCase) CreateProduct(ctx context.Context, product *entities.P
 	return uc.productRepo.Create(ctx, product)
 }
 
-// GetProductByID retrieves a product by ID
-func (uc *ProductUseCase) GetProductByID(ctx context.Context, id uuid.UUID) (*entities.Product, error) {
-	return uc.productRepo.GetByID(ctx, id)
+// GetProductByID retrieves a product of gymID by ID. One of another gym is
+// ErrNotFound, as if it did not exist.
+func (uc *ProductUseCase) GetProductByID(ctx context.Context, gymID, id uuid.UUID) (*entities.Product, error) {
+	product, err := uc.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.GymID != gymID {
+		return nil, errors.ErrNotFound
+	}
+	return product, nil
 }
 
-// GetAllProducts retrieves all products, optionally filtered by status
-func (uc *ProductUseCase) GetAllProducts(ctx context.Context, status *entities.ProductStatus) ([]entities.Product, error) {
-	return uc.productRepo.GetAll(ctx, status)
+// GetAllProducts retrieves the products of gymID, optionally filtered by status
+func (uc *ProductUseCase) GetAllProducts(ctx context.Context, gymID uuid.UUID, status *entities.ProductStatus) ([]entities.Product, error) {
+	return uc.productRepo.GetAll(ctx, gymID, status)
 }
 
-// UpdateProduct updates an existing product
-func (uc *ProductUseCase) UpdateProduct(ctx context.Context, product *entities.Product) error {
+// UpdateProduct updates an existing product of gymID
+func (uc *ProductUseCase) UpdateProduct(ctx context.Context, gymID uuid.UUID, product *entities.Product) error {
 	if product.ID == uuid.Nil {
 		return errors.ErrInvalidInput
 	}
@@ -66,67 +79,57 @@ func (uc *ProductUseCase) UpdateProduct(ctx context.Context, product *entities.P
 	if product.UnitPrice < 0 {
 		return errors.ErrInvalidPrice
 	}
+	if err := entities.ValidateTaxRate(product.TaxRate); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+	}
 	if product.Stock < 0 {
 		return errors.ErrInvalidQuantity
 	}
 
 	// Check if product exists
-	existing, err := uc.productRepo.GetByID(ctx, product.ID)
+	existing, err := uc.GetProductByID(ctx, gymID, product.ID)
 	if err != nil {
 		return err
 	}
-	if existing == nil {
-		return errors.ErrNotFound
-	}
 
+	product.GymID = existing.GymID
 	product.UpdatedAt = time.Now().UTC().Round(0)
 	product.CreatedAt = existing.CreatedAt // Preserve creation date
 
 	return uc.productRepo.Update(ctx, product)
 }
 
-// DeleteProduct deletes a product
-func (uc *ProductUseCase) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+// DeleteProduct deletes a product of gymID
+func (uc *ProductUseCase) DeleteProduct(ctx context.Context, gymID, id uuid.UUID) error {
 	// Check if product exists
-	existing, err := uc.productRepo.GetByID(ctx, id)
-	if err != nil {
+	if _, err := uc.GetProductByID(ctx, gymID, id); err != nil {
 		return err
 	}
-	if existing == nil {
-		return errors.ErrNotFound
-	}
 
 	return uc.productRepo.Delete(ctx, id)
 }
 
 // SetProductStock sets a product's stock to an absolute value, for manual
 // inventory corrections.
-func (uc *ProductUseCase) SetProductStock(ctx context.Context, productID uuid.UUID, stock int) error {
+func (uc *ProductUseCase) SetProductStock(ctx context.Context, gymID, productID uuid.UUID, stock int) error {
 	if stock < 0 {
 		return errors.ErrInvalidQuantity
 	}
 
-	product, err := uc.productRepo.GetByID(ctx, productID)
-	if err != nil {
+	if _, err := uc.GetProductByID(ctx, gymID, productID); err != nil {
 		return err
 	}
-	if product == nil {
-		return errors.ErrNotFound
-	}
 
 	return uc.productRepo.SetStock(ctx, productID, stock)
 }
 
 // AdjustProductStock adds `quantity` to a product's stock (negative to subtract),
 // refusing to leave it negative.
-func (uc *ProductUseCase) AdjustProductStock(ctx context.Context, productID uuid.UUID, quantity int) error {
-	product, err := uc.productRepo.GetByID(ctx, productID)
+func (uc *ProductUseCase) AdjustProductStock(ctx context.Context, gymID, productID uuid.UUID, quantity int) error {
+	product, err := uc.GetProductByID(ctx, gymID, productID)
 	if err != nil {
 		return err
 	}
-	if product == nil {
-		return errors.ErrNotFound
-	}
 
 	if product.Stock+quantity < 0 {
 		return errors.ErrInsufficientStock
@@ -135,7 +138,7 @@ func (uc *ProductUseCase) AdjustProductStock(ctx context.Context, productID uuid
 	return uc.productRepo.UpdateStock(ctx, productID, quantity)
 }
 
-// SearchProducts searches for products by name or description
-func (uc *ProductUseCase) SearchProducts(ctx context.Context, searchTerm string) ([]entities.Product, error) {
-	return uc.productRepo.Search(ctx, searchTerm)
+// SearchProducts searches the products of gymID by name or description
+func (uc *ProductUseCase) SearchProducts(ctx context.Context, gymID uuid.UUID, searchTerm string) ([]entities.Product, error) {
+	return uc.productRepo.Search(ctx, gymID, searchTerm)
 }
@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/printer"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// ReceiptUseCase prints the receipts of sales and subscriptions on the gym's
+// receipt printer.
+type ReceiptUseCase struct {
+	saleUseCase         *SaleUseCase
+	subscriptionUseCase *SubscriptionUseCase
+	deviceRepo          repositories.DeviceRepository
+	gymRepo             repositories.GymRepository
+	planRepo            repositories.PlanRepository
+	userRepo            repositories.UserRepository
+	// open opens the printer of a device: printer.Open, but for the tests.
+	open func(*entities.Device) (printer.Printer, error)
+}
+
+func NewReceiptUseCase(saleUseCase *SaleUseCase, subscriptionUseCase *SubscriptionUseCase, deviceRepo repositories.DeviceRepository, gymRepo repositories.GymRepository, planRepo repositories.PlanRepository, userRepo repositories.UserRepository) *ReceiptUseCase {
+	return &ReceiptUseCase{
+		saleUseCase:         saleUseCase,
+		subscriptionUseCase: subscriptionUseCase,
+		deviceRepo:          deviceRepo,
+		gymRepo:             gymRepo,
+		planRepo:            planRepo,
+		userRepo:            userRepo,
+		open:                printer.Open,
+	}
+}
+
+// PrintSale prints the receipt of a sale. Without a receipt printer configured
+// it returns ErrNotFound.
+func (uc *ReceiptUseCase) PrintSale(ctx context.Context, gymID, saleID uuid.UUID) error {
+	device, p, err := uc.receiptPrinter(gymID)
+	if err != nil {
+		return err
+	}
+	gym, err := uc.gymRepo.FindByID(gymID)
+	if err != nil {
+		return err
+	}
+	sale, err := uc.saleUseCase.GetSaleByID(ctx, gymID, saleID)
+	if err != nil {
+		return err
+	}
+
+	return p.Print(printer.RenderSale(gym, sale, printer.Columns(device)))
+}
+
+// PrintSubscription prints the receipt of a subscription of the gym. Without
+// a receipt printer configured it returns ErrNotFound.
+func (uc *ReceiptUseCase) PrintSubscription(gymID, subscriptionID uuid.UUID, loc *time.Location) error {
+	device, p, err := uc.receiptPrinter(gymID)
+	if err != nil {
+		return err
+	}
+	sub, err := uc.subscriptionUseCase.GetSubscription(subscriptionID)
+	if err != nil {
+		return err
+	}
+	if sub.GymID != gymID {
+		return apperrors.ErrNotFound
+	}
+	gym, err := uc.gymRepo.FindByID(gymID)
+	if err != nil {
+		return err
+	}
+	// Without the plan or the member the receipt still has the dates and
+	// the amounts, which is what the member keeps it for.
+	plan, _ := uc.planRepo.FindByID(sub.PlanID)
+	member, _ := uc.userRepo.FindByID(sub.UserID)
+
+	return p.Print(printer.RenderSubscription(gym, sub, plan, member, loc, printer.Columns(device)))
+}
+
+// receiptPrinter opens the gym's active receipt printer. A gym with several
+// prints on the one added last.
+func (uc *ReceiptUseCase) receiptPrinter(gymID uuid.UUID) (*entities.Device, printer.Printer, error) {
+	devices, err := uc.deviceRepo.FindActiveByGymID(gymID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range devices {
+		if d.DeviceType != entities.DeviceTypeReceiptPrinter {
+			continue
+		}
+		p, err := uc.open(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, p, nil
+	}
+	return nil, nil, fmt.Errorf("%w: no hay impresora de recibos configurada", apperrors.ErrNotFound)
+}
@@ -0,0 +1,73 @@
+package usecases_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/printer"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// TestPrintSale_WritesTheESCPOSReceiptToThePrinter prints a sale on a
+// file-backed receipt printer and checks the receipt is a whole ESC/POS job:
+// reset, the gym, the product, the total and the cut.
+func TestPrintSale_WritesTheESCPOSReceiptToThePrinter(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	gym := entities.NewGym("Gym Test", "gym@test.local", "3000000000")
+	gym.TaxID = "800197268-4"
+	if err := gymRepo.Create(gym); err != nil {
+		t.Fatalf("creando gym: %v", err)
+	}
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, gym.ID, 5)
+	deviceRepo := persistence.NewSQLiteDeviceRepository(db)
+	receiptUC := usecases.NewReceiptUseCase(saleUC, nil, deviceRepo, gymRepo,
+		persistence.NewSQLitePlanRepository(db), persistence.NewSQLiteUserRepository(db))
+
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Details:         []entities.SaleDetail{{ProductID: product.ID, UnitPrice: product.UnitPrice, Quantity: 3}},
+	}
+	if err := saleUC.CreateSale(ctx, gym.ID, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+
+	if err := receiptUC.PrintSale(ctx, gym.ID, sale.ID); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("PrintSale sin impresora = %v, want ErrNotFound", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "recibos.bin")
+	device := entities.NewDevice(gym.ID, "Caja", entities.DeviceTypeReceiptPrinter, "", "Recepción")
+	device.COMPort = "COM3"
+	device.PaperWidth = 58
+	if err := deviceRepo.Create(device); err != nil {
+		t.Fatalf("creando impresora: %v", err)
+	}
+	receiptUC.PrintTo(printer.NewFilePrinter(path))
+	if err := receiptUC.PrintSale(ctx, gym.ID, sale.ID); err != nil {
+		t.Fatalf("PrintSale: %v", err)
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("leyendo recibo: %v", err)
+	}
+	for _, want := range []string{"\x1b@", "Gym Test", "NIT 800197268-4", "Botella de agua", "$ 6.000", "Efectivo", "\x1dVB"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("el recibo no tiene %q:\n%q", want, out)
+		}
+	}
+	if !bytes.HasPrefix(out, []byte("\x1b@")) || !bytes.HasSuffix(out, []byte("\x1dVB\x03")) {
+		t.Errorf("el recibo no empieza con ESC @ o no termina con el corte:\n%q", out)
+	}
+}
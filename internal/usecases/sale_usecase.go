@@ -2,6 +2,8 @@ package usecases
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,8 +16,11 @@ import (
 type SaleUseCase struct {
 	saleRepo          repositories.SaleRepository
 	saleDetailRepo    repositories.SaleDetailRepository
+	saleTenderRepo    repositories.SaleTenderRepository
 	productRepo       repositories.ProductRepository
 	paymentMethodRepo repositories.PaymentMethodRepository
+	couponRepo        repositories.CouponRepository
+	gymRepo           repositories.GymRepository
 	uow               repositories.UnitOfWork
 }
 
@@ -23,45 +28,75 @@ type SaleUseCase struct {
 func NewSaleUseCase(
 	saleRepo repositories.SaleRepository,
 	saleDetailRepo repositories.SaleDetailRepository,
+	saleTenderRepo repositories.SaleTenderRepository,
 	productRepo repositories.ProductRepository,
 	paymentMethodRepo repositories.PaymentMethodRepository,
+	couponRepo repositories.CouponRepository,
+	gymRepo repositories.GymRepository,
 	uow repositories.UnitOfWork,
 ) *SaleUseCase {
 	return &SaleUseCase{
 		saleRepo:          saleRepo,
 		saleDetailRepo:    saleDetailRepo,
+		saleTenderRepo:    saleTenderRepo,
 		productRepo:       productRepo,
 		paymentMethodRepo: paymentMethodRepo,
+		couponRepo:        couponRepo,
+		gymRepo:           gymRepo,
 		uow:               uow,
 	}
 }
 
 // CreateSale creates a new sale with its details
 // This function handles inventory updates and transaction management
-func (uc *SaleUseCase) CreateSale(ctx context.Context, sale *entities.Sale) error {
+//
+// gymID is the gym the sale is made in. Its products and payment methods must
+// be the gym's own, and it scopes sale.CouponCode, whose discount is spread over
+// the lines the coupon applies to.
+//
+// sale.Tenders splits the payment over several methods, and must add up to the
+// total. Without them the whole sale is paid with sale.PaymentMethodID.
+func (uc *SaleUseCase) CreateSale(ctx context.Context, gymID uuid.UUID, sale *entities.Sale) error {
 	// Validate sale
 	if sale.UserID == uuid.Nil {
 		return errors.ErrInvalidInput
 	}
-	if sale.PaymentMethodID == uuid.Nil {
+	if sale.PaymentMethodID == uuid.Nil && len(sale.Tenders) == 0 {
 		return errors.ErrInvalidInput
 	}
 	if len(sale.Details) == 0 {
 		return errors.ErrInvalidInput
 	}
 
-	// Validate payment method
-	paymentMethod, err := uc.paymentMethodRepo.GetByID(ctx, sale.PaymentMethodID)
-	if err != nil {
-		return err
+	// Validate payment methods. A single method is a tender for the whole
+	// total, filled in once the total is known.
+	splitTender := len(sale.Tenders) > 0
+	if !splitTender {
+		sale.Tenders = []entities.SaleTender{{PaymentMethodID: sale.PaymentMethodID}}
 	}
-	if paymentMethod == nil {
-		return errors.ErrNotFound
-	}
-	if !paymentMethod.IsActive() {
-		return errors.ErrPaymentMethodNotActive
+	paymentMethods := make(map[uuid.UUID]*entities.SalePaymentMethod, len(sale.Tenders))
+	for _, tender := range sale.Tenders {
+		if tender.PaymentMethodID == uuid.Nil || (splitTender && tender.Amount <= 0) {
+			return fmt.Errorf("%w: cada medio de pago necesita un método y un monto mayor que cero", errors.ErrInvalidInput)
+		}
+		if _, ok := paymentMethods[tender.PaymentMethodID]; ok {
+			continue
+		}
+		paymentMethod, err := uc.paymentMethodRepo.GetByID(ctx, tender.PaymentMethodID)
+		if err != nil {
+			return err
+		}
+		if paymentMethod == nil || paymentMethod.GymID != gymID {
+			return errors.ErrNotFound
+		}
+		if !paymentMethod.IsActive() {
+			return errors.ErrPaymentMethodNotActive
+		}
+		paymentMethods[tender.PaymentMethodID] = paymentMethod
 	}
 
+	var err error
+
 	// Validate all details and check stock
 	productMap := make(map[uuid.UUID]*entities.Product)
 	for i := range sale.Details {
@@ -79,7 +114,7 @@ func (uc *SaleUseCase) CreateSale(ctx context.Context, sale *entities.Sale) erro
 			if err != nil {
 				return err
 			}
-			if product == nil {
+			if product == nil || product.GymID != gymID {
 				return errors.ErrNotFound
 			}
 			if !product.IsActive() {
@@ -93,17 +128,39 @@ func (uc *SaleUseCase) CreateSale(ctx context.Context, sale *entities.Sale) erro
 			return errors.ErrInsufficientStock
 		}
 
-		// Set unit price from product if not set
+		// Set unit price from product if not set, with its IVA on top when the
+		// product's is before IVA. A price typed at the till is what is
+		// charged, IVA included, like the price_with_tax of the product.
 		if detail.UnitPrice == 0 {
-			detail.UnitPrice = product.UnitPrice
+			detail.UnitPrice = product.Gross(product.UnitPrice)
 		}
+		detail.TaxRate = product.TaxRate
 
 		// Calculate subtotal
 		detail.CalculateSubtotal()
 	}
 
+	var coupon *entities.Coupon
+	if sale.CouponCode != "" {
+		coupon, err = lookupCoupon(uc.couponRepo, gymID, sale.CouponCode, time.Now())
+		if err != nil {
+			return err
+		}
+		if coupon.MaxUsesPerMember > 0 && sale.MemberID == nil {
+			return fmt.Errorf("%w: el cupón %s tiene límite por socio, indique a qué socio se vende", errors.ErrInvalidCoupon, coupon.Code)
+		}
+		sale.CouponCode = coupon.Code
+	}
+	couponAmount := 0.0
+	if coupon != nil {
+		if couponAmount = coupon.ApplyToSale(sale.Details); couponAmount == 0 {
+			return fmt.Errorf("%w: el cupón %s no aplica a ningún producto de la venta", errors.ErrInvalidCoupon, coupon.Code)
+		}
+	}
+
 	// Set sale defaults
 	sale.ID = uuid.New()
+	sale.GymID = gymID
 	if sale.Type == "" {
 		sale.Type = entities.SaleTypeNormal
 	}
@@ -119,6 +176,66 @@ func (uc *SaleUseCase) CreateSale(ctx context.Context, sale *entities.Sale) erro
 	// Calculate totals
 	sale.CalculateTotal()
 
+	if !splitTender {
+		sale.Tenders[0].Amount = sale.Total
+	}
+	// Half a peso of slack for the rounding of the amounts typed at the till.
+	if math.Abs(sale.TendersTotal()-sale.Total) >= 0.5 {
+		return fmt.Errorf("%w: los medios de pago suman %.2f y la venta %.2f", errors.ErrInvalidInput, sale.TendersTotal(), sale.Total)
+	}
+	for i := range sale.Tenders {
+		tender := &sale.Tenders[i]
+		tender.ID = uuid.New()
+		tender.SaleID = sale.ID
+		tender.CreatedAt = sale.CreatedAt
+		tender.PaymentMethod = paymentMethods[tender.PaymentMethodID]
+		if tender.Amount > sale.Tenders[0].Amount {
+			sale.Tenders[0], *tender = *tender, sale.Tenders[0]
+		}
+	}
+	sale.PaymentMethodID = sale.Tenders[0].PaymentMethodID
+
+	var redemption *entities.CouponRedemption
+	if coupon != nil {
+		redemption = entities.NewCouponRedemption(coupon, sale.MemberID, couponAmount, sale.UserID, sale.SaleDate, time.UTC)
+		redemption.SaleID = &sale.ID
+		// The handler stamps the sale in the gym's time; the redemption goes on
+		// the same day of the daily close.
+		if sale.Date != "" {
+			redemption.Date, redemption.Hour = sale.Date, sale.Hour
+		}
+	}
+
+	// The money goes into the payments ledger under the name of the method it
+	// was paid with, the name the daily close groups by: one charge per tender.
+	var payments []*entities.Payment
+	if sale.IsNormal() && sale.Total > 0 {
+		// The currency is only a label on the payment: a gym that cannot be
+		// read does not stop the sale.
+		currency := "COP"
+		if gym, err := uc.gymRepo.FindByID(gymID); err == nil && gym.Currency != "" {
+			currency = gym.Currency
+		}
+		buyer := uuid.Nil
+		if sale.MemberID != nil {
+			buyer = *sale.MemberID
+		}
+		for _, tender := range sale.Tenders {
+			payment := entities.NewPayment(gymID, buyer, sale.UserID, tender.Amount, currency,
+				entities.PaymentMethod(tender.PaymentMethod.Name), entities.PaymentTypeProduct, "Venta")
+			payment.SaleID = &sale.ID
+			payment.PaymentDate = sale.SaleDate
+			payment.Date = sale.Date
+			payment.Hour = sale.Hour
+			if payment.Date == "" {
+				payment.Date = sale.SaleDate.UTC().Format("2006-01-02")
+				payment.Hour = sale.SaleDate.UTC().Format("15:04")
+			}
+			payment.Complete("")
+			payments = append(payments, payment)
+		}
+	}
+
 	// Total quantity per product, computed before the transaction so a retry does
 	// not accumulate: everything inside uow.Do must be idempotent.
 	qtyByProduct := make(map[uuid.UUID]int, len(productMap))
@@ -139,6 +256,25 @@ func (uc *SaleUseCase) CreateSale(ctx context.Context, sale *entities.Sale) erro
 			return err
 		}
 
+		if err := r.SaleTenders.CreateBatch(ctx, sale.Tenders); err != nil {
+			return err
+		}
+
+		if redemption != nil {
+			if err := redeemCoupon(r, coupon, redemption); err != nil {
+				return err
+			}
+		}
+
+		for _, payment := range payments {
+			if err := takenAtRegister(r, payment); err != nil {
+				return err
+			}
+			if err := r.Payments.Create(payment); err != nil {
+				return err
+			}
+		}
+
 		if !sale.IsNormal() {
 			return nil
 		}
@@ -160,15 +296,12 @@ func (uc *SaleUseCase) CreateSale(ctx context.Context, sale *entities.Sale) erro
 //
 // loc is the gym's timezone, needed to stamp the local date/hour columns that all
 // the date-range reports filter on.
-func (uc *SaleUseCase) VoidSale(ctx context.Context, saleID uuid.UUID, userID uuid.UUID, loc *time.Location) (*entities.Sale, error) {
+func (uc *SaleUseCase) VoidSale(ctx context.Context, gymID, saleID uuid.UUID, userID uuid.UUID, loc *time.Location) (*entities.Sale, error) {
 	// Get original sale
-	originalSale, err := uc.saleRepo.GetByID(ctx, saleID)
+	originalSale, err := uc.ownSale(ctx, gymID, saleID)
 	if err != nil {
 		return nil, err
 	}
-	if originalSale == nil {
-		return nil, errors.ErrNotFound
-	}
 
 	// Check if sale can be voided
 	if !originalSale.CanBeVoided() {
@@ -180,6 +313,10 @@ func (uc *SaleUseCase) VoidSale(ctx context.Context, saleID uuid.UUID, userID uu
 	if err != nil {
 		return nil, err
 	}
+	tenders, err := uc.saleTenderRepo.GetBySaleID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
 
 	if loc == nil {
 		loc = time.UTC
@@ -194,6 +331,7 @@ func (uc *SaleUseCase) VoidSale(ctx context.Context, saleID uuid.UUID, userID uu
 
 	voidSale := &entities.Sale{
 		ID:              uuid.New(),
+		GymID:           originalSale.GymID,
 		SaleDate:        now,
 		Total:           -originalSale.Total,
 		TotalDiscount:   -originalSale.TotalDiscount,
@@ -222,12 +360,37 @@ func (uc *SaleUseCase) VoidSale(ctx context.Context, saleID uuid.UUID, userID uu
 			TotalPrice: -detail.TotalPrice,
 			Discount:   -detail.Discount,
 			Subtotal:   -detail.Subtotal,
+			TaxRate:    detail.TaxRate,
+			TaxBase:    -detail.TaxBase,
+			TaxAmount:  -detail.TaxAmount,
 			CreatedAt:  now,
 		}
 		qtyByProduct[detail.ProductID] += detail.Quantity
 	}
 
+	// So do the tenders: the void shows what went back through each method.
+	voidTenders := make([]entities.SaleTender, len(tenders))
+	for i, tender := range tenders {
+		voidTenders[i] = entities.SaleTender{
+			ID:              uuid.New(),
+			SaleID:          voidSale.ID,
+			PaymentMethodID: tender.PaymentMethodID,
+			Amount:          -tender.Amount,
+			CreatedAt:       now,
+		}
+	}
+
 	if err := uc.uow.Do(ctx, func(r repositories.Repos) error {
+		// Part of it went back already: voiding would restock and refund
+		// those units twice. What is left is returned instead.
+		returns, err := r.Sales.GetReturns(ctx, originalSale.ID)
+		if err != nil {
+			return err
+		}
+		if len(returns) > 0 {
+			return fmt.Errorf("%w: tiene devoluciones, devuelva lo que queda en lugar de anularla", errors.ErrSaleCannotBeVoided)
+		}
+
 		if err := r.Sales.Update(ctx, originalSale); err != nil {
 			return err
 		}
@@ -237,11 +400,37 @@ func (uc *SaleUseCase) VoidSale(ctx context.Context, saleID uuid.UUID, userID uu
 		if err := r.SaleDetails.CreateBatch(ctx, voidSale.ID, voidDetails); err != nil {
 			return err
 		}
+		if err := r.SaleTenders.CreateBatch(ctx, voidTenders); err != nil {
+			return err
+		}
 		for productID, totalQty := range qtyByProduct {
 			if err := r.Products.IncrementStock(ctx, productID, totalQty); err != nil {
 				return err
 			}
 		}
+
+		// The money goes back on the day of the void, in the ledger as on the
+		// till: the sale keeps counting on its own day. Every tender is given
+		// back through the method it came in.
+		payments, err := r.Payments.FindBySaleID(originalSale.ID)
+		if err != nil {
+			return err
+		}
+		for _, payment := range payments {
+			if !payment.IsCompleted() {
+				continue
+			}
+			if err := payment.Refund(payment.Amount, "Anulación de venta"); err != nil {
+				return err
+			}
+			payment.RefundDate = voidSale.Date
+			if err := refundedAtRegister(r, payment, userID); err != nil {
+				return err
+			}
+			if err := r.Payments.Update(payment); err != nil {
+				return err
+			}
+		}
 		return nil
 	}); err != nil {
 		return nil, err
@@ -250,14 +439,217 @@ func (uc *SaleUseCase) VoidSale(ctx context.Context, saleID uuid.UUID, userID uu
 	return voidSale, nil
 }
 
-// GetSaleByID retrieves a sale by ID with its details
-func (uc *SaleUseCase) GetSaleByID(ctx context.Context, id uuid.UUID) (*entities.Sale, error) {
-	sale, err := uc.saleRepo.GetByID(ctx, id)
+// ReturnItem is a line of a return: Quantity units of the sale detail DetailID.
+type ReturnItem struct {
+	DetailID uuid.UUID
+	Quantity int
+}
+
+// ReturnSale gives back part of a sale, the units of items. It records them on
+// a return document of its own (a Sale of type return pointing at the original
+// through ReturnedSaleID) dated now in loc, puts them back in stock and gives
+// their money back through the methods the sale was paid with, as negative
+// charges in the ledger of the day of the return.
+//
+// The sale stays completed with what was kept, so a line can be returned bit
+// by bit, never beyond what was sold; VoidSale refuses it from then on.
+func (uc *SaleUseCase) ReturnSale(ctx context.Context, gymID, saleID uuid.UUID, userID uuid.UUID, items []ReturnItem, loc *time.Location) (*entities.Sale, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: indique qué productos se devuelven", errors.ErrInvalidInput)
+	}
+	qtyByDetail := make(map[uuid.UUID]int, len(items))
+	detailIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, errors.ErrInvalidQuantity
+		}
+		if _, ok := qtyByDetail[item.DetailID]; !ok {
+			detailIDs = append(detailIDs, item.DetailID)
+		}
+		qtyByDetail[item.DetailID] += item.Quantity
+	}
+
+	originalSale, err := uc.ownSale(ctx, gymID, saleID)
 	if err != nil {
 		return nil, err
 	}
-	if sale == nil {
-		return nil, errors.ErrNotFound
+	if originalSale.Status != entities.SaleStatusCompleted || !originalSale.IsNormal() {
+		return nil, fmt.Errorf("%w: solo se devuelven productos de una venta completada", errors.ErrInvalidTransition)
+	}
+
+	details, err := uc.saleDetailRepo.GetBySaleID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	detailsByID := make(map[uuid.UUID]*entities.SaleDetail, len(details))
+	for i := range details {
+		detailsByID[details[i].ID] = &details[i]
+	}
+	for _, id := range detailIDs {
+		if detailsByID[id] == nil {
+			return nil, fmt.Errorf("%w: la línea %s no es de la venta", errors.ErrInvalidInput, id)
+		}
+	}
+
+	// The money goes back through the tenders of the sale, the largest first.
+	// A sale from before them was paid whole with its method.
+	tenders, err := uc.saleTenderRepo.GetBySaleID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tenders) == 0 {
+		tenders = []entities.SaleTender{{PaymentMethodID: originalSale.PaymentMethodID, Amount: originalSale.Total}}
+	}
+	methodNames := make(map[uuid.UUID]string, len(tenders))
+	for _, tender := range tenders {
+		if pm, err := uc.paymentMethodRepo.GetByID(ctx, tender.PaymentMethodID); err == nil && pm != nil {
+			methodNames[tender.PaymentMethodID] = pm.Name
+		}
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := time.Now().UTC().Round(0)
+	localNow := now.In(loc)
+	returnID := uuid.New()
+
+	var returnSale *entities.Sale
+	if err := uc.uow.Do(ctx, func(r repositories.Repos) error {
+		// What went back already is read inside the transaction, so two
+		// returns of the same line at once cannot give back more than sold.
+		returns, err := r.Sales.GetReturns(ctx, originalSale.ID)
+		if err != nil {
+			return err
+		}
+		returnIDs := make([]uuid.UUID, len(returns))
+		for i := range returns {
+			returnIDs[i] = returns[i].ID
+		}
+		returnedDetails, err := r.SaleDetails.GetBySaleIDs(ctx, returnIDs)
+		if err != nil {
+			return err
+		}
+		returnedQty := make(map[uuid.UUID]int)
+		for _, lines := range returnedDetails {
+			for _, d := range lines {
+				if d.ReturnedDetailID != nil {
+					returnedQty[*d.ReturnedDetailID] += d.Quantity
+				}
+			}
+		}
+		returnedByMethod := make(map[uuid.UUID]float64)
+		for _, id := range returnIDs {
+			previous, err := r.SaleTenders.GetBySaleID(ctx, id)
+			if err != nil {
+				return err
+			}
+			for _, t := range previous {
+				returnedByMethod[t.PaymentMethodID] -= t.Amount
+			}
+		}
+
+		returnSale = &entities.Sale{
+			ID:              returnID,
+			GymID:           originalSale.GymID,
+			SaleDate:        now,
+			UserID:          userID,
+			Type:            entities.SaleTypeReturn,
+			Status:          entities.SaleStatusCompleted,
+			PaymentMethodID: originalSale.PaymentMethodID,
+			ReturnedSaleID:  &originalSale.ID,
+			MemberID:        originalSale.MemberID,
+			Date:            localNow.Format("2006-01-02"),
+			Hour:            localNow.Format("15:04"),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		qtyByProduct := make(map[uuid.UUID]int, len(detailIDs))
+		for _, id := range detailIDs {
+			detail, qty := detailsByID[id], qtyByDetail[id]
+			if left := detail.Quantity - returnedQty[id]; qty > left {
+				return fmt.Errorf("%w: de la línea %s quedan %d por devolver, se pidieron %d", errors.ErrInvalidQuantity, id, left, qty)
+			}
+			line := detail.Return(qty, returnedQty[id])
+			line.CreatedAt = now
+			returnSale.Details = append(returnSale.Details, line)
+			qtyByProduct[detail.ProductID] += qty
+		}
+		returnSale.CalculateTotal()
+
+		toGiveBack := -returnSale.Total
+		for _, tender := range tenders {
+			if toGiveBack < 0.005 {
+				break
+			}
+			amount := math.Min(tender.Amount-returnedByMethod[tender.PaymentMethodID], toGiveBack)
+			if amount < 0.005 {
+				continue
+			}
+			returnedByMethod[tender.PaymentMethodID] += amount
+			toGiveBack -= amount
+			returnSale.Tenders = append(returnSale.Tenders, entities.SaleTender{
+				ID:              uuid.New(),
+				SaleID:          returnSale.ID,
+				PaymentMethodID: tender.PaymentMethodID,
+				Amount:          -amount,
+				CreatedAt:       now,
+			})
+		}
+		if len(returnSale.Tenders) > 0 {
+			returnSale.PaymentMethodID = returnSale.Tenders[0].PaymentMethodID
+		}
+
+		if err := r.Sales.Create(ctx, returnSale); err != nil {
+			return err
+		}
+		if err := r.SaleDetails.CreateBatch(ctx, returnSale.ID, returnSale.Details); err != nil {
+			return err
+		}
+		if err := r.SaleTenders.CreateBatch(ctx, returnSale.Tenders); err != nil {
+			return err
+		}
+		for productID, qty := range qtyByProduct {
+			if err := r.Products.IncrementStock(ctx, productID, qty); err != nil {
+				return err
+			}
+		}
+
+		// The ledger gets the money back on the day of the return, from the
+		// drawer of whoever gives it. A sale from before the ledger had no
+		// charge there, and gets no return either.
+		charges, err := r.Payments.FindBySaleID(originalSale.ID)
+		if err != nil || len(charges) == 0 {
+			return err
+		}
+		for _, tender := range returnSale.Tenders {
+			payment := entities.NewPayment(charges[0].GymID, charges[0].UserID, userID, tender.Amount, charges[0].Currency,
+				entities.PaymentMethod(methodNames[tender.PaymentMethodID]), entities.PaymentTypeProduct, "Devolución de venta")
+			payment.SaleID = &returnSale.ID
+			payment.PaymentDate = now
+			payment.Date = returnSale.Date
+			payment.Hour = returnSale.Hour
+			payment.Complete("")
+			if err := takenAtRegister(r, payment); err != nil {
+				return err
+			}
+			if err := r.Payments.Create(payment); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return returnSale, nil
+}
+
+// GetSaleByID retrieves a sale of gymID by ID with its details
+func (uc *SaleUseCase) GetSaleByID(ctx context.Context, gymID, id uuid.UUID) (*entities.Sale, error) {
+	sale, err := uc.ownSale(ctx, gymID, id)
+	if err != nil {
+		return nil, err
 	}
 
 	// Load details
@@ -281,12 +673,42 @@ func (uc *SaleUseCase) GetSaleByID(ctx context.Context, id uuid.UUID) (*entities
 		sale.PaymentMethod = pm
 	}
 
+	// Load tenders. A sale from before them was paid whole with its method.
+	tenders, err := uc.saleTenderRepo.GetBySaleID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(tenders) == 0 {
+		tenders = []entities.SaleTender{{SaleID: sale.ID, PaymentMethodID: sale.PaymentMethodID, Amount: sale.Total, CreatedAt: sale.CreatedAt}}
+	}
+	for i := range tenders {
+		if tenders[i].PaymentMethodID == sale.PaymentMethodID {
+			tenders[i].PaymentMethod = sale.PaymentMethod
+		} else if pm, err := uc.paymentMethodRepo.GetByID(ctx, tenders[i].PaymentMethodID); err == nil && pm != nil {
+			tenders[i].PaymentMethod = pm
+		}
+	}
+	sale.Tenders = tenders
+
+	return sale, nil
+}
+
+// ownSale loads a sale of gymID. One of another gym is ErrNotFound, as if it did
+// not exist.
+func (uc *SaleUseCase) ownSale(ctx context.Context, gymID, id uuid.UUID) (*entities.Sale, error) {
+	sale, err := uc.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sale == nil || sale.GymID != gymID {
+		return nil, errors.ErrNotFound
+	}
 	return sale, nil
 }
 
-// GetAllSales retrieves all sales
-func (uc *SaleUseCase) GetAllSales(ctx context.Context) ([]entities.Sale, error) {
-	sales, err := uc.saleRepo.GetAll(ctx)
+// GetAllSales retrieves the latest sales of gymID
+func (uc *SaleUseCase) GetAllSales(ctx context.Context, gymID uuid.UUID) ([]entities.Sale, error) {
+	sales, err := uc.saleRepo.GetAll(ctx, gymID)
 	if err != nil {
 		return nil, err
 	}
@@ -295,8 +717,8 @@ func (uc *SaleUseCase) GetAllSales(ctx context.Context) ([]entities.Sale, error)
 }
 
 // GetSalesByDateRange retrieves sales within a date range using local date strings (YYYY-MM-DD)
-func (uc *SaleUseCase) GetSalesByDateRange(ctx context.Context, startDate, endDate string, userID *uuid.UUID) ([]entities.Sale, error) {
-	sales, err := uc.saleRepo.GetByDateRange(ctx, startDate, endDate, userID)
+func (uc *SaleUseCase) GetSalesByDateRange(ctx context.Context, gymID uuid.UUID, startDate, endDate string, userID *uuid.UUID) ([]entities.Sale, error) {
+	sales, err := uc.saleRepo.GetByDateRange(ctx, gymID, startDate, endDate, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -305,8 +727,8 @@ func (uc *SaleUseCase) GetSalesByDateRange(ctx context.Context, startDate, endDa
 }
 
 // GetSalesReport generates a sales report for a date range using local date strings (YYYY-MM-DD)
-func (uc *SaleUseCase) GetSalesReport(ctx context.Context, startDate, endDate string, userID *uuid.UUID) ([]repositories.SaleReport, error) {
-	return uc.saleRepo.GetSalesReport(ctx, startDate, endDate, userID)
+func (uc *SaleUseCase) GetSalesReport(ctx context.Context, gymID uuid.UUID, startDate, endDate string, userID *uuid.UUID) ([]repositories.SaleReport, error) {
+	return uc.saleRepo.GetSalesReport(ctx, gymID, startDate, endDate, userID)
 }
 
 // loadPaymentMethods batch-loads payment methods for a list of sales
@@ -330,6 +752,6 @@ func (uc *SaleUseCase) loadPaymentMethods(ctx context.Context, sales []entities.
 }
 
 // GetSalesReportByProduct generates a sales report grouped by product using local date strings (YYYY-MM-DD)
-func (uc *SaleUseCase) GetSalesReportByProduct(ctx context.Context, startDate, endDate string) ([]repositories.SaleProductReport, error) {
-	return uc.saleRepo.GetSalesReportByProduct(ctx, startDate, endDate)
+func (uc *SaleUseCase) GetSalesReportByProduct(ctx context.Context, gymID uuid.UUID, startDate, endDate string) ([]repositories.SaleProductReport, error) {
+	return uc.saleRepo.GetSalesReportByProduct(ctx, gymID, startDate, endDate)
 }
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sebastiancorrales/gym-go/internal/config"
@@ -34,7 +35,7 @@ func TestConcurrentSales_NoOversell(t *testing.T) {
 	const tills = 20
 
 	db := newTestDB(t)
-	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, initialStock)
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, uuid.Nil, initialStock)
 
 	var wg sync.WaitGroup
 	results := make(chan error, tills)
@@ -52,7 +53,7 @@ func TestConcurrentSales_NoOversell(t *testing.T) {
 					Quantity:  1,
 				}},
 			}
-			results <- saleUC.CreateSale(context.Background(), sale)
+			results <- saleUC.CreateSale(context.Background(), uuid.Nil, sale)
 		}()
 	}
 
@@ -106,7 +107,7 @@ func TestConcurrentSales_NoOversell(t *testing.T) {
 // items were already committed by then.
 func TestCreateSale_RollsBackOnStockFailure(t *testing.T) {
 	db := newTestDB(t)
-	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, 1)
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, uuid.Nil, 1)
 
 	// Pide 2 unidades habiendo 1: la validación previa lo rechaza y no debe quedar
 	// rastro de la venta.
@@ -120,7 +121,7 @@ func TestCreateSale_RollsBackOnStockFailure(t *testing.T) {
 		}},
 	}
 
-	err := saleUC.CreateSale(context.Background(), sale)
+	err := saleUC.CreateSale(context.Background(), uuid.Nil, sale)
 	if !errors.Is(err, apperrors.ErrInsufficientStock) {
 		t.Fatalf("err = %v, want ErrInsufficientStock", err)
 	}
@@ -159,9 +160,9 @@ func newTestDB(t *testing.T) *gorm.DB {
 	return database.DB
 }
 
-// seedPOS creates a product, a payment method and a seller, and returns a wired
-// SaleUseCase.
-func seedPOS(t *testing.T, db *gorm.DB, stock int) (*usecases.SaleUseCase, *entities.Product, uuid.UUID, uuid.UUID) {
+// seedPOS creates a product, a payment method and a seller of gymID, and returns
+// a wired SaleUseCase.
+func seedPOS(t *testing.T, db *gorm.DB, gymID uuid.UUID, stock int) (*usecases.SaleUseCase, *entities.Product, uuid.UUID, uuid.UUID) {
 	t.Helper()
 
 	ctx := context.Background()
@@ -173,6 +174,7 @@ func seedPOS(t *testing.T, db *gorm.DB, stock int) (*usecases.SaleUseCase, *enti
 
 	product := &entities.Product{
 		ID:        uuid.New(),
+		GymID:     gymID,
 		Name:      "Botella de agua",
 		UnitPrice: 2000,
 		Stock:     stock,
@@ -184,6 +186,7 @@ func seedPOS(t *testing.T, db *gorm.DB, stock int) (*usecases.SaleUseCase, *enti
 
 	method := &entities.SalePaymentMethod{
 		ID:     uuid.New(),
+		GymID:  gymID,
 		Name:   "Efectivo",
 		Type:   entities.PaymentTypeCash,
 		Status: entities.PaymentMethodStatusActive,
@@ -195,10 +198,332 @@ func seedPOS(t *testing.T, db *gorm.DB, stock int) (*usecases.SaleUseCase, *enti
 	sellerID := uuid.New()
 	if err := db.Exec(`INSERT INTO users (id, gym_id, email, first_name, last_name, role, status)
 	                   VALUES (?, ?, 'cajero@test.local', 'Caja', 'Uno', 'RECEPCIONISTA', 'ACTIVE')`,
-		sellerID, uuid.Nil).Error; err != nil {
+		sellerID, gymID).Error; err != nil {
 		t.Fatalf("creando vendedor: %v", err)
 	}
 
-	saleUC := usecases.NewSaleUseCase(saleRepo, saleDetailRepo, productRepo, paymentMethodRepo, uow)
+	saleUC := usecases.NewSaleUseCase(saleRepo, saleDetailRepo, persistence.NewSQLiteSaleTenderRepository(db), productRepo, paymentMethodRepo, persistence.NewSQLiteCouponRepository(db), persistence.NewSQLiteGymRepository(db), uow)
 	return saleUC, product, method.ID, sellerID
 }
+
+// TestSaleAndVoid_AreChargedAndRefundedInTheLedger checks that a sale leaves its
+// payment in the ledger and voiding it refunds that payment in full, so the
+// revenue of the day nets to zero.
+func TestSaleAndVoid_AreChargedAndRefundedInTheLedger(t *testing.T) {
+	db := newTestDB(t)
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, uuid.Nil, 5)
+	paymentRepo := persistence.NewSQLitePaymentRepository(db)
+
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Details: []entities.SaleDetail{{
+			ProductID: product.ID,
+			UnitPrice: product.UnitPrice,
+			Quantity:  3,
+		}},
+	}
+	if err := saleUC.CreateSale(context.Background(), uuid.Nil, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+
+	payments, err := paymentRepo.FindBySaleID(sale.ID)
+	if err != nil || len(payments) != 1 {
+		t.Fatalf("FindBySaleID = %v, %v; want the payment of the sale", payments, err)
+	}
+	payment := payments[0]
+	if payment.Amount != sale.Total || payment.PaymentType != entities.PaymentTypeProduct || string(payment.PaymentMethod) != "Efectivo" {
+		t.Errorf("payment = %.0f %s %s, want %.0f PRODUCT Efectivo", payment.Amount, payment.PaymentType, payment.PaymentMethod, sale.Total)
+	}
+
+	if _, err := saleUC.VoidSale(context.Background(), uuid.Nil, sale.ID, sellerID, time.UTC); err != nil {
+		t.Fatalf("VoidSale: %v", err)
+	}
+	payments, _ = paymentRepo.FindBySaleID(sale.ID)
+	payment = payments[0]
+	if !payment.IsRefunded() || payment.RefundedAmount != sale.Total {
+		t.Errorf("tras anular: status %s, reembolsado %.0f; want REFUNDED %.0f", payment.Status, payment.RefundedAmount, sale.Total)
+	}
+
+	revenue, err := usecases.NewPaymentUseCase(paymentRepo).GetRevenue(uuid.Nil, payment.Date, payment.Date)
+	if err != nil {
+		t.Fatalf("GetRevenue: %v", err)
+	}
+	if revenue.Charged != sale.Total || revenue.Refunded != sale.Total || revenue.Net != 0 {
+		t.Errorf("revenue = cobrado %.0f, reembolsado %.0f, neto %.0f; want %.0f, %.0f, 0",
+			revenue.Charged, revenue.Refunded, revenue.Net, sale.Total, sale.Total)
+	}
+}
+
+// TestSplitTenderSale_ChargesAndRefundsEveryTender checks that a sale paid part
+// in cash and part by transfer leaves a charge per method in the ledger, that
+// tenders not adding up to the total are rejected, and that the void refunds
+// all of them.
+func TestSplitTenderSale_ChargesAndRefundsEveryTender(t *testing.T) {
+	db := newTestDB(t)
+	saleUC, product, cashID, sellerID := seedPOS(t, db, uuid.Nil, 5)
+	paymentRepo := persistence.NewSQLitePaymentRepository(db)
+	ctx := context.Background()
+
+	transfer := &entities.SalePaymentMethod{
+		ID:     uuid.New(),
+		Name:   "Nequi",
+		Type:   entities.PaymentTypeTransfer,
+		Status: entities.PaymentMethodStatusActive,
+	}
+	if err := persistence.NewSQLitePaymentMethodRepository(db).Create(ctx, transfer); err != nil {
+		t.Fatalf("creando método de pago: %v", err)
+	}
+
+	newSale := func(cash, bank float64) *entities.Sale {
+		return &entities.Sale{
+			UserID: sellerID,
+			Tenders: []entities.SaleTender{
+				{PaymentMethodID: cashID, Amount: cash},
+				{PaymentMethodID: transfer.ID, Amount: bank},
+			},
+			Details: []entities.SaleDetail{{ProductID: product.ID, UnitPrice: product.UnitPrice, Quantity: 3}},
+		}
+	}
+
+	if err := saleUC.CreateSale(ctx, uuid.Nil, newSale(1000, 4000)); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Fatalf("tenders que no suman el total: err = %v, want ErrInvalidInput", err)
+	}
+
+	sale := newSale(2000, 4000)
+	if err := saleUC.CreateSale(ctx, uuid.Nil, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+	if sale.PaymentMethodID != transfer.ID {
+		t.Errorf("PaymentMethodID = %s, want el del medio mayor (Nequi)", sale.PaymentMethodID)
+	}
+
+	payments, err := paymentRepo.FindBySaleID(sale.ID)
+	if err != nil || len(payments) != 2 {
+		t.Fatalf("FindBySaleID = %d pagos, %v; want 2", len(payments), err)
+	}
+	byMethod := map[string]float64{}
+	for _, p := range payments {
+		byMethod[string(p.PaymentMethod)] += p.Amount
+	}
+	if byMethod["Efectivo"] != 2000 || byMethod["Nequi"] != 4000 {
+		t.Errorf("cobros por medio = %v, want Efectivo 2000, Nequi 4000", byMethod)
+	}
+
+	if _, err := saleUC.VoidSale(ctx, uuid.Nil, sale.ID, sellerID, time.UTC); err != nil {
+		t.Fatalf("VoidSale: %v", err)
+	}
+	payments, _ = paymentRepo.FindBySaleID(sale.ID)
+	for _, p := range payments {
+		if !p.IsRefunded() || p.RefundedAmount != p.Amount {
+			t.Errorf("tras anular, %s: status %s, reembolsado %.0f de %.0f", p.PaymentMethod, p.Status, p.RefundedAmount, p.Amount)
+		}
+	}
+}
+
+// TestSaleOfTaxExcludedProduct_ChargesIVAAndReportsItByRate checks that a
+// product priced before IVA is charged with the IVA on top, that the detail
+// keeps the taxable base and the tax apart, and that the sales report breaks
+// them down by rate next to the IVA-included products. A price typed at the
+// till already has its IVA and is charged as it is.
+func TestSaleOfTaxExcludedProduct_ChargesIVAAndReportsItByRate(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	saleUC, water, paymentMethodID, sellerID := seedPOS(t, db, uuid.Nil, 5)
+
+	towel := &entities.Product{
+		ID:          uuid.New(),
+		Name:        "Toalla",
+		UnitPrice:   10000,
+		TaxRate:     19,
+		TaxExcluded: true,
+		Stock:       5,
+		Status:      entities.ProductStatusActive,
+	}
+	if err := persistence.NewSQLiteProductRepository(db).Create(ctx, towel); err != nil {
+		t.Fatalf("creando producto: %v", err)
+	}
+
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Date:            "2026-10-17",
+		Details: []entities.SaleDetail{
+			{ProductID: towel.ID, Quantity: 2},
+			{ProductID: water.ID, UnitPrice: water.UnitPrice, Quantity: 1},
+		},
+	}
+	if err := saleUC.CreateSale(ctx, uuid.Nil, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+
+	d := sale.Details[0]
+	if d.UnitPrice != 11900 || d.Subtotal != 23800 || d.TaxBase != 20000 || d.TaxAmount != 3800 {
+		t.Errorf("detalle = unitario %.0f, subtotal %.0f, base %.0f, IVA %.0f; want 11900, 23800, 20000, 3800",
+			d.UnitPrice, d.Subtotal, d.TaxBase, d.TaxAmount)
+	}
+	if sale.Total != 25800 {
+		t.Errorf("total = %.0f, want 25800", sale.Total)
+	}
+
+	reports, err := saleUC.GetSalesReport(ctx, uuid.Nil, "2026-10-17", "2026-10-17", nil)
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("GetSalesReport = %v, %v", reports, err)
+	}
+	report := reports[0]
+	want := []entities.TaxBreakdown{{Rate: 0, Base: 2000, Tax: 0}, {Rate: 19, Base: 20000, Tax: 3800}}
+	if len(report.Taxes) != len(want) {
+		t.Fatalf("taxes = %+v, want %+v", report.Taxes, want)
+	}
+	for i, w := range want {
+		if report.Taxes[i] != w {
+			t.Errorf("taxes[%d] = %+v, want %+v", i, report.Taxes[i], w)
+		}
+	}
+	if report.TaxableBase != 22000 || report.TaxTotal != 3800 {
+		t.Errorf("base gravable %.0f, IVA %.0f; want 22000, 3800", report.TaxableBase, report.TaxTotal)
+	}
+
+	// El precio con IVA que muestra el producto, tecleado en caja, se cobra
+	// tal cual: el IVA no se suma dos veces.
+	typed := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Date:            "2026-10-18",
+		Details:         []entities.SaleDetail{{ProductID: towel.ID, UnitPrice: 11900, Quantity: 1}},
+	}
+	if err := saleUC.CreateSale(ctx, uuid.Nil, typed); err != nil {
+		t.Fatalf("CreateSale con precio tecleado: %v", err)
+	}
+	if d := typed.Details[0]; d.UnitPrice != 11900 || d.TaxBase != 10000 || d.TaxAmount != 1900 {
+		t.Errorf("precio tecleado = unitario %.0f, base %.0f, IVA %.0f; want 11900, 10000, 1900", d.UnitPrice, d.TaxBase, d.TaxAmount)
+	}
+}
+
+// TestReturnSale_GivesBackPartOfASale checks that returning some units of a
+// line restocks them, refunds their share in the ledger and takes them off the
+// by-product report, that a line cannot be returned beyond what was sold, and
+// that a sale with returns can no longer be voided.
+func TestReturnSale_GivesBackPartOfASale(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, uuid.Nil, 5)
+	productRepo := persistence.NewSQLiteProductRepository(db)
+	today := time.Now().UTC().Format("2006-01-02")
+
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Date:            today,
+		Details: []entities.SaleDetail{{
+			ProductID: product.ID,
+			UnitPrice: product.UnitPrice,
+			Quantity:  3,
+		}},
+	}
+	if err := saleUC.CreateSale(ctx, uuid.Nil, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+	line := sale.Details[0].ID
+
+	returned, err := saleUC.ReturnSale(ctx, uuid.Nil, sale.ID, sellerID, []usecases.ReturnItem{{DetailID: line, Quantity: 1}}, time.UTC)
+	if err != nil {
+		t.Fatalf("ReturnSale: %v", err)
+	}
+	if !returned.IsReturn() || returned.ReturnedSaleID == nil || *returned.ReturnedSaleID != sale.ID || returned.Total != -2000 {
+		t.Errorf("devolución = %s de %v por %.0f; want return de %s por -2000", returned.Type, returned.ReturnedSaleID, returned.Total, sale.ID)
+	}
+	if p, _ := productRepo.GetByID(ctx, product.ID); p.Stock != 3 {
+		t.Errorf("stock = %d, want 3", p.Stock)
+	}
+
+	revenue, err := usecases.NewPaymentUseCase(persistence.NewSQLitePaymentRepository(db)).GetRevenue(uuid.Nil, today, today)
+	if err != nil {
+		t.Fatalf("GetRevenue: %v", err)
+	}
+	if revenue.Charged != 6000 || revenue.Refunded != 2000 || revenue.Net != 4000 {
+		t.Errorf("revenue = cobrado %.0f, devuelto %.0f, neto %.0f; want 6000, 2000, 4000", revenue.Charged, revenue.Refunded, revenue.Net)
+	}
+
+	byProduct, err := saleUC.GetSalesReportByProduct(ctx, uuid.Nil, today, today)
+	if err != nil || len(byProduct) != 1 {
+		t.Fatalf("GetSalesReportByProduct = %v, %v", byProduct, err)
+	}
+	if byProduct[0].QuantitySold != 2 || byProduct[0].NetRevenue != 4000 {
+		t.Errorf("por producto = %d unidades, %.0f; want 2, 4000", byProduct[0].QuantitySold, byProduct[0].NetRevenue)
+	}
+
+	if _, err := saleUC.ReturnSale(ctx, uuid.Nil, sale.ID, sellerID, []usecases.ReturnItem{{DetailID: line, Quantity: 3}}, time.UTC); !errors.Is(err, apperrors.ErrInvalidQuantity) {
+		t.Errorf("devolver más de lo que queda: err = %v, want ErrInvalidQuantity", err)
+	}
+	if _, err := saleUC.VoidSale(ctx, uuid.Nil, sale.ID, sellerID, time.UTC); !errors.Is(err, apperrors.ErrSaleCannotBeVoided) {
+		t.Errorf("anular con devoluciones: err = %v, want ErrSaleCannotBeVoided", err)
+	}
+	if _, err := saleUC.ReturnSale(ctx, uuid.Nil, sale.ID, sellerID, []usecases.ReturnItem{{DetailID: line, Quantity: 2}}, time.UTC); err != nil {
+		t.Errorf("devolver lo que queda: %v", err)
+	}
+	if p, _ := productRepo.GetByID(ctx, product.ID); p.Stock != 5 {
+		t.Errorf("stock tras devolverlo todo = %d, want 5", p.Stock)
+	}
+}
+
+// TestGymScope_HidesProductsPaymentMethodsAndSalesOfOtherGyms sells at one gym
+// and checks another gym can neither see nor use its product, payment method or
+// sale.
+func TestGymScope_HidesProductsPaymentMethodsAndSalesOfOtherGyms(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	gymA, gymB := uuid.New(), uuid.New()
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, gymA, 5)
+	productUC := usecases.NewProductUseCase(persistence.NewSQLiteProductRepository(db))
+	methodUC := usecases.NewPaymentMethodUseCase(persistence.NewSQLitePaymentMethodRepository(db))
+	today := time.Now().UTC().Format("2006-01-02")
+
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Date:            today,
+		Details:         []entities.SaleDetail{{ProductID: product.ID, UnitPrice: product.UnitPrice, Quantity: 1}},
+	}
+	if err := saleUC.CreateSale(ctx, gymA, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+
+	if _, err := productUC.GetProductByID(ctx, gymB, product.ID); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("producto de otro gym: err = %v, want ErrNotFound", err)
+	}
+	if _, err := methodUC.GetPaymentMethodByID(ctx, gymB, paymentMethodID); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("método de pago de otro gym: err = %v, want ErrNotFound", err)
+	}
+	if _, err := saleUC.GetSaleByID(ctx, gymB, sale.ID); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("venta de otro gym: err = %v, want ErrNotFound", err)
+	}
+	if _, err := saleUC.VoidSale(ctx, gymB, sale.ID, sellerID, time.UTC); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("anular venta de otro gym: err = %v, want ErrNotFound", err)
+	}
+
+	foreign := &entities.Sale{
+		UserID:          sellerID,
+		PaymentMethodID: paymentMethodID,
+		Details:         []entities.SaleDetail{{ProductID: product.ID, UnitPrice: product.UnitPrice, Quantity: 1}},
+	}
+	if err := saleUC.CreateSale(ctx, gymB, foreign); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("vender con el catálogo de otro gym: err = %v, want ErrNotFound", err)
+	}
+
+	if products, _ := productUC.GetAllProducts(ctx, gymB, nil); len(products) != 0 {
+		t.Errorf("productos del gym B = %d, want 0", len(products))
+	}
+	if methods, _ := methodUC.GetAllPaymentMethods(ctx, gymB, nil); len(methods) != 0 {
+		t.Errorf("métodos de pago del gym B = %d, want 0", len(methods))
+	}
+	if sales, _ := saleUC.GetAllSales(ctx, gymB); len(sales) != 0 {
+		t.Errorf("ventas del gym B = %d, want 0", len(sales))
+	}
+	if report, _ := saleUC.GetSalesReportByProduct(ctx, gymB, today, today); len(report) != 0 {
+		t.Errorf("reporte por producto del gym B = %v, want vacío", report)
+	}
+	if sales, _ := saleUC.GetAllSales(ctx, gymA); len(sales) != 1 {
+		t.Errorf("ventas del gym A = %d, want 1", len(sales))
+	}
+}
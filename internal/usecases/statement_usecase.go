@@ -0,0 +1,292 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/email"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// StatementUseCase produces a member's account statement: the certificate of
+// what they paid the gym in a period that members ask for, for their employer
+// or their taxes. Like the daily close, every amount is read off the payments
+// ledger.
+type StatementUseCase struct {
+	userRepo            repositories.UserRepository
+	gymRepo             repositories.GymRepository
+	subscriptionRepo    repositories.SubscriptionRepository
+	planRepo            repositories.PlanRepository
+	paymentRepo         repositories.PaymentRepository
+	saleDetailRepo      repositories.SaleDetailRepository
+	productRepo         repositories.ProductRepository
+	notificationUseCase *NotificationUseCase
+}
+
+func NewStatementUseCase(
+	userRepo repositories.UserRepository,
+	gymRepo repositories.GymRepository,
+	subscriptionRepo repositories.SubscriptionRepository,
+	planRepo repositories.PlanRepository,
+	paymentRepo repositories.PaymentRepository,
+	saleDetailRepo repositories.SaleDetailRepository,
+	productRepo repositories.ProductRepository,
+	notificationUseCase *NotificationUseCase,
+) *StatementUseCase {
+	return &StatementUseCase{
+		userRepo:            userRepo,
+		gymRepo:             gymRepo,
+		subscriptionRepo:    subscriptionRepo,
+		planRepo:            planRepo,
+		paymentRepo:         paymentRepo,
+		saleDetailRepo:      saleDetailRepo,
+		productRepo:         productRepo,
+		notificationUseCase: notificationUseCase,
+	}
+}
+
+// GeneratePDF renders the statement of memberID between the local dates from
+// and to (YYYY-MM-DD). When sendEmail is set it also goes to the member's
+// email, and failing to send it fails the call.
+func (uc *StatementUseCase) GeneratePDF(ctx context.Context, gymID, memberID uuid.UUID, from, to string, loc *time.Location, sendEmail bool) ([]byte, error) {
+	gym, member, err := uc.gymMember(gymID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if sendEmail && member.Email == "" {
+		return nil, fmt.Errorf("%w: el socio no tiene correo registrado", apperrors.ErrInvalidInput)
+	}
+
+	doc, err := uc.buildStatement(ctx, gym, member, from, to, loc)
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := email.BuildStatementPDF(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if sendEmail {
+		if err := uc.notificationUseCase.SendMemberStatement(gym, member, doc.From, doc.To, pdf); err != nil {
+			return nil, err
+		}
+	}
+	return pdf, nil
+}
+
+// BuildStatement returns what the statement of memberID between from and to
+// shows, without rendering it.
+func (uc *StatementUseCase) BuildStatement(ctx context.Context, gymID, memberID uuid.UUID, from, to string, loc *time.Location) (*email.MemberStatement, error) {
+	gym, member, err := uc.gymMember(gymID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.buildStatement(ctx, gym, member, from, to, loc)
+}
+
+// gymMember loads the gym and the member, answering ErrNotFound for a member
+// of another gym.
+func (uc *StatementUseCase) gymMember(gymID, memberID uuid.UUID) (*entities.Gym, *entities.User, error) {
+	gym, err := uc.gymRepo.FindByID(gymID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := uc.userRepo.FindByID(memberID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member.GymID != gymID {
+		return nil, nil, apperrors.ErrNotFound
+	}
+	return gym, member, nil
+}
+
+// buildStatement gathers the subscriptions of the member that ran in the
+// period and the money that went in and out of their account in it: charges
+// on the day they were made, refunds on the day they were given back.
+func (uc *StatementUseCase) buildStatement(ctx context.Context, gym *entities.Gym, member *entities.User, from, to string, loc *time.Location) (*email.MemberStatement, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	start, err := time.ParseInLocation("2006-01-02", from, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fecha inicial %q", apperrors.ErrInvalidInput, from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fecha final %q", apperrors.ErrInvalidInput, to)
+	}
+	end = end.AddDate(0, 0, 1)
+
+	currency := gym.Currency
+	if currency == "" {
+		currency = "COP"
+	}
+	doc := &email.MemberStatement{
+		GymName:     gym.Name,
+		LegalName:   gym.LegalName,
+		TaxID:       gym.TaxID,
+		Address:     gym.Address,
+		Phone:       gym.Phone,
+		Email:       gym.Email,
+		MemberName:  member.FirstName + " " + member.LastName,
+		MemberEmail: member.Email,
+		From:        start.Format("02/01/2006"),
+		To:          end.AddDate(0, 0, -1).Format("02/01/2006"),
+		Currency:    currency,
+	}
+	if member.DocumentNumber != "" {
+		doc.MemberDocument = strings.TrimSpace(member.DocumentType + " " + member.DocumentNumber)
+	}
+	// Without its logo the letterhead is still the gym's: name and NIT.
+	if gym.LogoURL != "" {
+		if logo, err := email.LoadLogo(gym.LogoURL); err != nil {
+			log.Printf("⚠️  Statement of %s: logo not loaded: %v", member.ID, err)
+		} else {
+			doc.Logo = logo
+		}
+	}
+
+	planNames := make(map[uuid.UUID]string)
+	planName := func(planID uuid.UUID) string {
+		if name, ok := planNames[planID]; ok {
+			return name
+		}
+		name := "Plan"
+		if plan, err := uc.planRepo.FindByID(planID); err == nil && plan != nil {
+			name = plan.Name
+		}
+		planNames[planID] = name
+		return name
+	}
+
+	// ── Subscriptions ─────────────────────────────────────────────────────────
+	subs, err := uc.subscriptionRepo.FindByUserID(member.ID)
+	if err != nil {
+		return nil, fmt.Errorf("loading subscriptions: %w", err)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].StartDate.Before(subs[j].StartDate) })
+	subPlans := make(map[uuid.UUID]uuid.UUID, len(subs))
+	for _, sub := range subs {
+		subPlans[sub.ID] = sub.PlanID
+		if sub.GymID != gym.ID || sub.AwaitingPayment || !sub.StartDate.Before(end) || sub.EndDate.Before(start) {
+			continue
+		}
+		doc.Subscriptions = append(doc.Subscriptions, email.StatementSubscription{
+			PlanName:  planName(sub.PlanID),
+			StartDate: sub.StartDate.In(loc).Format("02/01/2006"),
+			EndDate:   sub.EndDate.In(loc).Format("02/01/2006"),
+			Status:    string(sub.Status),
+			Total:     sub.TotalPaid,
+		})
+	}
+
+	// ── Ledger ────────────────────────────────────────────────────────────────
+	payments, err := uc.paymentRepo.FindByUserID(member.ID)
+	if err != nil {
+		return nil, fmt.Errorf("loading payments: %w", err)
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		return payments[i].Date+payments[i].Hour < payments[j].Date+payments[j].Hour
+	})
+
+	var saleIDs []uuid.UUID
+	for _, p := range payments {
+		if p.GymID == gym.ID && p.SaleID != nil {
+			saleIDs = append(saleIDs, *p.SaleID)
+		}
+	}
+	detailsBySale, err := uc.saleDetailRepo.GetBySaleIDs(ctx, saleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("loading sale details: %w", err)
+	}
+	productNames := make(map[uuid.UUID]string)
+	if products, err := uc.productRepo.GetAll(ctx, gym.ID, nil); err == nil {
+		for _, p := range products {
+			productNames[p.ID] = p.Name
+		}
+	}
+	items := func(saleID *uuid.UUID) string {
+		if saleID == nil {
+			return ""
+		}
+		var parts []string
+		for _, d := range detailsBySale[*saleID] {
+			name, ok := productNames[d.ProductID]
+			if !ok {
+				name = "Producto"
+			}
+			parts = append(parts, fmt.Sprintf("%s x%d", name, d.Quantity))
+		}
+		return strings.Join(parts, ", ")
+	}
+	concept := func(p *entities.Payment) string {
+		switch {
+		case p.SaleID != nil:
+			if list := items(p.SaleID); list != "" {
+				return list
+			}
+			return p.Description
+		case p.SubscriptionID != nil && p.PaymentType == entities.PaymentTypeSubscription:
+			return planName(subPlans[*p.SubscriptionID])
+		case p.SubscriptionID != nil && p.PaymentType == entities.PaymentTypeInstallment:
+			return "Abono de saldo - " + planName(subPlans[*p.SubscriptionID])
+		}
+		return p.Description
+	}
+	inPeriod := func(date string) bool { return date >= from && date <= to }
+
+	for _, p := range payments {
+		if p.GymID != gym.ID || (!p.IsCompleted() && !p.IsRefunded()) {
+			continue
+		}
+		line := email.StatementLine{
+			Date:          dmy(p.Date),
+			Concept:       concept(p),
+			PaymentMethod: string(p.PaymentMethod),
+			Amount:        p.Amount,
+		}
+		switch {
+		case !inPeriod(p.Date):
+		case p.IsReturn():
+			line.Concept = "Devolución: " + line.Concept
+			doc.Refunds = append(doc.Refunds, line)
+			doc.TotalRefunded -= p.Amount
+		case p.PaymentType == entities.PaymentTypeProduct:
+			doc.Purchases = append(doc.Purchases, line)
+			doc.TotalPaid += p.Amount
+		default:
+			doc.MembershipCharges = append(doc.MembershipCharges, line)
+			doc.TotalPaid += p.Amount
+		}
+
+		if p.IsRefunded() && inPeriod(p.RefundDate) {
+			refund := line
+			refund.Date = dmy(p.RefundDate)
+			refund.Concept = "Reembolso: " + line.Concept
+			if p.RefundReason != "" {
+				refund.Concept += " (" + p.RefundReason + ")"
+			}
+			refund.Amount = -p.RefundedAmount
+			doc.Refunds = append(doc.Refunds, refund)
+			doc.TotalRefunded += p.RefundedAmount
+		}
+	}
+	return doc, nil
+}
+
+// dmy turns a local YYYY-MM-DD date into DD/MM/YYYY.
+func dmy(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("02/01/2006")
+}
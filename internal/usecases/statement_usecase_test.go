@@ -0,0 +1,85 @@
+package usecases_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+)
+
+// TestStatement_ListsTheMembershipThePurchasesAndTheReturns builds the statement
+// of a member who paid a subscription, bought three waters and returned one,
+// and checks every movement lands in its section with the net of the period.
+func TestStatement_ListsTheMembershipThePurchasesAndTheReturns(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	saleUC, product, paymentMethodID, sellerID := seedPOS(t, db, plan.GymID, 5)
+
+	if _, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	sale := &entities.Sale{
+		UserID:          sellerID,
+		MemberID:        &memberID,
+		PaymentMethodID: paymentMethodID,
+		Details:         []entities.SaleDetail{{ProductID: product.ID, UnitPrice: product.UnitPrice, Quantity: 3}},
+	}
+	if err := saleUC.CreateSale(ctx, plan.GymID, sale); err != nil {
+		t.Fatalf("CreateSale: %v", err)
+	}
+	items := []usecases.ReturnItem{{DetailID: sale.Details[0].ID, Quantity: 1}}
+	if _, err := saleUC.ReturnSale(ctx, plan.GymID, sale.ID, sellerID, items, time.UTC); err != nil {
+		t.Fatalf("ReturnSale: %v", err)
+	}
+
+	statementUC := usecases.NewStatementUseCase(
+		persistence.NewSQLiteUserRepository(db),
+		persistence.NewSQLiteGymRepository(db),
+		persistence.NewSQLiteSubscriptionRepository(db),
+		persistence.NewSQLitePlanRepository(db),
+		persistence.NewSQLitePaymentRepository(db),
+		persistence.NewSQLiteSaleDetailRepository(db),
+		persistence.NewSQLiteProductRepository(db),
+		nil,
+	)
+	today := time.Now().UTC().Format("2006-01-02")
+	doc, err := statementUC.BuildStatement(ctx, plan.GymID, memberID, today, today, time.UTC)
+	if err != nil {
+		t.Fatalf("BuildStatement: %v", err)
+	}
+
+	if len(doc.Subscriptions) != 1 || doc.Subscriptions[0].PlanName != "Mensual" {
+		t.Errorf("suscripciones = %+v, want la del plan Mensual", doc.Subscriptions)
+	}
+	if len(doc.MembershipCharges) != 1 || doc.MembershipCharges[0].Amount != 100000 {
+		t.Errorf("cobros de membresía = %+v, want uno de 100000", doc.MembershipCharges)
+	}
+	if len(doc.Purchases) != 1 || doc.Purchases[0].Concept != "Botella de agua x3" || doc.Purchases[0].Amount != 6000 {
+		t.Errorf("compras = %+v, want Botella de agua x3 por 6000", doc.Purchases)
+	}
+	if len(doc.Refunds) != 1 || doc.Refunds[0].Amount != -2000 {
+		t.Errorf("devoluciones = %+v, want una de -2000", doc.Refunds)
+	}
+	if doc.TotalPaid != 106000 || doc.TotalRefunded != 2000 {
+		t.Errorf("totales = pagado %.0f, reembolsado %.0f; want 106000 y 2000", doc.TotalPaid, doc.TotalRefunded)
+	}
+
+	pdf, err := statementUC.GeneratePDF(ctx, plan.GymID, memberID, today, today, time.UTC, false)
+	if err != nil || !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Fatalf("GeneratePDF = %d bytes, %v; want un PDF", len(pdf), err)
+	}
+	other := entities.NewGym("Otro Gym", "otro@test.local", "3000000001")
+	if err := persistence.NewSQLiteGymRepository(db).Create(other); err != nil {
+		t.Fatalf("creando gimnasio: %v", err)
+	}
+	if _, err := statementUC.GeneratePDF(ctx, other.ID, memberID, today, today, time.UTC, false); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("estado de cuenta desde otro gimnasio = %v, want ErrNotFound", err)
+	}
+}
@@ -2,13 +2,16 @@ package usecases
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
 	"github.com/sebastiancorrales/gym-go/internal/domain/repositories"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+	"github.com/sebastiancorrales/gym-go/pkg/timeutil"
 )
 
 type SubscriptionUseCase struct {
@@ -17,6 +20,9 @@ type SubscriptionUseCase struct {
 	planRepo         repositories.PlanRepository
 	userRepo         repositories.UserRepository
 	auditRepo        repositories.SubscriptionAuditLogRepository
+	gymRepo          repositories.GymRepository
+	couponRepo       repositories.CouponRepository
+	paymentRepo      repositories.PaymentRepository
 	uow              repositories.UnitOfWork
 }
 
@@ -26,6 +32,9 @@ func NewSubscriptionUseCase(
 	planRepo repositories.PlanRepository,
 	userRepo repositories.UserRepository,
 	auditRepo repositories.SubscriptionAuditLogRepository,
+	gymRepo repositories.GymRepository,
+	couponRepo repositories.CouponRepository,
+	paymentRepo repositories.PaymentRepository,
 	uow repositories.UnitOfWork,
 ) *SubscriptionUseCase {
 	return &SubscriptionUseCase{
@@ -34,53 +43,129 @@ func NewSubscriptionUseCase(
 		planRepo:         planRepo,
 		userRepo:         userRepo,
 		auditRepo:        auditRepo,
+		gymRepo:          gymRepo,
+		couponRepo:       couponRepo,
+		paymentRepo:      paymentRepo,
 		uow:              uow,
 	}
 }
 
-func (uc *SubscriptionUseCase) CreateSubscription(userID, planID, gymID uuid.UUID, discount float64, paymentMethod string, additionalMemberIDs []uuid.UUID, loc *time.Location) (*entities.Subscription, error) {
+// CreateSubscription sells a plan to a member. A zero startDate, or one already
+// past, starts the period now. A future startDate leaves the subscription
+// PENDING until ActivateDueSubscriptions starts it, so a member who pays today
+// for next Monday does not lose the days in between; the sale itself is still
+// dated today, which is when the money came in.
+//
+// couponCode, if not empty, is redeemed on top of the manual discount. A
+// non-nil installments sells it partly on credit.
+func (uc *SubscriptionUseCase) CreateSubscription(userID, planID, gymID uuid.UUID, discount float64, couponCode, paymentMethod string, additionalMemberIDs []uuid.UUID, startDate time.Time, installments *Installments, changedByID uuid.UUID, changedByName string, loc *time.Location) (*entities.Subscription, error) {
+	subscription, _, err := uc.createSubscription(userID, planID, gymID, discount, couponCode, paymentMethod, additionalMemberIDs, startDate, installments, false, changedByID, changedByName, loc)
+	return subscription, err
+}
+
+// CreateSubscriptionAwaitingPayment sells a plan to be paid online through
+// paymentMethod's gateway. The subscription is left PENDING with a pending
+// payment for all of it, and nothing counts as sold until ConfirmOnlinePayment;
+// the caller opens the checkout link for the payment it returns.
+func (uc *SubscriptionUseCase) CreateSubscriptionAwaitingPayment(userID, planID, gymID uuid.UUID, couponCode, paymentMethod string, additionalMemberIDs []uuid.UUID, startDate time.Time, changedByID uuid.UUID, changedByName string, loc *time.Location) (*entities.Subscription, *entities.Payment, error) {
+	return uc.createSubscription(userID, planID, gymID, 0, couponCode, paymentMethod, additionalMemberIDs, startDate, nil, true, changedByID, changedByName, loc)
+}
+
+func (uc *SubscriptionUseCase) createSubscription(userID, planID, gymID uuid.UUID, discount float64, couponCode, paymentMethod string, additionalMemberIDs []uuid.UUID, startDate time.Time, installments *Installments, awaitPayment bool, changedByID uuid.UUID, changedByName string, loc *time.Location) (*entities.Subscription, *entities.Payment, error) {
 	// Block if primary user already has an active subscription
 	if active, err := uc.subscriptionRepo.FindActiveByUserID(userID); err == nil && active != nil {
-		return nil, errors.New("el usuario ya tiene una suscripción activa")
+		return nil, nil, fmt.Errorf("%w: el usuario ya tiene una suscripción activa", apperrors.ErrConflict)
 	}
 
 	plan, err := uc.planRepo.FindByID(planID)
 	if err != nil {
-		return nil, err
-	}
-
-	// Validate member count matches plan requirement
-	required := plan.MaxMembers - 1
-	if plan.MaxMembers > 1 && len(additionalMemberIDs) != required {
-		return nil, fmt.Errorf("el plan '%s' requiere %d persona(s) adicional(es) y se recibieron %d", plan.Name, required, len(additionalMemberIDs))
+		return nil, nil, err
 	}
 
-	// Prevent the primary user from appearing in the additional members list
-	for _, mid := range additionalMemberIDs {
-		if mid == userID {
-			return nil, errors.New("el titular no puede ser incluido como miembro adicional")
-		}
+	if err := validateGroup(plan, userID, additionalMemberIDs); err != nil {
+		return nil, nil, err
 	}
 
 	// Enrollment fee only applies on first subscription
-	enrollmentFee := plan.EnrollmentFee
+	enrollmentFee := plan.Gross(plan.EnrollmentFee)
 	if existing, err := uc.subscriptionRepo.FindByUserID(userID); err == nil && len(existing) > 0 {
 		enrollmentFee = 0
 	}
 
+	now := time.Now()
+	startsLater := startDate.After(now)
+	if !startsLater {
+		startDate = now
+	}
+
+	coupon, redemption, err := uc.applyCoupon(couponCode, plan, gymID, userID, discount, changedByID, now, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	subscription := entities.NewSubscription(
 		userID, planID, gymID,
-		time.Now(), plan.DurationDays, string(plan.BillingMode),
-		plan.Price, enrollmentFee, discount,
+		startDate, plan.DurationDays, string(plan.BillingMode),
+		plan.Gross(plan.Price), enrollmentFee, discount+redemption.Amount,
 	)
+	subscription.TaxRate = plan.TaxRate
+	subscription.CouponCode = redemption.Code
 	subscription.PaymentMethod = paymentMethod
-	localNow := time.Now().In(loc)
+	subscription.VisitAllowance = plan.VisitAllowance
+	localNow := now.In(loc)
 	subscription.Date = localNow.Format("2006-01-02")
 	subscription.Hour = localNow.Format("15:04")
-	subscription.Activate()
+	if overlap := uc.overlappingSubscription(userID, subscription); overlap != nil {
+		return nil, nil, fmt.Errorf("%w: el usuario ya tiene una suscripción del %s al %s",
+			apperrors.ErrConflict, overlap.StartDate.In(loc).Format("02/01/2006"), overlap.EndDate.In(loc).Format("02/01/2006"))
+	}
+	if !startsLater && !awaitPayment {
+		if err := subscription.Activate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// What is paid today goes into the payments ledger: all of it or, sold in
+	// installments, the down payment, with the later payments of the balance
+	// next to it in the same list.
+	if installments != nil {
+		if err := subscription.SellInInstallments(installments.DownPayment, installments.DueDate); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+		}
+	}
+	gym, err := uc.gymRepo.FindByID(gymID)
+	if err != nil {
+		return nil, nil, err
+	}
+	charge := chargeOf(subscription, uuid.New(), gym.Currency, changedByID, "Suscripción")
+	if awaitPayment {
+		if subscription.TotalPaid <= 0 {
+			return nil, nil, fmt.Errorf("%w: la suscripción no tiene nada que pagar en línea", apperrors.ErrInvalidInput)
+		}
+		if err := subscription.AwaitPayment(); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+		}
+		charge = pendingChargeOf(subscription, charge.ID, gym.Currency, changedByID)
+	}
 
 	// Members are built before the transaction so a retry reuses the same IDs.
 	members := buildGroupMembers(subscription.ID, userID, additionalMemberIDs)
+	description := fmt.Sprintf("Alta en el plan '%s'", plan.Name)
+	if startsLater {
+		description += ", empieza el " + startDate.In(loc).Format("02/01/2006")
+	}
+	if coupon != nil {
+		description += fmt.Sprintf(", cupón %s por %.0f", coupon.Code, redemption.Amount)
+		redemption.SubscriptionID = &subscription.ID
+	}
+	if subscription.BalanceDueDate != nil {
+		description += fmt.Sprintf(", a cuotas: pagó %.0f, saldo de %.0f hasta el %s",
+			subscription.AmountPaid, subscription.Balance(), subscription.BalanceDueDate.In(loc).Format("02/01/2006"))
+	}
+	if subscription.AwaitingPayment {
+		description += fmt.Sprintf(", pendiente del pago en línea de %.0f", charge.Amount)
+	}
+	log := uc.audit(subscription, entities.AuditEventCreated, nil, changedByID, changedByName, description)
 
 	// The subscription and its beneficiaries are one atomic unit. Previously each
 	// was a separate autocommit insert AND the errors from creating members were
@@ -95,12 +180,160 @@ func (uc *SubscriptionUseCase) CreateSubscription(userID, planID, gymID uuid.UUI
 				return fmt.Errorf("registrando miembro %s del grupo: %w", m.UserID, err)
 			}
 		}
-		return nil
+		if coupon != nil {
+			if err := redeemCoupon(r, coupon, redemption); err != nil {
+				return err
+			}
+		}
+		if subscription.AwaitingPayment {
+			// Not taken at any till: the money comes in through the gateway.
+			if err := r.Payments.Create(charge); err != nil {
+				return fmt.Errorf("registrando el pago de la suscripción: %w", err)
+			}
+		} else if err := createCharge(r, charge); err != nil {
+			return err
+		}
+		return r.Audit.Create(log)
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return subscription, charge, nil
+}
+
+// Installments sells a subscription partly on credit: the member pays
+// DownPayment at the counter and the rest, in as many payments as they like
+// (see RecordPayment), by DueDate.
+type Installments struct {
+	DownPayment float64
+	DueDate     time.Time
+}
+
+// RecordPayment registers a payment towards the balance of a subscription sold
+// in installments, dated today in loc. It returns the payment.
+func (uc *SubscriptionUseCase) RecordPayment(subID uuid.UUID, amount float64, paymentMethod string, changedByID uuid.UUID, changedByName string, loc *time.Location) (*entities.Payment, error) {
+	sub, err := uc.subscriptionRepo.FindByID(subID)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.IsLive() {
+		return nil, fmt.Errorf("%w: la suscripción está en estado %s, no admite abonos", apperrors.ErrConflict, sub.Status)
+	}
+	if sub.Balance() <= 0 {
+		return nil, fmt.Errorf("%w: la suscripción no tiene saldo pendiente", apperrors.ErrConflict)
+	}
+	gym, err := uc.gymRepo.FindByID(sub.GymID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := sub.Snapshot()
+	if err := sub.AddPayment(amount); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidInput, err)
+	}
+
+	if paymentMethod == "" {
+		paymentMethod = sub.PaymentMethod
+	}
+	payment := entities.NewPayment(sub.GymID, sub.UserID, changedByID, amount, gym.Currency,
+		entities.PaymentMethod(paymentMethod), entities.PaymentTypeInstallment, "Abono a suscripción")
+	payment.SubscriptionID = &sub.ID
+	payment.TaxRate = sub.TaxRate
+	localNow := payment.PaymentDate.In(loc)
+	payment.Date = localNow.Format("2006-01-02")
+	payment.Hour = localNow.Format("15:04")
+	payment.Complete("")
+
+	description := fmt.Sprintf("Abono de %.0f, saldo pendiente %.0f", amount, sub.Balance())
+	log := uc.audit(sub, entities.AuditEventPaymentRecorded, before, changedByID, changedByName, description)
+	log.OldAmount, log.NewAmount = before.AmountPaid, sub.AmountPaid
+
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		// The balance checked above may be stale by now: the repository checks
+		// it again against the row.
+		ok, err := r.Subscriptions.AddPayment(sub.ID, amount)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: el abono de %.0f supera el saldo pendiente", apperrors.ErrConflict, amount)
+		}
+		if err := takenAtRegister(r, payment); err != nil {
+			return err
+		}
+		if err := r.Payments.Create(payment); err != nil {
+			return err
+		}
+		return r.Audit.Create(log)
 	}); err != nil {
 		return nil, err
 	}
 
-	return subscription, nil
+	return payment, nil
+}
+
+// GetPayments lists what has been paid towards a subscription, oldest first.
+func (uc *SubscriptionUseCase) GetPayments(subID uuid.UUID) ([]*entities.Payment, error) {
+	return uc.paymentRepo.FindBySubscriptionID(subID)
+}
+
+// applyCoupon resolves couponCode for a subscription of memberID to plan. The
+// coupon discounts what is left of the plan price after the manual discount,
+// never the enrollment fee. With no code it returns a nil coupon and an empty
+// redemption, so callers can add redemption.Amount unconditionally.
+func (uc *SubscriptionUseCase) applyCoupon(couponCode string, plan *entities.Plan, gymID, memberID uuid.UUID, discount float64, redeemedBy uuid.UUID, now time.Time, loc *time.Location) (*entities.Coupon, *entities.CouponRedemption, error) {
+	if strings.TrimSpace(couponCode) == "" {
+		return nil, &entities.CouponRedemption{}, nil
+	}
+	coupon, err := lookupCoupon(uc.couponRepo, gymID, couponCode, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !coupon.AppliesToPlan(plan.ID) {
+		return nil, nil, fmt.Errorf("%w: el cupón %s no aplica al plan '%s'", apperrors.ErrInvalidCoupon, coupon.Code, plan.Name)
+	}
+	amount := coupon.DiscountOn(plan.Gross(plan.Price) - discount)
+	if amount == 0 {
+		return nil, nil, fmt.Errorf("%w: el cupón %s no deja nada que descontar", apperrors.ErrInvalidCoupon, coupon.Code)
+	}
+	return coupon, entities.NewCouponRedemption(coupon, &memberID, amount, redeemedBy, now, loc), nil
+}
+
+// overlappingSubscription returns a live subscription of the user, running or
+// paid to start later, whose period overlaps that of sub, or nil. The active
+// check above only sees the running one; without this a future-dated sale
+// could be stacked on top of another. Checkouts still awaiting their online
+// payment hold no paid days and do not count.
+func (uc *SubscriptionUseCase) overlappingSubscription(userID uuid.UUID, sub *entities.Subscription) *entities.Subscription {
+	subs, err := uc.subscriptionRepo.FindByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	for _, s := range subs {
+		if s.IsLive() && !s.AwaitingPayment && s.StartDate.Before(sub.EndDate) && sub.StartDate.Before(s.EndDate) {
+			return s
+		}
+	}
+	return nil
+}
+
+// validateGroup checks the beneficiaries handed in for a plan: a group plan needs
+// exactly MaxMembers-1 of them besides the holder, and the holder cannot be
+// listed again as a beneficiary.
+func validateGroup(plan *entities.Plan, holderID uuid.UUID, additionalMemberIDs []uuid.UUID) error {
+	// Validate member count matches plan requirement
+	required := plan.MaxMembers - 1
+	if plan.MaxMembers > 1 && len(additionalMemberIDs) != required {
+		return fmt.Errorf("%w: el plan '%s' requiere %d persona(s) adicional(es) y se recibieron %d", apperrors.ErrInvalidInput, plan.Name, required, len(additionalMemberIDs))
+	}
+
+	// Prevent the primary user from appearing in the additional members list
+	for _, mid := range additionalMemberIDs {
+		if mid == holderID {
+			return fmt.Errorf("%w: el titular no puede ser incluido como miembro adicional", apperrors.ErrInvalidInput)
+		}
+	}
+	return nil
 }
 
 // buildGroupMembers returns the subscription_members rows for a group plan: the
@@ -132,6 +365,10 @@ func buildGroupMembers(subscriptionID, holderID uuid.UUID, additionalMemberIDs [
 	return members
 }
 
+func (uc *SubscriptionUseCase) GetSubscription(id uuid.UUID) (*entities.Subscription, error) {
+	return uc.subscriptionRepo.FindByID(id)
+}
+
 func (uc *SubscriptionUseCase) GetActiveSubscription(userID uuid.UUID) (*entities.Subscription, error) {
 	return uc.subscriptionRepo.FindActiveByUserID(userID)
 }
@@ -144,21 +381,236 @@ func (uc *SubscriptionUseCase) ListSubscriptionsWithFilters(gymID uuid.UUID, fil
 	return uc.subscriptionRepo.FindByGymIDWithFilters(gymID, filter, limit, offset)
 }
 
-func (uc *SubscriptionUseCase) CancelSubscription(id uuid.UUID, reason string, cancelledBy uuid.UUID) error {
+// CancelSubscription cancels a subscription. With refund set, the gym's refund
+// policy (Gym.CancellationRefund) decides how much goes back, and it is recorded
+// against the payments of the subscription so the daily close of the day shows
+// it as money out. It returns the refunded payments, none when nothing was
+// refunded.
+func (uc *SubscriptionUseCase) CancelSubscription(id uuid.UUID, reason string, cancelledBy uuid.UUID, cancelledByName string, refund bool, loc *time.Location) ([]*entities.Payment, error) {
 	subscription, err := uc.subscriptionRepo.FindByID(id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	// Checked before the refund is worked out: a cancelled or expired
+	// subscription has nothing left to give back.
+	if err := subscription.CanTransitionTo(entities.SubscriptionStatusCancelled); err != nil {
+		return nil, err
+	}
+
+	var amount float64
+	var currency string
+	if refund {
+		gym, err := uc.gymRepo.FindByID(subscription.GymID)
+		if err != nil {
+			return nil, err
+		}
+		amount = gym.CancellationRefund(subscription, time.Now())
+		currency = gym.Currency
+	}
+
+	before := subscription.Snapshot()
+	if err := subscription.Cancel(reason, cancelledBy); err != nil {
+		return nil, err
+	}
+
+	refundReason := "Cancelación"
+	if reason != "" {
+		refundReason = "Cancelación: " + reason
+	}
+	description := refundReason
+	if amount > 0 {
+		description = fmt.Sprintf("%s, reembolso de %.0f", refundReason, amount)
+	}
+	log := uc.audit(subscription, entities.AuditEventCancelled, before, cancelledBy, cancelledByName, description)
+	refundDate := time.Now().In(loc).Format("2006-01-02")
+	chargeID := uuid.New()
+
+	var refunded []*entities.Payment
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Subscriptions.Update(subscription); err != nil {
+			return err
+		}
+		if err := r.Audit.Create(log); err != nil {
+			return err
+		}
+		if amount == 0 {
+			return nil
+		}
+
+		payments, err := r.Payments.FindBySubscriptionID(subscription.ID)
+		if err != nil {
+			return err
+		}
+		// Only what paid for the plan is refundable; freeze fees and the like
+		// hang from the subscription too. Of a subscription paid in
+		// installments the refund is spread over its payments newest first,
+		// each giving back at most what it brought in.
+		var paidWith []*entities.Payment
+		paidFor := false
+		covered := 0.0
+		for i := len(payments) - 1; i >= 0 && covered < amount; i-- {
+			p := payments[i]
+			if p.PaymentType != entities.PaymentTypeSubscription && p.PaymentType != entities.PaymentTypeInstallment {
+				continue
+			}
+			paidFor = true
+			if p.IsCompleted() && p.Amount > 0 {
+				paidWith = append(paidWith, p)
+				covered += p.Amount
+			}
+		}
+		if paidFor && covered < amount {
+			return fmt.Errorf("%w: los pagos de la suscripción no cubren el reembolso de %.0f", apperrors.ErrConflict, amount)
+		}
+		if !paidFor {
+			// Subscriptions sold before the payments ledger carry their charge only
+			// in TotalPaid: record it now so the refund has something to hang from.
+			payment := chargeOf(subscription, chargeID, currency, cancelledBy, "Suscripción")
+			if err := r.Payments.Create(payment); err != nil {
+				return fmt.Errorf("registrando el pago de la suscripción: %w", err)
+			}
+			paidWith = []*entities.Payment{payment}
+		}
+
+		// The function may run again on a retry: start over every time.
+		refunded = nil
+		left := amount
+		for _, payment := range paidWith {
+			share := math.Min(left, payment.Amount)
+			if err := payment.Refund(share, refundReason); err != nil {
+				return fmt.Errorf("%w: %v", apperrors.ErrConflict, err)
+			}
+			payment.RefundDate = refundDate
+			if err := refundedAtRegister(r, payment, cancelledBy); err != nil {
+				return err
+			}
+			if err := r.Payments.Update(payment); err != nil {
+				return err
+			}
+			refunded = append(refunded, payment)
+			left -= share
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return refunded, nil
+}
+
+// chargeOf builds the completed payment a subscription was sold with, dated on
+// the day of the sale: all of it, or the down payment of an installment sale.
+// Its amount is 0 when nothing was paid (a free transfer, a plan change the
+// credit covers) and createCharge then leaves it out of the ledger.
+func chargeOf(sub *entities.Subscription, id uuid.UUID, currency string, processedBy uuid.UUID, description string) *entities.Payment {
+	payment := entities.NewPayment(sub.GymID, sub.UserID, processedBy, sub.AmountPaid, currency,
+		entities.PaymentMethod(sub.PaymentMethod), entities.PaymentTypeSubscription, description)
+	payment.ID = id
+	payment.SubscriptionID = &sub.ID
+	payment.TaxRate = sub.TaxRate
+	payment.PaymentDate = sub.CreatedAt
+	payment.Date = sub.Date
+	payment.Hour = sub.Hour
+	payment.Complete("")
+	return payment
+}
+
+// pendingChargeOf builds the payment a subscription awaiting an online payment
+// waits for. It has no Date until it comes in: the ledger counts the money on
+// the day it arrives.
+func pendingChargeOf(sub *entities.Subscription, id uuid.UUID, currency string, processedBy uuid.UUID) *entities.Payment {
+	payment := entities.NewPayment(sub.GymID, sub.UserID, processedBy, sub.TotalPaid, currency,
+		entities.PaymentMethod(sub.PaymentMethod), entities.PaymentTypeSubscription, "Suscripción (pago en línea)")
+	payment.ID = id
+	payment.SubscriptionID = &sub.ID
+	payment.TaxRate = sub.TaxRate
+	return payment
+}
+
+// ConfirmOnlinePayment records that the online payment paymentID came in, for
+// amount under the gateway's transactionID, and starts its subscription (see
+// Subscription.ConfirmPayment). Gateways deliver their webhooks more than once:
+// a payment already confirmed is not an error, it returns its subscription as
+// it is.
+func (uc *SubscriptionUseCase) ConfirmOnlinePayment(paymentID uuid.UUID, transactionID string, amount float64) (*entities.Subscription, error) {
+	var confirmed *entities.Subscription
+	err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		// Read inside the transaction: two deliveries of the same webhook must
+		// not both find the payment pending.
+		payment, err := r.Payments.FindByID(paymentID)
+		if err != nil {
+			return err
+		}
+		if payment.SubscriptionID == nil {
+			return fmt.Errorf("%w: el pago %s no es de una suscripción", apperrors.ErrInvalidInput, paymentID)
+		}
+		sub, err := r.Subscriptions.FindByID(*payment.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		confirmed = sub
+		if payment.IsCompleted() {
+			return nil
+		}
+		if payment.Status != entities.PaymentStatusPending || !sub.AwaitingPayment {
+			return fmt.Errorf("%w: el pago está %s y la suscripción %s", apperrors.ErrConflict, payment.Status, sub.Status)
+		}
+		if amount < payment.Amount {
+			return fmt.Errorf("%w: se pagaron %.0f de %.0f", apperrors.ErrConflict, amount, payment.Amount)
+		}
+		gym, err := r.Gyms.FindByID(sub.GymID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC().Round(0)
+		before := sub.Snapshot()
+		if err := sub.ConfirmPayment(now); err != nil {
+			return err
+		}
+		payment.Complete(transactionID)
+		local := now.In(timeutil.LoadLocationOrUTC(gym.Timezone))
+		payment.PaymentDate = now
+		payment.Date = local.Format("2006-01-02")
+		payment.Hour = local.Format("15:04")
+
+		description := fmt.Sprintf("Pago en línea de %.0f confirmado (%s)", payment.Amount, transactionID)
+		log := uc.audit(sub, entities.AuditEventPaymentRecorded, before, uuid.Nil, entities.SystemActor, description)
+		log.OldAmount, log.NewAmount = before.AmountPaid, sub.AmountPaid
+		if err := r.Subscriptions.Update(sub); err != nil {
+			return err
+		}
+		if err := r.Payments.Update(payment); err != nil {
+			return err
+		}
+		return r.Audit.Create(log)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return confirmed, nil
+}
 
-	subscription.Cancel(reason, cancelledBy)
-	return uc.subscriptionRepo.Update(subscription)
+// createCharge records charge inside the transaction that sells its
+// subscription, unless nothing was charged.
+func createCharge(r repositories.Repos, charge *entities.Payment) error {
+	if charge.Amount <= 0 {
+		return nil
+	}
+	if err := takenAtRegister(r, charge); err != nil {
+		return err
+	}
+	if err := r.Payments.Create(charge); err != nil {
+		return fmt.Errorf("registrando el pago de la suscripción: %w", err)
+	}
+	return nil
 }
 
 func (uc *SubscriptionUseCase) UpdateSubscription(sub *entities.Subscription) error {
 	return uc.subscriptionRepo.Update(sub)
 }
 
-func (uc *SubscriptionUseCase) RenewSubscription(currentSubID uuid.UUID, planID uuid.UUID, gymID uuid.UUID, discount float64, paymentMethod string, additionalMemberIDs []uuid.UUID, loc *time.Location) (*entities.Subscription, error) {
+func (uc *SubscriptionUseCase) RenewSubscription(currentSubID uuid.UUID, planID uuid.UUID, gymID uuid.UUID, discount float64, couponCode, paymentMethod string, additionalMemberIDs []uuid.UUID, changedByID uuid.UUID, changedByName string, loc *time.Location) (*entities.Subscription, error) {
 	current, err := uc.subscriptionRepo.FindByID(currentSubID)
 	if err != nil {
 		return nil, err
@@ -168,86 +620,584 @@ func (uc *SubscriptionUseCase) RenewSubscription(currentSubID uuid.UUID, planID
 		return nil, err
 	}
 
-	// Validate member count matches plan requirement
-	required := plan.MaxMembers - 1
-	if plan.MaxMembers > 1 && len(additionalMemberIDs) != required {
-		return nil, fmt.Errorf("el plan '%s' requiere %d persona(s) adicional(es) y se recibieron %d", plan.Name, required, len(additionalMemberIDs))
+	coupon, redemption, err := uc.applyCoupon(couponCode, plan, gymID, current.UserID, discount, changedByID, time.Now(), loc)
+	if err != nil {
+		return nil, err
+	}
+	newSub, members, _, err := uc.buildRenewal(current, plan, gymID, plan.Gross(plan.EnrollmentFee), discount+redemption.Amount, paymentMethod, additionalMemberIDs, loc)
+	if err != nil {
+		return nil, err
+	}
+	newSub.CouponCode = redemption.Code
+	gym, err := uc.gymRepo.FindByID(gymID)
+	if err != nil {
+		return nil, err
+	}
+	charge := chargeOf(newSub, uuid.New(), gym.Currency, changedByID, "Renovación")
+	description := fmt.Sprintf("Renovación de la suscripción %s en el plan '%s'", current.ID, plan.Name)
+	if coupon != nil {
+		description += fmt.Sprintf(", cupón %s por %.0f", coupon.Code, redemption.Amount)
+		redemption.SubscriptionID = &newSub.ID
 	}
+	log := uc.audit(newSub, entities.AuditEventRenewed, nil, changedByID, changedByName, description)
 
-	// Prevent the primary user from appearing in the additional members list
-	for _, mid := range additionalMemberIDs {
-		if mid == current.UserID {
-			return nil, errors.New("el titular no puede ser incluido como miembro adicional")
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Subscriptions.Create(newSub); err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := r.Members.Create(m); err != nil {
+				return fmt.Errorf("registrando miembro %s del grupo: %w", m.UserID, err)
+			}
 		}
+		if coupon != nil {
+			if err := redeemCoupon(r, coupon, redemption); err != nil {
+				return err
+			}
+		}
+		if err := createCharge(r, charge); err != nil {
+			return err
+		}
+		return r.Audit.Create(log)
+	}); err != nil {
+		return nil, err
+	}
+
+	return newSub, nil
+}
+
+// buildRenewal validates a renewal of `current` onto `plan` and returns the new
+// period, its group members and the latest subscription of the holder, without
+// persisting anything. Both the renew endpoint and the auto-renew job go through
+// here so they chain periods and validate groups the same way.
+func (uc *SubscriptionUseCase) buildRenewal(current *entities.Subscription, plan *entities.Plan, gymID uuid.UUID, enrollmentFee, discount float64, paymentMethod string, additionalMemberIDs []uuid.UUID, loc *time.Location) (*entities.Subscription, []*entities.SubscriptionMember, *entities.Subscription, error) {
+	if err := current.CanRenew(); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := validateGroup(plan, current.UserID, additionalMemberIDs); err != nil {
+		return nil, nil, nil, err
 	}
 
-	// Find the latest end date across all user subscriptions (chain from the furthest)
-	latestEnd := current.EndDate
+	// Find the latest end date across the user's live subscriptions (chain from
+	// the furthest). A cancelled or expired one is not a period the member will
+	// use, so it neither pushes the start out nor counts as already renewed.
+	latest := current
 	if allSubs, err := uc.subscriptionRepo.FindByUserID(current.UserID); err == nil {
 		for _, s := range allSubs {
-			if s.EndDate.After(latestEnd) {
-				latestEnd = s.EndDate
+			if s.IsLive() && s.EndDate.After(latest.EndDate) {
+				latest = s
 			}
 		}
 	}
 
 	// Start from the latest end date, or today if it's already in the past
-	startDate := latestEnd
+	startDate := latest.EndDate
 	if startDate.Before(time.Now()) {
 		startDate = time.Now()
 	}
 	newSub := entities.NewSubscription(
-		current.UserID, planID, gymID,
+		current.UserID, plan.ID, gymID,
 		startDate, plan.DurationDays, string(plan.BillingMode),
-		plan.Price, plan.EnrollmentFee, discount,
+		plan.Gross(plan.Price), enrollmentFee, discount,
 	)
+	newSub.TaxRate = plan.TaxRate
 	newSub.PaymentMethod = paymentMethod
+	newSub.VisitAllowance = plan.VisitAllowance
 	localNow := time.Now().In(loc)
 	newSub.Date = localNow.Format("2006-01-02")
 	newSub.Hour = localNow.Format("15:04")
-	newSub.Activate()
+	if err := newSub.Activate(); err != nil {
+		return nil, nil, nil, err
+	}
 
 	members := buildGroupMembers(newSub.ID, current.UserID, additionalMemberIDs)
+	return newSub, members, latest, nil
+}
 
-	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
-		if err := r.Subscriptions.Create(newSub); err != nil {
-			return err
+// ChangePlan moves an active subscription to another plan mid-cycle. The current
+// period is closed today and a new one starts on the new plan, with the unused
+// days of the old one credited against its price (see Subscription.UnusedCredit
+// and ApplyCredit). No enrollment fee is charged: the member already joined.
+//
+// Going from an individual plan to a group plan needs the new beneficiaries in
+// additionalMemberIDs. Between group plans of the same size they may be omitted
+// and the current ones carry over; moving to an individual plan drops them.
+//
+// Both subscriptions get an audit entry with the amount paid for the old plan and
+// the amount due for the new one, so the credit can be traced from either side.
+func (uc *SubscriptionUseCase) ChangePlan(subID, newPlanID uuid.UUID, additionalMemberIDs []uuid.UUID, paymentMethod string, changedByID uuid.UUID, changedByName string, loc *time.Location) (*entities.Subscription, error) {
+	current, err := uc.subscriptionRepo.FindByID(subID)
+	if err != nil {
+		return nil, err
+	}
+	if !current.IsActive() {
+		return nil, fmt.Errorf("%w: solo se puede cambiar el plan de una suscripción activa", apperrors.ErrInvalidInput)
+	}
+	if current.PlanID == newPlanID {
+		return nil, fmt.Errorf("%w: la suscripción ya está en ese plan", apperrors.ErrInvalidInput)
+	}
+	// The credit assumes the plan was paid in full: what is still owed has to
+	// be paid (or the subscription cancelled) first.
+	if balance := current.Balance(); balance > 0 {
+		return nil, fmt.Errorf("%w: la suscripción tiene un saldo pendiente de %.0f", apperrors.ErrConflict, balance)
+	}
+
+	oldPlan, err := uc.planRepo.FindByID(current.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	newPlan, err := uc.planRepo.FindByID(newPlanID)
+	if err != nil {
+		return nil, err
+	}
+	if !newPlan.IsActive() || newPlan.GymID != current.GymID {
+		return nil, fmt.Errorf("%w: el plan '%s' no está disponible", apperrors.ErrInvalidInput, newPlan.Name)
+	}
+
+	if len(additionalMemberIDs) == 0 && newPlan.MaxMembers > 1 && newPlan.MaxMembers == oldPlan.MaxMembers {
+		members, err := uc.memberRepo.FindBySubscriptionID(current.ID)
+		if err != nil {
+			return nil, err
 		}
 		for _, m := range members {
-			if err := r.Members.Create(m); err != nil {
-				return fmt.Errorf("registrando miembro %s del grupo: %w", m.UserID, err)
+			if !m.IsPrimary {
+				additionalMemberIDs = append(additionalMemberIDs, m.UserID)
 			}
 		}
-		return nil
-	}); err != nil {
+	}
+	if newPlan.MaxMembers <= 1 {
+		additionalMemberIDs = nil
+	}
+	if err := validateGroup(newPlan, current.UserID, additionalMemberIDs); err != nil {
 		return nil, err
 	}
+	for _, memberID := range additionalMemberIDs {
+		if _, err := uc.checkBeneficiary(current.GymID, current.ID, memberID); err != nil {
+			return nil, err
+		}
+	}
 
-	return newSub, nil
-}
+	now := time.Now().UTC().Round(0)
+	credit := current.UnusedCredit(now)
 
-func (uc *SubscriptionUseCase) FreezeSubscription(id uuid.UUID, days int, reason string) error {
-	sub, err := uc.subscriptionRepo.FindByID(id)
-	if err != nil {
-		return err
+	newSub := entities.NewSubscription(
+		current.UserID, newPlan.ID, current.GymID,
+		now, newPlan.DurationDays, string(newPlan.BillingMode),
+		newPlan.Gross(newPlan.Price), 0, 0,
+	)
+	newSub.TaxRate = newPlan.TaxRate
+	newSub.VisitAllowance = newPlan.VisitAllowance
+	newSub.ApplyCredit(credit)
+	newSub.PaymentMethod = paymentMethod
+	newSub.AutoRenew = current.AutoRenew
+	newSub.Notes = fmt.Sprintf("Cambio desde el plan '%s' (suscripción %s)", oldPlan.Name, current.ID)
+	localNow := now.In(loc)
+	newSub.Date = localNow.Format("2006-01-02")
+	newSub.Hour = localNow.Format("15:04")
+	if err := newSub.Activate(); err != nil {
+		return nil, err
 	}
+	members := buildGroupMembers(newSub.ID, current.UserID, additionalMemberIDs)
+	gym, err := uc.gymRepo.FindByID(current.GymID)
+	if err != nil {
+		return nil, err
+	}
+	charge := chargeOf(newSub, uuid.New(), gym.Currency, changedByID, "Cambio de plan")
+
+	before := current.Snapshot()
+	// The unused days now live in the new subscription, so the old period is
+	// closed as changed, not cancelled: the member did not leave.
+	if err := current.CloseForPlanChange(now); err != nil {
+		return nil, err
+	}
+
+	closedLog := uc.audit(current, entities.AuditEventPlanChanged, before, changedByID, changedByName,
+		fmt.Sprintf("Cambio de plan '%s' → '%s': crédito de %.0f por los días no usados", oldPlan.Name, newPlan.Name, credit))
+	openedLog := uc.audit(newSub, entities.AuditEventPlanChanged, nil, changedByID, changedByName,
+		fmt.Sprintf("Cambio de plan '%s' → '%s' desde la suscripción %s: precio %.0f, crédito %.0f, a pagar %.0f",
+			oldPlan.Name, newPlan.Name, current.ID, newSub.PricePaid, newSub.ProrationCredit, newSub.TotalPaid))
+	closedLog.OldAmount, closedLog.NewAmount = before.TotalPaid, newSub.TotalPaid
+	openedLog.OldAmount, openedLog.NewAmount = before.TotalPaid, newSub.TotalPaid
+
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Subscriptions.Update(current); err != nil {
+			return err
+		}
+		if err := r.Subscriptions.Create(newSub); err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := r.Members.Create(m); err != nil {
+				return fmt.Errorf("registrando miembro %s del grupo: %w", m.UserID, err)
+			}
+		}
+		if err := createCharge(r, charge); err != nil {
+			return err
+		}
+		if err := r.Audit.Create(closedLog); err != nil {
+			return err
+		}
+		return r.Audit.Create(openedLog)
+	}); err != nil {
+		return nil, err
+	}
+
+	return newSub, nil
+}
+
+// TransferSubscription hands the rest of an active subscription to another
+// member of the same gym, for the member who sells or gives away the rest of
+// their month.
+//
+// The original subscription keeps its TotalPaid untouched — that money came in
+// on the day it was sold and the daily close already counted it — and is closed
+// today. The receiver gets a new subscription on the same plan, from now to the
+// original end date, whose only charge is the optional transfer fee: PricePaid
+// is zero so the period is not counted as revenue twice. Punch cards pass on the
+// visits that are left, and group beneficiaries stay with the group. When the
+// receiver was one of them, the former holder takes their seat, so the group
+// keeps the size it was sold with; they can leave it through
+// RemoveGroupMember. The original is closed as TRANSFERRED, not cancelled.
+func (uc *SubscriptionUseCase) TransferSubscription(subID, toUserID uuid.UUID, fee float64, paymentMethod string, changedByID uuid.UUID, changedByName string, loc *time.Location) (*entities.Subscription, error) {
+	if fee < 0 {
+		return nil, fmt.Errorf("%w: la tarifa de traspaso no puede ser negativa", apperrors.ErrInvalidInput)
+	}
+	source, err := uc.subscriptionRepo.FindByID(subID)
+	if err != nil {
+		return nil, err
+	}
+	if !source.IsActive() {
+		return nil, fmt.Errorf("%w: solo se puede traspasar una suscripción activa", apperrors.ErrInvalidInput)
+	}
+	if source.UserID == toUserID {
+		return nil, fmt.Errorf("%w: la suscripción ya es de ese usuario", apperrors.ErrInvalidInput)
+	}
+	// The new holder only pays the fee: a balance left on the source would be
+	// written off with it.
+	if balance := source.Balance(); balance > 0 {
+		return nil, fmt.Errorf("%w: la suscripción tiene un saldo pendiente de %.0f", apperrors.ErrConflict, balance)
+	}
+
+	from, err := uc.userRepo.FindByID(source.UserID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := uc.userRepo.FindByID(toUserID)
+	if err != nil {
+		return nil, err
+	}
+	if to.GymID != source.GymID {
+		return nil, fmt.Errorf("%w: el usuario no pertenece a este gimnasio", apperrors.ErrInvalidInput)
+	}
+	if active, err := uc.subscriptionRepo.FindActiveByUserID(toUserID); err == nil && active != nil {
+		return nil, fmt.Errorf("%w: %s ya tiene una suscripción activa", apperrors.ErrConflict, to.FullName())
+	}
+	if group := uc.liveGroupOf(toUserID); group != nil && group.ID != source.ID {
+		return nil, fmt.Errorf("%w: %s ya es beneficiario de otra suscripción grupal activa", apperrors.ErrConflict, to.FullName())
+	}
+
+	members, err := uc.memberRepo.FindBySubscriptionID(source.ID)
+	if err != nil {
+		return nil, err
+	}
+	var beneficiaries []uuid.UUID
+	for _, m := range members {
+		switch {
+		case m.IsPrimary:
+		case m.UserID == toUserID:
+			beneficiaries = append(beneficiaries, source.UserID)
+		default:
+			beneficiaries = append(beneficiaries, m.UserID)
+		}
+	}
+
+	now := time.Now().UTC().Round(0)
+	sourceID := source.ID
+	received := entities.NewSubscription(toUserID, source.PlanID, source.GymID, now, 0, "", 0, 0, 0)
+	received.EndDate = source.EndDate
+	// The fee is what the receiver pays today; it is kept out of PricePaid so
+	// proration and refunds never treat it as the price of the plan.
+	received.TotalPaid = fee
+	received.AmountPaid = fee
+	received.PaymentMethod = paymentMethod
+	received.TransferredFromID = &sourceID
+	received.TaxRate = source.TaxRate
+	if source.IsVisitBased() {
+		received.VisitAllowance = source.VisitsLeft()
+	}
+	received.Notes = fmt.Sprintf("Traspaso de %s (suscripción %s)", from.FullName(), source.ID)
+	localNow := now.In(loc)
+	received.Date = localNow.Format("2006-01-02")
+	received.Hour = localNow.Format("15:04")
+	if err := received.Activate(); err != nil {
+		return nil, err
+	}
+	newMembers := buildGroupMembers(received.ID, toUserID, beneficiaries)
+	gym, err := uc.gymRepo.FindByID(source.GymID)
+	if err != nil {
+		return nil, err
+	}
+	charge := chargeOf(received, uuid.New(), gym.Currency, changedByID, "Traspaso")
+
+	before := source.Snapshot()
+	daysLeft := source.DaysRemaining()
+	if err := source.CloseForTransfer(now); err != nil {
+		return nil, err
+	}
+
+	sentLog := uc.audit(source, entities.AuditEventTransferred, before, changedByID, changedByName,
+		fmt.Sprintf("Traspasada a %s con %d día(s) restantes", to.FullName(), daysLeft))
+	receivedLog := uc.audit(received, entities.AuditEventTransferred, nil, changedByID, changedByName,
+		fmt.Sprintf("Recibida por traspaso de %s (suscripción %s), tarifa %.0f", from.FullName(), source.ID, fee))
+	receivedLog.OldAmount, receivedLog.NewAmount = 0, fee
+
+	if err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Subscriptions.Update(source); err != nil {
+			return err
+		}
+		if err := r.Subscriptions.Create(received); err != nil {
+			return err
+		}
+		for _, m := range newMembers {
+			if err := r.Members.Create(m); err != nil {
+				return fmt.Errorf("registrando miembro %s del grupo: %w", m.UserID, err)
+			}
+		}
+		if err := createCharge(r, charge); err != nil {
+			return err
+		}
+		if err := r.Audit.Create(sentLog); err != nil {
+			return err
+		}
+		return r.Audit.Create(receivedLog)
+	}); err != nil {
+		return nil, err
+	}
+
+	return received, nil
+}
+
+// FreezeSubscription pauses a subscription under the freeze rules of its plan
+// (see entities.FreezePolicy). A request that breaks them fails with
+// apperrors.ErrFreezeNotAllowed and the rule that was broken. When the plan
+// charges for freezing, the fee is recorded as its own payment, dated today, in
+// the same transaction as the freeze.
+func (uc *SubscriptionUseCase) FreezeSubscription(id uuid.UUID, days int, reason, paymentMethod string, changedByID uuid.UUID, changedByName string, loc *time.Location) error {
+	sub, err := uc.subscriptionRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := sub.CanTransitionTo(entities.SubscriptionStatusFrozen); err != nil {
+		return err
+	}
+	plan, err := uc.planRepo.FindByID(sub.PlanID)
+	if err != nil {
+		return err
+	}
+	if err := plan.Freeze.Check(sub, days); err != nil {
+		return err
+	}
+
+	var fee *entities.Payment
+	if plan.Freeze.Fee > 0 {
+		gym, err := uc.gymRepo.FindByID(sub.GymID)
+		if err != nil {
+			return err
+		}
+		if paymentMethod == "" {
+			paymentMethod = sub.PaymentMethod
+		}
+		fee = entities.NewPayment(sub.GymID, sub.UserID, changedByID, plan.Gross(plan.Freeze.Fee), gym.Currency,
+			entities.PaymentMethod(paymentMethod), entities.PaymentTypeOther, "Congelamiento")
+		fee.SubscriptionID = &sub.ID
+		fee.TaxRate = plan.TaxRate
+		localNow := fee.PaymentDate.In(loc)
+		fee.Date = localNow.Format("2006-01-02")
+		fee.Hour = localNow.Format("15:04")
+		fee.Complete("")
+	}
+
+	before := sub.Snapshot()
 	// Freeze owns the end-date extension now; doing it here as well was what
 	// double-counted the freeze.
-	sub.Freeze(days, reason)
-	return uc.subscriptionRepo.Update(sub)
+	if err := sub.Freeze(days, reason); err != nil {
+		return err
+	}
+
+	description := fmt.Sprintf("Congelada %d día(s)", days)
+	if reason != "" {
+		description += ": " + reason
+	}
+	if fee != nil {
+		description += fmt.Sprintf(", tarifa de %.0f", fee.Amount)
+	}
+	log := uc.audit(sub, entities.AuditEventFrozen, before, changedByID, changedByName, description)
+	if fee != nil {
+		log.OldAmount, log.NewAmount = 0, fee.Amount
+	}
+
+	return uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Subscriptions.Update(sub); err != nil {
+			return err
+		}
+		if fee != nil {
+			if err := takenAtRegister(r, fee); err != nil {
+				return err
+			}
+			if err := r.Payments.Create(fee); err != nil {
+				return fmt.Errorf("registrando la tarifa de congelamiento: %w", err)
+			}
+		}
+		return r.Audit.Create(log)
+	})
 }
 
-func (uc *SubscriptionUseCase) UnfreezeSubscription(id uuid.UUID) error {
+func (uc *SubscriptionUseCase) UnfreezeSubscription(id uuid.UUID, changedByID uuid.UUID, changedByName string) error {
 	sub, err := uc.subscriptionRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
-	sub.Unfreeze()
-	return uc.subscriptionRepo.Update(sub)
+	before := sub.Snapshot()
+	if err := sub.Unfreeze(); err != nil {
+		return err
+	}
+	return uc.saveWithAudit(sub, uc.audit(sub, entities.AuditEventUnfrozen, before, changedByID, changedByName, "Descongelada"))
+}
+
+// BulkExtension is the outcome of ExtendSubscriptions: the subscriptions it
+// extended or, on a dry run, would extend.
+type BulkExtension struct {
+	Days            int         `json:"days"`
+	DryRun          bool        `json:"dry_run"`
+	Affected        int         `json:"affected"`
+	SubscriptionIDs []uuid.UUID `json:"subscription_ids"`
+}
+
+// ExtendSubscriptions gives every ACTIVE and FROZEN subscription of a gym days
+// more, to make up for days the gym was closed. planIDs narrows it to some
+// plans. It is all or nothing: the lookup, every new end date and an audit
+// entry per subscription share one transaction. A dry run only looks them up,
+// so the admin can see how many members a closure touches before applying it.
+func (uc *SubscriptionUseCase) ExtendSubscriptions(gymID uuid.UUID, days int, planIDs []uuid.UUID, reason string, dryRun bool, changedByID uuid.UUID, changedByName string) (*BulkExtension, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("%w: los días a extender deben ser más de cero", apperrors.ErrInvalidInput)
+	}
+	description := fmt.Sprintf("Extendida %d día(s)", days)
+	if reason != "" {
+		description += ": " + reason
+	}
+
+	result := &BulkExtension{Days: days, DryRun: dryRun}
+	collect := func(subs []*entities.Subscription) {
+		result.Affected = len(subs)
+		result.SubscriptionIDs = make([]uuid.UUID, len(subs))
+		for i, sub := range subs {
+			result.SubscriptionIDs[i] = sub.ID
+		}
+	}
+
+	if dryRun {
+		subs, err := uc.subscriptionRepo.FindToExtend(gymID, planIDs)
+		if err != nil {
+			return nil, err
+		}
+		collect(subs)
+		return result, nil
+	}
+
+	err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		subs, err := r.Subscriptions.FindToExtend(gymID, planIDs)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			before := sub.Snapshot()
+			if err := sub.Extend(days); err != nil {
+				return err
+			}
+			if err := r.Subscriptions.Update(sub); err != nil {
+				return err
+			}
+			if err := r.Audit.Create(uc.audit(sub, entities.AuditEventExtended, before, changedByID, changedByName, description)); err != nil {
+				return err
+			}
+		}
+		collect(subs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
+// AutoExpireSubscriptions expires the active subscriptions past their end date,
+// leaving a system entry in the audit log of each one. The lookup, the update
+// and the entries share one transaction so no subscription is expired without
+// its entry.
 func (uc *SubscriptionUseCase) AutoExpireSubscriptions() (int64, error) {
-	return uc.subscriptionRepo.MarkExpiredSubscriptions()
+	var expired int64
+	err := uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		subs, err := r.Subscriptions.FindToExpire(time.Now())
+		if err != nil || len(subs) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(subs))
+		for i, sub := range subs {
+			ids[i] = sub.ID
+		}
+		n, err := r.Subscriptions.ExpireByIDs(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range subs {
+			before := sub.Snapshot()
+			if err := sub.Expire(); err != nil {
+				return err
+			}
+			log := entities.NewSubscriptionAuditLog(sub.ID, entities.AuditEventExpired, before, sub.Snapshot(),
+				uuid.Nil, entities.SystemActor, "Vencida")
+			if err := r.Audit.Create(log); err != nil {
+				return err
+			}
+		}
+		expired = n
+		return nil
+	})
+	return expired, err
+}
+
+// ActivateDueSubscriptions starts the future-dated subscriptions whose start
+// date has arrived. It runs hourly, so a period that starts at midnight is live
+// within the hour; a member who arrives before the pass gets it started by
+// RecordEntry, and before the start date is told when it starts.
+func (uc *SubscriptionUseCase) ActivateDueSubscriptions() (int, error) {
+	now := time.Now()
+	subs, err := uc.subscriptionRepo.FindDueToStart(now)
+	if err != nil {
+		return 0, err
+	}
+
+	activated := 0
+	var firstErr error
+	for _, sub := range subs {
+		before := sub.Snapshot()
+		if !sub.ActivateIfDue(now) {
+			continue
+		}
+		log := uc.audit(sub, entities.AuditEventActivated, before, uuid.Nil, "", "Inicio del periodo")
+		if err := uc.saveWithAudit(sub, log); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		activated++
+	}
+
+	return activated, firstErr
 }
 
 // AutoUnfreezeSubscriptions reactivates subscriptions whose freeze period is over.
@@ -267,8 +1217,15 @@ func (uc *SubscriptionUseCase) AutoUnfreezeSubscriptions() (int, error) {
 		if !sub.FreezeExpired() {
 			continue
 		}
-		sub.Unfreeze()
-		if err := uc.subscriptionRepo.Update(sub); err != nil {
+		before := sub.Snapshot()
+		if err := sub.Unfreeze(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		log := uc.audit(sub, entities.AuditEventUnfrozen, before, uuid.Nil, "", "Fin del congelamiento")
+		if err := uc.saveWithAudit(sub, log); err != nil {
 			if firstErr == nil {
 				firstErr = err
 			}
@@ -280,6 +1237,160 @@ func (uc *SubscriptionUseCase) AutoUnfreezeSubscriptions() (int, error) {
 	return reactivated, firstErr
 }
 
+// SetAutoRenew turns automatic renewal on or off for a subscription. Only a
+// subscription that still has a future can be put on auto-renew.
+func (uc *SubscriptionUseCase) SetAutoRenew(id uuid.UUID, enabled bool, changedByID uuid.UUID, changedByName string) error {
+	sub, err := uc.subscriptionRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if enabled && sub.HasEnded() {
+		return fmt.Errorf("%w: una suscripción %s no puede renovarse automáticamente", apperrors.ErrInvalidInput, sub.Status)
+	}
+	before := sub.Snapshot()
+	sub.AutoRenew = enabled
+
+	description := "Renovación automática desactivada"
+	if enabled {
+		description = "Renovación automática activada"
+	}
+	return uc.saveWithAudit(sub, uc.audit(sub, entities.AuditEventAutoRenewChanged, before, changedByID, changedByName, description))
+}
+
+// AutoRenewSubscriptions creates the next period for every auto-renew
+// subscription that ends within `grace`, through the same path as the renew
+// endpoint: same plan, same beneficiaries, same payment method, no enrollment
+// fee and no discount.
+//
+// It runs before AutoExpireSubscriptions on purpose. Renewing ahead of the end
+// date means the member always has an ACTIVE period when the expiry pass gets to
+// the old one, so a member on auto-renew is never turned away at the turnstile
+// for a charge the system itself was supposed to make.
+//
+// The flag moves to the new period in the same transaction that creates it; the
+// old one is left with AutoRenew off so the next run cannot renew it twice. If
+// reception already renewed by hand, no second period is charged: the flag is
+// just handed over to the latest one.
+func (uc *SubscriptionUseCase) AutoRenewSubscriptions(grace time.Duration, defaultLoc *time.Location) (int, error) {
+	subs, err := uc.subscriptionRepo.FindDueForAutoRenew(time.Now().Add(grace))
+	if err != nil {
+		return 0, err
+	}
+
+	locs := make(map[uuid.UUID]*time.Location)
+	renewed := 0
+	var firstErr error
+	for _, sub := range subs {
+		loc, ok := locs[sub.GymID]
+		if !ok {
+			loc = defaultLoc
+			if gym, err := uc.gymRepo.FindByID(sub.GymID); err == nil && gym.Timezone != "" {
+				loc = timeutil.LoadLocationOrUTC(gym.Timezone)
+			}
+			locs[sub.GymID] = loc
+		}
+
+		if err := uc.autoRenew(sub, loc); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("renovando suscripción %s: %w", sub.ID, err)
+			}
+			continue
+		}
+		renewed++
+	}
+
+	return renewed, firstErr
+}
+
+func (uc *SubscriptionUseCase) autoRenew(current *entities.Subscription, loc *time.Location) error {
+	plan, err := uc.planRepo.FindByID(current.PlanID)
+	if err != nil {
+		return err
+	}
+	if !plan.IsActive() {
+		return fmt.Errorf("el plan '%s' ya no está activo", plan.Name)
+	}
+
+	var additionalIDs []uuid.UUID
+	members, err := uc.memberRepo.FindBySubscriptionID(current.ID)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if !m.IsPrimary {
+			additionalIDs = append(additionalIDs, m.UserID)
+		}
+	}
+
+	newSub, newMembers, latest, err := uc.buildRenewal(current, plan, current.GymID, 0, 0, current.PaymentMethod, additionalIDs, loc)
+	if err != nil {
+		return err
+	}
+
+	before := current.Snapshot()
+	current.AutoRenew = false
+
+	// Already renewed by hand: hand the flag over instead of charging twice.
+	if latest.ID != current.ID {
+		latestBefore := latest.Snapshot()
+		latest.AutoRenew = true
+		handedOff := uc.audit(current, entities.AuditEventAutoRenewChanged, before, uuid.Nil, "",
+			fmt.Sprintf("Renovación automática pasada a la suscripción %s, ya renovada", latest.ID))
+		handedOn := uc.audit(latest, entities.AuditEventAutoRenewChanged, latestBefore, uuid.Nil, "",
+			fmt.Sprintf("Renovación automática recibida de la suscripción %s", current.ID))
+		return uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+			if err := r.Subscriptions.Update(current); err != nil {
+				return err
+			}
+			if err := r.Subscriptions.Update(latest); err != nil {
+				return err
+			}
+			if err := r.Audit.Create(handedOff); err != nil {
+				return err
+			}
+			return r.Audit.Create(handedOn)
+		})
+	}
+
+	renewedFrom := current.ID
+	newSub.AutoRenew = true
+	newSub.RenewedFromID = &renewedFrom
+	method := current.PaymentMethod
+	if method == "" {
+		method = "sin método registrado"
+	}
+	newSub.Notes = fmt.Sprintf("Renovación automática de la suscripción %s, cobrada con %s", current.ID, method)
+	renewedLog := uc.audit(newSub, entities.AuditEventAutoRenewed, nil, uuid.Nil, "", newSub.Notes)
+	gym, err := uc.gymRepo.FindByID(current.GymID)
+	if err != nil {
+		return err
+	}
+	charge := chargeOf(newSub, uuid.New(), gym.Currency, uuid.Nil, "Renovación automática")
+	handedOff := uc.audit(current, entities.AuditEventAutoRenewChanged, before, uuid.Nil, "",
+		fmt.Sprintf("Renovación automática pasada a la suscripción %s", newSub.ID))
+
+	return uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Subscriptions.Create(newSub); err != nil {
+			return err
+		}
+		for _, m := range newMembers {
+			if err := r.Members.Create(m); err != nil {
+				return fmt.Errorf("registrando miembro %s del grupo: %w", m.UserID, err)
+			}
+		}
+		if err := r.Subscriptions.Update(current); err != nil {
+			return err
+		}
+		if err := createCharge(r, charge); err != nil {
+			return err
+		}
+		if err := r.Audit.Create(renewedLog); err != nil {
+			return err
+		}
+		return r.Audit.Create(handedOff)
+	})
+}
+
 func (uc *SubscriptionUseCase) GetActiveCount(gymID uuid.UUID) (int64, error) {
 	return uc.subscriptionRepo.CountActiveByGymID(gymID)
 }
@@ -311,6 +1422,177 @@ func (uc *SubscriptionUseCase) GetMembersBySubscriptionIDs(subscriptionIDs []uui
 	return bySub, nil
 }
 
+// AddGroupMember adds a beneficiary to a group subscription after it was sold,
+// in a free slot of the plan.
+func (uc *SubscriptionUseCase) AddGroupMember(subID, userID, changedByID uuid.UUID, changedByName string) error {
+	return uc.changeGroupMembers(subID, uuid.Nil, userID, changedByID, changedByName)
+}
+
+// RemoveGroupMember takes a beneficiary out of a group subscription, leaving
+// their slot free. The holder cannot be removed: the subscription is theirs.
+func (uc *SubscriptionUseCase) RemoveGroupMember(subID, userID, changedByID uuid.UUID, changedByName string) error {
+	return uc.changeGroupMembers(subID, userID, uuid.Nil, changedByID, changedByName)
+}
+
+// ReplaceGroupMember swaps one beneficiary for another in a single step, for the
+// partner who quits and is replaced by someone else.
+func (uc *SubscriptionUseCase) ReplaceGroupMember(subID, oldUserID, newUserID, changedByID uuid.UUID, changedByName string) error {
+	return uc.changeGroupMembers(subID, oldUserID, newUserID, changedByID, changedByName)
+}
+
+// changeGroupMembers removes `out` and/or adds `in` (uuid.Nil to skip either)
+// and writes the audit entry, all in one transaction.
+//
+// The rules are the ones CreateSubscription applies when the group is sold: the
+// group never exceeds Plan.MaxMembers, the holder stays, and a beneficiary must
+// be a user of the same gym who does not already belong to another live group —
+// a user in two groups would have check-in resolve whichever the query finds
+// first.
+func (uc *SubscriptionUseCase) changeGroupMembers(subID, out, in, changedByID uuid.UUID, changedByName string) error {
+	sub, err := uc.subscriptionRepo.FindByID(subID)
+	if err != nil {
+		return err
+	}
+	if sub.HasEnded() {
+		return fmt.Errorf("%w: no se pueden cambiar los beneficiarios de una suscripción %s", apperrors.ErrInvalidInput, sub.Status)
+	}
+	plan, err := uc.planRepo.FindByID(sub.PlanID)
+	if err != nil {
+		return err
+	}
+	if plan.MaxMembers <= 1 {
+		return fmt.Errorf("%w: el plan '%s' es individual", apperrors.ErrInvalidInput, plan.Name)
+	}
+
+	members, err := uc.memberRepo.FindBySubscriptionID(subID)
+	if err != nil {
+		return err
+	}
+	count := len(members)
+	holderRow := count > 0
+	if !holderRow {
+		count = 1 // the holder always counts, even before the group has rows
+	}
+
+	var description []string
+	if out != uuid.Nil {
+		if out == sub.UserID {
+			return fmt.Errorf("%w: el titular no puede salir de su propia suscripción", apperrors.ErrInvalidInput)
+		}
+		if !containsMember(members, out) {
+			return fmt.Errorf("%w: el usuario no es beneficiario de esta suscripción", apperrors.ErrNotFound)
+		}
+		count--
+		description = append(description, "Beneficiario retirado: "+uc.memberName(out))
+	}
+
+	if in != uuid.Nil {
+		if in == sub.UserID || containsMember(members, in) {
+			return fmt.Errorf("%w: el usuario ya pertenece a este grupo", apperrors.ErrConflict)
+		}
+		user, err := uc.checkBeneficiary(sub.GymID, subID, in)
+		if err != nil {
+			return err
+		}
+		if count+1 > plan.MaxMembers {
+			return fmt.Errorf("%w: el plan '%s' admite %d persona(s) y el grupo está completo", apperrors.ErrConflict, plan.Name, plan.MaxMembers)
+		}
+		description = append(description, "Beneficiario agregado: "+user.FullName())
+	}
+
+	now := time.Now().UTC().Round(0)
+	var rows []*entities.SubscriptionMember
+	if in != uuid.Nil {
+		if !holderRow {
+			rows = append(rows, &entities.SubscriptionMember{
+				ID:             uuid.New(),
+				SubscriptionID: subID,
+				UserID:         sub.UserID,
+				IsPrimary:      true,
+				CreatedAt:      now,
+			})
+		}
+		rows = append(rows, &entities.SubscriptionMember{
+			ID:             uuid.New(),
+			SubscriptionID: subID,
+			UserID:         in,
+			IsPrimary:      false,
+			CreatedAt:      now,
+		})
+	}
+
+	log := uc.audit(sub, entities.AuditEventMembersChanged, sub.Snapshot(), changedByID, changedByName, strings.Join(description, "; "))
+
+	return uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if out != uuid.Nil {
+			if err := r.Members.DeleteBySubscriptionAndUser(subID, out); err != nil {
+				return err
+			}
+		}
+		for _, m := range rows {
+			if err := r.Members.Create(m); err != nil {
+				return fmt.Errorf("registrando miembro %s del grupo: %w", m.UserID, err)
+			}
+		}
+		return r.Audit.Create(log)
+	})
+}
+
+// checkBeneficiary returns the user joining the group subscription groupID
+// after checking they may: a user of the same gym who does not already belong to
+// another live group. groupID is the group they may already be in, so members
+// carried over to a new period of the same group pass.
+func (uc *SubscriptionUseCase) checkBeneficiary(gymID, groupID, userID uuid.UUID) (*entities.User, error) {
+	user, err := uc.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("%w: el beneficiario %s no existe", apperrors.ErrInvalidInput, userID)
+	}
+	if user.GymID != gymID {
+		return nil, fmt.Errorf("%w: el usuario no pertenece a este gimnasio", apperrors.ErrInvalidInput)
+	}
+	if other := uc.liveGroupOf(userID); other != nil && other.ID != groupID {
+		return nil, fmt.Errorf("%w: %s ya es beneficiario de otra suscripción grupal activa", apperrors.ErrConflict, user.FullName())
+	}
+	return user, nil
+}
+
+func containsMember(members []*entities.SubscriptionMember, userID uuid.UUID) bool {
+	for _, m := range members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// liveGroupOf returns a group subscription the user belongs to that is still in
+// force (active, frozen or about to start), or nil.
+func (uc *SubscriptionUseCase) liveGroupOf(userID uuid.UUID) *entities.Subscription {
+	subs, err := uc.memberRepo.FindSubscriptionsByMemberUserID(userID)
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	for _, s := range subs {
+		switch s.Status {
+		case entities.SubscriptionStatusActive, entities.SubscriptionStatusFrozen, entities.SubscriptionStatusPending:
+			if s.EndDate.After(now) {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// memberName resolves a user's name for an audit description, falling back to
+// the ID so a deleted user still leaves a readable trail.
+func (uc *SubscriptionUseCase) memberName(userID uuid.UUID) string {
+	if u, err := uc.userRepo.FindByID(userID); err == nil {
+		return u.FullName()
+	}
+	return userID.String()
+}
+
 // GetSubscriptionsAsMember returns subscriptions where the user is a group member (beneficiary).
 func (uc *SubscriptionUseCase) GetSubscriptionsAsMember(userID uuid.UUID) ([]*entities.Subscription, error) {
 	return uc.memberRepo.FindSubscriptionsByMemberUserID(userID)
@@ -323,26 +1605,17 @@ func (uc *SubscriptionUseCase) UpdateDates(subID uuid.UUID, newStart, newEnd tim
 		return err
 	}
 
-	log := &entities.SubscriptionAuditLog{
-		ID:             uuid.New(),
-		SubscriptionID: subID,
-		ChangedByID:    changedByID,
-		ChangedByName:  changedByName,
-		Description:    "Fechas editadas manualmente",
-		OldStartDate:   sub.StartDate,
-		NewStartDate:   newStart,
-		OldEndDate:     sub.EndDate,
-		NewEndDate:     newEnd,
-		CreatedAt:      time.Now().UTC().Round(0),
-	}
-
+	before := sub.Snapshot()
 	sub.StartDate = newStart
 	sub.EndDate = newEnd
 	sub.UpdatedAt = time.Now().UTC().Round(0)
 	// Reactivate if it was expired and new end is in the future
 	if sub.Status == entities.SubscriptionStatusExpired && newEnd.After(time.Now()) {
-		sub.Status = entities.SubscriptionStatusActive
+		if err := sub.TransitionTo(entities.SubscriptionStatusActive); err != nil {
+			return err
+		}
 	}
+	log := uc.audit(sub, entities.AuditEventDatesEdited, before, changedByID, changedByName, "Fechas editadas manualmente")
 
 	// Atomic: the dates and the audit entry that explains them go together. Before,
 	// a failure writing the log left the dates already changed with no record of
@@ -355,7 +1628,31 @@ func (uc *SubscriptionUseCase) UpdateDates(subID uuid.UUID, newStart, newEnd tim
 	})
 }
 
-// GetAuditLog returns the edit history of a subscription.
+// GetAuditLog returns the full history of a subscription, newest first.
 func (uc *SubscriptionUseCase) GetAuditLog(subID uuid.UUID) ([]*entities.SubscriptionAuditLog, error) {
 	return uc.auditRepo.FindBySubscriptionID(subID)
 }
+
+// audit builds the entry for an event on sub, whose current state is the
+// "after". uuid.Nil as the actor means one of the scheduler jobs. The access
+// token carries only the user's ID, so a missing name is resolved here.
+func (uc *SubscriptionUseCase) audit(sub *entities.Subscription, event entities.AuditEvent, before *entities.SubscriptionSnapshot, changedByID uuid.UUID, changedByName, description string) *entities.SubscriptionAuditLog {
+	switch {
+	case changedByID == uuid.Nil:
+		changedByName = entities.SystemActor
+	case changedByName == "":
+		changedByName = uc.memberName(changedByID)
+	}
+	return entities.NewSubscriptionAuditLog(sub.ID, event, before, sub.Snapshot(), changedByID, changedByName, description)
+}
+
+// saveWithAudit updates sub and writes the entry that explains the change in
+// one transaction.
+func (uc *SubscriptionUseCase) saveWithAudit(sub *entities.Subscription, log *entities.SubscriptionAuditLog) error {
+	return uc.uow.Do(context.Background(), func(r repositories.Repos) error {
+		if err := r.Subscriptions.Update(sub); err != nil {
+			return err
+		}
+		return r.Audit.Create(log)
+	})
+}
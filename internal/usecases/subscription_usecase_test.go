@@ -0,0 +1,613 @@
+package usecases_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sebastiancorrales/gym-go/internal/domain/entities"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
+	"github.com/sebastiancorrales/gym-go/internal/usecases"
+	apperrors "github.com/sebastiancorrales/gym-go/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// TestAutoRenew_RenewsOnceAheadOfEndDate checks the two things the hourly job
+// must never get wrong: the new period chains from the old end date (no days
+// lost, none gifted), and a second run does not charge the member again.
+func TestAutoRenew_RenewsOnceAheadOfEndDate(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	subRepo := persistence.NewSQLiteSubscriptionRepository(db)
+
+	current := entities.NewSubscription(memberID, plan.ID, plan.GymID,
+		time.Now().AddDate(0, -1, 0), plan.DurationDays, string(plan.BillingMode),
+		plan.Price, 0, 0)
+	current.EndDate = time.Now().Add(3 * time.Hour).UTC().Round(0)
+	current.PaymentMethod = "CARD"
+	current.AutoRenew = true
+	current.Activate()
+	if err := subRepo.Create(current); err != nil {
+		t.Fatalf("creando suscripción: %v", err)
+	}
+	// Un periodo posterior ya cancelado no es una renovación hecha a mano: no debe
+	// llevarse el flag ni correr el inicio del nuevo periodo.
+	cancelled := entities.NewSubscription(memberID, plan.ID, plan.GymID,
+		current.EndDate, plan.DurationDays, string(plan.BillingMode), plan.Price, 0, 0)
+	cancelled.Activate()
+	cancelled.Cancel("se arrepintió", uuid.Nil)
+	if err := subRepo.Create(cancelled); err != nil {
+		t.Fatalf("creando suscripción cancelada: %v", err)
+	}
+
+	n, err := subUC.AutoRenewSubscriptions(48*time.Hour, time.UTC)
+	if err != nil {
+		t.Fatalf("AutoRenewSubscriptions: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("renovadas = %d, want 1", n)
+	}
+
+	subs, err := subRepo.FindByUserID(memberID)
+	if err != nil {
+		t.Fatalf("FindByUserID: %v", err)
+	}
+	if len(subs) != 3 {
+		t.Fatalf("el socio tiene %d suscripciones, want 3", len(subs))
+	}
+
+	var renewed, old *entities.Subscription
+	for _, s := range subs {
+		switch s.ID {
+		case current.ID:
+			old = s
+		case cancelled.ID:
+		default:
+			renewed = s
+		}
+	}
+	if renewed.RenewedFromID == nil || *renewed.RenewedFromID != current.ID {
+		t.Errorf("RenewedFromID = %v, want %s", renewed.RenewedFromID, current.ID)
+	}
+	if !renewed.StartDate.Equal(current.EndDate) {
+		t.Errorf("el nuevo periodo empieza %v, want %v", renewed.StartDate, current.EndDate)
+	}
+	if renewed.PaymentMethod != "CARD" || renewed.EnrollmentFeePaid != 0 || renewed.TotalPaid != plan.Price {
+		t.Errorf("cobro = %s/%v/%v, want CARD sin matrícula por %v",
+			renewed.PaymentMethod, renewed.EnrollmentFeePaid, renewed.TotalPaid, plan.Price)
+	}
+	if old.AutoRenew || !renewed.AutoRenew {
+		t.Errorf("AutoRenew viejo=%v nuevo=%v, el flag debe pasar al nuevo periodo", old.AutoRenew, renewed.AutoRenew)
+	}
+
+	if n, err := subUC.AutoRenewSubscriptions(48*time.Hour, time.UTC); err != nil || n != 0 {
+		t.Fatalf("segunda pasada renovó %d (err %v), want 0", n, err)
+	}
+}
+
+// TestGroupMembers_RespectsPlanSizeAndOtherGroups covers the two rules that keep
+// check-in unambiguous: a group never exceeds its plan, and nobody belongs to
+// two live groups at once.
+func TestGroupMembers_RespectsPlanSizeAndOtherGroups(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, holderID := seedSubscriptions(t, db)
+
+	duo := entities.NewPlan(plan.GymID, "Pareja", 30, 140000)
+	duo.MaxMembers = 2
+	if err := persistence.NewSQLitePlanRepository(db).Create(duo); err != nil {
+		t.Fatalf("creando plan: %v", err)
+	}
+	partner := seedMember(t, db, plan.GymID, "pareja@test.local")
+	replacement := seedMember(t, db, plan.GymID, "reemplazo@test.local")
+	otherHolder := seedMember(t, db, plan.GymID, "otro@test.local")
+
+	sub, err := subUC.CreateSubscription(holderID, duo.ID, duo.GymID, 0, "", "CASH", []uuid.UUID{partner}, time.Time{}, nil, holderID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	if err := subUC.AddGroupMember(sub.ID, replacement, holderID, "test"); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("agregar a un grupo completo: err = %v, want ErrConflict", err)
+	}
+	if err := subUC.RemoveGroupMember(sub.ID, holderID, holderID, "test"); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("retirar al titular: err = %v, want ErrInvalidInput", err)
+	}
+	if err := subUC.ReplaceGroupMember(sub.ID, partner, replacement, holderID, "test"); err != nil {
+		t.Fatalf("ReplaceGroupMember: %v", err)
+	}
+
+	members, _ := subUC.GetSubscriptionMembers(sub.ID)
+	if len(members) != 2 {
+		t.Fatalf("el grupo tiene %d filas, want 2", len(members))
+	}
+	for _, m := range members {
+		if m.UserID == partner {
+			t.Error("el beneficiario reemplazado sigue en el grupo")
+		}
+	}
+
+	// `replacement` ya está en un grupo vivo: no puede entrar en otro.
+	other, err := subUC.CreateSubscription(otherHolder, duo.ID, duo.GymID, 0, "", "CASH", []uuid.UUID{partner}, time.Time{}, nil, holderID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if err := subUC.ReplaceGroupMember(other.ID, partner, replacement, otherHolder, "test"); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("beneficiario en dos grupos: err = %v, want ErrConflict", err)
+	}
+
+	logs, _ := subUC.GetAuditLog(sub.ID)
+	changes := 0
+	for _, l := range logs {
+		if l.Event == entities.AuditEventMembersChanged {
+			changes++
+		}
+	}
+	if changes != 1 {
+		t.Errorf("cambios de beneficiarios auditados = %d, want 1 (el reemplazo)", changes)
+	}
+}
+
+// TestCancel_RefundsTheUnusedDaysUnderAProratedPolicy checks that a prorated
+// refund leaves the enrollment fee with the gym, lands on the subscription's
+// payment and is dated on the day of the cancellation, and that the cancelled
+// subscription cannot be renewed.
+func TestCancel_RefundsTheUnusedDaysUnderAProratedPolicy(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	gym, _ := gymRepo.FindByID(plan.GymID)
+	gym.RefundPolicy = entities.RefundPolicyProrated
+	gym.RefundWindowDays = 15
+	if err := gymRepo.Update(gym); err != nil {
+		t.Fatalf("actualizando gimnasio: %v", err)
+	}
+
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	// 10 de 30 días ya usados: se devuelven 20/30 del plan, nunca la matrícula.
+	sub.StartDate = sub.StartDate.AddDate(0, 0, -10)
+	sub.EndDate = sub.EndDate.AddDate(0, 0, -10)
+	if err := subUC.UpdateSubscription(sub); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+
+	refunds, err := subUC.CancelSubscription(sub.ID, "se muda", memberID, "test", true, time.UTC)
+	if err != nil {
+		t.Fatalf("CancelSubscription: %v", err)
+	}
+	if len(refunds) != 1 {
+		t.Fatalf("pagos reembolsados = %d, want 1", len(refunds))
+	}
+	refund := refunds[0]
+	want := sub.UnusedCredit(time.Now())
+	if refund.RefundedAmount != want || want >= plan.Price {
+		t.Fatalf("reembolso = %+v, want %v (< %v)", refund, want, plan.Price)
+	}
+	if refund.Amount != plan.Price+plan.EnrollmentFee {
+		t.Errorf("el pago registra %v, want lo cobrado %v", refund.Amount, plan.Price+plan.EnrollmentFee)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	dayRefunds, err := persistence.NewSQLitePaymentRepository(db).FindRefundsByDateRange(plan.GymID, today, today)
+	if err != nil || len(dayRefunds) != 1 {
+		t.Fatalf("reembolsos del día = %d (err %v), want 1", len(dayRefunds), err)
+	}
+
+	cancelled, _ := persistence.NewSQLiteSubscriptionRepository(db).FindByID(sub.ID)
+	if cancelled.Status != entities.SubscriptionStatusCancelled {
+		t.Errorf("estado = %s, want CANCELLED", cancelled.Status)
+	}
+	if _, err := subUC.RenewSubscription(sub.ID, plan.ID, plan.GymID, 0, "", "CASH", nil, memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrInvalidTransition) {
+		t.Errorf("renovar una cancelada: err = %v, want ErrInvalidTransition", err)
+	}
+}
+
+// TestInstallments_OweBeforeChangingAndRefundEveryPayment sells a plan in
+// installments: while a balance is owed it can be neither changed nor
+// transferred, a cancellation refunds its payments newest first, and a
+// cancelled subscription takes no more payments.
+func TestInstallments_OweBeforeChangingAndRefundEveryPayment(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	gym, _ := gymRepo.FindByID(plan.GymID)
+	gym.RefundPolicy = entities.RefundPolicyFull
+	if err := gymRepo.Update(gym); err != nil {
+		t.Fatalf("actualizando gimnasio: %v", err)
+	}
+	other := entities.NewPlan(plan.GymID, "Trimestral", 90, 200000)
+	if err := persistence.NewSQLitePlanRepository(db).Create(other); err != nil {
+		t.Fatalf("creando plan: %v", err)
+	}
+	receiver := seedMember(t, db, plan.GymID, "receptor@test.local")
+
+	installments := &usecases.Installments{DownPayment: 50000, DueDate: time.Now().AddDate(0, 0, 15)}
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, installments, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	if _, err := subUC.ChangePlan(sub.ID, other.ID, nil, "CASH", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("cambio de plan con saldo pendiente: err = %v, want ErrConflict", err)
+	}
+	if _, err := subUC.TransferSubscription(sub.ID, receiver, 0, "CASH", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("traspaso con saldo pendiente: err = %v, want ErrConflict", err)
+	}
+
+	if _, err := subUC.RecordPayment(sub.ID, 30000, "CASH", memberID, "test", time.UTC); err != nil {
+		t.Fatalf("RecordPayment: %v", err)
+	}
+	// Lo pagado menos la matrícula: 80000 - 20000 = 60000, más de lo que trae
+	// cualquiera de los dos pagos.
+	refunds, err := subUC.CancelSubscription(sub.ID, "", memberID, "test", true, time.UTC)
+	if err != nil {
+		t.Fatalf("CancelSubscription: %v", err)
+	}
+	if len(refunds) != 2 {
+		t.Fatalf("pagos reembolsados = %d, want 2", len(refunds))
+	}
+	if refunds[0].PaymentType != entities.PaymentTypeInstallment || refunds[0].RefundedAmount != 30000 || refunds[1].RefundedAmount != 30000 {
+		t.Errorf("reembolsos = %s %.0f y %s %.0f, want el abono de 30000 entero y 30000 del pago inicial",
+			refunds[0].PaymentType, refunds[0].RefundedAmount, refunds[1].PaymentType, refunds[1].RefundedAmount)
+	}
+
+	if _, err := subUC.RecordPayment(sub.ID, 20000, "CASH", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("abono a una suscripción cancelada: err = %v, want ErrConflict", err)
+	}
+}
+
+// TestAuditLog_RecordsEveryMutationWithItsActor walks a subscription through the
+// operations reception and the scheduler perform and checks the timeline names
+// each event, who did it and the state on both sides.
+func TestAuditLog_RecordsEveryMutationWithItsActor(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	staff := seedMember(t, db, plan.GymID, "recepcion@test.local")
+
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, staff, "", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if err := subUC.FreezeSubscription(sub.ID, 5, "viaje", "", staff, "", time.UTC); err != nil {
+		t.Fatalf("FreezeSubscription: %v", err)
+	}
+	if err := subUC.UnfreezeSubscription(sub.ID, staff, ""); err != nil {
+		t.Fatalf("UnfreezeSubscription: %v", err)
+	}
+
+	// Vencida a mano para que la pase el job horario.
+	sub, _ = persistence.NewSQLiteSubscriptionRepository(db).FindByID(sub.ID)
+	sub.EndDate = time.Now().Add(-time.Hour)
+	if err := subUC.UpdateSubscription(sub); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+	if n, err := subUC.AutoExpireSubscriptions(); err != nil || n != 1 {
+		t.Fatalf("AutoExpireSubscriptions = %d (err %v), want 1", n, err)
+	}
+
+	logs, err := subUC.GetAuditLog(sub.ID)
+	if err != nil {
+		t.Fatalf("GetAuditLog: %v", err)
+	}
+	want := []entities.AuditEvent{
+		entities.AuditEventCreated, entities.AuditEventFrozen,
+		entities.AuditEventUnfrozen, entities.AuditEventExpired,
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("la línea de tiempo tiene %d entradas, want %d", len(logs), len(want))
+	}
+	byEvent := make(map[entities.AuditEvent]*entities.SubscriptionAuditLog)
+	for _, l := range logs {
+		byEvent[l.Event] = l
+	}
+	for _, e := range want {
+		if byEvent[e] == nil {
+			t.Errorf("falta el evento %s", e)
+		}
+	}
+
+	if frozen := byEvent[entities.AuditEventFrozen]; frozen != nil {
+		if frozen.ChangedByID != staff || frozen.ChangedByName == "" {
+			t.Errorf("congelamiento hecho por %s %q, want %s con su nombre", frozen.ChangedByID, frozen.ChangedByName, staff)
+		}
+		if frozen.Before.Status != entities.SubscriptionStatusActive || frozen.After.Status != entities.SubscriptionStatusFrozen {
+			t.Errorf("congelamiento %s → %s, want ACTIVE → FROZEN", frozen.Before.Status, frozen.After.Status)
+		}
+	}
+	if expired := byEvent[entities.AuditEventExpired]; expired != nil {
+		if expired.ChangedByID != uuid.Nil || expired.ChangedByName != entities.SystemActor {
+			t.Errorf("vencimiento hecho por %s %q, want el sistema", expired.ChangedByID, expired.ChangedByName)
+		}
+	}
+}
+
+// TestFreeze_EnforcesThePlanPolicyAndChargesItsFee checks each rule of a plan's
+// freeze policy against TotalFreezeDays/FreezeCount, and that the fee is
+// recorded as a payment of its own that a cancellation never refunds.
+func TestFreeze_EnforcesThePlanPolicyAndChargesItsFee(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+
+	plan.Freeze = entities.FreezePolicy{MaxDays: 10, MinDays: 3, MaxCount: 2, Fee: 5000}
+	if err := persistence.NewSQLitePlanRepository(db).Update(plan); err != nil {
+		t.Fatalf("actualizando plan: %v", err)
+	}
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	if err := subUC.FreezeSubscription(sub.ID, 2, "", "", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrFreezeNotAllowed) {
+		t.Fatalf("congelar 2 días con mínimo de 3: err = %v, want ErrFreezeNotAllowed", err)
+	}
+	if err := subUC.FreezeSubscription(sub.ID, 7, "viaje", "", memberID, "test", time.UTC); err != nil {
+		t.Fatalf("FreezeSubscription: %v", err)
+	}
+	if err := subUC.UnfreezeSubscription(sub.ID, memberID, "test"); err != nil {
+		t.Fatalf("UnfreezeSubscription: %v", err)
+	}
+	// Volvió de inmediato: los 7 días se devuelven y el máximo vuelve a estar libre.
+	if err := subUC.FreezeSubscription(sub.ID, 10, "", "", memberID, "test", time.UTC); err != nil {
+		t.Fatalf("FreezeSubscription tras descongelar: %v", err)
+	}
+	if err := subUC.UnfreezeSubscription(sub.ID, memberID, "test"); err != nil {
+		t.Fatalf("UnfreezeSubscription: %v", err)
+	}
+	if err := subUC.FreezeSubscription(sub.ID, 3, "", "", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrFreezeNotAllowed) {
+		t.Fatalf("tercer congelamiento con máximo de 2: err = %v, want ErrFreezeNotAllowed", err)
+	}
+
+	payments, err := persistence.NewSQLitePaymentRepository(db).FindBySubscriptionID(sub.ID)
+	if err != nil || len(payments) != 3 {
+		t.Fatalf("pagos de la suscripción = %d (err %v), want la venta y 2 tarifas", len(payments), err)
+	}
+	if payments[0].PaymentType != entities.PaymentTypeSubscription || payments[0].Amount != sub.TotalPaid {
+		t.Errorf("primer pago = %+v, want la venta por %v", payments[0], sub.TotalPaid)
+	}
+	for _, p := range payments[1:] {
+		if p.Amount != plan.Freeze.Fee || p.PaymentType != entities.PaymentTypeOther || !p.IsCompleted() {
+			t.Errorf("tarifa = %+v, want %v completada", p, plan.Freeze.Fee)
+		}
+	}
+}
+
+// TestExtend_DryRunCountsThenExtendsRunningSubscriptions closes the gym for a
+// week: the dry run changes nothing, the real run moves the end date of the
+// active and frozen subscriptions only and audits each of them.
+func TestExtend_DryRunCountsThenExtendsRunningSubscriptions(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	subRepo := persistence.NewSQLiteSubscriptionRepository(db)
+
+	active, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	other := seedMember(t, db, plan.GymID, "congelado@test.local")
+	frozen, err := subUC.CreateSubscription(other, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if err := subUC.FreezeSubscription(frozen.ID, 5, "", "", memberID, "test", time.UTC); err != nil {
+		t.Fatalf("FreezeSubscription: %v", err)
+	}
+	third := seedMember(t, db, plan.GymID, "cancelado@test.local")
+	cancelled, err := subUC.CreateSubscription(third, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if _, err := subUC.CancelSubscription(cancelled.ID, "", memberID, "test", false, time.UTC); err != nil {
+		t.Fatalf("CancelSubscription: %v", err)
+	}
+	frozen, _ = subRepo.FindByID(frozen.ID)
+
+	dry, err := subUC.ExtendSubscriptions(plan.GymID, 7, nil, "remodelación", true, memberID, "test")
+	if err != nil || dry.Affected != 2 {
+		t.Fatalf("simulación = %+v (err %v), want 2 afectadas", dry, err)
+	}
+	if stored, _ := subRepo.FindByID(active.ID); !stored.EndDate.Equal(active.EndDate) {
+		t.Fatalf("la simulación movió la fecha de fin a %v", stored.EndDate)
+	}
+
+	if _, err := subUC.ExtendSubscriptions(plan.GymID, 7, []uuid.UUID{plan.ID}, "remodelación", false, memberID, "test"); err != nil {
+		t.Fatalf("ExtendSubscriptions: %v", err)
+	}
+	for _, before := range []*entities.Subscription{active, frozen} {
+		stored, _ := subRepo.FindByID(before.ID)
+		if want := before.EndDate.AddDate(0, 0, 7); !stored.EndDate.Equal(want) {
+			t.Errorf("fin de %s = %v, want %v", before.Status, stored.EndDate, want)
+		}
+		logs, _ := subUC.GetAuditLog(before.ID)
+		extended := 0
+		for _, l := range logs {
+			if l.Event == entities.AuditEventExtended {
+				extended++
+			}
+		}
+		if extended != 1 {
+			t.Errorf("%d eventos EXTENDED para la suscripción %s, want 1", extended, before.Status)
+		}
+	}
+	if stored, _ := subRepo.FindByID(cancelled.ID); !stored.EndDate.Equal(cancelled.EndDate) {
+		t.Errorf("una suscripción cancelada fue extendida hasta %v", stored.EndDate)
+	}
+}
+
+// TestChangePlan_CreditsTheUnusedDaysAndChecksTheNewGroup moves a member to a
+// duo plan mid-cycle. The beneficiaries go through the same rules as a group
+// sold at the counter, and the old period is closed as a plan change, not as a
+// cancellation.
+func TestChangePlan_CreditsTheUnusedDaysAndChecksTheNewGroup(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, memberID := seedSubscriptions(t, db)
+	subRepo := persistence.NewSQLiteSubscriptionRepository(db)
+
+	duo := entities.NewPlan(plan.GymID, "Dúo", 30, 120000)
+	duo.MaxMembers = 2
+	if err := persistence.NewSQLitePlanRepository(db).Create(duo); err != nil {
+		t.Fatalf("creando plan dúo: %v", err)
+	}
+
+	sub, err := subUC.CreateSubscription(memberID, plan.ID, plan.GymID, 0, "", "CASH", nil, time.Time{}, nil, memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	// 10 de 30 días ya usados.
+	sub.StartDate = sub.StartDate.AddDate(0, 0, -10)
+	sub.EndDate = sub.EndDate.AddDate(0, 0, -10)
+	if err := subUC.UpdateSubscription(sub); err != nil {
+		t.Fatalf("UpdateSubscription: %v", err)
+	}
+
+	otherGym := entities.NewGym("Otro Gym", "otro@test.local", "3000000001")
+	if err := persistence.NewSQLiteGymRepository(db).Create(otherGym); err != nil {
+		t.Fatalf("creando otro gimnasio: %v", err)
+	}
+	stranger := seedMember(t, db, otherGym.ID, "ajeno@test.local")
+	if _, err := subUC.ChangePlan(sub.ID, duo.ID, []uuid.UUID{stranger}, "CASH", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("beneficiario de otro gimnasio: err = %v, want ErrInvalidInput", err)
+	}
+
+	holder := seedMember(t, db, plan.GymID, "titular@test.local")
+	partner := seedMember(t, db, plan.GymID, "pareja@test.local")
+	if _, err := subUC.CreateSubscription(holder, duo.ID, plan.GymID, 0, "", "CASH", []uuid.UUID{partner}, time.Time{}, nil, holder, "test", time.UTC); err != nil {
+		t.Fatalf("CreateSubscription dúo: %v", err)
+	}
+	if _, err := subUC.ChangePlan(sub.ID, duo.ID, []uuid.UUID{partner}, "CASH", memberID, "test", time.UTC); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("beneficiario de otro grupo vivo: err = %v, want ErrConflict", err)
+	}
+
+	friend := seedMember(t, db, plan.GymID, "amigo@test.local")
+	credit := sub.UnusedCredit(time.Now())
+	changed, err := subUC.ChangePlan(sub.ID, duo.ID, []uuid.UUID{friend}, "CASH", memberID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("ChangePlan: %v", err)
+	}
+	if changed.ProrationCredit < credit-1 || changed.ProrationCredit > credit || changed.TotalPaid != duo.Price-changed.ProrationCredit {
+		t.Errorf("nuevo periodo: crédito %.0f, a pagar %.0f; want ~%.0f y %.0f", changed.ProrationCredit, changed.TotalPaid, credit, duo.Price-credit)
+	}
+	if members, _ := subUC.GetSubscriptionMembers(changed.ID); len(members) != 2 {
+		t.Errorf("el grupo nuevo tiene %d miembros, want 2", len(members))
+	}
+	if history, _ := subUC.GetAuditLog(changed.ID); len(history) != 1 || history[0].Before != nil {
+		t.Errorf("historial del periodo nuevo = %+v, want una entrada sin estado anterior", history)
+	}
+
+	old, _ := subRepo.FindByID(sub.ID)
+	if old.Status != entities.SubscriptionStatusPlanChanged || old.CancelledAt != nil {
+		t.Errorf("periodo viejo = %s (cancelado %v), want PLAN_CHANGED sin cancelación", old.Status, old.CancelledAt)
+	}
+	if old.EndDate.After(time.Now()) {
+		t.Errorf("el periodo viejo termina %v, want cerrado hoy", old.EndDate)
+	}
+}
+
+// TestTransfer_HandsTheRestToTheReceiverAndKeepsTheGroup transfers a duo to its
+// own beneficiary: the receiver becomes the holder, the former holder takes
+// their seat, the fee is the only charge and the original is closed as a
+// transfer, not a cancellation.
+func TestTransfer_HandsTheRestToTheReceiverAndKeepsTheGroup(t *testing.T) {
+	db := newTestDB(t)
+	subUC, plan, holderID := seedSubscriptions(t, db)
+	subRepo := persistence.NewSQLiteSubscriptionRepository(db)
+
+	duo := entities.NewPlan(plan.GymID, "Dúo", 30, 120000)
+	duo.MaxMembers = 2
+	if err := persistence.NewSQLitePlanRepository(db).Create(duo); err != nil {
+		t.Fatalf("creando plan dúo: %v", err)
+	}
+	partnerID := seedMember(t, db, plan.GymID, "pareja@test.local")
+	source, err := subUC.CreateSubscription(holderID, duo.ID, plan.GymID, 0, "", "CASH", []uuid.UUID{partnerID}, time.Time{}, nil, holderID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	received, err := subUC.TransferSubscription(source.ID, partnerID, 15000, "CASH", holderID, "test", time.UTC)
+	if err != nil {
+		t.Fatalf("TransferSubscription: %v", err)
+	}
+	if received.UserID != partnerID || !received.EndDate.Equal(source.EndDate) {
+		t.Errorf("recibida por %s hasta %v, want %s hasta %v", received.UserID, received.EndDate, partnerID, source.EndDate)
+	}
+	if received.PricePaid != 0 || received.TotalPaid != 15000 || received.AmountPaid != 15000 {
+		t.Errorf("cobro del traspaso = precio %.0f, total %.0f, pagado %.0f; want 0, 15000, 15000", received.PricePaid, received.TotalPaid, received.AmountPaid)
+	}
+
+	if history, _ := subUC.GetAuditLog(received.ID); len(history) != 1 || history[0].Before != nil {
+		t.Errorf("historial de la recibida = %+v, want una entrada sin estado anterior", history)
+	}
+
+	members, _ := subUC.GetSubscriptionMembers(received.ID)
+	if len(members) != 2 {
+		t.Fatalf("el grupo traspasado tiene %d miembros, want 2", len(members))
+	}
+	for _, m := range members {
+		if m.IsPrimary != (m.UserID == partnerID) || (m.UserID != partnerID && m.UserID != holderID) {
+			t.Errorf("miembro %s (titular %v), want %s titular y %s beneficiario", m.UserID, m.IsPrimary, partnerID, holderID)
+		}
+	}
+
+	old, _ := subRepo.FindByID(source.ID)
+	if old.Status != entities.SubscriptionStatusTransferred || old.CancelledAt != nil || old.TotalPaid != source.TotalPaid {
+		t.Errorf("original = %s (cancelada %v, total %.0f), want TRANSFERRED sin cancelación y el total intacto", old.Status, old.CancelledAt, old.TotalPaid)
+	}
+
+	if _, err := subUC.TransferSubscription(source.ID, holderID, 0, "CASH", holderID, "test", time.UTC); !errors.Is(err, apperrors.ErrInvalidInput) {
+		t.Errorf("traspasar una suscripción ya traspasada: err = %v, want ErrInvalidInput", err)
+	}
+}
+
+// seedSubscriptions creates a gym, a monthly plan and a member, and returns a
+// wired SubscriptionUseCase.
+func seedSubscriptions(t *testing.T, db *gorm.DB) (*usecases.SubscriptionUseCase, *entities.Plan, uuid.UUID) {
+	t.Helper()
+
+	gymRepo := persistence.NewSQLiteGymRepository(db)
+	planRepo := persistence.NewSQLitePlanRepository(db)
+	userRepo := persistence.NewSQLiteUserRepository(db)
+
+	gym := entities.NewGym("Gym Test", "gym@test.local", "3000000000")
+	gym.Timezone = "America/Bogota"
+	if err := gymRepo.Create(gym); err != nil {
+		t.Fatalf("creando gimnasio: %v", err)
+	}
+
+	plan := entities.NewPlan(gym.ID, "Mensual", 30, 80000)
+	plan.EnrollmentFee = 20000
+	if err := planRepo.Create(plan); err != nil {
+		t.Fatalf("creando plan: %v", err)
+	}
+
+	member := entities.NewUser(gym.ID, "socio@test.local", "Ana", "Pérez", entities.RoleMember)
+	if err := userRepo.Create(member); err != nil {
+		t.Fatalf("creando socio: %v", err)
+	}
+
+	subUC := usecases.NewSubscriptionUseCase(
+		persistence.NewSQLiteSubscriptionRepository(db),
+		persistence.NewSQLiteSubscriptionMemberRepository(db),
+		planRepo,
+		userRepo,
+		persistence.NewSQLiteSubscriptionAuditLogRepository(db),
+		gymRepo,
+		persistence.NewSQLiteCouponRepository(db),
+		persistence.NewSQLitePaymentRepository(db),
+		persistence.NewUnitOfWork(db),
+	)
+	return subUC, plan, member.ID
+}
+
+func seedMember(t *testing.T, db *gorm.DB, gymID uuid.UUID, email string) uuid.UUID {
+	t.Helper()
+
+	member := entities.NewUser(gymID, email, "Socio", email, entities.RoleMember)
+	if err := persistence.NewSQLiteUserRepository(db).Create(member); err != nil {
+		t.Fatalf("creando socio %s: %v", email, err)
+	}
+	return member.ID
+}
@@ -22,13 +22,16 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sebastiancorrales/gym-go/internal/config"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/dian"
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/email"
+	"github.com/sebastiancorrales/gym-go/internal/infrastructure/gateway"
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/handlers"
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/http/middleware"
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence"
 	"github.com/sebastiancorrales/gym-go/internal/infrastructure/persistence/migrations"
 	"github.com/sebastiancorrales/gym-go/internal/usecases"
 	"github.com/sebastiancorrales/gym-go/pkg/security"
+	"github.com/sebastiancorrales/gym-go/pkg/timeutil"
 	"gorm.io/gorm"
 )
 
@@ -187,12 +190,19 @@ func main() {
 	paymentMethodRepo := persistence.NewSQLitePaymentMethodRepository(database.DB)
 	saleRepo := persistence.NewSQLiteSaleRepository(database.DB)
 	saleDetailRepo := persistence.NewSQLiteSaleDetailRepository(database.DB)
+	saleTenderRepo := persistence.NewSQLiteSaleTenderRepository(database.DB)
 	classRepo := persistence.NewSQLiteClassRepository(database.DB)
 	attendanceRepo := persistence.NewSQLiteAttendanceRepository(database.DB)
 	memberRepo := persistence.NewInMemoryMemberRepository()
 	instructorRepo := persistence.NewInMemoryInstructorRepository()
 	notifRecipientRepo := persistence.NewSQLiteNotificationRecipientRepository(database.DB)
 	deviceRepo := persistence.NewSQLiteDeviceRepository(database.DB)
+	paymentRepo := persistence.NewSQLitePaymentRepository(database.DB)
+	invoiceRepo := persistence.NewSQLiteInvoiceRepository(database.DB)
+	resolutionRepo := persistence.NewSQLiteInvoiceResolutionRepository(database.DB)
+	couponRepo := persistence.NewSQLiteCouponRepository(database.DB)
+	closureRepo := persistence.NewSQLiteGymClosureRepository(database.DB)
+	registerRepo := persistence.NewSQLiteCashRegisterRepository(database.DB)
 
 	// Unit of work for the flows that must be atomic (sales, voids, group
 	// subscriptions, date edits, gym registration). It rebuilds the repositories
@@ -202,14 +212,25 @@ func main() {
 	// Initialize use cases
 	userUseCase := usecases.NewUserUseCase(userRepo)
 	planUseCase := usecases.NewPlanUseCase(planRepo)
-	subscriptionUseCase := usecases.NewSubscriptionUseCase(subscriptionRepo, subscriptionMemberRepo, planRepo, userRepo, subscriptionAuditRepo, uow)
-	accessUseCase := usecases.NewAccessUseCase(accessLogRepo, userRepo, subscriptionRepo, subscriptionMemberRepo)
+	couponUseCase := usecases.NewCouponUseCase(couponRepo)
+	paymentUseCase := usecases.NewPaymentUseCase(paymentRepo)
+	registerUseCase := usecases.NewCashRegisterUseCase(registerRepo, paymentRepo, userRepo, uow)
+	subscriptionUseCase := usecases.NewSubscriptionUseCase(subscriptionRepo, subscriptionMemberRepo, planRepo, userRepo, subscriptionAuditRepo, gymRepo, couponRepo, paymentRepo, uow)
+	paymentGateway, err := newPaymentGateway(cfg.Gateway)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	onlinePaymentUseCase := usecases.NewOnlinePaymentUseCase(paymentGateway, subscriptionUseCase, paymentRepo, planRepo, userRepo)
+	invoiceUseCase := usecases.NewInvoiceUseCase(invoiceRepo, resolutionRepo, gymRepo, saleRepo, saleDetailRepo, productRepo, paymentRepo, subscriptionRepo, planRepo, userRepo, dian.NewStubSubmitter(), uow)
+	accessUseCase := usecases.NewAccessUseCase(accessLogRepo, userRepo, subscriptionRepo, subscriptionMemberRepo, planRepo, gymRepo, closureRepo, uow)
+	closureUseCase := usecases.NewGymClosureUseCase(closureRepo, gymRepo, uow)
 	biometricService := usecases.NewBiometricService(fingerprintRepo, userRepo)
 	productUseCase := usecases.NewProductUseCase(productRepo)
 	paymentMethodUseCase := usecases.NewPaymentMethodUseCase(paymentMethodRepo)
-	saleUseCase := usecases.NewSaleUseCase(saleRepo, saleDetailRepo, productRepo, paymentMethodRepo, uow)
+	saleUseCase := usecases.NewSaleUseCase(saleRepo, saleDetailRepo, saleTenderRepo, productRepo, paymentMethodRepo, couponRepo, gymRepo, uow)
 	classUseCase := usecases.NewClassUseCase(classRepo, instructorRepo)
 	attendanceUseCase := usecases.NewAttendanceUseCase(attendanceRepo, memberRepo, classRepo)
+	receiptUseCase := usecases.NewReceiptUseCase(saleUseCase, subscriptionUseCase, deviceRepo, gymRepo, planRepo, userRepo)
 
 	emailSender := email.NewSender(email.Config{
 		Host:     cfg.SMTP.Host,
@@ -228,19 +249,29 @@ func main() {
 		userRepo,
 		paymentMethodRepo,
 		productRepo,
+		paymentRepo,
+		couponRepo,
 		emailSender,
 	)
+	statementUseCase := usecases.NewStatementUseCase(userRepo, gymRepo, subscriptionRepo, planRepo, paymentRepo, saleDetailRepo, productRepo, notifUseCase)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(userRepo, gymRepo, jwtManager)
 	registerHandler := handlers.NewRegisterHandler(gymRepo, userRepo, jwtManager, uow)
 	userHandler := handlers.NewUserHandler(userUseCase, subscriptionUseCase, planUseCase)
 	planHandler := handlers.NewPlanHandler(planUseCase)
-	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionUseCase, userUseCase, planUseCase)
+	couponHandler := handlers.NewCouponHandler(couponUseCase)
+	paymentHandler := handlers.NewPaymentHandler(paymentUseCase)
+	cashRegisterHandler := handlers.NewCashRegisterHandler(registerUseCase, notifUseCase)
+	onlinePaymentHandler := handlers.NewOnlinePaymentHandler(onlinePaymentUseCase)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceUseCase)
+	statementHandler := handlers.NewStatementHandler(statementUseCase)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionUseCase, userUseCase, planUseCase, receiptUseCase)
 	productHandler := handlers.NewProductHandler(productUseCase)
 	paymentMethodHandler := handlers.NewPaymentMethodHandler(paymentMethodUseCase)
-	saleHandler := handlers.NewSaleHandler(saleUseCase)
+	saleHandler := handlers.NewSaleHandler(saleUseCase, receiptUseCase)
 	gymHandler := handlers.NewGymHandler(gymRepo)
+	closureHandler := handlers.NewGymClosureHandler(closureUseCase)
 	classHandler := handlers.NewClassHandler(classUseCase)
 	attendanceHandler := handlers.NewAttendanceHandler(attendanceUseCase)
 	accessHandler := handlers.NewAccessHandler(accessUseCase)
@@ -278,6 +309,12 @@ func main() {
 			})
 		})
 
+		// Online payment gateway callback, authenticated by its signature. With
+		// no gateway configured there is nothing for it to confirm.
+		if paymentGateway != nil {
+			public.POST("/payments/webhook", onlinePaymentHandler.Webhook)
+		}
+
 		// Auth routes
 		auth := public.Group("/auth")
 		{
@@ -326,6 +363,11 @@ func main() {
 		{
 			gym.GET("", gymHandler.Get)
 			gym.PUT("", gymHandler.Update)
+			gym.GET("/closures", closureHandler.List)
+			gym.POST("/closures", closureHandler.Create)
+			gym.DELETE("/closures/:id", closureHandler.Delete)
+			gym.GET("/invoice-resolutions", invoiceHandler.ListResolutions)
+			gym.POST("/invoice-resolutions", invoiceHandler.CreateResolution)
 		}
 
 		// Profile route - any authenticated user can change their own password
@@ -382,6 +424,7 @@ func main() {
 			users.PUT("/:id", userHandler.Update)
 			users.DELETE("/:id", userHandler.Delete)
 			users.GET("/:id/profile", userHandler.GetProfile)
+			users.GET("/:id/statement", statementHandler.Get)
 		}
 
 		// Plan routes - Only SUPER_ADMIN and ADMIN_GYM can manage plans
@@ -395,20 +438,82 @@ func main() {
 			plans.DELETE("/:id", planHandler.Deactivate)
 		}
 
+		// Coupon routes - Only SUPER_ADMIN and ADMIN_GYM create them; reception
+		// only types the code at the counter.
+		coupons := protected.Group("/coupons")
+		coupons.Use(middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM"))
+		{
+			coupons.GET("", couponHandler.List)
+			coupons.POST("", couponHandler.Create)
+			coupons.PUT("/:id", couponHandler.Update)
+			coupons.DELETE("/:id", couponHandler.Deactivate)
+			coupons.GET("/:id/redemptions", couponHandler.Redemptions)
+		}
+
+		// Payments ledger - Only SUPER_ADMIN and ADMIN_GYM; it is written by the
+		// subscription and sale routes.
+		payments := protected.Group("/payments")
+		payments.Use(middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM"))
+		{
+			payments.GET("", paymentHandler.List)
+			payments.GET("/revenue", paymentHandler.Revenue)
+		}
+
+		// Cash register shifts - every role that takes money. A receptionist only
+		// sees and closes their own register (checked in the handler).
+		cashRegisters := protected.Group("/cash-registers")
+		cashRegisters.Use(middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM", "RECEPCIONISTA"))
+		{
+			cashRegisters.POST("/open", cashRegisterHandler.Open)
+			cashRegisters.GET("/current", cashRegisterHandler.Current)
+			cashRegisters.GET("", middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM"), cashRegisterHandler.List)
+			cashRegisters.GET("/:id", cashRegisterHandler.Get)
+			cashRegisters.POST("/:id/close", cashRegisterHandler.Close)
+		}
+
+		// DIAN electronic invoices - reception invoices what it sells; the
+		// listing and the catch-up of the day are for the admins.
+		invoices := protected.Group("/invoices")
+		invoices.Use(middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM", "RECEPCIONISTA"))
+		{
+			invoices.GET("", middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM"), invoiceHandler.List)
+			invoices.POST("/pending", middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM"), invoiceHandler.InvoicePending)
+			invoices.POST("/sales/:id", invoiceHandler.InvoiceSale)
+			invoices.POST("/payments/:id", invoiceHandler.InvoicePayment)
+			invoices.GET("/:id", invoiceHandler.Get)
+			invoices.GET("/:id/xml", invoiceHandler.XML)
+			invoices.GET("/:id/pdf", invoiceHandler.PDF)
+			invoices.POST("/:id/submit", middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM"), invoiceHandler.Submit)
+		}
+
 		// Subscription routes - Multiple roles can access
 		subscriptions := protected.Group("/subscriptions")
 		subscriptions.Use(middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM", "RECEPCIONISTA"))
 		{
 			subscriptions.GET("", subscriptionHandler.List)
 			subscriptions.POST("", subscriptionHandler.Create)
+			if paymentGateway != nil {
+				subscriptions.POST("/checkout", onlinePaymentHandler.Checkout)
+			}
 			subscriptions.GET("/stats", subscriptionHandler.GetStats)
 			subscriptions.GET("/report", subscriptionHandler.Report)
+			subscriptions.POST("/extend", middleware.RequireRole("SUPER_ADMIN", "ADMIN_GYM"), subscriptionHandler.Extend)
 			subscriptions.POST("/:id/cancel", subscriptionHandler.Cancel)
 			subscriptions.POST("/:id/renew", subscriptionHandler.Renew)
+			subscriptions.POST("/:id/change-plan", subscriptionHandler.ChangePlan)
+			subscriptions.POST("/:id/transfer", subscriptionHandler.Transfer)
+			subscriptions.GET("/:id/payments", subscriptionHandler.ListPayments)
+			subscriptions.POST("/:id/print", subscriptionHandler.Print)
+			subscriptions.POST("/:id/payments", subscriptionHandler.RecordPayment)
 			subscriptions.POST("/:id/freeze", subscriptionHandler.Freeze)
 			subscriptions.POST("/:id/unfreeze", subscriptionHandler.Unfreeze)
 			subscriptions.PATCH("/:id/dates", subscriptionHandler.UpdateDates)
+			subscriptions.PATCH("/:id/auto-renew", subscriptionHandler.SetAutoRenew)
 			subscriptions.GET("/:id/audit", subscriptionHandler.GetAuditLog)
+			subscriptions.GET("/:id/members", subscriptionHandler.ListMembers)
+			subscriptions.POST("/:id/members", subscriptionHandler.AddMember)
+			subscriptions.PUT("/:id/members/:userId", subscriptionHandler.ReplaceMember)
+			subscriptions.DELETE("/:id/members/:userId", subscriptionHandler.RemoveMember)
 		}
 
 		// Access routes - Multiple roles can access
@@ -518,6 +623,8 @@ func main() {
 			sales.GET("/:id", saleHandler.GetSale)
 			sales.POST("", saleHandler.CreateSale)
 			sales.POST("/:id/void", saleHandler.VoidSale)
+			sales.POST("/:id/return", saleHandler.ReturnSale)
+			sales.POST("/:id/print", saleHandler.PrintSale)
 		}
 	}
 
@@ -563,7 +670,31 @@ func main() {
 	// The offset is deliberate: on the hour it collided with the backup and the
 	// daily close, and the expiry pass takes a write lock over the subscriptions
 	// table. Minute 17 keeps the background jobs out of each other's way.
+	//
+	// Auto-renew goes first: anything it renews already has its next period by the
+	// time the expiry pass looks at it.
+	defaultLoc := timeutil.LoadLocationOrUTC(cfg.App.DefaultTimezone)
 	go runHourlyAt(rootCtx, 17, func() {
+		if n, err := subscriptionUseCase.AutoRenewSubscriptions(cfg.App.AutoRenewGrace, defaultLoc); err != nil {
+			log.Printf("⚠️ Auto-renew error: %v", err)
+		} else if n > 0 {
+			log.Printf("🔁 Renovadas automáticamente %d suscripciones", n)
+		}
+
+		if n, err := subscriptionUseCase.ActivateDueSubscriptions(); err != nil {
+			log.Printf("⚠️ Activate-pending error: %v", err)
+		} else if n > 0 {
+			log.Printf("▶️ Iniciadas %d suscripciones con fecha de inicio futura", n)
+		}
+
+		// Before the expiry pass: a day given back for a closure may keep a
+		// subscription from expiring.
+		if n, err := closureUseCase.CompensateClosures(time.Now()); err != nil {
+			log.Printf("⚠️ Closure compensation error: %v", err)
+		} else if n > 0 {
+			log.Printf("📅 Extendidas %d suscripciones por días de cierre", n)
+		}
+
 		if n, err := subscriptionUseCase.AutoExpireSubscriptions(); err != nil {
 			log.Printf("⚠️ Auto-expire error: %v", err)
 		} else if n > 0 {
@@ -759,6 +890,39 @@ func runHourlyAt(ctx context.Context, minute int, task func()) {
 	}()
 }
 
+// newPaymentGateway builds the online payment gateway the config asks for, or
+// nil when PAYMENT_GATEWAY is unset and online payments are off. The webhook
+// is public and only its signature authenticates it, so a gateway without
+// PAYMENT_WEBHOOK_SECRET is refused. "fake" charges nothing and confirms
+// whatever is signed: it is for development and has to be asked for by name.
+func newPaymentGateway(cfg config.GatewayConfig) (gateway.PaymentGateway, error) {
+	switch cfg.Provider {
+	case "":
+		log.Println("💳 PAYMENT_GATEWAY sin configurar: pagos en línea deshabilitados")
+		return nil, nil
+	case "mercadopago", "fake":
+	default:
+		return nil, fmt.Errorf("PAYMENT_GATEWAY=%q no es una pasarela conocida (mercadopago o fake)", cfg.Provider)
+	}
+	if cfg.WebhookSecret == "" {
+		return nil, fmt.Errorf("PAYMENT_GATEWAY=%s requiere PAYMENT_WEBHOOK_SECRET: sin él cualquiera podría confirmar un pago", cfg.Provider)
+	}
+
+	webhookURL := strings.TrimRight(cfg.PublicURL, "/") + "/api/v1/payments/webhook"
+	if cfg.Provider == "mercadopago" {
+		if cfg.MercadoPagoAccessToken == "" {
+			log.Println("⚠️  PAYMENT_GATEWAY=mercadopago sin MERCADOPAGO_ACCESS_TOKEN: no se podrán generar links de pago")
+		}
+		return gateway.NewMercadoPagoGateway(gateway.MercadoPagoConfig{
+			AccessToken:     cfg.MercadoPagoAccessToken,
+			WebhookSecret:   cfg.WebhookSecret,
+			NotificationURL: webhookURL,
+		}), nil
+	}
+	log.Printf("⚠️  Pasarela de pagos de prueba, solo para desarrollo: los pagos se confirman con un POST firmado a %s", webhookURL)
+	return gateway.NewFakeGateway(cfg.PublicURL, cfg.WebhookSecret), nil
+}
+
 // corsMiddleware provides basic CORS support
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
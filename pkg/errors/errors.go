@@ -22,6 +22,13 @@ var (
 	ErrProductNotActive       = errors.New("el producto no está activo")
 	ErrSaleCannotBeVoided     = errors.New("la venta no puede ser anulada")
 	ErrPaymentMethodNotActive = errors.New("método de pago no activo")
+	ErrFreezeNotAllowed       = errors.New("congelamiento no permitido por el plan")
+	ErrInvalidCoupon          = errors.New("cupón no válido")
+
+	// ErrInvalidTransition señala un cambio de estado que el ciclo de vida de
+	// la entidad no permite (congelar una suscripción cancelada, por ejemplo).
+	// La capa HTTP lo traduce a 409.
+	ErrInvalidTransition = errors.New("transición de estado inválida")
 
 	// ErrDatabaseBusy señala contención de locks en SQLite: es un fallo
 	// transitorio y reintentable, no un error de negocio. La capa HTTP lo